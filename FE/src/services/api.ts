@@ -66,18 +66,19 @@ export const api = {
       return handleResponse(response);
     },
 
-getAll: async (): Promise<User[]> => {
-  const response = await fetch(`${API_URL}/users/all`, {
+getAll: async (query = ""): Promise<User[]> => {
+  const params = new URLSearchParams({ q: query, limit: "100" });
+  const response = await fetch(`${API_URL}/users?${params}`, {
     headers: getAuthHeaders(),
   });
   const data = await handleResponse(response);
   
-  return data.map(user => ({
-    id: user.ID,
-    username: user.Username,
-    email: user.Email,
-    status: user.Status,
-    last_active: user.LastActiveAt
+  return data.users.map(user => ({
+    id: user.id,
+    username: user.username,
+    email: user.email ?? "",
+    status: user.status,
+    last_active: user.last_active
   }));
 },
 
@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"runtime/debug"
+	"time"
 
 	"github.com/AmeerHeiba/chatting-service/internal/application"
 	"github.com/AmeerHeiba/chatting-service/internal/config"
 	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
 	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/middleware"
 	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/routes"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/auth"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/database"
+	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/imaging"
+	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/mail"
+	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/oidc"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/realtime"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/storage"
 
@@ -55,7 +61,8 @@ func main() {
 
 	// Initialize core dependencies
 	db := initDB()
-	app := fiber.New()
+	// Direct uploads and upload chunks arrive as a single body
+	app := fiber.New(fiber.Config{BodyLimit: config.LoadUploadConfig().BodyLimit()})
 
 	shared.InitLogger(os.Getenv("APP_ENV"))
 	app.Use(recover.New(recover.Config{
@@ -65,10 +72,10 @@ func main() {
 
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "http://127.0.0.1:8081",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Access-Control-Allow-Origin",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Access-Control-Allow-Origin, Upload-Offset",
 		AllowCredentials: true,
-		ExposeHeaders:    "Content-Length",
+		ExposeHeaders:    "Content-Length, Upload-Offset",
 	}))
 
 	app.Static("/swagger", "./docs")
@@ -109,12 +116,15 @@ func initDB() *gorm.DB {
 
 func initDependencies(db *gorm.DB) routes.Dependencies {
 	// Initialize storage
-	localStorage := storage.NewLocalStorage(
-		os.Getenv("MEDIA_STORAGE_PATH"),
-		os.Getenv("MEDIA_BASE_URL"),
-	)
-	mediaService := application.NewMediaService(localStorage)
-	mediaHandler := handlers.NewMediaHandler(mediaService)
+	storageCfg := config.LoadStorageConfig()
+	if err := storageCfg.Validate(); err != nil {
+		log.Fatalf("Storage config invalid: %v", err)
+	}
+	mediaStorage := initMediaStorage(storageCfg)
+	uploadCfg := config.LoadUploadConfig()
+	if err := uploadCfg.Validate(); err != nil {
+		log.Fatalf("Upload config invalid: %v", err)
+	}
 
 	// Initialize WebSocket notifier (implements MessageNotifier interface)
 	wsNotifier := realtime.NewWebSocketNotifier()
@@ -123,12 +133,88 @@ func initDependencies(db *gorm.DB) routes.Dependencies {
 	userRepo := database.NewUserRepository(db)
 	messageRepo := database.NewMessageRepository(db)
 	messageRecipientRepo := database.NewMessageRecipientRepository(db)
+	emailVerificationRepo := database.NewEmailVerificationRepository(db)
+	loginAttemptRepo := database.NewLoginAttemptRepository(db)
+	apiTokenRepo := database.NewAPITokenRepository(db)
+	externalIdentityRepo := database.NewExternalIdentityRepository(db)
+	oidcStateRepo := database.NewOIDCStateRepository(db)
+	userBlockRepo := database.NewUserBlockRepository(db)
+	notificationPreferenceRepo := database.NewNotificationPreferenceRepository(db)
+	contactRepo := database.NewContactRepository(db)
+	announcementRepo := database.NewAnnouncementRepository(db)
+	mediaRepo := database.NewMediaRepository(db)
+	txManager := database.NewTransactionManager(db)
+	auditLogger := application.NewAuditLogger(database.NewAuditEventRepository(db))
+
+	// Mailer (falls back to logging emails when SMTP is not configured)
+	mailCfg := config.LoadMailConfig()
+	var mailer domain.Mailer = mail.NewLogMailer()
+	if mailCfg.SMTPEnabled() {
+		mailer = mail.NewSMTPMailer(mailCfg)
+	}
 
 	// Services
-	userService := application.NewUserService(userRepo)
+	mediaService := application.NewMediaService(application.MediaServiceDeps{
+		Storage:       mediaStorage,
+		Resizer:       imaging.NewResizer(imaging.DefaultMaxPixels),
+		Messages:      messageRepo,
+		Announcements: announcementRepo,
+		SignedURLTTL:  storageCfg.SignedURLTTL,
+		Media:         mediaRepo,
+		Uploads:       uploadCfg,
+		Stripper:      imaging.NewMetadataStripper(imaging.DefaultMaxPixels),
+		Transactions:  txManager,
+		Users:         userRepo,
+	})
+	go mediaService.RunJanitor(context.Background(), time.Hour)
+	if storageCfg.OrphanInterval > 0 {
+		orphanCollector := application.NewOrphanCollector(mediaStorage, database.NewMediaReferenceRepository(db), storageCfg.OrphanGracePeriod, mediaService)
+		go orphanCollector.RunJanitor(context.Background(), storageCfg.OrphanInterval)
+	}
+	resumableUploadService := application.NewResumableUploadService(mediaService, database.NewMediaUploadRepository(db))
+	go resumableUploadService.RunJanitor(context.Background(), time.Hour)
+	emailVerificationService := application.NewEmailVerificationService(userRepo, emailVerificationRepo, mailer, mailCfg)
+	passwordPolicy, err := application.NewPasswordPolicy(config.LoadPasswordPolicyConfig())
+	if err != nil {
+		log.Fatalf("Password policy setup failed: %v", err)
+	}
+	userService := application.NewUserService(userRepo, emailVerificationService, passwordPolicy, auditLogger, userBlockRepo, mediaService)
 	authCfg := config.LoadAuthConfig()
 	jwtProvider := auth.NewJWTProvider(authCfg)
-	authService := application.NewAuthService(userRepo, userService, jwtProvider)
+	loginGuard := application.NewLoginGuard(loginAttemptRepo, config.LoadLoginProtectionConfig(), auditLogger)
+
+	// Single sign-on is only wired when an issuer is configured
+	var oidcLogin *application.OIDCLogin
+	if oidcCfg := config.LoadOIDCConfig(); oidcCfg.Enabled() {
+		oidcLogin = application.NewOIDCLogin(oidc.NewClient(oidcCfg), oidcStateRepo, externalIdentityRepo, userRepo, userService, oidcCfg)
+	}
+	authService := application.NewAuthService(userRepo, userService, jwtProvider, loginGuard, oidcLogin, auditLogger)
+	apiTokenService := application.NewAPITokenService(apiTokenRepo, userRepo, auditLogger)
+	preferenceService := application.NewNotificationPreferenceService(notificationPreferenceRepo, txManager)
+	contactService := application.NewContactService(contactRepo, userRepo, userBlockRepo)
+	accountCfg := config.LoadAccountConfig()
+	if err := accountCfg.Validate(); err != nil {
+		log.Fatalf("Account config invalid: %v", err)
+	}
+	accountService := application.NewAccountService(txManager, userRepo, mediaStorage, wsNotifier, accountCfg, auditLogger)
+	userAdminService := application.NewUserAdminService(userRepo, messageRepo, apiTokenRepo, mediaRepo, wsNotifier, auditLogger, emailVerificationService)
+	exportService := application.NewExportService(
+		database.NewDataExportRepository(db),
+		userRepo,
+		messageRepo,
+		notificationPreferenceRepo,
+		contactRepo,
+		userBlockRepo,
+		mediaStorage,
+		storage.NewLocalArchiveStore(accountCfg.ExportStoragePath),
+		accountCfg,
+		auditLogger,
+	)
+	go exportService.RunJanitor(context.Background(), time.Hour)
+	messagingCfg := config.LoadMessagingConfig()
+	if err := messagingCfg.Validate(); err != nil {
+		log.Fatalf("Messaging config invalid: %v", err)
+	}
 
 	// Message service with WebSocket notifier
 	messageService := application.NewMessageService(
@@ -137,20 +223,56 @@ func initDependencies(db *gorm.DB) routes.Dependencies {
 		userRepo,
 		wsNotifier,
 		mediaService,
+		messagingCfg,
+		auditLogger,
+		userBlockRepo,
+		preferenceService,
+		contactRepo,
 	)
 
+	announcementService := application.NewAnnouncementService(announcementRepo, userRepo, wsNotifier, auditLogger)
+
 	// WebSocket handler (for routes)
 	wsHandler := handlers.NewWebSocketHandler(wsNotifier)
 
 	return routes.Dependencies{
-		DB:             db,
-		UserHandler:    handlers.NewUserHandler(userService),
-		AuthHandler:    handlers.NewAuthHandler(authService),
-		MessageHandler: handlers.NewMessageHandler(messageService),
-		MediaHandler:   mediaHandler,
-		WSHandler:      wsHandler,
-		JWTProvider:    jwtProvider,
+		DB:                  db,
+		UserHandler:         handlers.NewUserHandler(userService, preferenceService, accountService),
+		AuthHandler:         handlers.NewAuthHandler(authService),
+		MessageHandler:      handlers.NewMessageHandler(messageService),
+		MediaHandler:        handlers.NewMediaHandler(mediaService, resumableUploadService),
+		MediaFilesPath:      mediaFilesPath(storageCfg),
+		WSHandler:           wsHandler,
+		AdminHandler:        handlers.NewAdminHandler(authService, auditLogger, accountService, userAdminService),
+		TokenHandler:        handlers.NewTokenHandler(apiTokenService, userService),
+		ContactHandler:      handlers.NewContactHandler(contactService),
+		ExportHandler:       handlers.NewExportHandler(exportService),
+		AnnouncementHandler: handlers.NewAnnouncementHandler(announcementService),
+		JWTProvider:         jwtProvider,
+		APITokenValidator:   apiTokenService,
+		SessionValidator:    application.NewSessionGuard(userRepo),
+	}
+}
+
+// initMediaStorage picks the media backend. Local disk only works with a single
+// instance, deployments with several replicas need the shared s3 driver.
+func initMediaStorage(storageCfg config.StorageConfig) domain.MediaStorage {
+	mediaStorage, err := storage.NewMediaStorage(storageCfg)
+	if err != nil {
+		log.Fatalf("Media storage setup failed: %v", err)
+	}
+	if storageCfg.Driver == config.StorageS3 {
+		log.Printf("Storing media in bucket %s at %s", storageCfg.S3.Bucket, storageCfg.S3.Endpoint)
+	}
+	return mediaStorage
+}
+
+// mediaFilesPath is where the API serves local media, object stores serve their own
+func mediaFilesPath(storageCfg config.StorageConfig) string {
+	if storageCfg.Driver == config.StorageS3 {
+		return ""
 	}
+	return storageCfg.FilesPath()
 }
 
 func startServer(app *fiber.App) {
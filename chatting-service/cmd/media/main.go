@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/database"
+	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/storage"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: media <command> [flags]
+
+Commands:
+  gc    Delete stored files nothing refers to anymore
+`
+
+func main() {
+	// Same environment as the API, which may come from a .env file
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "gc":
+		collectOrphans(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+func collectOrphans(args []string) {
+	storageCfg := config.LoadStorageConfig()
+
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only report orphans, delete nothing")
+	grace := flags.Duration("grace", storageCfg.OrphanGracePeriod, "keep files younger than this")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args)
+
+	storageCfg.OrphanGracePeriod = *grace
+	if err := storageCfg.Validate(); err != nil {
+		log.Fatalf("Storage config invalid: %v", err)
+	}
+	shared.InitLogger(os.Getenv("APP_ENV"))
+
+	db, err := config.NewDBConnection(config.LoadDBConfig())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	mediaStorage, err := storage.NewMediaStorage(storageCfg)
+	if err != nil {
+		log.Fatalf("Media storage setup failed: %v", err)
+	}
+
+	// Unsent library entries are released the way their owner would delete them
+	mediaService := application.NewMediaService(application.MediaServiceDeps{
+		Storage:      mediaStorage,
+		Media:        database.NewMediaRepository(db),
+		Transactions: database.NewTransactionManager(db),
+	})
+	collector := application.NewOrphanCollector(mediaStorage, database.NewMediaReferenceRepository(db), storageCfg.OrphanGracePeriod, mediaService)
+	report, err := collector.Collect(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatalf("Collecting orphaned media failed: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Writing report failed: %v", err)
+		}
+	} else {
+		for _, file := range report.Unsent {
+			fmt.Printf("%s\t%d\t%s\tunsent\n", file.ModifiedAt.Format("2006-01-02 15:04"), file.Size, file.Path)
+		}
+		for _, file := range report.Orphans {
+			fmt.Printf("%s\t%d\t%s\n", file.ModifiedAt.Format("2006-01-02 15:04"), file.Size, file.Path)
+		}
+		fmt.Printf("Scanned %d files, %d orphans (%d bytes), %d unsent library entries", report.Scanned, len(report.Orphans), report.Bytes, len(report.Unsent))
+		if report.DryRun {
+			fmt.Println(", nothing deleted in a dry run")
+		} else {
+			fmt.Printf(", deleted %d and released %d\n", report.Deleted, report.Released)
+		}
+	}
+	if report.Failed > 0 {
+		log.Fatalf("%d orphans or unsent entries could not be removed", report.Failed)
+	}
+}
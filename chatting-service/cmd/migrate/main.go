@@ -34,6 +34,7 @@ func runMigrations(db *gorm.DB) error {
 		`CREATE TYPE message_status AS ENUM ('sent', 'delivered', 'read')`,
 		`CREATE TYPE user_status AS ENUM ('online', 'offline', 'away')`,
 		`CREATE TYPE message_type AS ENUM ('direct', 'broadcast')`,
+		`CREATE TYPE user_role AS ENUM ('user', 'admin')`,
 	}
 
 	for _, e := range enums {
@@ -47,6 +48,35 @@ func runMigrations(db *gorm.DB) error {
 		&domain.User{},
 		&domain.Message{},
 		&domain.MessageRecipient{},
+		&domain.EmailVerification{},
+		&domain.LoginAttempt{},
+		&domain.APIToken{},
+		&domain.ExternalIdentity{},
+		&domain.OIDCLoginState{},
+		&domain.AuditEvent{},
+		&domain.UserBlock{},
+		&domain.NotificationPreferences{},
+		&domain.Mute{},
+		&domain.Contact{},
+		&domain.DataExport{},
+		&domain.AnnouncementChannel{},
+		&domain.AnnouncementPoster{},
+		&domain.Announcement{},
+		&domain.AnnouncementReadState{},
+		&domain.Media{},
+		&domain.MediaBlob{},
+		&domain.MediaUsage{},
+		&domain.MediaUploadSession{},
+	}
+
+	// Uploads of the same content now share a path, AutoMigrate only adds the plain index
+	dropUniquePath := `DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM pg_indexes WHERE indexname = 'idx_media_path' AND indexdef LIKE 'CREATE UNIQUE%') THEN
+			DROP INDEX idx_media_path;
+		END IF;
+	END $$`
+	if err := db.Exec(dropUniquePath).Error; err != nil {
+		return fmt.Errorf("failed to drop unique media path index: %w", err)
 	}
 
 	for _, model := range models {
@@ -55,5 +85,44 @@ func runMigrations(db *gorm.DB) error {
 		}
 	}
 
+	// Media uploaded before usage was counted
+	backfillUsage := `INSERT INTO media_usages (owner_id, bytes, updated_at)
+		SELECT owner_id, SUM(size), NOW() FROM media GROUP BY owner_id
+		ON CONFLICT (owner_id) DO NOTHING`
+	if err := db.Exec(backfillUsage).Error; err != nil {
+		return fmt.Errorf("failed to count media usage: %w", err)
+	}
+
+	// Keep the audit trail append-only even for direct database access
+	guards := []string{
+		`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_events is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events`,
+		`CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+		FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+	}
+
+	for _, g := range guards {
+		if err := db.Exec(g).Error; err != nil {
+			return fmt.Errorf("failed to install audit guard: %w", err)
+		}
+	}
+
+	// Trigram indexes back the user directory substring search
+	indexes := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (username gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_users_display_name_trgm ON users USING gin (display_name gin_trgm_ops)`,
+	}
+
+	for _, idx := range indexes {
+		if err := db.Exec(idx).Error; err != nil {
+			return fmt.Errorf("failed to create search index: %w", err)
+		}
+	}
+
 	return nil
 }
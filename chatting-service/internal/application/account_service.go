@@ -0,0 +1,157 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+// AccountService erases accounts on request of their owner or an admin. Database
+// changes happen in one transaction, files and sockets are cleaned up after it commits.
+type AccountService struct {
+	txManager   domain.TransactionManager
+	userRepo    domain.UserRepository
+	storage     domain.MediaStorage     // Optional, uploaded files are kept when nil
+	connections domain.ConnectionCloser // Optional
+	cfg         config.AccountConfig
+	audit       *AuditLogger
+}
+
+func NewAccountService(txManager domain.TransactionManager, userRepo domain.UserRepository, storage domain.MediaStorage, connections domain.ConnectionCloser, cfg config.AccountConfig, audit *AuditLogger) *AccountService {
+	return &AccountService{
+		txManager:   txManager,
+		userRepo:    userRepo,
+		storage:     storage,
+		connections: connections,
+		cfg:         cfg,
+		audit:       audit,
+	}
+}
+
+// DeleteOwnAccount erases the caller's account once they confirmed their password
+func (s *AccountService) DeleteOwnAccount(ctx context.Context, userID uint, password string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.CheckPassword(password) {
+		s.audit.Record(ctx, domain.AuditEvent{
+			ActorID:    auditActor(userID),
+			Action:     domain.AuditAccountDelete,
+			Outcome:    domain.AuditDenied,
+			TargetType: "user",
+			TargetID:   auditID(userID),
+			Details:    "password confirmation failed",
+		})
+		return shared.ErrInvalidCredentials.WithDetails("password confirmation failed")
+	}
+	return s.erase(ctx, userID, user)
+}
+
+// DeleteAccount is the admin variant, used for erasure requests received out of band
+func (s *AccountService) DeleteAccount(ctx context.Context, adminID, userID uint) error {
+	if adminID == userID {
+		return shared.ErrBadRequest.WithDetails("delete your own account through /api/users/me")
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return shared.ErrUserNotFound
+	}
+	return s.erase(ctx, adminID, user)
+}
+
+func (s *AccountService) erase(ctx context.Context, actorID uint, user *domain.User) error {
+	// Bots belong to their owner and go with the account
+	bots, err := s.userRepo.FindBotsByOwner(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	userIDs := []uint{user.ID}
+	for _, bot := range bots {
+		userIDs = append(userIDs, bot.ID)
+	}
+
+	tombstone := s.cfg.DeletedMessagePolicy != config.DeletedMessagesDelete
+	err = s.txManager.WithTransaction(ctx, func(ctx context.Context, repos *domain.Repositories) error {
+		for _, id := range userIDs {
+			if err := eraseUserData(ctx, repos, id, tombstone); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		shared.Log.Error("erase account failed", zap.Error(err), zap.Uint("userID", user.ID))
+		return err
+	}
+
+	// Neither can be rolled back, so they only run once the erasure is committed
+	for _, id := range userIDs {
+		if s.connections != nil {
+			s.connections.CloseUserConnections(id, "account deleted")
+		}
+		if s.storage != nil {
+			if err := s.storage.DeleteUserFiles(ctx, id); err != nil {
+				shared.Log.Error("delete user files failed", zap.Error(err), zap.Uint("userID", id))
+			}
+		}
+	}
+
+	policy := config.DeletedMessagesDelete
+	if tombstone {
+		policy = config.DeletedMessagesTombstone
+	}
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(actorID),
+		Action:     domain.AuditAccountDelete,
+		TargetType: "user",
+		TargetID:   auditID(user.ID),
+		Details:    fmt.Sprintf("messages: %s, bots: %d", policy, len(bots)),
+	})
+	return nil
+}
+
+// eraseUserData removes everything tied to one user. Revoked tokens are kept so
+// they can never validate again, the anonymized user row keeps foreign keys intact.
+func eraseUserData(ctx context.Context, repos *domain.Repositories, userID uint, tombstone bool) error {
+	if err := repos.Messages.EraseFromSender(ctx, userID, tombstone); err != nil {
+		return err
+	}
+	if err := repos.APITokens.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := repos.ExternalIdentities.DeleteForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := repos.EmailVerifications.DeleteForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := repos.Contacts.DeleteAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := repos.Blocks.DeleteAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := repos.NotificationPreferences.DeleteForUser(ctx, userID); err != nil {
+		return err
+	}
+	// Finished exports stop being downloadable and the janitor removes the archives
+	if err := repos.DataExports.ExpireForUser(ctx, userID, time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := repos.Announcements.DeleteForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := repos.Media.DeleteForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := repos.MediaUploads.DeleteForUser(ctx, userID); err != nil {
+		return err
+	}
+	return repos.Users.Anonymize(ctx, userID)
+}
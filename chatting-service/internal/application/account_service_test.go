@@ -0,0 +1,101 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// stubTransactionManager reports the outcome of the transaction without running it,
+// the repository work itself is covered by the database layer
+type stubTransactionManager struct {
+	err   error
+	calls int
+}
+
+func (m *stubTransactionManager) WithTransaction(ctx context.Context, fn func(ctx context.Context, repos *domain.Repositories) error) error {
+	m.calls++
+	return m.err
+}
+
+type MockConnectionCloser struct {
+	mock.Mock
+}
+
+func (m *MockConnectionCloser) CloseUserConnections(userID uint, reason string) {
+	m.Called(userID, reason)
+}
+
+func accountOwner(t *testing.T) *domain.User {
+	user := &domain.User{Model: gorm.Model{ID: 1}, Username: "johndoe"}
+	assert.NoError(t, user.SetPassword("Password123"))
+	return user
+}
+
+func TestAccountService_DeleteOwnAccount(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("RequiresPassword", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(accountOwner(t), nil)
+		txManager := &stubTransactionManager{}
+
+		err := NewAccountService(txManager, userRepo, nil, nil, config.AccountConfig{}, nil).DeleteOwnAccount(ctx, 1, "wrong-password")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrInvalidCredentials.Code, appErr.Code)
+		assert.Zero(t, txManager.calls)
+	})
+
+	t.Run("CleansUpUserAndBotsAfterCommit", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(accountOwner(t), nil)
+		userRepo.On("FindBotsByOwner", mock.Anything, uint(1)).Return([]*domain.User{{Model: gorm.Model{ID: 5}, IsBot: true}}, nil)
+		storage := &MockMediaStorage{}
+		storage.On("DeleteUserFiles", mock.Anything, uint(1)).Return(nil)
+		storage.On("DeleteUserFiles", mock.Anything, uint(5)).Return(nil)
+		connections := &MockConnectionCloser{}
+		connections.On("CloseUserConnections", uint(1), mock.Anything).Return()
+		connections.On("CloseUserConnections", uint(5), mock.Anything).Return()
+		txManager := &stubTransactionManager{}
+
+		err := NewAccountService(txManager, userRepo, storage, connections, config.AccountConfig{}, nil).DeleteOwnAccount(ctx, 1, "Password123")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, txManager.calls)
+		storage.AssertExpectations(t)
+		connections.AssertExpectations(t)
+	})
+
+	t.Run("KeepsFilesWhenTransactionFails", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(accountOwner(t), nil)
+		userRepo.On("FindBotsByOwner", mock.Anything, uint(1)).Return([]*domain.User{}, nil)
+		storage := &MockMediaStorage{}
+		connections := &MockConnectionCloser{}
+
+		err := NewAccountService(&stubTransactionManager{err: shared.ErrDatabaseOperation}, userRepo, storage, connections, config.AccountConfig{}, nil).
+			DeleteOwnAccount(ctx, 1, "Password123")
+		assert.Error(t, err)
+		storage.AssertNotCalled(t, "DeleteUserFiles", mock.Anything, mock.Anything)
+		connections.AssertNotCalled(t, "CloseUserConnections", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAccountService_DeleteAccount(t *testing.T) {
+	shared.InitLogger("test")
+
+	err := NewAccountService(&stubTransactionManager{}, &MockUserRepository{}, nil, nil, config.AccountConfig{}, nil).
+		DeleteAccount(context.Background(), 3, 3)
+
+	var appErr shared.Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, shared.ErrBadRequest.Code, appErr.Code)
+}
@@ -0,0 +1,305 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAnnouncementPageSize = 50
+	maxAnnouncementPageSize     = 100
+)
+
+// AnnouncementService runs the organization-wide channels. Every user of a channel's
+// audience follows it implicitly, only admins and granted posters can publish.
+type AnnouncementService struct {
+	repo     domain.AnnouncementRepository
+	userRepo domain.UserRepository
+	notifier domain.AnnouncementNotifier // Optional, posts are only delivered on fetch when nil
+	audit    *AuditLogger
+}
+
+func NewAnnouncementService(repo domain.AnnouncementRepository, userRepo domain.UserRepository, notifier domain.AnnouncementNotifier, audit *AuditLogger) *AnnouncementService {
+	return &AnnouncementService{
+		repo:     repo,
+		userRepo: userRepo,
+		notifier: notifier,
+		audit:    audit,
+	}
+}
+
+// CreateChannel opens a channel for everyone, or for one role when audience is set
+func (s *AnnouncementService) CreateChannel(ctx context.Context, adminID uint, name, description string, audience *domain.UserRole) (*domain.AnnouncementChannel, error) {
+	channel := &domain.AnnouncementChannel{
+		Name:         name,
+		Description:  description,
+		AudienceRole: audience,
+		CreatedByID:  adminID,
+	}
+	if err := channel.Validate(); err != nil {
+		return nil, shared.ErrValidation.WithDetails(err.Error())
+	}
+	if err := s.repo.CreateChannel(ctx, channel); err != nil {
+		return nil, err
+	}
+
+	shared.Log.Info("announcement channel created", zap.Uint("adminID", adminID), zap.Uint("channelID", channel.ID))
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(adminID),
+		Action:     domain.AuditChannelCreate,
+		TargetType: "announcement_channel",
+		TargetID:   auditID(channel.ID),
+		Details:    channel.Name,
+	})
+	return channel, nil
+}
+
+// DeleteChannel removes the channel together with its posts
+func (s *AnnouncementService) DeleteChannel(ctx context.Context, adminID, channelID uint) error {
+	channel, err := s.repo.FindChannel(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteChannel(ctx, channelID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(adminID),
+		Action:     domain.AuditChannelDelete,
+		TargetType: "announcement_channel",
+		TargetID:   auditID(channelID),
+		Details:    channel.Name,
+	})
+	return nil
+}
+
+// AddPoster lets a non-admin user publish to the channel
+func (s *AnnouncementService) AddPoster(ctx context.Context, adminID, channelID, userID uint) error {
+	if _, err := s.repo.FindChannel(ctx, channelID); err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsBot {
+		return shared.ErrBadRequest.WithDetails("bot accounts cannot post announcements")
+	}
+	if err := s.repo.AddPoster(ctx, channelID, userID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(adminID),
+		Action:     domain.AuditPosterGrant,
+		TargetType: "announcement_channel",
+		TargetID:   auditID(channelID),
+		Details:    fmt.Sprintf("user %d", userID),
+	})
+	return nil
+}
+
+func (s *AnnouncementService) RemovePoster(ctx context.Context, adminID, channelID, userID uint) error {
+	if err := s.repo.RemovePoster(ctx, channelID, userID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(adminID),
+		Action:     domain.AuditPosterRevoke,
+		TargetType: "announcement_channel",
+		TargetID:   auditID(channelID),
+		Details:    fmt.Sprintf("user %d", userID),
+	})
+	return nil
+}
+
+func (s *AnnouncementService) ListPosters(ctx context.Context, channelID uint) ([]domain.AnnouncementPoster, error) {
+	if _, err := s.repo.FindChannel(ctx, channelID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPosters(ctx, channelID)
+}
+
+// ListChannels returns the channels the user follows with their unread counts
+func (s *AnnouncementService) ListChannels(ctx context.Context, userID uint, role domain.UserRole) ([]domain.ChannelSummary, error) {
+	channels, err := s.repo.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]domain.AnnouncementChannel, 0, len(channels))
+	ids := make([]uint, 0, len(channels))
+	for _, channel := range channels {
+		if channel.VisibleTo(role) {
+			visible = append(visible, channel)
+			ids = append(ids, channel.ID)
+		}
+	}
+
+	reads, err := s.repo.ReadSummaries(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]domain.ChannelSummary, 0, len(visible))
+	for _, channel := range visible {
+		summary := reads[channel.ID]
+		summary.Channel = channel
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// ListPosts pages backwards through a channel, newest first
+func (s *AnnouncementService) ListPosts(ctx context.Context, role domain.UserRole, channelID, beforeID uint, limit int) ([]domain.Announcement, error) {
+	if _, err := s.visibleChannel(ctx, role, channelID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultAnnouncementPageSize
+	}
+	if limit > maxAnnouncementPageSize {
+		limit = maxAnnouncementPageSize
+	}
+	return s.repo.ListPosts(ctx, channelID, beforeID, limit)
+}
+
+func (s *AnnouncementService) ListPinned(ctx context.Context, role domain.UserRole, channelID uint) ([]domain.Announcement, error) {
+	if _, err := s.visibleChannel(ctx, role, channelID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPinned(ctx, channelID)
+}
+
+// Post publishes to the channel and pushes the post to connected followers
+func (s *AnnouncementService) Post(ctx context.Context, authorID uint, role domain.UserRole, channelID uint, content, mediaURL string) (*domain.Announcement, error) {
+	channel, err := s.visibleChannel(ctx, role, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPoster(ctx, authorID, role, channelID); err != nil {
+		return nil, err
+	}
+
+	post := &domain.Announcement{
+		ChannelID: channelID,
+		AuthorID:  authorID,
+		Content:   content,
+		MediaURL:  mediaURL,
+	}
+	if err := post.Validate(); err != nil {
+		return nil, shared.ErrValidation.WithDetails(err.Error())
+	}
+	if err := s.repo.CreatePost(ctx, post); err != nil {
+		return nil, err
+	}
+
+	// Reload so the author is attached for the response and the push
+	if full, err := s.repo.FindPost(ctx, post.ID); err == nil {
+		post = full
+	}
+
+	if s.notifier != nil {
+		if err := s.notifier.Announce(ctx, channel, post); err != nil {
+			shared.Log.Error("announcement push failed", zap.Uint("postID", post.ID), zap.Error(err))
+		}
+	}
+	return post, nil
+}
+
+// SetPinned pins or unpins a post. Anyone who can post to the channel can pin.
+func (s *AnnouncementService) SetPinned(ctx context.Context, userID uint, role domain.UserRole, postID uint, pinned bool) (*domain.Announcement, error) {
+	post, err := s.repo.FindPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.visibleChannel(ctx, role, post.ChannelID); err != nil {
+		return nil, err
+	}
+	if err := s.checkPoster(ctx, userID, role, post.ChannelID); err != nil {
+		return nil, err
+	}
+	if post.Pinned() == pinned {
+		return post, nil
+	}
+
+	var pinnedAt *time.Time
+	if pinned {
+		now := time.Now().UTC()
+		pinnedAt = &now
+	}
+	if err := s.repo.SetPinned(ctx, postID, pinnedAt); err != nil {
+		return nil, err
+	}
+	post.PinnedAt = pinnedAt
+	return post, nil
+}
+
+// DeletePost removes a post. Authors can delete their own, admins any.
+func (s *AnnouncementService) DeletePost(ctx context.Context, userID uint, role domain.UserRole, postID uint) error {
+	post, err := s.repo.FindPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if post.AuthorID != userID && role != domain.RoleAdmin {
+		return shared.ErrForbidden.WithDetails("you can only delete your own announcements")
+	}
+	if err := s.repo.DeletePost(ctx, postID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(userID),
+		Action:     domain.AuditAnnouncementDelete,
+		TargetType: "announcement",
+		TargetID:   auditID(postID),
+	})
+	return nil
+}
+
+// MarkRead moves the user's read position up to the given post
+func (s *AnnouncementService) MarkRead(ctx context.Context, userID uint, role domain.UserRole, channelID, postID uint) error {
+	if _, err := s.visibleChannel(ctx, role, channelID); err != nil {
+		return err
+	}
+	post, err := s.repo.FindPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if post.ChannelID != channelID {
+		return shared.ErrBadRequest.WithDetails("announcement does not belong to this channel")
+	}
+	return s.repo.MarkRead(ctx, channelID, userID, postID)
+}
+
+// visibleChannel hides channels outside the user's audience as if they did not exist
+func (s *AnnouncementService) visibleChannel(ctx context.Context, role domain.UserRole, channelID uint) (*domain.AnnouncementChannel, error) {
+	channel, err := s.repo.FindChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if !channel.VisibleTo(role) {
+		return nil, shared.ErrRecordNotFound.WithDetails("announcement channel not found")
+	}
+	return channel, nil
+}
+
+func (s *AnnouncementService) checkPoster(ctx context.Context, userID uint, role domain.UserRole, channelID uint) error {
+	if role == domain.RoleAdmin {
+		return nil
+	}
+	ok, err := s.repo.IsPoster(ctx, channelID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return shared.ErrForbidden.WithDetails("you are not allowed to post in this channel")
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockAnnouncementRepository struct {
+	mock.Mock
+}
+
+func (m *MockAnnouncementRepository) CreateChannel(ctx context.Context, channel *domain.AnnouncementChannel) error {
+	return m.Called(ctx, channel).Error(0)
+}
+
+func (m *MockAnnouncementRepository) FindChannel(ctx context.Context, channelID uint) (*domain.AnnouncementChannel, error) {
+	args := m.Called(ctx, channelID)
+	return args.Get(0).(*domain.AnnouncementChannel), args.Error(1)
+}
+
+func (m *MockAnnouncementRepository) ListChannels(ctx context.Context) ([]domain.AnnouncementChannel, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.AnnouncementChannel), args.Error(1)
+}
+
+func (m *MockAnnouncementRepository) DeleteChannel(ctx context.Context, channelID uint) error {
+	return m.Called(ctx, channelID).Error(0)
+}
+
+func (m *MockAnnouncementRepository) AddPoster(ctx context.Context, channelID, userID uint) error {
+	return m.Called(ctx, channelID, userID).Error(0)
+}
+
+func (m *MockAnnouncementRepository) RemovePoster(ctx context.Context, channelID, userID uint) error {
+	return m.Called(ctx, channelID, userID).Error(0)
+}
+
+func (m *MockAnnouncementRepository) IsPoster(ctx context.Context, channelID, userID uint) (bool, error) {
+	args := m.Called(ctx, channelID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAnnouncementRepository) ListPosters(ctx context.Context, channelID uint) ([]domain.AnnouncementPoster, error) {
+	args := m.Called(ctx, channelID)
+	return args.Get(0).([]domain.AnnouncementPoster), args.Error(1)
+}
+
+func (m *MockAnnouncementRepository) CreatePost(ctx context.Context, post *domain.Announcement) error {
+	return m.Called(ctx, post).Error(0)
+}
+
+func (m *MockAnnouncementRepository) FindPost(ctx context.Context, postID uint) (*domain.Announcement, error) {
+	args := m.Called(ctx, postID)
+	return args.Get(0).(*domain.Announcement), args.Error(1)
+}
+
+func (m *MockAnnouncementRepository) ListPosts(ctx context.Context, channelID, beforeID uint, limit int) ([]domain.Announcement, error) {
+	args := m.Called(ctx, channelID, beforeID, limit)
+	return args.Get(0).([]domain.Announcement), args.Error(1)
+}
+
+func (m *MockAnnouncementRepository) ListPinned(ctx context.Context, channelID uint) ([]domain.Announcement, error) {
+	args := m.Called(ctx, channelID)
+	return args.Get(0).([]domain.Announcement), args.Error(1)
+}
+
+func (m *MockAnnouncementRepository) SetPinned(ctx context.Context, postID uint, pinnedAt *time.Time) error {
+	return m.Called(ctx, postID, pinnedAt).Error(0)
+}
+
+func (m *MockAnnouncementRepository) DeletePost(ctx context.Context, postID uint) error {
+	return m.Called(ctx, postID).Error(0)
+}
+
+func (m *MockAnnouncementRepository) MarkRead(ctx context.Context, channelID, userID, lastReadID uint) error {
+	return m.Called(ctx, channelID, userID, lastReadID).Error(0)
+}
+
+func (m *MockAnnouncementRepository) ReadSummaries(ctx context.Context, userID uint, channelIDs []uint) (map[uint]domain.ChannelSummary, error) {
+	args := m.Called(ctx, userID, channelIDs)
+	return args.Get(0).(map[uint]domain.ChannelSummary), args.Error(1)
+}
+
+func (m *MockAnnouncementRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockAnnouncementRepository) HasMedia(ctx context.Context, mediaURL string, role domain.UserRole) (bool, error) {
+	args := m.Called(ctx, mediaURL, role)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockAnnouncementNotifier struct {
+	mock.Mock
+}
+
+func (m *MockAnnouncementNotifier) Announce(ctx context.Context, channel *domain.AnnouncementChannel, post *domain.Announcement) error {
+	return m.Called(ctx, channel, post).Error(0)
+}
+
+func TestAnnouncementService_Post(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	channel := &domain.AnnouncementChannel{Model: gorm.Model{ID: 3}, Name: "News"}
+
+	t.Run("PosterPublishesAndPushes", func(t *testing.T) {
+		repo := &MockAnnouncementRepository{}
+		repo.On("FindChannel", ctx, uint(3)).Return(channel, nil)
+		repo.On("IsPoster", ctx, uint(3), uint(5)).Return(true, nil)
+		repo.On("CreatePost", ctx, mock.AnythingOfType("*domain.Announcement")).Run(func(args mock.Arguments) {
+			args.Get(1).(*domain.Announcement).ID = 40
+		}).Return(nil)
+		stored := &domain.Announcement{Model: gorm.Model{ID: 40}, ChannelID: 3, AuthorID: 5, Content: "Hello"}
+		repo.On("FindPost", ctx, uint(40)).Return(stored, nil)
+		notifier := &MockAnnouncementNotifier{}
+		notifier.On("Announce", ctx, channel, stored).Return(nil)
+
+		post, err := NewAnnouncementService(repo, nil, notifier, nil).Post(ctx, 5, domain.RoleUser, 3, "Hello", "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(40), post.ID)
+		repo.AssertExpectations(t)
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("OtherUsersCannotPost", func(t *testing.T) {
+		repo := &MockAnnouncementRepository{}
+		repo.On("FindChannel", ctx, uint(3)).Return(channel, nil)
+		repo.On("IsPoster", ctx, uint(3), uint(6)).Return(false, nil)
+
+		_, err := NewAnnouncementService(repo, nil, nil, nil).Post(ctx, 6, domain.RoleUser, 3, "Hello", "")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrForbidden.Code, appErr.Code)
+		repo.AssertNotCalled(t, "CreatePost", mock.Anything, mock.Anything)
+	})
+
+	t.Run("AdminsNeedNoGrant", func(t *testing.T) {
+		repo := &MockAnnouncementRepository{}
+		repo.On("FindChannel", ctx, uint(3)).Return(channel, nil)
+		repo.On("CreatePost", ctx, mock.AnythingOfType("*domain.Announcement")).Return(nil)
+		repo.On("FindPost", ctx, uint(0)).Return(&domain.Announcement{ChannelID: 3}, nil)
+
+		_, err := NewAnnouncementService(repo, nil, nil, nil).Post(ctx, 1, domain.RoleAdmin, 3, "Hello", "")
+
+		assert.NoError(t, err)
+		repo.AssertNotCalled(t, "IsPoster", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("HiddenChannelLooksMissing", func(t *testing.T) {
+		adminsOnly := domain.RoleAdmin
+		repo := &MockAnnouncementRepository{}
+		repo.On("FindChannel", ctx, uint(4)).Return(&domain.AnnouncementChannel{Model: gorm.Model{ID: 4}, AudienceRole: &adminsOnly}, nil)
+
+		_, err := NewAnnouncementService(repo, nil, nil, nil).Post(ctx, 5, domain.RoleUser, 4, "Hello", "")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrRecordNotFound.Code, appErr.Code)
+	})
+
+	t.Run("EmptyPostRejected", func(t *testing.T) {
+		repo := &MockAnnouncementRepository{}
+		repo.On("FindChannel", ctx, uint(3)).Return(channel, nil)
+
+		_, err := NewAnnouncementService(repo, nil, nil, nil).Post(ctx, 1, domain.RoleAdmin, 3, "   ", "")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrValidation.Code, appErr.Code)
+	})
+}
+
+func TestAnnouncementService_ListChannels(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	adminsOnly := domain.RoleAdmin
+	repo := &MockAnnouncementRepository{}
+	repo.On("ListChannels", ctx).Return([]domain.AnnouncementChannel{
+		{Model: gorm.Model{ID: 1}, Name: "All hands"},
+		{Model: gorm.Model{ID: 2}, Name: "Admins", AudienceRole: &adminsOnly},
+	}, nil)
+	repo.On("ReadSummaries", ctx, uint(5), []uint{1}).Return(map[uint]domain.ChannelSummary{
+		1: {LastReadID: 10, UnreadCount: 2},
+	}, nil)
+
+	summaries, err := NewAnnouncementService(repo, nil, nil, nil).ListChannels(ctx, 5, domain.RoleUser)
+
+	assert.NoError(t, err)
+	assert.Len(t, summaries, 1)
+	assert.Equal(t, "All hands", summaries[0].Channel.Name)
+	assert.Equal(t, int64(2), summaries[0].UnreadCount)
+}
+
+func TestAnnouncementService_MarkRead(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	channel := &domain.AnnouncementChannel{Model: gorm.Model{ID: 3}}
+
+	t.Run("MovesReadPosition", func(t *testing.T) {
+		repo := &MockAnnouncementRepository{}
+		repo.On("FindChannel", ctx, uint(3)).Return(channel, nil)
+		repo.On("FindPost", ctx, uint(40)).Return(&domain.Announcement{Model: gorm.Model{ID: 40}, ChannelID: 3}, nil)
+		repo.On("MarkRead", ctx, uint(3), uint(5), uint(40)).Return(nil)
+
+		err := NewAnnouncementService(repo, nil, nil, nil).MarkRead(ctx, 5, domain.RoleUser, 3, 40)
+
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("PostFromOtherChannel", func(t *testing.T) {
+		repo := &MockAnnouncementRepository{}
+		repo.On("FindChannel", ctx, uint(3)).Return(channel, nil)
+		repo.On("FindPost", ctx, uint(41)).Return(&domain.Announcement{Model: gorm.Model{ID: 41}, ChannelID: 9}, nil)
+
+		err := NewAnnouncementService(repo, nil, nil, nil).MarkRead(ctx, 5, domain.RoleUser, 3, 41)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrBadRequest.Code, appErr.Code)
+		repo.AssertNotCalled(t, "MarkRead", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAnnouncementService_DeletePost(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	repo := &MockAnnouncementRepository{}
+	repo.On("FindPost", ctx, uint(40)).Return(&domain.Announcement{Model: gorm.Model{ID: 40}, AuthorID: 5}, nil)
+
+	err := NewAnnouncementService(repo, nil, nil, nil).DeletePost(ctx, 6, domain.RoleUser, 40)
+
+	var appErr shared.Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, shared.ErrForbidden.Code, appErr.Code)
+	repo.AssertNotCalled(t, "DeletePost", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,178 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+type APITokenService struct {
+	tokenRepo domain.APITokenRepository
+	userRepo  domain.UserRepository
+	audit     *AuditLogger
+}
+
+func NewAPITokenService(tokenRepo domain.APITokenRepository, userRepo domain.UserRepository, audit *AuditLogger) *APITokenService {
+	return &APITokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+		audit:     audit,
+	}
+}
+
+// Create issues a token for the caller or for a bot the caller owns.
+// The plain token is returned once and never stored.
+func (s *APITokenService) Create(ctx context.Context, callerID, userID uint, name string, scopes []domain.TokenScope, expiresIn time.Duration) (string, *domain.APIToken, error) {
+	if err := s.authorizeFor(ctx, callerID, userID); err != nil {
+		return "", nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", nil, shared.ErrValidation.WithDetails("token name must be between 1 and 100 characters")
+	}
+
+	token := &domain.APIToken{
+		UserID:      userID,
+		CreatedByID: callerID,
+		Name:        name,
+	}
+	if err := token.SetScopes(scopes); err != nil {
+		shared.Log.Debug("invalid token scopes", zap.Any("scopes", scopes))
+		return "", nil, shared.ErrValidation.WithDetails("invalid or missing scopes")
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().UTC().Add(expiresIn)
+		token.ExpiresAt = &expiresAt
+	}
+
+	plain, err := generateAPIToken()
+	if err != nil {
+		shared.Log.Error("generate api token failed", zap.Error(err))
+		return "", nil, shared.ErrInternalServer.WithDetails("generate api token failed")
+	}
+	token.TokenHash = hashAPIToken(plain)
+	token.Prefix = plain[:len(domain.APITokenPrefix)+6]
+
+	created, err := s.tokenRepo.Create(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+
+	shared.Log.Info("api token created",
+		zap.Uint("tokenID", created.ID),
+		zap.Uint("userID", userID),
+		zap.Uint("createdBy", callerID),
+		zap.String("scopes", created.Scopes))
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(callerID),
+		Action:     domain.AuditTokenCreate,
+		TargetType: "api_token",
+		TargetID:   auditID(created.ID),
+		Details:    "user " + auditID(userID) + ", scopes " + created.Scopes,
+	})
+	return plain, created, nil
+}
+
+func (s *APITokenService) List(ctx context.Context, callerID, userID uint) ([]domain.APIToken, error) {
+	if err := s.authorizeFor(ctx, callerID, userID); err != nil {
+		return nil, err
+	}
+	return s.tokenRepo.ListByUser(ctx, userID)
+}
+
+func (s *APITokenService) Revoke(ctx context.Context, callerID, tokenID uint) error {
+	token, err := s.tokenRepo.FindByID(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeFor(ctx, callerID, token.UserID); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.Revoke(ctx, tokenID); err != nil {
+		return err
+	}
+
+	shared.Log.Info("api token revoked", zap.Uint("tokenID", tokenID), zap.Uint("revokedBy", callerID))
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(callerID),
+		Action:     domain.AuditTokenRevoke,
+		TargetType: "api_token",
+		TargetID:   auditID(tokenID),
+	})
+	return nil
+}
+
+// ValidateAPIToken implements domain.APITokenValidator for the auth middleware
+func (s *APITokenService) ValidateAPIToken(ctx context.Context, plain string) (*domain.TokenClaims, error) {
+	if !domain.IsAPIToken(plain) {
+		return nil, domain.ErrInvalidToken
+	}
+
+	token, err := s.tokenRepo.FindByHash(ctx, hashAPIToken(plain))
+	if err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+	if !token.IsActive(time.Now().UTC()) {
+		shared.Log.Debug("inactive api token used", zap.Uint("tokenID", token.ID))
+		return nil, domain.ErrInvalidToken
+	}
+	// Deleted users are not preloaded, tokens of deleted or suspended accounts stop working
+	if token.User.ID == 0 || token.User.IsSuspended() {
+		shared.Log.Debug("api token of deleted or suspended user used", zap.Uint("tokenID", token.ID), zap.Uint("userID", token.UserID))
+		return nil, domain.ErrInvalidToken
+	}
+
+	if err := s.tokenRepo.TouchLastUsed(ctx, token.ID); err != nil {
+		shared.Log.Warn("update api token last used failed", zap.Uint("tokenID", token.ID), zap.Error(err))
+	}
+
+	return &domain.TokenClaims{
+		UserID:     token.UserID,
+		Username:   token.User.Username,
+		Email:      token.User.Email,
+		Role:       token.User.Role,
+		APITokenID: token.ID,
+		Scopes:     token.ScopeList(),
+		IsBot:      token.User.IsBot,
+	}, nil
+}
+
+// authorizeFor allows managing tokens of yourself or of bots you own
+func (s *APITokenService) authorizeFor(ctx context.Context, callerID, userID uint) error {
+	if callerID == userID {
+		return nil
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsOwnedBy(callerID) {
+		shared.Log.Debug("api token access denied", zap.Uint("callerID", callerID), zap.Uint("userID", userID))
+		return shared.ErrForbidden.WithDetails("you can only manage your own tokens or tokens of your bots")
+	}
+	return nil
+}
+
+func generateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return domain.APITokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
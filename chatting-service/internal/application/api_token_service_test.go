@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockAPITokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPITokenRepository) Create(ctx context.Context, token *domain.APIToken) (*domain.APIToken, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(*domain.APIToken), args.Error(1)
+}
+
+func (m *MockAPITokenRepository) FindByID(ctx context.Context, tokenID uint) (*domain.APIToken, error) {
+	args := m.Called(ctx, tokenID)
+	return args.Get(0).(*domain.APIToken), args.Error(1)
+}
+
+func (m *MockAPITokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*domain.APIToken), args.Error(1)
+}
+
+func (m *MockAPITokenRepository) ListByUser(ctx context.Context, userID uint) ([]domain.APIToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]domain.APIToken), args.Error(1)
+}
+
+func (m *MockAPITokenRepository) Revoke(ctx context.Context, tokenID uint) error {
+	args := m.Called(ctx, tokenID)
+	return args.Error(0)
+}
+
+func (m *MockAPITokenRepository) TouchLastUsed(ctx context.Context, tokenID uint) error {
+	args := m.Called(ctx, tokenID)
+	return args.Error(0)
+}
+
+func (m *MockAPITokenRepository) RevokeAllForUser(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func TestAPITokenService(t *testing.T) {
+	shared.InitLogger("test")
+	ownerID := uint(1)
+
+	t.Run("CreateForOwnedBot", func(t *testing.T) {
+		tokenRepo := &MockAPITokenRepository{}
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(2)).
+			Return(&domain.User{Model: gorm.Model{ID: 2}, IsBot: true, BotOwnerID: &ownerID}, nil)
+		tokenRepo.On("Create", mock.Anything, mock.Anything).
+			Return(&domain.APIToken{Model: gorm.Model{ID: 5}, UserID: 2, Scopes: "messages:send"}, nil)
+
+		plain, created, err := NewAPITokenService(tokenRepo, userRepo, nil).
+			Create(context.Background(), ownerID, 2, "ci", []domain.TokenScope{domain.ScopeMessagesSend}, 0)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(plain, domain.APITokenPrefix))
+		assert.Equal(t, uint(5), created.ID)
+		stored := tokenRepo.Calls[0].Arguments.Get(1).(*domain.APIToken)
+		assert.Equal(t, hashAPIToken(plain), stored.TokenHash)
+		assert.NotContains(t, stored.TokenHash, plain)
+	})
+
+	t.Run("CreateRejectsForeignUser", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(3)).
+			Return(&domain.User{Model: gorm.Model{ID: 3}}, nil)
+
+		_, _, err := NewAPITokenService(&MockAPITokenRepository{}, userRepo, nil).
+			Create(context.Background(), ownerID, 3, "ci", []domain.TokenScope{domain.ScopeMessagesSend}, 0)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrForbidden.Code, appErr.Code)
+	})
+
+	t.Run("CreateRejectsUnknownScope", func(t *testing.T) {
+		_, _, err := NewAPITokenService(&MockAPITokenRepository{}, &MockUserRepository{}, nil).
+			Create(context.Background(), ownerID, ownerID, "ci", []domain.TokenScope{"admin:all"}, 0)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrValidation.Code, appErr.Code)
+	})
+
+	t.Run("ValidateReturnsScopedClaims", func(t *testing.T) {
+		tokenRepo := &MockAPITokenRepository{}
+		tokenRepo.On("FindByHash", mock.Anything, hashAPIToken("cst_valid")).
+			Return(&domain.APIToken{
+				Model:  gorm.Model{ID: 9},
+				UserID: 2,
+				Scopes: "messages:send,messages:read",
+				User:   domain.User{Model: gorm.Model{ID: 2}, Username: "ci-bot", IsBot: true},
+			}, nil)
+		tokenRepo.On("TouchLastUsed", mock.Anything, uint(9)).Return(nil)
+
+		claims, err := NewAPITokenService(tokenRepo, &MockUserRepository{}, nil).ValidateAPIToken(context.Background(), "cst_valid")
+
+		assert.NoError(t, err)
+		assert.True(t, claims.IsBot)
+		assert.True(t, claims.HasScope(domain.ScopeMessagesSend))
+		assert.False(t, claims.HasScope(domain.ScopeMediaUpload))
+	})
+
+	t.Run("ValidateRejectsRevoked", func(t *testing.T) {
+		revokedAt := time.Now().UTC().Add(-time.Minute)
+		tokenRepo := &MockAPITokenRepository{}
+		tokenRepo.On("FindByHash", mock.Anything, hashAPIToken("cst_revoked")).
+			Return(&domain.APIToken{Model: gorm.Model{ID: 10}, UserID: 2, Scopes: "messages:send", RevokedAt: &revokedAt}, nil)
+
+		_, err := NewAPITokenService(tokenRepo, &MockUserRepository{}, nil).ValidateAPIToken(context.Background(), "cst_revoked")
+
+		assert.ErrorIs(t, err, domain.ErrInvalidToken)
+		tokenRepo.AssertNotCalled(t, "TouchLastUsed", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ValidateRejectsDeletedOrSuspendedUser", func(t *testing.T) {
+		suspendedAt := time.Now().UTC().Add(-time.Hour)
+		tokenRepo := &MockAPITokenRepository{}
+		// A soft-deleted bot is not preloaded
+		tokenRepo.On("FindByHash", mock.Anything, hashAPIToken("cst_deleted")).
+			Return(&domain.APIToken{Model: gorm.Model{ID: 11}, UserID: 3, Scopes: "messages:send"}, nil)
+		tokenRepo.On("FindByHash", mock.Anything, hashAPIToken("cst_suspended")).
+			Return(&domain.APIToken{
+				Model:  gorm.Model{ID: 12},
+				UserID: 4,
+				Scopes: "messages:send",
+				User:   domain.User{Model: gorm.Model{ID: 4}, Username: "spam-bot", IsBot: true, SuspendedAt: &suspendedAt},
+			}, nil)
+		service := NewAPITokenService(tokenRepo, &MockUserRepository{}, nil)
+
+		_, err := service.ValidateAPIToken(context.Background(), "cst_deleted")
+		assert.ErrorIs(t, err, domain.ErrInvalidToken)
+		_, err = service.ValidateAPIToken(context.Background(), "cst_suspended")
+		assert.ErrorIs(t, err, domain.ErrInvalidToken)
+		tokenRepo.AssertNotCalled(t, "TouchLastUsed", mock.Anything, mock.Anything)
+	})
+}
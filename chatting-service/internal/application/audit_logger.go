@@ -0,0 +1,78 @@
+package application
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditQueryLimit = 100
+	maxAuditQueryLimit     = 1000
+)
+
+// AuditLogger writes the security audit trail. A nil *AuditLogger is valid and
+// records nothing, so services can hold an optional one without nil checks.
+type AuditLogger struct {
+	repo domain.AuditEventRepository
+}
+
+func NewAuditLogger(repo domain.AuditEventRepository) *AuditLogger {
+	return &AuditLogger{repo: repo}
+}
+
+// Record stores the event with the IP and request ID of the current request.
+// Failing to write the trail is logged but never fails the audited action.
+func (a *AuditLogger) Record(ctx context.Context, event domain.AuditEvent) {
+	if a == nil {
+		return
+	}
+
+	meta := shared.RequestMetaFrom(ctx)
+	if event.IP == "" {
+		event.IP = meta.ClientIP
+	}
+	if event.RequestID == "" {
+		event.RequestID = meta.RequestID
+	}
+	if event.Outcome == "" {
+		event.Outcome = domain.AuditSuccess
+	}
+	event.CreatedAt = time.Now().UTC()
+
+	if err := a.repo.Create(ctx, &event); err != nil {
+		shared.Log.Error("write audit event failed",
+			zap.Error(err),
+			zap.String("action", string(event.Action)),
+			zap.String("outcome", string(event.Outcome)),
+			zap.String("requestID", event.RequestID))
+	}
+}
+
+func (a *AuditLogger) Query(ctx context.Context, query domain.AuditQuery) ([]domain.AuditEvent, error) {
+	if a == nil {
+		return nil, shared.ErrServiceUnavailable.WithDetails("audit log is not enabled")
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultAuditQueryLimit
+	}
+	if query.Limit > maxAuditQueryLimit {
+		query.Limit = maxAuditQueryLimit
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return nil, shared.ErrValidation.WithDetails("to must be after from")
+	}
+	return a.repo.Find(ctx, query)
+}
+
+func auditActor(userID uint) *uint {
+	return &userID
+}
+
+func auditID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,93 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditEventRepository) Create(ctx context.Context, event *domain.AuditEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockAuditEventRepository) Find(ctx context.Context, query domain.AuditQuery) ([]domain.AuditEvent, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]domain.AuditEvent), args.Error(1)
+}
+
+func TestAuditLogger(t *testing.T) {
+	shared.InitLogger("test")
+
+	t.Run("RecordAddsRequestMeta", func(t *testing.T) {
+		repo := &MockAuditEventRepository{}
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		ctx := shared.WithRequestMeta(context.Background(), shared.RequestMeta{RequestID: "req-1", ClientIP: "10.0.0.1"})
+
+		NewAuditLogger(repo).Record(ctx, domain.AuditEvent{ActorID: auditActor(3), Action: domain.AuditPasswordChange})
+
+		event := repo.Calls[0].Arguments.Get(1).(*domain.AuditEvent)
+		assert.Equal(t, "req-1", event.RequestID)
+		assert.Equal(t, "10.0.0.1", event.IP)
+		assert.Equal(t, domain.AuditSuccess, event.Outcome)
+		assert.False(t, event.CreatedAt.IsZero())
+	})
+
+	t.Run("ExplicitIPWins", func(t *testing.T) {
+		repo := &MockAuditEventRepository{}
+		repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		ctx := shared.WithRequestMeta(context.Background(), shared.RequestMeta{ClientIP: "10.0.0.1"})
+
+		NewAuditLogger(repo).Record(ctx, domain.AuditEvent{Action: domain.AuditLogin, IP: "192.168.1.5"})
+
+		event := repo.Calls[0].Arguments.Get(1).(*domain.AuditEvent)
+		assert.Equal(t, "192.168.1.5", event.IP)
+	})
+
+	t.Run("WriteFailureDoesNotPanic", func(t *testing.T) {
+		repo := &MockAuditEventRepository{}
+		repo.On("Create", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+		assert.NotPanics(t, func() {
+			NewAuditLogger(repo).Record(context.Background(), domain.AuditEvent{Action: domain.AuditLogin})
+		})
+	})
+
+	t.Run("NilLoggerIsNoop", func(t *testing.T) {
+		var logger *AuditLogger
+		assert.NotPanics(t, func() {
+			logger.Record(context.Background(), domain.AuditEvent{Action: domain.AuditLogin})
+		})
+	})
+
+	t.Run("QueryClampsLimit", func(t *testing.T) {
+		repo := &MockAuditEventRepository{}
+		repo.On("Find", mock.Anything, mock.MatchedBy(func(q domain.AuditQuery) bool {
+			return q.Limit == maxAuditQueryLimit
+		})).Return([]domain.AuditEvent{}, nil)
+
+		_, err := NewAuditLogger(repo).Query(context.Background(), domain.AuditQuery{Limit: 50000})
+
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("QueryRejectsInvertedRange", func(t *testing.T) {
+		now := time.Now()
+		_, err := NewAuditLogger(&MockAuditEventRepository{}).Query(context.Background(), domain.AuditQuery{From: now, To: now.Add(-time.Hour)})
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrValidation.Code, appErr.Code)
+	})
+}
@@ -14,21 +14,35 @@ type AuthService struct {
 	userRepo      domain.UserRepository
 	userService   *UserService
 	tokenProvider domain.TokenProvider
+	loginGuard    *LoginGuard // Optional, brute-force protection is disabled when nil
+	oidcLogin     *OIDCLogin  // Optional, single sign-on is disabled when nil
+	audit         *AuditLogger
 }
 
 func NewAuthService(
 	repo domain.UserRepository,
 	userService *UserService,
 	provider domain.TokenProvider,
+	loginGuard *LoginGuard,
+	oidcLogin *OIDCLogin,
+	audit *AuditLogger,
 ) *AuthService {
 	return &AuthService{
 		userRepo:      repo,
 		userService:   userService,
 		tokenProvider: provider,
+		loginGuard:    loginGuard,
+		oidcLogin:     oidcLogin,
+		audit:         audit,
 	}
 }
 
 func (s *AuthService) Register(ctx context.Context, username, email, password string) (*auth.AuthResponse, error) {
+	if err := s.userService.ValidatePassword(password, username); err != nil {
+		shared.Log.Debug("password rejected by policy", zap.String("username", username))
+		return nil, err
+	}
+
 	// Create user through service (includes validation)
 	user, err := s.userService.CreateUser(ctx, username, email, password)
 	if err != nil {
@@ -40,31 +54,23 @@ func (s *AuthService) Register(ctx context.Context, username, email, password st
 		return nil, err
 	}
 
-	// Generate tokens
-	accessToken, err := s.tokenProvider.GenerateToken(ctx, user)
-	if err != nil {
-		shared.Log.Error("generate token failed", zap.Error(err))
-		return nil, shared.ErrInternalServer.WithDetails("generate token failed").WithDetails(err.Error())
+	// Send verification link, registration still succeeds if the mail could not be sent
+	if err := s.userService.SendEmailVerification(ctx, user); err != nil {
+		shared.Log.Error("send verification email failed", zap.Error(err), zap.Uint("userID", user.ID))
 	}
 
-	refreshToken, err := s.tokenProvider.GenerateRefreshToken(ctx, user)
-	if err != nil {
-		shared.Log.Error("generate refresh token failed", zap.Error(err))
-		return nil, shared.ErrInternalServer.WithDetails("generate refresh token failed").WithDetails(err.Error())
-	}
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(user.ID),
+		Actor:      user.Username,
+		Action:     domain.AuditRegister,
+		TargetType: "user",
+		TargetID:   auditID(user.ID),
+	})
 
-	return &auth.AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int(s.tokenProvider.GetAccessExpiry().Seconds()),
-		TokenType:    "Bearer",
-		UserID:       user.ID,
-		Username:     user.Username,
-		Email:        user.Email,
-	}, nil
+	return s.issueTokens(ctx, user)
 }
 
-func (s *AuthService) Login(ctx context.Context, username, password string) (*auth.AuthResponse, error) {
+func (s *AuthService) Login(ctx context.Context, username, password, clientIP string) (*auth.AuthResponse, error) {
 
 	// Trim input first
 	username = strings.TrimSpace(username)
@@ -74,40 +80,110 @@ func (s *AuthService) Login(ctx context.Context, username, password string) (*au
 		return nil, shared.ErrBadRequest.WithDetails("Invalid username")
 	}
 
+	if s.loginGuard != nil {
+		if err := s.loginGuard.Check(ctx, username, clientIP); err != nil {
+			s.audit.Record(ctx, domain.AuditEvent{
+				Actor:   username,
+				Action:  domain.AuditLoginBlocked,
+				Outcome: domain.AuditDenied,
+				IP:      clientIP,
+			})
+			return nil, err
+		}
+	}
+
 	user, err := s.userService.VerifyCredentials(ctx, username, password)
 	if err != nil {
 		shared.Log.Error("verify credentials failed", zap.Error(err), zap.String("username", username))
+		if s.loginGuard != nil {
+			if guardErr := s.loginGuard.RecordFailure(ctx, username, clientIP); guardErr != nil {
+				shared.Log.Error("record login failure failed", zap.Error(guardErr), zap.String("username", username))
+			}
+		}
+		s.audit.Record(ctx, domain.AuditEvent{
+			Actor:   username,
+			Action:  domain.AuditLogin,
+			Outcome: domain.AuditFailure,
+			IP:      clientIP,
+		})
 		return nil, shared.ErrValidation.WithDetails("invalid credentials").WithDetails(err.Error())
 	}
 
+	if s.loginGuard != nil {
+		if err := s.loginGuard.RecordSuccess(ctx, username); err != nil {
+			shared.Log.Error("reset login failures failed", zap.Error(err), zap.String("username", username))
+		}
+	}
+
+	if err := s.checkAccountAccess(ctx, user, clientIP); err != nil {
+		return nil, err
+	}
+	if user.PasswordResetRequired {
+		// The password is right but has to be replaced through ResetPassword first
+		return nil, shared.ErrPasswordResetRequired
+	}
+
 	// Update last active
 	if err := s.userService.UpdateUserLastActive(ctx, user.ID); err != nil {
 		shared.Log.Error("update user last active failed", zap.Error(err), zap.Uint("userID", user.ID))
 		return nil, shared.ErrDatabaseOperation.WithDetails("update user last active failed").WithDetails(err.Error())
 	}
 
-	// Generate tokens
-	accessToken, err := s.tokenProvider.GenerateToken(ctx, user)
-	if err != nil {
-		shared.Log.Error("generate token failed", zap.Error(err))
-		return nil, shared.ErrInternalServer.WithDetails("generate token failed").WithDetails(err.Error())
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID: auditActor(user.ID),
+		Actor:   user.Username,
+		Action:  domain.AuditLogin,
+		IP:      clientIP,
+	})
+
+	return s.issueTokens(ctx, user)
+}
+
+// BeginOIDCLogin returns the identity provider URL the browser should be sent to
+func (s *AuthService) BeginOIDCLogin(ctx context.Context) (string, error) {
+	if s.oidcLogin == nil {
+		return "", shared.ErrServiceUnavailable.WithDetails("single sign-on is not enabled")
 	}
+	return s.oidcLogin.Begin(ctx)
+}
 
-	refreshToken, err := s.tokenProvider.GenerateRefreshToken(ctx, user)
+// CompleteOIDCLogin handles the provider callback and issues the usual session tokens
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, code, state string) (*auth.AuthResponse, error) {
+	if s.oidcLogin == nil {
+		return nil, shared.ErrServiceUnavailable.WithDetails("single sign-on is not enabled")
+	}
+
+	user, err := s.oidcLogin.Complete(ctx, code, state)
 	if err != nil {
-		shared.Log.Error("generate refresh token failed", zap.Error(err))
-		return nil, shared.ErrInternalServer.WithDetails("generate refresh token failed").WithDetails(err.Error())
+		shared.Log.Debug("oidc login failed", zap.Error(err))
+		s.audit.Record(ctx, domain.AuditEvent{
+			Action:  domain.AuditSSOLogin,
+			Outcome: domain.AuditFailure,
+			Details: err.Error(),
+		})
+		return nil, err
 	}
 
-	return &auth.AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int(s.tokenProvider.GetAccessExpiry().Seconds()),
-		TokenType:    "Bearer",
-		UserID:       user.ID,
-		Username:     user.Username,
-		Email:        user.Email,
-	}, nil
+	if err := s.checkAccountAccess(ctx, user, ""); err != nil {
+		return nil, err
+	}
+	if user.PasswordResetRequired {
+		// The account may be compromised, single sign-on waits for the reset as well
+		return nil, shared.ErrPasswordResetRequired
+	}
+
+	if err := s.userService.UpdateUserLastActive(ctx, user.ID); err != nil {
+		shared.Log.Error("update user last active failed", zap.Error(err), zap.Uint("userID", user.ID))
+		return nil, shared.ErrDatabaseOperation.WithDetails("update user last active failed").WithDetails(err.Error())
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID: auditActor(user.ID),
+		Actor:   user.Username,
+		Action:  domain.AuditSSOLogin,
+	})
+
+	return s.issueTokens(ctx, user)
 }
 
 func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*auth.AuthResponse, error) {
@@ -123,29 +199,14 @@ func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*auth.A
 		shared.Log.Error("find user by ID failed", zap.Error(err), zap.Uint("userID", claims.UserID))
 		return nil, shared.ErrDatabaseOperation.WithDetails("find user by ID failed").WithDetails(err.Error())
 	}
-
-	// Generate new tokens
-	accessToken, err := s.tokenProvider.GenerateToken(ctx, user)
-	if err != nil {
-		shared.Log.Error("generate token failed", zap.Error(err))
-		return nil, shared.ErrInternalServer.WithDetails("generate token failed").WithDetails(err.Error())
+	if user.IsSuspended() {
+		return nil, shared.ErrAccountSuspended
 	}
-
-	newRefreshToken, err := s.tokenProvider.GenerateRefreshToken(ctx, user)
-	if err != nil {
-		shared.Log.Error("generate refresh token failed", zap.Error(err))
-		return nil, shared.ErrInternalServer.WithDetails("generate refresh token failed").WithDetails(err.Error())
+	if claims.IssuedAt != nil && user.SessionRevoked(claims.IssuedAt.Time) {
+		return nil, shared.ErrUnauthorized.WithDetails("session has been revoked")
 	}
 
-	return &auth.AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
-		ExpiresIn:    int(s.tokenProvider.GetAccessExpiry().Seconds()),
-		TokenType:    "Bearer",
-		UserID:       user.ID,
-		Username:     user.Username,
-		Email:        user.Email,
-	}, nil
+	return s.issueTokens(ctx, user)
 }
 
 func (s *AuthService) Logout(ctx context.Context, token string) error {
@@ -163,6 +224,50 @@ func (s *AuthService) Logout(ctx context.Context, token string) error {
 	return err
 }
 
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	user, err := s.userService.VerifyEmail(ctx, token)
+	if err != nil {
+		shared.Log.Debug("verify email failed", zap.Error(err))
+		return err
+	}
+
+	shared.Log.Info("email verified", zap.Uint("userID", user.ID))
+	return nil
+}
+
+func (s *AuthService) ResendVerification(ctx context.Context, userID uint) error {
+	return s.userService.ResendEmailVerification(ctx, userID)
+}
+
+// UnlockAccount lets an admin clear a login lockout before it expires
+func (s *AuthService) UnlockAccount(ctx context.Context, adminID, userID uint) error {
+	if s.loginGuard == nil {
+		return nil
+	}
+
+	user, err := s.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.loginGuard.Unlock(ctx, user.Username); err != nil {
+		shared.Log.Error("unlock account failed", zap.Error(err), zap.Uint("userID", userID))
+		return err
+	}
+
+	shared.Log.Info("account unlocked",
+		zap.String("event", "login_unlocked"),
+		zap.Uint("adminID", adminID),
+		zap.Uint("userID", userID))
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(adminID),
+		Action:     domain.AuditUnlock,
+		TargetType: "user",
+		TargetID:   auditID(userID),
+	})
+	return nil
+}
+
 func (s *AuthService) ValidateToken(ctx context.Context, token string) (*domain.TokenClaims, error) {
 	return s.tokenProvider.ValidateToken(ctx, token)
 }
@@ -175,14 +280,105 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID uint, currentPa
 	}
 	if !user.CheckPassword(currentPassword) {
 		shared.Log.Debug("invalid credentials", zap.String("username", user.Username))
+		s.audit.Record(ctx, domain.AuditEvent{
+			ActorID: auditActor(userID),
+			Actor:   user.Username,
+			Action:  domain.AuditPasswordChange,
+			Outcome: domain.AuditFailure,
+			Details: "current password mismatch",
+		})
 		return shared.ErrInvalidCredentials
 	}
 
+	if err := s.userService.ValidatePassword(newPassword, user.Username); err != nil {
+		shared.Log.Debug("password rejected by policy", zap.Uint("userID", userID))
+		return err
+	}
+
 	// Set new password
 	if err := user.SetPassword(newPassword); err != nil {
-		shared.Log.Error("password must be at least 8 characters", zap.Error(err), zap.String("hashed password", newPassword))
+		shared.Log.Error("password must be at least 8 characters", zap.Error(err), zap.Uint("userID", userID))
 		return shared.ErrValidation.WithDetails("password must be at least 8 characters").WithDetails(err.Error())
 	}
 
-	return s.userRepo.UpdatePassword(ctx, userID, user.PasswordHash)
+	if err := s.userRepo.UpdatePassword(ctx, userID, user.PasswordHash); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID: auditActor(userID),
+		Actor:   user.Username,
+		Action:  domain.AuditPasswordChange,
+	})
+	return nil
+}
+
+// ResetPassword completes a forced password reset with the single-use code that was
+// mailed to the verified address or handed over by the admin
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword, clientIP string) (*auth.AuthResponse, error) {
+	user, err := s.userService.CompletePasswordReset(ctx, token, newPassword)
+	if err != nil {
+		shared.Log.Debug("password reset failed", zap.Error(err))
+		s.audit.Record(ctx, domain.AuditEvent{
+			Action:  domain.AuditPasswordChange,
+			Outcome: domain.AuditFailure,
+			IP:      clientIP,
+			Details: "forced reset rejected",
+		})
+		return nil, err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID: auditActor(user.ID),
+		Actor:   user.Username,
+		Action:  domain.AuditPasswordChange,
+		IP:      clientIP,
+		Details: "forced reset completed",
+	})
+
+	if err := s.userService.UpdateUserLastActive(ctx, user.ID); err != nil {
+		shared.Log.Error("update user last active failed", zap.Error(err), zap.Uint("userID", user.ID))
+	}
+	return s.issueTokens(ctx, user)
+}
+
+// checkAccountAccess refuses sign-ins to suspended accounts
+func (s *AuthService) checkAccountAccess(ctx context.Context, user *domain.User, clientIP string) error {
+	if !user.IsSuspended() {
+		return nil
+	}
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID: auditActor(user.ID),
+		Actor:   user.Username,
+		Action:  domain.AuditLogin,
+		Outcome: domain.AuditDenied,
+		IP:      clientIP,
+		Details: "account suspended",
+	})
+	return shared.ErrAccountSuspended
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, user *domain.User) (*auth.AuthResponse, error) {
+	accessToken, err := s.tokenProvider.GenerateToken(ctx, user)
+	if err != nil {
+		shared.Log.Error("generate token failed", zap.Error(err))
+		return nil, shared.ErrInternalServer.WithDetails("generate token failed").WithDetails(err.Error())
+	}
+
+	refreshToken, err := s.tokenProvider.GenerateRefreshToken(ctx, user)
+	if err != nil {
+		shared.Log.Error("generate refresh token failed", zap.Error(err))
+		return nil, shared.ErrInternalServer.WithDetails("generate refresh token failed").WithDetails(err.Error())
+	}
+
+	return &auth.AuthResponse{
+		AccessToken:   accessToken,
+		RefreshToken:  refreshToken,
+		ExpiresIn:     int(s.tokenProvider.GetAccessExpiry().Seconds()),
+		TokenType:     "Bearer",
+		UserID:        user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+	}, nil
 }
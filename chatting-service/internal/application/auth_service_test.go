@@ -5,11 +5,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AmeerHeiba/chatting-service/internal/config"
 	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/AmeerHeiba/chatting-service/internal/dto/auth"
 	"github.com/AmeerHeiba/chatting-service/internal/shared"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 type MockUserRepository struct {
@@ -36,6 +38,14 @@ func (m *MockUserRepository) FindByUsername(ctx context.Context, username string
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
 func (m *MockUserRepository) FindByID(ctx context.Context, userID uint) (*domain.User, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).(*domain.User), args.Error(1)
@@ -51,11 +61,26 @@ func (m *MockUserRepository) Update(ctx context.Context, userID uint, username,
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdateDetails(ctx context.Context, userID uint, details domain.ProfileDetails) error {
+	args := m.Called(ctx, userID, details)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateAvatar(ctx context.Context, userID uint, avatar domain.Avatar) error {
+	args := m.Called(ctx, userID, avatar)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) UpdatePassword(ctx context.Context, userID uint, passwordHash string) error {
 	args := m.Called(ctx, userID, passwordHash)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) UpdateLastActiveAt(ctx context.Context, userID uint) error {
 	args := m.Called(ctx, userID)
 	return args.Error(0)
@@ -66,11 +91,46 @@ func (m *MockUserRepository) Exists(ctx context.Context, userID uint) (bool, err
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockUserRepository) GetAll(ctx context.Context) ([]*domain.User, error) {
-	args := m.Called(ctx)
+func (m *MockUserRepository) Search(ctx context.Context, search domain.UserSearch) ([]*domain.User, error) {
+	args := m.Called(ctx, search)
+	return args.Get(0).([]*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) CreateBot(ctx context.Context, username, email, passwordHash string, ownerID uint) (*domain.User, error) {
+	args := m.Called(ctx, username, email, passwordHash, ownerID)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindBotsByOwner(ctx context.Context, ownerID uint) ([]*domain.User, error) {
+	args := m.Called(ctx, ownerID)
 	return args.Get(0).([]*domain.User), args.Error(1)
 }
 
+func (m *MockUserRepository) Anonymize(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) List(ctx context.Context, filter domain.UserListFilter) ([]*domain.User, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*domain.User), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRepository) SetSuspension(ctx context.Context, userID uint, suspendedAt *time.Time, reason string) error {
+	args := m.Called(ctx, userID, suspendedAt, reason)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RequirePasswordReset(ctx context.Context, userID uint, sessionsValidAfter time.Time) error {
+	args := m.Called(ctx, userID, sessionsValidAfter)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CompletePasswordReset(ctx context.Context, userID uint, passwordHash string) error {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Error(0)
+}
+
 type MockTokenProvider struct {
 	mock.Mock
 }
@@ -151,13 +211,13 @@ func TestAuthService_Register(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			userRepo := &MockUserRepository{}
 			tokenProvider := &MockTokenProvider{}
-			userService := NewUserService(userRepo)
+			userService := NewUserService(userRepo, nil, nil, nil, nil, nil)
 
 			if tt.mockSetup != nil {
 				tt.mockSetup(userRepo, tokenProvider)
 			}
 
-			authService := NewAuthService(userRepo, userService, tokenProvider)
+			authService := NewAuthService(userRepo, userService, tokenProvider, nil, nil, nil)
 			res, err := authService.Register(context.Background(), tt.username, tt.email, tt.password)
 
 			if tt.expectedErr != nil {
@@ -172,3 +232,68 @@ func TestAuthService_Register(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	cfg := config.MailConfig{PasswordResetTTL: time.Hour}
+
+	setup := func() (*AuthService, *MockUserRepository, *MockEmailVerificationRepository, *MockTokenProvider) {
+		userRepo := &MockUserRepository{}
+		verificationRepo := &MockEmailVerificationRepository{}
+		tokenProvider := &MockTokenProvider{}
+		user := &domain.User{Model: gorm.Model{ID: 7}, Username: "johndoe", Email: "john@example.com", PasswordResetRequired: true}
+		if err := user.SetPassword("Stolen123"); err != nil {
+			t.Fatal(err)
+		}
+
+		verificationRepo.On("FindByTokenHash", mock.Anything, hashVerificationToken("reset-code")).
+			Return(&domain.EmailVerification{Model: gorm.Model{ID: 3}, UserID: 7, Email: "john@example.com", Purpose: domain.VerificationPasswordReset, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+		verificationRepo.On("FindByTokenHash", mock.Anything, mock.Anything).Return((*domain.EmailVerification)(nil), shared.ErrRecordNotFound)
+		userRepo.On("FindByID", mock.Anything, uint(7)).Return(user, nil)
+
+		verifier := NewEmailVerificationService(userRepo, verificationRepo, &MockMailer{}, cfg)
+		userService := NewUserService(userRepo, verifier, nil, nil, nil, nil)
+		return NewAuthService(userRepo, userService, tokenProvider, nil, nil, nil), userRepo, verificationRepo, tokenProvider
+	}
+
+	t.Run("CompletesWithResetCode", func(t *testing.T) {
+		service, userRepo, verificationRepo, tokenProvider := setup()
+		verificationRepo.On("MarkVerified", mock.Anything, uint(3)).Return(nil)
+		userRepo.On("CompletePasswordReset", mock.Anything, uint(7), mock.Anything).Return(nil)
+		userRepo.On("UpdateLastActiveAt", mock.Anything, uint(7)).Return(nil)
+		tokenProvider.On("GetAccessExpiry").Return(time.Hour)
+		tokenProvider.On("GenerateToken", mock.Anything, mock.Anything).Return("access_token", nil)
+		tokenProvider.On("GenerateRefreshToken", mock.Anything, mock.Anything).Return("refresh_token", nil)
+
+		res, err := service.ResetPassword(ctx, "reset-code", "NewPassword123", "127.0.0.1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "access_token", res.AccessToken)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("OldPasswordIsNoCode", func(t *testing.T) {
+		service, userRepo, _, _ := setup()
+
+		_, err := service.ResetPassword(ctx, "Stolen123", "NewPassword123", "127.0.0.1")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrInvalidVerificationToken.Code, appErr.Code)
+		userRepo.AssertNotCalled(t, "CompletePasswordReset", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("UsedCodeKeepsPassword", func(t *testing.T) {
+		service, userRepo, verificationRepo, _ := setup()
+		verificationRepo.On("MarkVerified", mock.Anything, uint(3)).
+			Return(shared.ErrInvalidVerificationToken.WithDetails(domain.ErrVerificationUsed.Error()))
+
+		_, err := service.ResetPassword(ctx, "reset-code", "NewPassword123", "127.0.0.1")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrInvalidVerificationToken.Code, appErr.Code)
+		userRepo.AssertNotCalled(t, "CompletePasswordReset", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,128 @@
+package application
+
+import (
+	"context"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+type ContactService struct {
+	contactRepo domain.ContactRepository
+	userRepo    domain.UserRepository
+	blockRepo   domain.UserBlockRepository // Optional, blocked users can still send requests when nil
+}
+
+func NewContactService(contactRepo domain.ContactRepository, userRepo domain.UserRepository, blockRepo domain.UserBlockRepository) *ContactService {
+	return &ContactService{
+		contactRepo: contactRepo,
+		userRepo:    userRepo,
+		blockRepo:   blockRepo,
+	}
+}
+
+// SendRequest asks addresseeID to become a contact. A request crossing one already
+// sent the other way is treated as acceptance.
+func (s *ContactService) SendRequest(ctx context.Context, requesterID, addresseeID uint) (*domain.Contact, error) {
+	if requesterID == addresseeID {
+		return nil, shared.ErrValidation.WithDetails("you cannot add yourself as a contact")
+	}
+	if exists, err := s.userRepo.Exists(ctx, addresseeID); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, shared.ErrUserNotFound
+	}
+
+	if s.blockRepo != nil {
+		hidden, err := s.blockRepo.HiddenFrom(ctx, requesterID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range hidden {
+			if id == addresseeID {
+				return nil, shared.ErrBlocked.WithDetails("contact request not allowed")
+			}
+		}
+	}
+
+	existing, err := s.contactRepo.FindBetween(ctx, requesterID, addresseeID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.Status == domain.ContactPending && existing.AddresseeID == requesterID {
+			if err := s.contactRepo.Accept(ctx, existing.ID); err != nil {
+				return nil, err
+			}
+			return s.contactRepo.FindByID(ctx, existing.ID)
+		}
+		return nil, shared.ErrConflict.WithDetails("a contact request already exists")
+	}
+
+	contact, err := s.contactRepo.Create(ctx, &domain.Contact{
+		RequesterID: requesterID,
+		AddresseeID: addresseeID,
+		Status:      domain.ContactPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shared.Log.Debug("contact request sent", zap.Uint("requesterID", requesterID), zap.Uint("addresseeID", addresseeID))
+	return s.contactRepo.FindByID(ctx, contact.ID)
+}
+
+// Accept is only allowed for the addressee of a pending request
+func (s *ContactService) Accept(ctx context.Context, userID, contactID uint) (*domain.Contact, error) {
+	contact, err := s.contactRepo.FindByID(ctx, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if contact.AddresseeID != userID {
+		return nil, shared.ErrRecordNotFound.WithDetails("contact request not found")
+	}
+	if contact.Status != domain.ContactPending {
+		return nil, shared.ErrConflict.WithDetails("contact request already accepted")
+	}
+
+	if err := s.contactRepo.Accept(ctx, contact.ID); err != nil {
+		return nil, err
+	}
+	return s.contactRepo.FindByID(ctx, contact.ID)
+}
+
+// Decline rejects an incoming request or withdraws an outgoing one
+func (s *ContactService) Decline(ctx context.Context, userID, contactID uint) error {
+	contact, err := s.contactRepo.FindByID(ctx, contactID)
+	if err != nil {
+		return err
+	}
+	if contact.AddresseeID != userID && contact.RequesterID != userID {
+		return shared.ErrRecordNotFound.WithDetails("contact request not found")
+	}
+	if contact.Status != domain.ContactPending {
+		return shared.ErrConflict.WithDetails("contact request already accepted")
+	}
+	return s.contactRepo.Delete(ctx, contact.ID)
+}
+
+// Remove ends an accepted contact relationship from either side
+func (s *ContactService) Remove(ctx context.Context, userID, otherID uint) error {
+	contact, err := s.contactRepo.FindBetween(ctx, userID, otherID)
+	if err != nil {
+		return err
+	}
+	if contact == nil || contact.Status != domain.ContactAccepted {
+		return shared.ErrRecordNotFound.WithDetails("contact not found")
+	}
+	return s.contactRepo.Delete(ctx, contact.ID)
+}
+
+func (s *ContactService) ListContacts(ctx context.Context, userID uint) ([]domain.Contact, error) {
+	return s.contactRepo.ListAccepted(ctx, userID)
+}
+
+func (s *ContactService) ListRequests(ctx context.Context, userID uint) ([]domain.Contact, error) {
+	return s.contactRepo.ListPending(ctx, userID)
+}
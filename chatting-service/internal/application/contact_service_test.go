@@ -0,0 +1,117 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockContactRepository struct {
+	mock.Mock
+}
+
+func (m *MockContactRepository) Create(ctx context.Context, contact *domain.Contact) (*domain.Contact, error) {
+	args := m.Called(ctx, contact)
+	contact.ID = 1
+	return contact, args.Error(0)
+}
+
+func (m *MockContactRepository) FindByID(ctx context.Context, contactID uint) (*domain.Contact, error) {
+	args := m.Called(ctx, contactID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Contact), args.Error(1)
+}
+
+func (m *MockContactRepository) FindBetween(ctx context.Context, userA, userB uint) (*domain.Contact, error) {
+	args := m.Called(ctx, userA, userB)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Contact), args.Error(1)
+}
+
+func (m *MockContactRepository) Accept(ctx context.Context, contactID uint) error {
+	args := m.Called(ctx, contactID)
+	return args.Error(0)
+}
+
+func (m *MockContactRepository) Delete(ctx context.Context, contactID uint) error {
+	args := m.Called(ctx, contactID)
+	return args.Error(0)
+}
+
+func (m *MockContactRepository) ListAccepted(ctx context.Context, userID uint) ([]domain.Contact, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]domain.Contact), args.Error(1)
+}
+
+func (m *MockContactRepository) ListPending(ctx context.Context, userID uint) ([]domain.Contact, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]domain.Contact), args.Error(1)
+}
+
+func (m *MockContactRepository) ContactIDsAmong(ctx context.Context, userID uint, candidateIDs []uint) ([]uint, error) {
+	args := m.Called(ctx, userID, candidateIDs)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockContactRepository) DeleteAllForUser(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func TestContactService(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("CrossingRequestAccepts", func(t *testing.T) {
+		contactRepo := &MockContactRepository{}
+		userRepo := &MockUserRepository{}
+		pending := &domain.Contact{ID: 4, RequesterID: 2, AddresseeID: 1, Status: domain.ContactPending}
+		userRepo.On("Exists", mock.Anything, uint(2)).Return(true, nil)
+		contactRepo.On("FindBetween", mock.Anything, uint(1), uint(2)).Return(pending, nil)
+		contactRepo.On("Accept", mock.Anything, uint(4)).Return(nil)
+		contactRepo.On("FindByID", mock.Anything, uint(4)).
+			Return(&domain.Contact{ID: 4, RequesterID: 2, AddresseeID: 1, Status: domain.ContactAccepted}, nil)
+
+		contact, err := NewContactService(contactRepo, userRepo, nil).SendRequest(ctx, 1, 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ContactAccepted, contact.Status)
+		contactRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("BlockedUserCannotRequest", func(t *testing.T) {
+		contactRepo := &MockContactRepository{}
+		userRepo := &MockUserRepository{}
+		blockRepo := &MockUserBlockRepository{}
+		userRepo.On("Exists", mock.Anything, uint(2)).Return(true, nil)
+		blockRepo.On("HiddenFrom", mock.Anything, uint(1)).Return([]uint{2}, nil)
+
+		_, err := NewContactService(contactRepo, userRepo, blockRepo).SendRequest(ctx, 1, 2)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrBlocked.Code, appErr.Code)
+		contactRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("OnlyAddresseeCanAccept", func(t *testing.T) {
+		contactRepo := &MockContactRepository{}
+		contactRepo.On("FindByID", mock.Anything, uint(4)).
+			Return(&domain.Contact{ID: 4, RequesterID: 1, AddresseeID: 2, Status: domain.ContactPending}, nil)
+
+		_, err := NewContactService(contactRepo, &MockUserRepository{}, nil).Accept(ctx, 1, 4)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrRecordNotFound.Code, appErr.Code)
+		contactRepo.AssertNotCalled(t, "Accept", mock.Anything, mock.Anything)
+	})
+}
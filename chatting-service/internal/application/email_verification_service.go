@@ -0,0 +1,181 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+type EmailVerificationService struct {
+	userRepo         domain.UserRepository
+	verificationRepo domain.EmailVerificationRepository
+	mailer           domain.Mailer
+	cfg              config.MailConfig
+}
+
+func NewEmailVerificationService(
+	userRepo domain.UserRepository,
+	verificationRepo domain.EmailVerificationRepository,
+	mailer domain.Mailer,
+	cfg config.MailConfig,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:         userRepo,
+		verificationRepo: verificationRepo,
+		mailer:           mailer,
+		cfg:              cfg,
+	}
+}
+
+// SendVerification issues a fresh token for the user's current email and mails the link
+func (s *EmailVerificationService) SendVerification(ctx context.Context, user *domain.User) error {
+	if user.EmailVerified {
+		return nil
+	}
+
+	token, err := generateVerificationToken()
+	if err != nil {
+		shared.Log.Error("generate verification token failed", zap.Error(err), zap.Uint("userID", user.ID))
+		return shared.ErrInternalServer.WithDetails("generate verification token failed")
+	}
+
+	// Only the most recent link should work
+	if err := s.verificationRepo.InvalidateForUser(ctx, user.ID, domain.VerificationEmail); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().UTC().Add(s.cfg.VerificationTokenTTL)
+	if _, err := s.verificationRepo.Create(ctx, user.ID, user.Email, hashVerificationToken(token), domain.VerificationEmail, expiresAt); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/auth/verify-email?token=%s", strings.TrimRight(s.cfg.AppBaseURL, "/"), url.QueryEscape(token))
+	body := fmt.Sprintf(
+		"Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s. If you did not request this, you can ignore this email.\n",
+		user.Username, link, s.cfg.VerificationTokenTTL)
+
+	if err := s.mailer.Send(ctx, user.Email, "Verify your email address", body); err != nil {
+		shared.Log.Error("send verification email failed", zap.Error(err), zap.Uint("userID", user.ID))
+		return shared.ErrServiceUnavailable.WithDetails("failed to send verification email")
+	}
+	return nil
+}
+
+// Verify consumes a token from a verification link and flags the user's email as verified
+func (s *EmailVerificationService) Verify(ctx context.Context, token string) (*domain.User, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, shared.ErrInvalidVerificationToken
+	}
+
+	verification, err := s.verificationRepo.FindByTokenHash(ctx, hashVerificationToken(token))
+	if err != nil || verification.Purpose != domain.VerificationEmail {
+		shared.Log.Debug("verification token not found", zap.Error(err))
+		return nil, shared.ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.FindByID(ctx, verification.UserID)
+	if err != nil {
+		shared.Log.Error("find user by ID failed", zap.Error(err), zap.Uint("userID", verification.UserID))
+		return nil, err
+	}
+
+	if err := verification.IsUsable(user.Email); err != nil {
+		shared.Log.Debug("verification token rejected", zap.Error(err), zap.Uint("userID", user.ID))
+		return nil, shared.ErrInvalidVerificationToken.WithDetails(err.Error())
+	}
+
+	if err := s.verificationRepo.MarkVerified(ctx, verification.ID); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	user.MarkEmailVerified()
+	return user, nil
+}
+
+// IssuePasswordReset creates the single-use code that completes a forced password
+// reset. It is mailed to a verified address and returned otherwise, an unverified
+// address may belong to anyone so the admin has to hand the code over instead.
+func (s *EmailVerificationService) IssuePasswordReset(ctx context.Context, user *domain.User) (string, error) {
+	token, err := generateVerificationToken()
+	if err != nil {
+		shared.Log.Error("generate password reset token failed", zap.Error(err), zap.Uint("userID", user.ID))
+		return "", shared.ErrInternalServer.WithDetails("generate password reset token failed")
+	}
+
+	if err := s.verificationRepo.InvalidateForUser(ctx, user.ID, domain.VerificationPasswordReset); err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().UTC().Add(s.cfg.PasswordResetTTL)
+	if _, err := s.verificationRepo.Create(ctx, user.ID, user.Email, hashVerificationToken(token), domain.VerificationPasswordReset, expiresAt); err != nil {
+		return "", err
+	}
+	if !user.EmailVerified {
+		return token, nil
+	}
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nAn administrator requires you to choose a new password before you can sign in again. Send this reset code with your new password to %s/api/auth/password/reset:\n\n%s\n\nThe code expires in %s. Your old password no longer signs you in.\n",
+		user.Username, strings.TrimRight(s.cfg.AppBaseURL, "/"), token, s.cfg.PasswordResetTTL)
+	if err := s.mailer.Send(ctx, user.Email, "Choose a new password", body); err != nil {
+		shared.Log.Error("send password reset email failed", zap.Error(err), zap.Uint("userID", user.ID))
+		return "", shared.ErrServiceUnavailable.WithDetails("failed to send password reset email")
+	}
+	return "", nil
+}
+
+// FindPasswordReset returns the user a reset code was issued to, UsePasswordReset
+// then spends it
+func (s *EmailVerificationService) FindPasswordReset(ctx context.Context, token string) (*domain.User, *domain.EmailVerification, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, nil, shared.ErrInvalidVerificationToken
+	}
+
+	reset, err := s.verificationRepo.FindByTokenHash(ctx, hashVerificationToken(token))
+	if err != nil || reset.Purpose != domain.VerificationPasswordReset {
+		shared.Log.Debug("password reset token not found", zap.Error(err))
+		return nil, nil, shared.ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.FindByID(ctx, reset.UserID)
+	if err != nil {
+		shared.Log.Error("find user by ID failed", zap.Error(err), zap.Uint("userID", reset.UserID))
+		return nil, nil, err
+	}
+	if err := reset.IsUsable(user.Email); err != nil {
+		shared.Log.Debug("password reset token rejected", zap.Error(err), zap.Uint("userID", user.ID))
+		return nil, nil, shared.ErrInvalidVerificationToken.WithDetails(err.Error())
+	}
+	return user, reset, nil
+}
+
+// UsePasswordReset spends a reset code, it fails when the code was used meanwhile
+func (s *EmailVerificationService) UsePasswordReset(ctx context.Context, reset *domain.EmailVerification) error {
+	return s.verificationRepo.MarkVerified(ctx, reset.ID)
+}
+
+func generateVerificationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,158 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockEmailVerificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationRepository) Create(ctx context.Context, userID uint, email, tokenHash string, purpose domain.VerificationPurpose, expiresAt time.Time) (*domain.EmailVerification, error) {
+	args := m.Called(ctx, userID, email, tokenHash, purpose, expiresAt)
+	return args.Get(0).(*domain.EmailVerification), args.Error(1)
+}
+
+func (m *MockEmailVerificationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*domain.EmailVerification, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*domain.EmailVerification), args.Error(1)
+}
+
+func (m *MockEmailVerificationRepository) MarkVerified(ctx context.Context, verificationID uint) error {
+	args := m.Called(ctx, verificationID)
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationRepository) InvalidateForUser(ctx context.Context, userID uint, purpose domain.VerificationPurpose) error {
+	args := m.Called(ctx, userID, purpose)
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, to, subject, body string) error {
+	args := m.Called(ctx, to, subject, body)
+	return args.Error(0)
+}
+
+func TestEmailVerificationService(t *testing.T) {
+	shared.InitLogger("test")
+	cfg := config.MailConfig{AppBaseURL: "http://localhost:8080", VerificationTokenTTL: time.Hour}
+	user := &domain.User{Model: gorm.Model{ID: 1}, Username: "testuser", Email: "test@example.com"}
+
+	t.Run("SendVerificationMailsLink", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verificationRepo := &MockEmailVerificationRepository{}
+		mailer := &MockMailer{}
+
+		verificationRepo.On("InvalidateForUser", mock.Anything, uint(1), domain.VerificationEmail).Return(nil)
+		verificationRepo.On("Create", mock.Anything, uint(1), "test@example.com", mock.Anything, domain.VerificationEmail, mock.Anything).
+			Return(&domain.EmailVerification{}, nil)
+		mailer.On("Send", mock.Anything, "test@example.com", mock.Anything,
+			mock.MatchedBy(func(body string) bool {
+				return strings.Contains(body, "http://localhost:8080/api/auth/verify-email?token=")
+			})).Return(nil)
+
+		service := NewEmailVerificationService(userRepo, verificationRepo, mailer, cfg)
+		assert.NoError(t, service.SendVerification(context.Background(), user))
+
+		verificationRepo.AssertExpectations(t)
+		mailer.AssertExpectations(t)
+	})
+
+	t.Run("VerifyRejectsChangedEmail", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verificationRepo := &MockEmailVerificationRepository{}
+
+		verificationRepo.On("FindByTokenHash", mock.Anything, hashVerificationToken("token")).
+			Return(&domain.EmailVerification{UserID: 1, Email: "old@example.com", Purpose: domain.VerificationEmail, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(user, nil)
+
+		service := NewEmailVerificationService(userRepo, verificationRepo, &MockMailer{}, cfg)
+		_, err := service.Verify(context.Background(), "token")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrInvalidVerificationToken.Code, appErr.Code)
+		verificationRepo.AssertNotCalled(t, "MarkVerified", mock.Anything, mock.Anything)
+	})
+
+	t.Run("VerifyMarksUserVerified", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		verificationRepo := &MockEmailVerificationRepository{}
+
+		verificationRepo.On("FindByTokenHash", mock.Anything, hashVerificationToken("token")).
+			Return(&domain.EmailVerification{Model: gorm.Model{ID: 7}, UserID: 1, Email: "test@example.com", Purpose: domain.VerificationEmail, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+		verificationRepo.On("MarkVerified", mock.Anything, uint(7)).Return(nil)
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, Email: "test@example.com"}, nil)
+		userRepo.On("MarkEmailVerified", mock.Anything, uint(1)).Return(nil)
+
+		service := NewEmailVerificationService(userRepo, verificationRepo, &MockMailer{}, cfg)
+		verified, err := service.Verify(context.Background(), "token")
+
+		assert.NoError(t, err)
+		assert.True(t, verified.EmailVerified)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("VerifyRejectsPasswordResetCode", func(t *testing.T) {
+		verificationRepo := &MockEmailVerificationRepository{}
+		verificationRepo.On("FindByTokenHash", mock.Anything, hashVerificationToken("token")).
+			Return(&domain.EmailVerification{UserID: 1, Email: "test@example.com", Purpose: domain.VerificationPasswordReset, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+		service := NewEmailVerificationService(&MockUserRepository{}, verificationRepo, &MockMailer{}, cfg)
+		_, err := service.Verify(context.Background(), "token")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrInvalidVerificationToken.Code, appErr.Code)
+	})
+
+	t.Run("PasswordResetMailedToVerifiedAddress", func(t *testing.T) {
+		verificationRepo := &MockEmailVerificationRepository{}
+		mailer := &MockMailer{}
+		verificationRepo.On("InvalidateForUser", mock.Anything, uint(1), domain.VerificationPasswordReset).Return(nil)
+		verificationRepo.On("Create", mock.Anything, uint(1), "test@example.com", mock.Anything, domain.VerificationPasswordReset, mock.Anything).
+			Return(&domain.EmailVerification{}, nil)
+		mailer.On("Send", mock.Anything, "test@example.com", "Choose a new password", mock.Anything).Return(nil)
+		verified := &domain.User{Model: gorm.Model{ID: 1}, Username: "testuser", Email: "test@example.com", EmailVerified: true}
+
+		code, err := NewEmailVerificationService(&MockUserRepository{}, verificationRepo, mailer, cfg).IssuePasswordReset(context.Background(), verified)
+
+		assert.NoError(t, err)
+		assert.Empty(t, code)
+		mailer.AssertExpectations(t)
+	})
+
+	t.Run("PasswordResetReturnedForUnverifiedAddress", func(t *testing.T) {
+		verificationRepo := &MockEmailVerificationRepository{}
+		mailer := &MockMailer{}
+		verificationRepo.On("InvalidateForUser", mock.Anything, uint(1), domain.VerificationPasswordReset).Return(nil)
+		verificationRepo.On("Create", mock.Anything, uint(1), "test@example.com", mock.Anything, domain.VerificationPasswordReset, mock.Anything).
+			Return(&domain.EmailVerification{}, nil)
+
+		code, err := NewEmailVerificationService(&MockUserRepository{}, verificationRepo, mailer, cfg).IssuePasswordReset(context.Background(), user)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, code)
+		verificationRepo.AssertCalled(t, "Create", mock.Anything, uint(1), "test@example.com", hashVerificationToken(code), domain.VerificationPasswordReset, mock.Anything)
+		mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,573 @@
+package application
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+const (
+	exportPageSize   = 500
+	exportTimeout    = 30 * time.Minute
+	exportStaleAfter = time.Hour // Unfinished exports older than this were lost to a restart
+)
+
+// ExportService builds personal data archives in the background. Archives hold the
+// profile, settings and every message the user sent or received plus attached media.
+type ExportService struct {
+	exportRepo     domain.DataExportRepository
+	userRepo       domain.UserRepository
+	messageRepo    domain.MessageRepository
+	preferenceRepo domain.NotificationPreferenceRepository
+	contactRepo    domain.ContactRepository
+	blockRepo      domain.UserBlockRepository
+	storage        domain.MediaStorage // Optional, media files are left out when nil
+	archives       domain.ArchiveStore
+	cfg            config.AccountConfig
+	audit          *AuditLogger
+}
+
+func NewExportService(exportRepo domain.DataExportRepository, userRepo domain.UserRepository, messageRepo domain.MessageRepository, preferenceRepo domain.NotificationPreferenceRepository, contactRepo domain.ContactRepository, blockRepo domain.UserBlockRepository, storage domain.MediaStorage, archives domain.ArchiveStore, cfg config.AccountConfig, audit *AuditLogger) *ExportService {
+	return &ExportService{
+		exportRepo:     exportRepo,
+		userRepo:       userRepo,
+		messageRepo:    messageRepo,
+		preferenceRepo: preferenceRepo,
+		contactRepo:    contactRepo,
+		blockRepo:      blockRepo,
+		storage:        storage,
+		archives:       archives,
+		cfg:            cfg,
+		audit:          audit,
+	}
+}
+
+// Request starts a new export, or returns the one still in progress
+func (s *ExportService) Request(ctx context.Context, userID uint) (*domain.DataExport, error) {
+	latest, err := s.exportRepo.FindLatestByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.InProgress() {
+		return latest, nil
+	}
+
+	export := &domain.DataExport{UserID: userID, Status: domain.ExportPending}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(userID),
+		Action:     domain.AuditDataExport,
+		TargetType: "data_export",
+		TargetID:   auditID(export.ID),
+	})
+
+	// The request context ends with the response, the job gets its own. It also
+	// works on its own copy, the caller serializes export while the job updates it.
+	job := *export
+	go s.build(&job)
+
+	return export, nil
+}
+
+// Latest returns the user's most recent export
+func (s *ExportService) Latest(ctx context.Context, userID uint) (*domain.DataExport, error) {
+	export, err := s.exportRepo.FindLatestByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, shared.ErrNotFound.WithDetails("no data export requested")
+	}
+	return export, nil
+}
+
+// DownloadURL is the signed, time-limited link for a finished export
+func (s *ExportService) DownloadURL(export *domain.DataExport) string {
+	if !export.DownloadableAt(time.Now().UTC()) {
+		return ""
+	}
+	expires := export.ExpiresAt.Unix()
+	return fmt.Sprintf("%s/api/exports/%d/download?expires=%d&signature=%s",
+		s.cfg.AppBaseURL, export.ID, expires, s.sign(export.ID, expires))
+}
+
+// OpenDownload checks a download link and opens the archive it points to
+func (s *ExportService) OpenDownload(ctx context.Context, exportID uint, expires int64, signature string) (io.ReadCloser, int64, error) {
+	if !hmac.Equal([]byte(signature), []byte(s.sign(exportID, expires))) {
+		return nil, 0, shared.ErrForbidden.WithDetails("invalid download link")
+	}
+	now := time.Now().UTC()
+	if now.Unix() >= expires {
+		return nil, 0, shared.ErrForbidden.WithDetails("download link has expired")
+	}
+
+	export, err := s.exportRepo.FindByID(ctx, exportID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !export.DownloadableAt(now) || export.ExpiresAt.Unix() != expires {
+		return nil, 0, shared.ErrForbidden.WithDetails("download link has expired")
+	}
+
+	return s.archives.Open(ctx, export.ArchiveName)
+}
+
+func (s *ExportService) sign(exportID uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.ExportSigningKey))
+	mac.Write([]byte(strconv.FormatUint(uint64(exportID), 10) + ":" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// PurgeExpired deletes archives past their expiry and fails jobs lost to a restart
+func (s *ExportService) PurgeExpired(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	if err := s.exportRepo.FailStale(ctx, now.Add(-exportStaleAfter)); err != nil {
+		return 0, err
+	}
+
+	expired, err := s.exportRepo.FindExpired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range expired {
+		export := &expired[i]
+		if err := s.archives.Delete(ctx, export.ArchiveName); err != nil {
+			shared.Log.Error("delete export archive failed", zap.Error(err), zap.Uint("exportID", export.ID))
+			continue
+		}
+		export.Status = domain.ExportExpired
+		export.ArchiveName = ""
+		if err := s.exportRepo.Save(ctx, export); err != nil {
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunJanitor purges expired exports every interval until ctx is done
+func (s *ExportService) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := s.PurgeExpired(ctx); err != nil {
+			shared.Log.Error("purge expired exports failed", zap.Error(err))
+		} else if purged > 0 {
+			shared.Log.Info("purged expired exports", zap.Int("count", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ExportService) build(export *domain.DataExport) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	export.Status = domain.ExportRunning
+	if err := s.exportRepo.Save(ctx, export); err != nil {
+		return
+	}
+
+	name, size, err := s.writeArchive(ctx, export)
+	if err != nil {
+		shared.Log.Error("data export failed", zap.Error(err), zap.Uint("exportID", export.ID), zap.Uint("userID", export.UserID))
+		export.Status = domain.ExportFailed
+		export.Error = "export could not be completed"
+		_ = s.exportRepo.Save(ctx, export)
+		return
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.cfg.ExportLinkTTL)
+	export.Status = domain.ExportReady
+	export.Progress = 100
+	export.ArchiveName = name
+	export.Size = size
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	if err := s.exportRepo.Save(ctx, export); err != nil {
+		_ = s.archives.Delete(ctx, name)
+	}
+}
+
+func (s *ExportService) writeArchive(ctx context.Context, export *domain.DataExport) (string, int64, error) {
+	suffix, err := generateRandomString(16)
+	if err != nil {
+		return "", 0, err
+	}
+	name := fmt.Sprintf("export_%d_%d_%s.zip", export.UserID, export.ID, suffix)
+
+	file, err := s.archives.Create(ctx, name)
+	if err != nil {
+		return "", 0, err
+	}
+	counter := &countingWriter{w: file}
+	archive := zip.NewWriter(counter)
+
+	err = s.writeContents(ctx, archive, export)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = s.archives.Delete(ctx, name)
+		return "", 0, err
+	}
+	return name, counter.n, nil
+}
+
+func (s *ExportService) writeContents(ctx context.Context, archive *zip.Writer, export *domain.DataExport) error {
+	userID := export.UserID
+
+	user, err := s.userRepo.FindProfileByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSONEntry(archive, "profile.json", toExportProfile(user)); err != nil {
+		return err
+	}
+
+	settings, err := s.collectSettings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSONEntry(archive, "settings.json", settings); err != nil {
+		return err
+	}
+	s.reportProgress(ctx, export, 10)
+
+	mediaPaths := map[string]bool{}
+	for _, path := range user.Avatar.Paths() {
+		mediaPaths[path] = true
+	}
+	if err := s.writeMessages(ctx, archive, export, mediaPaths); err != nil {
+		return err
+	}
+	s.reportProgress(ctx, export, 70)
+
+	return s.writeMedia(ctx, archive, export, mediaPaths)
+}
+
+func (s *ExportService) collectSettings(ctx context.Context, userID uint) (exportSettings, error) {
+	settings := exportSettings{
+		Contacts:        []exportContact{},
+		ContactRequests: []exportContact{},
+		BlockedUsers:    []exportBlock{},
+		Mutes:           []exportMute{},
+	}
+
+	prefs, err := s.preferenceRepo.Find(ctx, userID)
+	if err != nil {
+		return settings, err
+	}
+	if prefs == nil {
+		prefs = domain.DefaultNotificationPreferences(userID)
+	}
+	settings.Notifications = exportPreferences{
+		MuteBroadcasts:    prefs.MuteBroadcasts,
+		QuietHoursEnabled: prefs.QuietHoursEnabled,
+		QuietHoursStart:   prefs.QuietHoursStart,
+		QuietHoursEnd:     prefs.QuietHoursEnd,
+		TimeZone:          prefs.TimeZone,
+	}
+
+	mutes, err := s.preferenceRepo.ListMutes(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return settings, err
+	}
+	for _, m := range mutes {
+		settings.Mutes = append(settings.Mutes, exportMute{Scope: string(m.Scope), TargetUserID: m.TargetUserID, Until: m.Until})
+	}
+
+	accepted, err := s.contactRepo.ListAccepted(ctx, userID)
+	if err != nil {
+		return settings, err
+	}
+	for _, c := range accepted {
+		settings.Contacts = append(settings.Contacts, toExportContact(&c, userID))
+	}
+	pending, err := s.contactRepo.ListPending(ctx, userID)
+	if err != nil {
+		return settings, err
+	}
+	for _, c := range pending {
+		settings.ContactRequests = append(settings.ContactRequests, toExportContact(&c, userID))
+	}
+
+	blocks, err := s.blockRepo.ListBlocked(ctx, userID)
+	if err != nil {
+		return settings, err
+	}
+	for _, b := range blocks {
+		settings.BlockedUsers = append(settings.BlockedUsers, exportBlock{UserID: b.BlockedID, Username: b.Blocked.Username, BlockedAt: b.CreatedAt})
+	}
+	return settings, nil
+}
+
+// writeMessages streams messages.json a page at a time and collects attached media
+func (s *ExportService) writeMessages(ctx context.Context, archive *zip.Writer, export *domain.DataExport, mediaPaths map[string]bool) error {
+	total, err := s.messageRepo.CountForExport(ctx, export.UserID)
+	if err != nil {
+		return err
+	}
+
+	entry, err := archive.Create("messages.json")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(entry, "["); err != nil {
+		return err
+	}
+
+	var afterID uint
+	written := 0
+	for {
+		page, err := s.messageRepo.FindForExport(ctx, export.UserID, afterID, exportPageSize)
+		if err != nil {
+			return err
+		}
+		for i := range page {
+			msg := &page[i]
+			if written > 0 {
+				if _, err := io.WriteString(entry, ","); err != nil {
+					return err
+				}
+			}
+			raw, err := json.Marshal(toExportMessage(msg, export.UserID))
+			if err != nil {
+				return err
+			}
+			if _, err := entry.Write(raw); err != nil {
+				return err
+			}
+			written++
+
+			if s.storage != nil && msg.MediaURL != "" {
+				if path, ok := s.storage.PathFromURL(msg.MediaURL); ok {
+					mediaPaths[path] = true
+				}
+			}
+		}
+		if len(page) < exportPageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+		if total > 0 {
+			s.reportProgress(ctx, export, 10+int(60*int64(written)/total))
+		}
+	}
+
+	_, err = io.WriteString(entry, "]")
+	return err
+}
+
+func (s *ExportService) writeMedia(ctx context.Context, archive *zip.Writer, export *domain.DataExport, mediaPaths map[string]bool) error {
+	if s.storage == nil || len(mediaPaths) == 0 {
+		return nil
+	}
+
+	done := 0
+	for path := range mediaPaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.copyMedia(ctx, archive, path); err != nil {
+			// Files deleted since the message was sent are skipped, not fatal
+			shared.Log.Warn("skip media in export", zap.String("path", path), zap.Error(err))
+		}
+		done++
+		s.reportProgress(ctx, export, 70+25*done/len(mediaPaths))
+	}
+	return nil
+}
+
+func (s *ExportService) copyMedia(ctx context.Context, archive *zip.Writer, path string) error {
+	src, err := s.storage.Open(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	entry, err := archive.Create("media/" + path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, src)
+	return err
+}
+
+// reportProgress only moves forward, failures to store it do not stop the export
+func (s *ExportService) reportProgress(ctx context.Context, export *domain.DataExport, progress int) {
+	if progress <= export.Progress || progress >= 100 {
+		return
+	}
+	export.Progress = progress
+	_ = s.exportRepo.UpdateProgress(ctx, export.ID, progress)
+}
+
+func writeJSONEntry(archive *zip.Writer, name string, v interface{}) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Archive layout, kept separate from the API DTOs so the export format stays stable
+
+type exportProfile struct {
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+	TimeZone      string    `json:"time_zone"`
+	Role          string    `json:"role"`
+	IsBot         bool      `json:"is_bot"`
+	Avatar        []string  `json:"avatar_files"` // Paths inside media/
+	CreatedAt     time.Time `json:"created_at"`
+	LastActiveAt  time.Time `json:"last_active_at"`
+}
+
+type exportPreferences struct {
+	MuteBroadcasts    bool   `json:"mute_broadcasts"`
+	QuietHoursEnabled bool   `json:"quiet_hours_enabled"`
+	QuietHoursStart   string `json:"quiet_hours_start"`
+	QuietHoursEnd     string `json:"quiet_hours_end"`
+	TimeZone          string `json:"time_zone"`
+}
+
+type exportMute struct {
+	Scope        string     `json:"scope"`
+	TargetUserID uint       `json:"target_user_id"`
+	Until        *time.Time `json:"until,omitempty"`
+}
+
+type exportContact struct {
+	UserID     uint       `json:"user_id"`
+	Username   string     `json:"username"`
+	Status     string     `json:"status"`
+	Outgoing   bool       `json:"outgoing"` // The exporting user sent the request
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+type exportBlock struct {
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+type exportSettings struct {
+	Notifications   exportPreferences `json:"notifications"`
+	Mutes           []exportMute      `json:"mutes"`
+	Contacts        []exportContact   `json:"contacts"`
+	ContactRequests []exportContact   `json:"contact_requests"`
+	BlockedUsers    []exportBlock     `json:"blocked_users"`
+}
+
+type exportMessage struct {
+	ID             uint       `json:"id"`
+	Type           string     `json:"type"`
+	Direction      string     `json:"direction"` // sent or received
+	SenderID       uint       `json:"sender_id"`
+	SenderUsername string     `json:"sender_username,omitempty"`
+	RecipientID    *uint      `json:"recipient_id,omitempty"`
+	Content        string     `json:"content"`
+	MediaURL       string     `json:"media_url,omitempty"`
+	Erased         bool       `json:"erased,omitempty"`
+	SentAt         time.Time  `json:"sent_at"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+func toExportProfile(u *domain.User) exportProfile {
+	return exportProfile{
+		ID:            u.ID,
+		Username:      u.Username,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		DisplayName:   u.DisplayName,
+		Bio:           u.Bio,
+		TimeZone:      u.TimeZone,
+		Role:          string(u.Role),
+		IsBot:         u.IsBot,
+		Avatar:        u.Avatar.Paths(),
+		CreatedAt:     u.CreatedAt,
+		LastActiveAt:  u.LastActiveAt,
+	}
+}
+
+func toExportContact(c *domain.Contact, userID uint) exportContact {
+	other := c.Other(userID)
+	return exportContact{
+		UserID:     other.ID,
+		Username:   other.Username,
+		Status:     string(c.Status),
+		Outgoing:   c.RequesterID == userID,
+		CreatedAt:  c.CreatedAt,
+		AcceptedAt: c.AcceptedAt,
+	}
+}
+
+func toExportMessage(m *domain.Message, userID uint) exportMessage {
+	msg := exportMessage{
+		ID:             m.ID,
+		Type:           string(m.MessageType),
+		Direction:      "received",
+		SenderID:       m.SenderID,
+		SenderUsername: m.Sender.Username,
+		RecipientID:    m.RecipientID,
+		Content:        m.Content,
+		MediaURL:       m.MediaURL,
+		Erased:         m.Erased,
+		SentAt:         m.SentAt,
+		ReadAt:         m.ReadAt,
+	}
+	if m.SenderID == userID {
+		msg.Direction = "sent"
+	}
+	if m.DeletedAt.Valid {
+		msg.DeletedAt = &m.DeletedAt.Time
+	}
+	return msg
+}
@@ -0,0 +1,207 @@
+package application
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDataExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockDataExportRepository) Create(ctx context.Context, export *domain.DataExport) error {
+	return m.Called(ctx, export).Error(0)
+}
+
+func (m *MockDataExportRepository) FindByID(ctx context.Context, id uint) (*domain.DataExport, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) FindLatestByUser(ctx context.Context, userID uint) (*domain.DataExport, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) Save(ctx context.Context, export *domain.DataExport) error {
+	return m.Called(ctx, export).Error(0)
+}
+
+func (m *MockDataExportRepository) UpdateProgress(ctx context.Context, id uint, progress int) error {
+	return m.Called(ctx, id, progress).Error(0)
+}
+
+func (m *MockDataExportRepository) FindExpired(ctx context.Context, now time.Time) ([]domain.DataExport, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]domain.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) FailStale(ctx context.Context, startedBefore time.Time) error {
+	return m.Called(ctx, startedBefore).Error(0)
+}
+
+func (m *MockDataExportRepository) ExpireForUser(ctx context.Context, userID uint, now time.Time) error {
+	return m.Called(ctx, userID, now).Error(0)
+}
+
+type MockArchiveStore struct {
+	mock.Mock
+}
+
+func (m *MockArchiveStore) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.WriteCloser), args.Error(1)
+}
+
+func (m *MockArchiveStore) Open(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).(io.ReadCloser), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockArchiveStore) Delete(ctx context.Context, name string) error {
+	return m.Called(ctx, name).Error(0)
+}
+
+var exportConfig = config.AccountConfig{
+	ExportLinkTTL:    time.Hour,
+	ExportSigningKey: "test-signing-key",
+	AppBaseURL:       "https://chat.example.com",
+}
+
+func readyExport() *domain.DataExport {
+	expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	export := &domain.DataExport{UserID: 1, Status: domain.ExportReady, ArchiveName: "export_1_7_abc.zip", ExpiresAt: &expiresAt}
+	export.ID = 7
+	return export
+}
+
+func assertForbidden(t *testing.T, err error) {
+	var appErr shared.Error
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, shared.ErrForbidden.Code, appErr.Code)
+	}
+}
+
+// linkParams pulls expires and signature back out of a download URL
+func linkParams(t *testing.T, link string) (int64, string) {
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	expires, err := strconv.ParseInt(parsed.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+	return expires, parsed.Query().Get("signature")
+}
+
+func TestExportService_Request(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("ReturnsExportInProgress", func(t *testing.T) {
+		running := &domain.DataExport{UserID: 1, Status: domain.ExportRunning, Progress: 40}
+		exportRepo := &MockDataExportRepository{}
+		exportRepo.On("FindLatestByUser", ctx, uint(1)).Return(running, nil)
+
+		service := NewExportService(exportRepo, nil, nil, nil, nil, nil, nil, &MockArchiveStore{}, exportConfig, nil)
+		export, err := service.Request(ctx, 1)
+
+		assert.NoError(t, err)
+		assert.Same(t, running, export)
+		exportRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("LatestWithoutExport", func(t *testing.T) {
+		exportRepo := &MockDataExportRepository{}
+		exportRepo.On("FindLatestByUser", ctx, uint(1)).Return(nil, nil)
+
+		service := NewExportService(exportRepo, nil, nil, nil, nil, nil, nil, &MockArchiveStore{}, exportConfig, nil)
+		_, err := service.Latest(ctx, 1)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrNotFound.Code, appErr.Code)
+	})
+}
+
+func TestExportService_Download(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("SignedLinkOpensArchive", func(t *testing.T) {
+		export := readyExport()
+		exportRepo := &MockDataExportRepository{}
+		exportRepo.On("FindByID", ctx, uint(7)).Return(export, nil)
+		archives := &MockArchiveStore{}
+		archives.On("Open", ctx, "export_1_7_abc.zip").Return(io.NopCloser(strings.NewReader("zip")), int64(3), nil)
+
+		service := NewExportService(exportRepo, nil, nil, nil, nil, nil, nil, archives, exportConfig, nil)
+		link := service.DownloadURL(export)
+		assert.True(t, strings.HasPrefix(link, "https://chat.example.com/api/exports/7/download?"))
+
+		expires, signature := linkParams(t, link)
+		reader, size, err := service.OpenDownload(ctx, 7, expires, signature)
+		require.NoError(t, err)
+		defer reader.Close()
+		assert.Equal(t, int64(3), size)
+	})
+
+	t.Run("RejectsTamperedLink", func(t *testing.T) {
+		export := readyExport()
+		exportRepo := &MockDataExportRepository{}
+		service := NewExportService(exportRepo, nil, nil, nil, nil, nil, nil, &MockArchiveStore{}, exportConfig, nil)
+		expires, signature := linkParams(t, service.DownloadURL(export))
+
+		// Another export ID or a pushed back expiry must not verify
+		_, _, err := service.OpenDownload(ctx, 8, expires, signature)
+		assertForbidden(t, err)
+		_, _, err = service.OpenDownload(ctx, 7, expires+3600, signature)
+		assertForbidden(t, err)
+		exportRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("RejectsExpiredLink", func(t *testing.T) {
+		export := readyExport()
+		past := time.Now().UTC().Add(-time.Minute)
+		export.ExpiresAt = &past
+		service := NewExportService(&MockDataExportRepository{}, nil, nil, nil, nil, nil, nil, &MockArchiveStore{}, exportConfig, nil)
+
+		assert.Empty(t, service.DownloadURL(export))
+		_, _, err := service.OpenDownload(ctx, 7, past.Unix(), service.sign(7, past.Unix()))
+		assertForbidden(t, err)
+	})
+
+	t.Run("RejectsPurgedExport", func(t *testing.T) {
+		export := readyExport()
+		exportRepo := &MockDataExportRepository{}
+		service := NewExportService(exportRepo, nil, nil, nil, nil, nil, nil, &MockArchiveStore{}, exportConfig, nil)
+		expires, signature := linkParams(t, service.DownloadURL(export))
+
+		export.Status = domain.ExportExpired
+		exportRepo.On("FindByID", ctx, uint(7)).Return(export, nil)
+
+		_, _, err := service.OpenDownload(ctx, 7, expires, signature)
+		assertForbidden(t, err)
+	})
+}
@@ -0,0 +1,146 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+// LoginGuard throttles password guessing per username and per client IP.
+// Once a key crosses its threshold it is locked out, doubling the lockout
+// on every further failure up to the configured maximum.
+type LoginGuard struct {
+	attemptRepo domain.LoginAttemptRepository
+	cfg         config.LoginProtectionConfig
+	audit       *AuditLogger
+}
+
+func NewLoginGuard(attemptRepo domain.LoginAttemptRepository, cfg config.LoginProtectionConfig, audit *AuditLogger) *LoginGuard {
+	return &LoginGuard{
+		attemptRepo: attemptRepo,
+		cfg:         cfg,
+		audit:       audit,
+	}
+}
+
+// Check rejects the login before any password comparison if the username or IP is locked
+func (g *LoginGuard) Check(ctx context.Context, username, clientIP string) error {
+	now := time.Now().UTC()
+
+	for _, k := range g.keys(username, clientIP) {
+		attempt, err := g.attemptRepo.Find(ctx, k.scope, k.key)
+		if err != nil {
+			return err
+		}
+		if attempt != nil && attempt.IsLocked(now) {
+			retryAfter := attempt.RetryAfter(now)
+			shared.Log.Warn("login blocked by lockout",
+				zap.String("event", "login_locked"),
+				zap.String("scope", string(k.scope)),
+				zap.String("username", username),
+				zap.String("ip", clientIP),
+				zap.Duration("retryAfter", retryAfter))
+			return shared.ErrAccountLocked.WithDetails(shared.RetryAfterDetails{
+				RetryAfterSeconds: int(math.Ceil(retryAfter.Seconds())),
+				Reason:            string(k.scope) + " temporarily locked",
+			})
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login and locks the username/IP once over the threshold
+func (g *LoginGuard) RecordFailure(ctx context.Context, username, clientIP string) error {
+	for _, k := range g.keys(username, clientIP) {
+		attempt, err := g.attemptRepo.RegisterFailure(ctx, k.scope, k.key, g.cfg.AttemptWindow)
+		if err != nil {
+			return err
+		}
+
+		lockout := g.lockoutFor(attempt.FailedCount, g.threshold(k.scope))
+		if lockout == 0 {
+			continue
+		}
+
+		if err := g.attemptRepo.Lock(ctx, k.scope, k.key, time.Now().UTC().Add(lockout)); err != nil {
+			return err
+		}
+		shared.Log.Warn("login locked after repeated failures",
+			zap.String("event", "login_lockout"),
+			zap.String("scope", string(k.scope)),
+			zap.String("username", username),
+			zap.String("ip", clientIP),
+			zap.Int("failedCount", attempt.FailedCount),
+			zap.Duration("lockout", lockout))
+		g.audit.Record(ctx, domain.AuditEvent{
+			Actor:      username,
+			Action:     domain.AuditLockout,
+			Outcome:    domain.AuditDenied,
+			TargetType: string(k.scope),
+			TargetID:   k.key,
+			IP:         clientIP,
+			Details:    fmt.Sprintf("%d failed attempts, locked for %s", attempt.FailedCount, lockout),
+		})
+	}
+	return nil
+}
+
+// RecordSuccess clears the username counter, the IP counter is left to expire
+// so a valid account cannot be used to reset an attacker's budget
+func (g *LoginGuard) RecordSuccess(ctx context.Context, username string) error {
+	return g.attemptRepo.Reset(ctx, domain.LoginScopeUsername, normalizeLoginKey(username))
+}
+
+// Unlock lifts a username lockout ahead of time
+func (g *LoginGuard) Unlock(ctx context.Context, username string) error {
+	return g.attemptRepo.Reset(ctx, domain.LoginScopeUsername, normalizeLoginKey(username))
+}
+
+func (g *LoginGuard) threshold(scope domain.LoginAttemptScope) int {
+	if scope == domain.LoginScopeIP {
+		return g.cfg.MaxAttemptsPerIP
+	}
+	return g.cfg.MaxAttemptsPerUser
+}
+
+// lockoutFor returns zero below the threshold, then BaseLockout doubled per extra failure
+func (g *LoginGuard) lockoutFor(failedCount, threshold int) time.Duration {
+	if threshold <= 0 || failedCount < threshold {
+		return 0
+	}
+
+	exponent := failedCount - threshold
+	if exponent > 20 {
+		exponent = 20 // Avoid overflowing the shift, MaxLockout caps it anyway
+	}
+
+	lockout := g.cfg.BaseLockout << exponent
+	if g.cfg.MaxLockout > 0 && lockout > g.cfg.MaxLockout {
+		lockout = g.cfg.MaxLockout
+	}
+	return lockout
+}
+
+type loginKey struct {
+	scope domain.LoginAttemptScope
+	key   string
+}
+
+func (g *LoginGuard) keys(username, clientIP string) []loginKey {
+	keys := []loginKey{{scope: domain.LoginScopeUsername, key: normalizeLoginKey(username)}}
+	if clientIP != "" {
+		keys = append(keys, loginKey{scope: domain.LoginScopeIP, key: clientIP})
+	}
+	return keys
+}
+
+func normalizeLoginKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
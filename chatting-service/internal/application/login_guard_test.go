@@ -0,0 +1,91 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepository) Find(ctx context.Context, scope domain.LoginAttemptScope, key string) (*domain.LoginAttempt, error) {
+	args := m.Called(ctx, scope, key)
+	attempt, _ := args.Get(0).(*domain.LoginAttempt)
+	return attempt, args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) RegisterFailure(ctx context.Context, scope domain.LoginAttemptScope, key string, window time.Duration) (*domain.LoginAttempt, error) {
+	args := m.Called(ctx, scope, key, window)
+	return args.Get(0).(*domain.LoginAttempt), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) Lock(ctx context.Context, scope domain.LoginAttemptScope, key string, until time.Time) error {
+	args := m.Called(ctx, scope, key, until)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) Reset(ctx context.Context, scope domain.LoginAttemptScope, key string) error {
+	args := m.Called(ctx, scope, key)
+	return args.Error(0)
+}
+
+func TestLoginGuard(t *testing.T) {
+	shared.InitLogger("test")
+	cfg := config.LoginProtectionConfig{
+		MaxAttemptsPerUser: 3,
+		MaxAttemptsPerIP:   10,
+		AttemptWindow:      time.Minute * 15,
+		BaseLockout:        time.Minute,
+		MaxLockout:         time.Minute * 10,
+	}
+
+	t.Run("LockoutBackoff", func(t *testing.T) {
+		guard := NewLoginGuard(&MockLoginAttemptRepository{}, cfg, nil)
+
+		assert.Equal(t, time.Duration(0), guard.lockoutFor(2, 3))
+		assert.Equal(t, time.Minute, guard.lockoutFor(3, 3))
+		assert.Equal(t, time.Minute*2, guard.lockoutFor(4, 3))
+		assert.Equal(t, time.Minute*8, guard.lockoutFor(6, 3))
+		assert.Equal(t, time.Minute*10, guard.lockoutFor(7, 3)) // capped
+		assert.Equal(t, time.Minute*10, guard.lockoutFor(500, 3))
+	})
+
+	t.Run("CheckRejectsLockedUsername", func(t *testing.T) {
+		repo := &MockLoginAttemptRepository{}
+		lockedUntil := time.Now().UTC().Add(time.Minute * 2)
+		repo.On("Find", mock.Anything, domain.LoginScopeUsername, "testuser").
+			Return(&domain.LoginAttempt{FailedCount: 3, LockedUntil: &lockedUntil}, nil)
+
+		err := NewLoginGuard(repo, cfg, nil).Check(context.Background(), " TestUser ", "10.0.0.1")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrAccountLocked.Code, appErr.Code)
+		details, ok := appErr.Details.(shared.RetryAfterDetails)
+		assert.True(t, ok)
+		assert.InDelta(t, 120, details.RetryAfterSeconds, 1)
+	})
+
+	t.Run("RecordFailureLocksAtThreshold", func(t *testing.T) {
+		repo := &MockLoginAttemptRepository{}
+		repo.On("RegisterFailure", mock.Anything, domain.LoginScopeUsername, "testuser", cfg.AttemptWindow).
+			Return(&domain.LoginAttempt{FailedCount: 3}, nil)
+		repo.On("RegisterFailure", mock.Anything, domain.LoginScopeIP, "10.0.0.1", cfg.AttemptWindow).
+			Return(&domain.LoginAttempt{FailedCount: 3}, nil)
+		repo.On("Lock", mock.Anything, domain.LoginScopeUsername, "testuser", mock.Anything).Return(nil)
+
+		err := NewLoginGuard(repo, cfg, nil).RecordFailure(context.Background(), "testuser", "10.0.0.1")
+
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "Lock", mock.Anything, domain.LoginScopeIP, mock.Anything, mock.Anything)
+	})
+}
@@ -1,83 +1,809 @@
 package application
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/AmeerHeiba/chatting-service/internal/config"
 	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/AmeerHeiba/chatting-service/internal/shared"
 	"go.uber.org/zap"
 )
 
+const (
+	maxAvatarSize = 5 * 1024 * 1024 // 5MB
+
+	defaultMediaPageSize = 50
+	maxMediaPageSize     = 200
+)
+
+// Formats the image resizer can decode
+var resizableContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
 type MediaService struct {
-	storage domain.MediaStorage
+	storage          domain.MediaStorage
+	resizer          domain.ImageResizer
+	messageRepo      domain.MessageRepository
+	announcementRepo domain.AnnouncementRepository
+	signedURLTTL     time.Duration
+	mediaRepo        domain.MediaRepository
+	uploads          config.UploadConfig
+	stripper         domain.MetadataStripper
+	txManager        domain.TransactionManager
+	userRepo         domain.UserRepository
+}
+
+// MediaServiceDeps wires a MediaService, optional fields may be left out
+type MediaServiceDeps struct {
+	Storage       domain.MediaStorage
+	Resizer       domain.ImageResizer // Optional, avatar uploads are unavailable when nil
+	Messages      domain.MessageRepository
+	Announcements domain.AnnouncementRepository // Optional, announcement media is only served to its uploader when nil
+	SignedURLTTL  time.Duration
+	Media         domain.MediaRepository
+	Uploads       config.UploadConfig     // Allowed types and their size limits
+	Stripper      domain.MetadataStripper // Optional, images keep their metadata when nil
+	Transactions  domain.TransactionManager
+	Users         domain.UserRepository // Optional, quotas of roles are not applied when nil
 }
 
-func NewMediaService(storage domain.MediaStorage) *MediaService {
+func NewMediaService(deps MediaServiceDeps) *MediaService {
 	return &MediaService{
-		storage: storage,
+		storage:          deps.Storage,
+		resizer:          deps.Resizer,
+		messageRepo:      deps.Messages,
+		announcementRepo: deps.Announcements,
+		signedURLTTL:     deps.SignedURLTTL,
+		mediaRepo:        deps.Media,
+		uploads:          deps.Uploads,
+		stripper:         deps.Stripper,
+		txManager:        deps.Transactions,
+		userRepo:         deps.Users,
 	}
 }
 
 func (s *MediaService) Upload(ctx context.Context, userID uint, file io.Reader, filename string, contentType string, size int64, userId uint) (*domain.MediaResponse, error) {
 	// Validate file size
-	if size > 10*1024*1024 { // 10MB
+	largest := s.uploads.LargestSize()
+	if size > largest {
 		shared.Log.Debug("file too large",
 			zap.Int64("size", size),
 			zap.Uint("userID", userID))
-		return nil, shared.ErrValidation.WithDetails("file size exceeds 10MB limit")
+		return nil, fileTooLarge(largest)
 	}
 
-	// Generate unique filename
-	uniqueFilename := generateUniqueFilename(userID, filename)
+	// The declared size is only a hint, the record keeps what was actually received
+	data, err := io.ReadAll(io.LimitReader(file, largest+1))
+	if err != nil {
+		shared.Log.Error("failed to read upload",
+			zap.String("filename", filename),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+	size = int64(len(data))
 
-	// Upload to storage
-	path, err := s.storage.Upload(ctx, file, uniqueFilename, contentType, size, userID)
+	contentType, err = s.checkContentType(data, contentType, userID)
 	if err != nil {
+		return nil, err
+	}
+	if limit, _ := s.uploads.MaxSize(contentType); size > limit {
+		return nil, fileTooLarge(limit)
+	}
+	return s.store(ctx, userID, filename, contentType, data)
+}
+
+// checkContentType decides the type of an upload from its leading bytes and checks
+// it is allowed. Clients can claim any type, what is stored and served follows the content.
+func (s *MediaService) checkContentType(head []byte, declared string, userID uint) (string, error) {
+	detected, ok := detectContentType(head, declared)
+	if !ok {
+		shared.Log.Debug("upload content does not match its type",
+			zap.String("declared", declared),
+			zap.String("detected", detected),
+			zap.Uint("userID", userID))
+		return "", shared.ErrUnsupportedMediaType.WithDetails(fmt.Sprintf("file content is %s, not %s", detected, declared))
+	}
+	if _, allowed := s.uploads.MaxSize(detected); !allowed {
+		return "", shared.ErrUnsupportedMediaType.WithDetails(fmt.Sprintf("%s files are not allowed", detected))
+	}
+	return detected, nil
+}
+
+// store saves an upload held in memory, images lose their metadata and get thumbnails
+func (s *MediaService) store(ctx context.Context, userID uint, filename string, contentType string, data []byte) (*domain.MediaResponse, error) {
+	// Photos carry the place they were taken, the orientation is applied to the pixels instead
+	if s.stripper != nil && !s.uploads.KeepMetadata {
+		stripped, err := s.stripper.StripMetadata(ctx, data, contentType)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidImage) || errors.Is(err, domain.ErrImageTooLarge) {
+				return nil, shared.ErrValidation.WithDetails(err.Error())
+			}
+			shared.Log.Error("failed to strip image metadata",
+				zap.String("filename", filename),
+				zap.Uint("userID", userID),
+				zap.Error(err))
+			return nil, err
+		}
+		data = stripped
+	}
+	size := int64(len(data))
+	if err := s.checkQuota(ctx, userID, size); err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(data)
+
+	blob := newMediaBlob(hex.EncodeToString(checksum[:]), contentType, size)
+	if err := s.storage.Put(ctx, bytes.NewReader(data), blob.Path, contentType, size); err != nil {
 		shared.Log.Error("failed to upload media",
 			zap.String("filename", filename),
 			zap.Uint("userID", userID),
 			zap.Error(err))
+		s.discard(ctx, blob.Path)
 		return nil, err
 	}
+	if s.resizer != nil && strings.HasPrefix(contentType, "image/") {
+		// Formats the resizer cannot decode are stored without dimensions
+		if width, height, err := s.resizer.Dimensions(ctx, bytes.NewReader(data)); err == nil {
+			blob.Width, blob.Height = &width, &height
+		}
+	}
+	blob.Thumbnails = s.storeThumbnails(ctx, data, blob)
 
-	// Get public URL
-	url, err := s.storage.GetURL(ctx, path)
+	record := newMediaRecord(userID, filename, blob)
+	if err := s.reference(ctx, record, blob); err != nil {
+		return nil, err
+	}
+	return s.respond(ctx, record)
+}
+
+// storeStream saves an upload too large to hold in memory as it is read. The content
+// is hashed on the way and discarded when it does not match checksum.
+func (s *MediaService) storeStream(ctx context.Context, userID uint, filename string, contentType string, file io.Reader, size int64, checksum string) (*domain.MediaResponse, error) {
+	if err := s.checkQuota(ctx, userID, size); err != nil {
+		return nil, err
+	}
+	blob := newMediaBlob(checksum, contentType, size)
+	hash := sha256.New()
+	if err := s.storage.Put(ctx, io.TeeReader(file, hash), blob.Path, contentType, size); err != nil {
+		shared.Log.Error("failed to upload media",
+			zap.String("filename", filename),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		s.discard(ctx, blob.Path)
+		return nil, err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != checksum {
+		s.discard(ctx, blob.Path)
+		return nil, errChecksumMismatch
+	}
+
+	record := newMediaRecord(userID, filename, blob)
+	if err := s.reference(ctx, record, blob); err != nil {
+		return nil, err
+	}
+	return s.respond(ctx, record)
+}
+
+// newMediaBlob describes a new copy of content, see domain.MediaBlobPath
+func newMediaBlob(checksum string, contentType string, size int64) *domain.MediaBlob {
+	copyID := make([]byte, 8)
+	rand.Read(copyID)
+	return &domain.MediaBlob{
+		Path:        domain.MediaBlobPath(checksum, hex.EncodeToString(copyID), mediaExtension(contentType)),
+		Checksum:    checksum,
+		ContentType: contentType,
+		Size:        size,
+	}
+}
+
+func newMediaRecord(userID uint, filename string, blob *domain.MediaBlob) *domain.Media {
+	return &domain.Media{
+		OwnerID:          userID,
+		Path:             blob.Path,
+		OriginalFilename: originalFilename(filename),
+		ContentType:      blob.ContentType,
+		Size:             blob.Size,
+		Checksum:         blob.Checksum,
+	}
+}
+
+// reference adds an upload to the media library as a reference to the blob holding its
+// content. stored is the copy written for the upload, it is only kept when the content
+// was not stored before. Storage is written before and cleaned up after the transaction,
+// so the blob and the usage count stay locked only while the reference is published.
+func (s *MediaService) reference(ctx context.Context, record *domain.Media, stored *domain.MediaBlob) error {
+	quota, err := s.quota(ctx, record.OwnerID)
+	if err != nil {
+		s.discard(ctx, append(stored.Thumbnails.Paths(), stored.Path)...)
+		return err
+	}
+
+	var blob *domain.MediaBlob
+	err = s.txManager.WithTransaction(ctx, func(ctx context.Context, repos *domain.Repositories) error {
+		// Checked again while locked, concurrent uploads may have used the room since checkQuota
+		if err := repos.Media.AddUsage(ctx, record.OwnerID, record.Size, quota); err != nil {
+			if isQuotaExceeded(err) {
+				return storageQuotaExceeded(quota)
+			}
+			return err
+		}
+
+		candidate := *stored
+		acquired, err := repos.Media.AcquireBlob(ctx, &candidate)
+		if err != nil {
+			return err
+		}
+		blob = acquired
+		blob.RefCount++
+		if err := repos.Media.SaveBlob(ctx, blob); err != nil {
+			return err
+		}
+		record.Path, record.Width, record.Height, record.Thumbnails = blob.Path, blob.Width, blob.Height, blob.Thumbnails
+		return repos.Media.Create(ctx, record)
+	})
+	if err != nil || blob == nil || blob.Path != stored.Path {
+		// Failed, or the content was stored before and the upload shares that copy
+		s.discard(ctx, append(stored.Thumbnails.Paths(), stored.Path)...)
+	}
+	return err
+}
+
+// respond describes a recorded upload to its uploader
+func (s *MediaService) respond(ctx context.Context, record *domain.Media) (*domain.MediaResponse, error) {
+	url, err := s.storage.GetURL(ctx, record.Path)
 	if err != nil {
 		shared.Log.Error("failed to get media URL",
-			zap.String("path", path),
-			zap.Uint("userID", userID),
+			zap.String("path", record.Path),
+			zap.Uint("userID", record.OwnerID),
 			zap.Error(err))
 		return nil, err
 	}
 
-	return &domain.MediaResponse{
+	response := &domain.MediaResponse{
+		ID:          record.ID,
 		URL:         url,
-		Size:        size,
-		ContentType: contentType,
+		Size:        record.Size,
+		ContentType: record.ContentType,
+		Width:       record.Width,
+		Height:      record.Height,
+		Thumbnails:  record.Thumbnails,
 		UploadedAt:  time.Now().UTC(),
-	}, nil
+	}
+
+	// The uploader can preview the file right away without sending credentials
+	if s.signedURLTTL > 0 {
+		signed, err := s.storage.GetSignedURL(ctx, record.Path, s.signedURLTTL)
+		if err != nil {
+			shared.Log.Warn("failed to sign media URL",
+				zap.String("path", record.Path),
+				zap.Error(err))
+		} else {
+			expiresAt := response.UploadedAt.Add(s.signedURLTTL)
+			response.SignedURL = signed
+			response.ExpiresAt = &expiresAt
+		}
+	}
+	return response, nil
 }
 
-func (s *MediaService) Delete(ctx context.Context, userID uint, path string) error {
-	err := s.storage.Delete(ctx, path)
+// OpenSigned opens a file for a link made by GetSignedURL, no login needed
+func (s *MediaService) OpenSigned(ctx context.Context, path string, expires int64, signature string) (io.ReadCloser, error) {
+	path, ok := cleanMediaPath(path)
+	if !ok {
+		return nil, shared.ErrNotFound.WithDetails("media not found")
+	}
+	verifier, ok := s.storage.(domain.SignedURLVerifier)
+	if !ok {
+		return nil, shared.ErrNotFound.WithDetails("media not found")
+	}
+	if err := verifier.VerifySignedURL(path, expires, signature); err != nil {
+		return nil, shared.ErrForbidden.WithDetails(err.Error())
+	}
+	return s.open(ctx, path)
+}
+
+// OpenForUser opens a file the user may see: their own uploads, avatars, and media
+// of messages and announcements they can read. Anything else looks like it does not exist.
+func (s *MediaService) OpenForUser(ctx context.Context, userID uint, role domain.UserRole, path string) (io.ReadCloser, error) {
+	path, ok := cleanMediaPath(path)
+	if !ok {
+		return nil, shared.ErrNotFound.WithDetails("media not found")
+	}
+	allowed, err := s.canAccess(ctx, userID, role, path)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		shared.Log.Debug("media access denied", zap.Uint("userID", userID), zap.String("path", path))
+		return nil, shared.ErrNotFound.WithDetails("media not found")
+	}
+	return s.open(ctx, path)
+}
+
+// CanShare tells whether the user may link mediaURL from a message. Links to files this
+// storage holds need access to the file already, links elsewhere are left alone.
+func (s *MediaService) CanShare(ctx context.Context, userID uint, role domain.UserRole, mediaURL string) (bool, error) {
+	link, _, _ := strings.Cut(mediaURL, "?")
+	path, ok := s.storage.PathFromURL(link)
+	if !ok {
+		return true, nil
+	}
+	if path, ok = cleanMediaPath(path); !ok {
+		return false, nil
+	}
+	return s.canAccess(ctx, userID, role, path)
+}
+
+func (s *MediaService) canAccess(ctx context.Context, userID uint, role domain.UserRole, path string) (bool, error) {
+	// Thumbnails are visible to whoever may see the original
+	if original, ok := thumbnailOriginal(path); ok {
+		path = original
+	}
+	if strings.HasPrefix(path, fmt.Sprintf("user_%d/", userID)) {
+		return true, nil
+	}
+	// Avatars are part of public profiles. Uploaded media always starts with the
+	// uploader's ID, so it cannot pass for an avatar.
+	if strings.HasPrefix(filepath.Base(path), "avatar") {
+		return true, nil
+	}
+	// Shared blobs belong to everyone who uploaded the content
+	if strings.HasPrefix(path, domain.MediaBlobDir+"/") {
+		if ok, err := s.mediaRepo.IsReferencedBy(ctx, path, userID); err != nil || ok {
+			return ok, err
+		}
+	}
+
+	url, err := s.storage.GetURL(ctx, path)
 	if err != nil {
-		shared.Log.Error("failed to delete media",
+		return false, err
+	}
+	if ok, err := s.messageRepo.HasMediaAccess(ctx, userID, url); err != nil || ok {
+		return ok, err
+	}
+	if s.announcementRepo != nil {
+		return s.announcementRepo.HasMedia(ctx, url, role)
+	}
+	return false, nil
+}
+
+// cleanMediaPath normalizes a requested path so user_1/../user_2/x cannot pass an
+// ownership check meant for user_1
+func cleanMediaPath(path string) (string, bool) {
+	cleaned := filepath.ToSlash(filepath.Clean(path))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.HasPrefix(cleaned, "/") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+func (s *MediaService) open(ctx context.Context, path string) (io.ReadCloser, error) {
+	file, err := s.storage.Open(ctx, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, shared.ErrNotFound.WithDetails("media not found")
+	}
+	if err != nil {
+		shared.Log.Error("failed to open media",
 			zap.String("path", path),
-			zap.Uint("userID", userID),
 			zap.Error(err))
+		return nil, err
+	}
+	return file, nil
+}
+
+// storeThumbnails keeps scaled down copies of an uploaded image next to it. Clients
+// fall back to the original, so failures are only logged.
+func (s *MediaService) storeThumbnails(ctx context.Context, data []byte, blob *domain.MediaBlob) domain.MediaThumbnails {
+	if s.resizer == nil || !resizableContentTypes[blob.ContentType] || blob.Width == nil || blob.Height == nil {
+		return nil
+	}
+	var sizes []int
+	for _, size := range domain.MediaThumbnailSizes {
+		if max(*blob.Width, *blob.Height) > size {
+			sizes = append(sizes, size)
+		}
+	}
+	if len(sizes) == 0 {
+		return nil
+	}
+
+	images, err := s.resizer.Resize(ctx, bytes.NewReader(data), sizes, false)
+	if err != nil {
+		shared.Log.Warn("failed to create thumbnails",
+			zap.String("path", blob.Path),
+			zap.Error(err))
+		return nil
+	}
+
+	thumbnails := make(domain.MediaThumbnails, 0, len(images))
+	for _, img := range images {
+		thumbnail, err := s.storeThumbnail(ctx, blob.Path, img)
+		if err != nil {
+			shared.Log.Warn("failed to store thumbnail",
+				zap.String("path", blob.Path),
+				zap.Int("size", img.Size),
+				zap.Error(err))
+			s.discard(ctx, thumbnails.Paths()...)
+			return nil
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+	return thumbnails
+}
+
+func (s *MediaService) storeThumbnail(ctx context.Context, originalPath string, img domain.ResizedImage) (domain.MediaThumbnail, error) {
+	path := thumbnailPath(originalPath, img.Size, img.ContentType)
+	if err := s.storage.Put(ctx, bytes.NewReader(img.Data), path, img.ContentType, int64(len(img.Data))); err != nil {
+		return domain.MediaThumbnail{}, err
+	}
+	url, err := s.storage.GetURL(ctx, path)
+	if err != nil {
+		s.discard(ctx, path)
+		return domain.MediaThumbnail{}, err
+	}
+	return domain.MediaThumbnail{Size: img.Size, Width: img.Width, Height: img.Height, Path: path, URL: url}, nil
+}
+
+// thumbnailPath names a thumbnail after its original so access to it follows the original
+func thumbnailPath(originalPath string, size int, contentType string) string {
+	return fmt.Sprintf("%s_thumb%d%s", originalPath, size, mediaExtension(contentType))
+}
+
+var thumbnailSuffix = regexp.MustCompile(`_thumb\d+\.[a-z0-9]+$`)
+
+// thumbnailOriginal is the path of the image a thumbnail was made from
+func thumbnailOriginal(path string) (string, bool) {
+	loc := thumbnailSuffix.FindStringIndex(path)
+	if loc == nil {
+		return "", false
+	}
+	return path[:loc[0]], true
+}
+
+// discard removes stored files that no record points at, such as those of an upload that
+// could not be completed. A file that is already gone is not an error.
+func (s *MediaService) discard(ctx context.Context, paths ...string) {
+	for _, path := range paths {
+		if err := s.storage.Delete(ctx, path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			shared.Log.Warn("failed to remove media file",
+				zap.String("path", path),
+				zap.Error(err))
+		}
+	}
+}
+
+// Attachments describes library media linked from messages, keyed by URL. Links to
+// anything else, such as files uploaded before the library existed, are left out.
+func (s *MediaService) Attachments(ctx context.Context, mediaURLs []string) (map[string]domain.MediaAttachment, error) {
+	urlsByPath := make(map[string]string, len(mediaURLs))
+	paths := make([]string, 0, len(mediaURLs))
+	for _, url := range mediaURLs {
+		path, ok := s.storage.PathFromURL(url)
+		if !ok {
+			continue
+		}
+		if _, seen := urlsByPath[path]; !seen {
+			paths = append(paths, path)
+		}
+		urlsByPath[path] = url
+	}
+
+	records, err := s.mediaRepo.FindByPaths(ctx, paths)
+	if err != nil {
+		return nil, err
+	}
+	attachments := make(map[string]domain.MediaAttachment, len(records))
+	for _, record := range records {
+		attachments[urlsByPath[record.Path]] = domain.MediaAttachment{
+			ContentType: record.ContentType,
+			Width:       record.Width,
+			Height:      record.Height,
+			Thumbnails:  record.Thumbnails,
+		}
+	}
+	return attachments, nil
+}
+
+// ListMedia pages through the user's own uploads, newest first
+func (s *MediaService) ListMedia(ctx context.Context, filter domain.MediaFilter) (*domain.MediaPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultMediaPageSize
+	}
+	filter.Limit = min(filter.Limit, maxMediaPageSize)
+	filter.Offset = max(filter.Offset, 0)
+
+	records, total, err := s.mediaRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.MediaPage{
+		Items:  make([]domain.MediaItem, len(records)),
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+	for i, record := range records {
+		url, err := s.storage.GetURL(ctx, record.Path)
+		if err != nil {
+			return nil, err
+		}
+		page.Items[i] = domain.MediaItem{Media: record, URL: url}
+	}
+	return page, nil
+}
+
+// DeleteMedia removes one of the user's uploads. Media of other users looks like it does not exist.
+func (s *MediaService) DeleteMedia(ctx context.Context, userID, mediaID uint) error {
+	record, err := s.mediaRepo.FindByID(ctx, mediaID)
+	if err != nil {
+		return err
+	}
+	return s.remove(ctx, userID, record)
+}
+
+// Delete removes one of the user's uploads by its storage path
+func (s *MediaService) Delete(ctx context.Context, userID uint, path string) error {
+	path, ok := cleanMediaPath(path)
+	if !ok {
+		return shared.ErrRecordNotFound.WithDetails("media not found")
+	}
+	record, err := s.mediaRepo.FindByPath(ctx, userID, path)
+	if err != nil {
 		return err
 	}
+	return s.remove(ctx, userID, record)
+}
+
+func (s *MediaService) remove(ctx context.Context, userID uint, record *domain.Media) error {
+	if record.OwnerID != userID {
+		shared.Log.Debug("media delete denied",
+			zap.Uint("mediaID", record.ID),
+			zap.Uint("userID", userID))
+		return shared.ErrRecordNotFound.WithDetails("media not found")
+	}
+
+	var files []string
+	err := s.txManager.WithTransaction(ctx, func(ctx context.Context, repos *domain.Repositories) error {
+		files = nil
+		if err := repos.Media.Delete(ctx, record.ID); err != nil {
+			return err
+		}
+		if err := repos.Media.AddUsage(ctx, record.OwnerID, -record.Size, 0); err != nil {
+			return err
+		}
+		blob, err := repos.Media.LockBlob(ctx, record.Path)
+		var appErr shared.Error
+		if errors.As(err, &appErr) && appErr.Code == shared.ErrRecordNotFound.Code {
+			// Uploaded before blobs were shared, the file is the record's own
+			files = append(record.Thumbnails.Paths(), record.Path)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		blob.RefCount = max(blob.RefCount-1, 0)
+		if blob.RefCount > 0 {
+			return repos.Media.SaveBlob(ctx, blob)
+		}
+		// The last reference takes the file with it
+		files = append(blob.Thumbnails.Paths(), blob.Path)
+		return repos.Media.DeleteBlob(ctx, blob.Path)
+	})
+	if err != nil {
+		return err
+	}
+	// Files go only once no record points at them, what fails to go is left to the orphan collector
+	s.discard(ctx, files...)
 	return nil
 }
 
-func (s *MediaService) GetByUser(ctx context.Context, userID uint) ([]domain.MediaResponse, error) {
-	return nil, nil
+// PurgeUnreferenced removes blobs left without references, such as the uploads of
+// erased accounts. Blobs another upload took up in the meantime are kept.
+func (s *MediaService) PurgeUnreferenced(ctx context.Context) (int, error) {
+	blobs, err := s.mediaRepo.FindUnreferencedBlobs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, candidate := range blobs {
+		var files []string
+		err := s.txManager.WithTransaction(ctx, func(ctx context.Context, repos *domain.Repositories) error {
+			files = nil
+			blob, err := repos.Media.LockBlob(ctx, candidate.Path)
+			if err != nil || blob.RefCount > 0 {
+				return err
+			}
+			files = append(blob.Thumbnails.Paths(), blob.Path)
+			return repos.Media.DeleteBlob(ctx, blob.Path)
+		})
+		if err != nil {
+			shared.Log.Error("purge media blob failed", zap.Error(err), zap.String("path", candidate.Path))
+			continue
+		}
+		if files != nil {
+			s.discard(ctx, files...)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Usage reports how much of their quota the user's media library takes
+func (s *MediaService) Usage(ctx context.Context, userID uint) (*domain.StorageUsage, error) {
+	used, err := s.mediaRepo.FindUsage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := s.quota(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &domain.StorageUsage{UsedBytes: used}
+	if quota > 0 {
+		remaining := max(quota-used, 0)
+		usage.QuotaBytes, usage.RemainingBytes = &quota, &remaining
+	}
+	return usage, nil
+}
+
+// checkQuota tells early whether size more bytes would fit the user's quota. The
+// upload itself is counted when it is recorded.
+func (s *MediaService) checkQuota(ctx context.Context, userID uint, size int64) error {
+	quota, err := s.quota(ctx, userID)
+	if err != nil || quota == 0 {
+		return err
+	}
+	used, err := s.mediaRepo.FindUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if used+size > quota {
+		return storageQuotaExceeded(quota)
+	}
+	return nil
+}
+
+// quota is how many bytes the user may keep, 0 when there is no limit
+func (s *MediaService) quota(ctx context.Context, userID uint) (int64, error) {
+	role := domain.RoleUser
+	if len(s.uploads.RoleQuotasMB) > 0 && s.userRepo != nil {
+		user, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		role = user.Role
+	}
+	return s.uploads.Quota(userID, string(role)), nil
+}
+
+func isQuotaExceeded(err error) bool {
+	var appErr shared.Error
+	return errors.As(err, &appErr) && appErr.Code == shared.ErrStorageQuotaExceeded.Code
+}
+
+func storageQuotaExceeded(quota int64) error {
+	return shared.ErrStorageQuotaExceeded.WithDetails(fmt.Sprintf("upload would exceed your storage quota of %dMB", quota/(1024*1024)))
+}
+
+// RunJanitor purges unreferenced blobs every interval until ctx is done
+func (s *MediaService) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := s.PurgeUnreferenced(ctx); err != nil {
+			shared.Log.Error("purge unreferenced media failed", zap.Error(err))
+		} else if purged > 0 {
+			shared.Log.Info("purged unreferenced media", zap.Int("count", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// UploadAvatar stores the image cropped to a square at every domain.AvatarSizes size
+func (s *MediaService) UploadAvatar(ctx context.Context, userID uint, file io.Reader, contentType string, size int64) (domain.Avatar, error) {
+	if s.resizer == nil {
+		return nil, shared.ErrServiceUnavailable.WithDetails("avatar uploads are not enabled")
+	}
+	if size > maxAvatarSize {
+		shared.Log.Debug("avatar too large",
+			zap.Int64("size", size),
+			zap.Uint("userID", userID))
+		return nil, shared.ErrValidation.WithDetails("avatar size exceeds 5MB limit")
+	}
+	if !resizableContentTypes[contentType] {
+		return nil, shared.ErrValidation.WithDetails("avatar must be a JPEG, PNG or GIF image")
+	}
+
+	images, err := s.resizer.Resize(ctx, io.LimitReader(file, maxAvatarSize), domain.AvatarSizes, true)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidImage) || errors.Is(err, domain.ErrImageTooLarge) {
+			return nil, shared.ErrValidation.WithDetails(err.Error())
+		}
+		shared.Log.Error("failed to resize avatar",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	avatar := make(domain.Avatar, 0, len(images))
+	for _, img := range images {
+		filename := fmt.Sprintf("avatar%d_%s", img.Size, generateUniqueFilename(userID, "avatar"+mediaExtension(img.ContentType)))
+		path, err := s.storage.Upload(ctx, bytes.NewReader(img.Data), filename, img.ContentType, int64(len(img.Data)), userID)
+		if err != nil {
+			shared.Log.Error("failed to upload avatar",
+				zap.Int("size", img.Size),
+				zap.Uint("userID", userID),
+				zap.Error(err))
+			s.DeleteAvatar(ctx, userID, avatar)
+			return nil, err
+		}
+
+		url, err := s.storage.GetURL(ctx, path)
+		if err != nil {
+			s.DeleteAvatar(ctx, userID, append(avatar, domain.AvatarVariant{Path: path}))
+			return nil, err
+		}
+		avatar = append(avatar, domain.AvatarVariant{Size: img.Size, Path: path, URL: url})
+	}
+
+	return avatar, nil
+}
+
+// DeleteAvatar removes every stored variant, failures only leave orphaned files behind
+func (s *MediaService) DeleteAvatar(ctx context.Context, userID uint, avatar domain.Avatar) {
+	for _, path := range avatar.Paths() {
+		if err := s.storage.Delete(ctx, path); err != nil {
+			shared.Log.Warn("failed to delete avatar variant",
+				zap.String("path", path),
+				zap.Uint("userID", userID),
+				zap.Error(err))
+		}
+	}
+}
+
+var errChecksumMismatch = shared.ErrValidation.WithDetails("checksum does not match the uploaded content")
+
+func fileTooLarge(limit int64) error {
+	return shared.ErrValidation.WithDetails(fmt.Sprintf("file size exceeds %dMB limit", limit/(1024*1024)))
+}
+
+// originalFilename keeps the name the client sent without any directories
+func originalFilename(filename string) string {
+	name := filepath.Base(filepath.ToSlash(filename))
+	if name == "." || name == "/" {
+		return "upload"
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
 }
 
 func generateUniqueFilename(userID uint, original string) string {
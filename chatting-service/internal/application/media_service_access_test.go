@@ -0,0 +1,154 @@
+package application
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubMediaAccess answers HasMediaAccess, other message repository calls are not expected
+type stubMediaAccess struct {
+	domain.MessageRepository
+	urls map[string]bool
+}
+
+func (s *stubMediaAccess) HasMediaAccess(ctx context.Context, userID uint, mediaURL string) (bool, error) {
+	return s.urls[mediaURL], nil
+}
+
+func TestMediaService_OpenForUser(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	newStorage := func(path string) *MockMediaStorage {
+		storage := &MockMediaStorage{}
+		storage.On("GetURL", ctx, mock.Anything).Return("http://localhost/media/"+path, nil).Maybe()
+		storage.On("Open", ctx, path).Return(io.NopCloser(strings.NewReader("data")), nil).Maybe()
+		return storage
+	}
+
+	t.Run("OwnUpload", func(t *testing.T) {
+		storage := newStorage("user_5/5_1_a.png")
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Messages: &stubMediaAccess{}})
+
+		file, err := service.OpenForUser(ctx, 5, domain.RoleUser, "user_5/5_1_a.png")
+
+		assert.NoError(t, err)
+		file.Close()
+	})
+
+	t.Run("MessageParticipant", func(t *testing.T) {
+		storage := newStorage("user_5/5_1_a.png")
+		messages := &stubMediaAccess{urls: map[string]bool{"http://localhost/media/user_5/5_1_a.png": true}}
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Messages: messages})
+
+		file, err := service.OpenForUser(ctx, 6, domain.RoleUser, "user_5/5_1_a.png")
+
+		assert.NoError(t, err)
+		file.Close()
+	})
+
+	t.Run("ThumbnailFollowsOriginal", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("GetURL", ctx, "user_5/5_1_a.jpg").Return("http://localhost/media/user_5/5_1_a.jpg", nil)
+		storage.On("Open", ctx, "user_5/5_1_a.jpg_thumb320.png").Return(io.NopCloser(strings.NewReader("data")), nil)
+		messages := &stubMediaAccess{urls: map[string]bool{"http://localhost/media/user_5/5_1_a.jpg": true}}
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Messages: messages})
+
+		file, err := service.OpenForUser(ctx, 6, domain.RoleUser, "user_5/5_1_a.jpg_thumb320.png")
+
+		assert.NoError(t, err)
+		file.Close()
+	})
+
+	t.Run("SharedBlobOfUploader", func(t *testing.T) {
+		path := "blobs/9f/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png"
+		storage := newStorage(path)
+		repo := &MockMediaRepository{}
+		repo.On("IsReferencedBy", ctx, path, uint(6)).Return(true, nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Messages: &stubMediaAccess{}, Media: repo})
+
+		file, err := service.OpenForUser(ctx, 6, domain.RoleUser, path)
+
+		assert.NoError(t, err)
+		file.Close()
+	})
+
+	t.Run("SharedBlobOfSomeoneElse", func(t *testing.T) {
+		path := "blobs/9f/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png"
+		storage := newStorage(path)
+		repo := &MockMediaRepository{}
+		repo.On("IsReferencedBy", ctx, path, uint(6)).Return(false, nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Messages: &stubMediaAccess{}, Media: repo})
+
+		_, err := service.OpenForUser(ctx, 6, domain.RoleUser, path)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrNotFound.Code, appErr.Code)
+	})
+
+	t.Run("AnnouncementReader", func(t *testing.T) {
+		storage := newStorage("user_1/1_1_a.pdf")
+		announcements := &MockAnnouncementRepository{}
+		announcements.On("HasMedia", ctx, "http://localhost/media/user_1/1_1_a.pdf", domain.RoleUser).Return(true, nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Messages: &stubMediaAccess{}, Announcements: announcements})
+
+		file, err := service.OpenForUser(ctx, 6, domain.RoleUser, "user_1/1_1_a.pdf")
+
+		assert.NoError(t, err)
+		file.Close()
+	})
+
+	t.Run("Stranger", func(t *testing.T) {
+		storage := newStorage("user_5/5_1_a.png")
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Messages: &stubMediaAccess{}})
+
+		_, err := service.OpenForUser(ctx, 6, domain.RoleUser, "user_5/5_1_a.png")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrNotFound.Code, appErr.Code)
+		storage.AssertNotCalled(t, "Open", mock.Anything, mock.Anything)
+	})
+
+	t.Run("TraversalOutOfOwnDirectory", func(t *testing.T) {
+		storage := newStorage("user_5/5_1_a.png")
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Messages: &stubMediaAccess{}})
+
+		_, err := service.OpenForUser(ctx, 6, domain.RoleUser, "user_6/../user_5/5_1_a.png")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrNotFound.Code, appErr.Code)
+		storage.AssertNotCalled(t, "Open", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMediaService_CanShare(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	storage := &MockMediaStorage{}
+	storage.On("PathFromURL", "http://localhost/media/user_5/5_1_a.png").Return("user_5/5_1_a.png", true)
+	storage.On("PathFromURL", "https://elsewhere.example/a.png").Return("", false)
+	storage.On("GetURL", ctx, "user_5/5_1_a.png").Return("http://localhost/media/user_5/5_1_a.png", nil)
+	service := NewMediaService(MediaServiceDeps{Storage: storage, Messages: &stubMediaAccess{}})
+
+	ok, err := service.CanShare(ctx, 5, domain.RoleUser, "http://localhost/media/user_5/5_1_a.png?expires=1&signature=x")
+	assert.NoError(t, err)
+	assert.True(t, ok, "own upload")
+
+	ok, err = service.CanShare(ctx, 6, domain.RoleUser, "http://localhost/media/user_5/5_1_a.png")
+	assert.NoError(t, err)
+	assert.False(t, ok, "upload of another user")
+
+	ok, err = service.CanShare(ctx, 6, domain.RoleUser, "https://elsewhere.example/a.png")
+	assert.NoError(t, err)
+	assert.True(t, ok, "link elsewhere")
+}
@@ -0,0 +1,519 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockMediaRepository struct {
+	mock.Mock
+}
+
+func (m *MockMediaRepository) Create(ctx context.Context, media *domain.Media) error {
+	args := m.Called(ctx, media)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) FindByID(ctx context.Context, mediaID uint) (*domain.Media, error) {
+	args := m.Called(ctx, mediaID)
+	media, _ := args.Get(0).(*domain.Media)
+	return media, args.Error(1)
+}
+
+func (m *MockMediaRepository) FindByPath(ctx context.Context, ownerID uint, path string) (*domain.Media, error) {
+	args := m.Called(ctx, ownerID, path)
+	media, _ := args.Get(0).(*domain.Media)
+	return media, args.Error(1)
+}
+
+func (m *MockMediaRepository) FindByPaths(ctx context.Context, paths []string) ([]domain.Media, error) {
+	args := m.Called(ctx, paths)
+	media, _ := args.Get(0).([]domain.Media)
+	return media, args.Error(1)
+}
+
+func (m *MockMediaRepository) List(ctx context.Context, filter domain.MediaFilter) ([]domain.Media, int64, error) {
+	args := m.Called(ctx, filter)
+	media, _ := args.Get(0).([]domain.Media)
+	return media, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockMediaRepository) Delete(ctx context.Context, mediaID uint) error {
+	args := m.Called(ctx, mediaID)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) IsReferencedBy(ctx context.Context, path string, userID uint) (bool, error) {
+	args := m.Called(ctx, path, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMediaRepository) AcquireBlob(ctx context.Context, blob *domain.MediaBlob) (*domain.MediaBlob, error) {
+	args := m.Called(ctx, blob)
+	// Without a stored blob to return the content is new
+	if stored, ok := args.Get(0).(*domain.MediaBlob); ok {
+		return stored, args.Error(1)
+	}
+	return blob, args.Error(1)
+}
+
+func (m *MockMediaRepository) LockBlob(ctx context.Context, path string) (*domain.MediaBlob, error) {
+	args := m.Called(ctx, path)
+	blob, _ := args.Get(0).(*domain.MediaBlob)
+	return blob, args.Error(1)
+}
+
+func (m *MockMediaRepository) SaveBlob(ctx context.Context, blob *domain.MediaBlob) error {
+	args := m.Called(ctx, blob)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) DeleteBlob(ctx context.Context, path string) error {
+	args := m.Called(ctx, path)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) FindUnreferencedBlobs(ctx context.Context) ([]domain.MediaBlob, error) {
+	args := m.Called(ctx)
+	blobs, _ := args.Get(0).([]domain.MediaBlob)
+	return blobs, args.Error(1)
+}
+
+func (m *MockMediaRepository) AddUsage(ctx context.Context, ownerID uint, delta int64, limit int64) error {
+	args := m.Called(ctx, ownerID, delta, limit)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) FindUsage(ctx context.Context, ownerID uint) (int64, error) {
+	args := m.Called(ctx, ownerID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// mediaTransactions runs transactions against the media repository mock
+type mediaTransactions struct {
+	repo domain.MediaRepository
+}
+
+func (m mediaTransactions) WithTransaction(ctx context.Context, fn func(ctx context.Context, repos *domain.Repositories) error) error {
+	return fn(ctx, &domain.Repositories{Media: m.repo})
+}
+
+// newBlobRepo expects uploads of content that was not stored before, without a quota
+func newBlobRepo() *MockMediaRepository {
+	repo := &MockMediaRepository{}
+	repo.On("AddUsage", mock.Anything, mock.Anything, mock.Anything, int64(0)).Return(nil)
+	repo.On("AcquireBlob", mock.Anything, mock.Anything).Return(nil, nil)
+	repo.On("SaveBlob", mock.Anything, mock.Anything).Return(nil)
+	return repo
+}
+
+// createdMedia is the record an upload added to the library
+func createdMedia(t *testing.T, repo *MockMediaRepository) *domain.Media {
+	for _, call := range repo.Calls {
+		if call.Method == "Create" {
+			return call.Arguments.Get(1).(*domain.Media)
+		}
+	}
+	t.Fatal("no media was recorded")
+	return nil
+}
+
+// testBlobPath is where the content "test" was stored before
+var testBlobPath = domain.MediaBlobPath("9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "7b6a5f4e3d2c1b0a", ".txt")
+
+// afterCommit runs transactions against the media repository mock and checks nothing
+// was written to or removed from storage while one was open
+type afterCommit struct {
+	t       *testing.T
+	repo    domain.MediaRepository
+	storage *MockMediaStorage
+	err     error // Returned as if the commit failed
+}
+
+func (m afterCommit) WithTransaction(ctx context.Context, fn func(ctx context.Context, repos *domain.Repositories) error) error {
+	before := len(m.storage.Calls)
+	err := fn(ctx, &domain.Repositories{Media: m.repo})
+	for _, call := range m.storage.Calls[before:] {
+		if call.Method == "Put" || call.Method == "Delete" {
+			m.t.Errorf("storage %s inside the transaction", call.Method)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return m.err
+}
+
+func TestMediaService_UploadRecordsMedia(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("RecordsWhatWasReceived", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, copyOf(testBlobPath, ""), "text/plain", int64(4)).Return(nil)
+		storage.On("GetURL", ctx, copyOf(testBlobPath, "")).Return("http://localhost/media/file", nil)
+		repo := newBlobRepo()
+		repo.On("Create", ctx, mock.MatchedBy(func(m *domain.Media) bool {
+			m.ID = 9
+			return true
+		})).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: afterCommit{t: t, repo: repo, storage: storage}})
+
+		// The client claims more bytes than it sends
+		resp, err := service.Upload(ctx, 3, strings.NewReader("test"), "../notes.txt", "text/plain", 100, 3)
+		require.NoError(t, err)
+		assert.Equal(t, uint(9), resp.ID)
+		assert.Equal(t, int64(4), resp.Size)
+
+		record := createdMedia(t, repo)
+		assert.Equal(t, uint(3), record.OwnerID)
+		storage.AssertCalled(t, "Put", ctx, mock.Anything, record.Path, "text/plain", int64(4))
+		assert.Equal(t, "notes.txt", record.OriginalFilename)
+		assert.Equal(t, int64(4), record.Size)
+		assert.Equal(t, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", record.Checksum)
+		assert.Nil(t, record.Width)
+	})
+
+	t.Run("SameContentIsStoredOnce", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, copyOf(testBlobPath, ""), "text/plain", int64(4)).Return(nil)
+		storage.On("Delete", ctx, copyOf(testBlobPath, "")).Return(nil)
+		storage.On("GetURL", ctx, testBlobPath).Return("http://localhost/media/"+testBlobPath, nil)
+		repo := &MockMediaRepository{}
+		repo.On("AddUsage", ctx, mock.Anything, int64(4), int64(0)).Return(nil)
+		repo.On("AcquireBlob", ctx, mock.Anything).Return(&domain.MediaBlob{Path: testBlobPath, RefCount: 2}, nil)
+		repo.On("SaveBlob", ctx, mock.MatchedBy(func(b *domain.MediaBlob) bool { return b.RefCount == 3 })).Return(nil)
+		repo.On("Create", ctx, mock.Anything).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		_, err := service.Upload(ctx, 4, strings.NewReader("test"), "copy.txt", "text/plain", 4, 4)
+		require.NoError(t, err)
+		// The new copy goes again, the upload shares the one stored before
+		storage.AssertExpectations(t)
+		storage.AssertNotCalled(t, "Delete", ctx, testBlobPath)
+		repo.AssertExpectations(t)
+		assert.Equal(t, testBlobPath, createdMedia(t, repo).Path)
+	})
+
+	t.Run("RemovesFileWhenRecordFails", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, copyOf(testBlobPath, ""), mock.Anything, mock.Anything).Return(nil)
+		storage.On("Delete", ctx, copyOf(testBlobPath, "")).Return(nil)
+		repo := newBlobRepo()
+		repo.On("Create", ctx, mock.Anything).Return(shared.ErrDatabaseOperation)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: afterCommit{t: t, repo: repo, storage: storage}})
+
+		_, err := service.Upload(ctx, 3, strings.NewReader("test"), "notes.txt", "text/plain", 4, 3)
+		assert.Error(t, err)
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("RemovesFileWhenCommitFails", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, copyOf(testBlobPath, ""), mock.Anything, mock.Anything).Return(nil)
+		storage.On("Delete", ctx, copyOf(testBlobPath, "")).Return(nil)
+		repo := newBlobRepo()
+		repo.On("Create", ctx, mock.Anything).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: afterCommit{t: t, repo: repo, storage: storage, err: shared.ErrDatabaseOperation}})
+
+		_, err := service.Upload(ctx, 3, strings.NewReader("test"), "notes.txt", "text/plain", 4, 3)
+		assert.Error(t, err)
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("KeepsSharedFileWhenRecordFails", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, copyOf(testBlobPath, ""), mock.Anything, mock.Anything).Return(nil)
+		storage.On("Delete", ctx, copyOf(testBlobPath, "")).Return(nil)
+		repo := &MockMediaRepository{}
+		repo.On("AddUsage", ctx, mock.Anything, int64(4), int64(0)).Return(nil)
+		repo.On("AcquireBlob", ctx, mock.Anything).Return(&domain.MediaBlob{Path: testBlobPath, RefCount: 1}, nil)
+		repo.On("SaveBlob", ctx, mock.Anything).Return(nil)
+		repo.On("Create", ctx, mock.Anything).Return(shared.ErrDatabaseOperation)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		_, err := service.Upload(ctx, 3, strings.NewReader("test"), "notes.txt", "text/plain", 4, 3)
+		assert.Error(t, err)
+		storage.AssertExpectations(t)
+		storage.AssertNotCalled(t, "Delete", ctx, testBlobPath)
+	})
+}
+
+// stubResizer reports fixed dimensions and returns one fitted rendition per size
+type stubResizer struct {
+	width, height int
+}
+
+func (r stubResizer) Resize(ctx context.Context, src io.Reader, sizes []int, crop bool) ([]domain.ResizedImage, error) {
+	images := make([]domain.ResizedImage, len(sizes))
+	for i, size := range sizes {
+		images[i] = domain.ResizedImage{Size: size, Width: size, Height: size * r.height / r.width, ContentType: "image/png", Data: []byte("thumb")}
+	}
+	return images, nil
+}
+
+func (r stubResizer) Dimensions(ctx context.Context, src io.Reader) (int, int, error) {
+	return r.width, r.height, nil
+}
+
+func TestMediaService_UploadThumbnails(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("OnlySizesSmallerThanTheImage", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, copyOf(jpegBlobPath, ""), "image/jpeg", mock.Anything).Return(nil)
+		storage.On("Put", ctx, mock.Anything, copyOf(jpegBlobPath, "_thumb320.png"), "image/png", int64(5)).Return(nil)
+		storage.On("GetURL", ctx, mock.Anything).Return("http://localhost/media/file", nil)
+		repo := newBlobRepo()
+		repo.On("Create", ctx, mock.Anything).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Resizer: stubResizer{width: 640, height: 480}, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		resp, err := service.Upload(ctx, 3, strings.NewReader(jpegHeader), "photo.jpg", "image/jpeg", int64(len(jpegHeader)), 3)
+		require.NoError(t, err)
+		require.NotNil(t, resp.Width)
+		assert.Equal(t, 640, *resp.Width)
+		assert.Equal(t, 480, *resp.Height)
+		require.Len(t, resp.Thumbnails, 1)
+		record := createdMedia(t, repo)
+		assert.Equal(t, domain.MediaThumbnail{Size: 320, Width: 320, Height: 240, Path: record.Path + "_thumb320.png", URL: "http://localhost/media/file"}, resp.Thumbnails[0])
+
+		assert.Equal(t, resp.Thumbnails, record.Thumbnails)
+	})
+
+	t.Run("ThumbnailFailureKeepsUpload", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, copyOf(jpegBlobPath, ""), "image/jpeg", mock.Anything).Return(nil)
+		storage.On("Put", ctx, mock.Anything, copyOf(jpegBlobPath, "_thumb320.png"), "image/png", mock.Anything).Return(nil)
+		storage.On("Put", ctx, mock.Anything, copyOf(jpegBlobPath, "_thumb800.png"), "image/png", mock.Anything).Return(errors.New("disk full"))
+		storage.On("Delete", ctx, copyOf(jpegBlobPath, "_thumb320.png")).Return(nil)
+		storage.On("GetURL", ctx, mock.Anything).Return("http://localhost/media/file", nil)
+		repo := newBlobRepo()
+		repo.On("Create", ctx, mock.Anything).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Resizer: stubResizer{width: 2000, height: 1000}, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		resp, err := service.Upload(ctx, 3, strings.NewReader(jpegHeader), "photo.jpg", "image/jpeg", int64(len(jpegHeader)), 3)
+		require.NoError(t, err)
+		assert.Empty(t, resp.Thumbnails)
+		storage.AssertCalled(t, "Delete", ctx, createdMedia(t, repo).Path+"_thumb320.png")
+	})
+}
+
+// stubStripper replaces image content so tests can tell what was stored
+type stubStripper struct{}
+
+func (stubStripper) StripMetadata(ctx context.Context, data []byte, contentType string) ([]byte, error) {
+	return append([]byte{}, jpegHeader[:4]...), nil
+}
+
+func TestMediaService_UploadStripsMetadata(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	upload := func(t *testing.T, uploads config.UploadConfig) *domain.Media {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, mock.Anything, "image/jpeg", mock.Anything).Return(nil)
+		storage.On("GetURL", ctx, mock.Anything).Return("http://localhost/media/file", nil)
+		repo := newBlobRepo()
+		repo.On("Create", ctx, mock.Anything).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: uploads, Stripper: stubStripper{}, Transactions: mediaTransactions{repo}})
+
+		_, err := service.Upload(ctx, 3, strings.NewReader(jpegHeader), "photo.jpg", "image/jpeg", int64(len(jpegHeader)), 3)
+		require.NoError(t, err)
+		return createdMedia(t, repo)
+	}
+
+	t.Run("Stripped", func(t *testing.T) {
+		record := upload(t, testUploadConfig)
+		assert.Equal(t, int64(4), record.Size)
+	})
+
+	t.Run("KeptWhenConfigured", func(t *testing.T) {
+		uploads := testUploadConfig
+		uploads.KeepMetadata = true
+		record := upload(t, uploads)
+		assert.Equal(t, int64(len(jpegHeader)), record.Size)
+	})
+}
+
+func TestMediaService_Attachments(t *testing.T) {
+	ctx := context.Background()
+	width, height := 640, 480
+	storage := &MockMediaStorage{}
+	storage.On("PathFromURL", "http://localhost/media/user_3/a.jpg").Return("user_3/a.jpg", true)
+	storage.On("PathFromURL", "https://elsewhere.example/b.jpg").Return("", false)
+	repo := &MockMediaRepository{}
+	repo.On("FindByPaths", ctx, []string{"user_3/a.jpg"}).
+		Return([]domain.Media{{Path: "user_3/a.jpg", ContentType: "image/jpeg", Width: &width, Height: &height}}, nil)
+	service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig})
+
+	attachments, err := service.Attachments(ctx, []string{"http://localhost/media/user_3/a.jpg", "https://elsewhere.example/b.jpg", "http://localhost/media/user_3/a.jpg"})
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	attachment := attachments["http://localhost/media/user_3/a.jpg"]
+	assert.Equal(t, "image/jpeg", attachment.ContentType)
+	assert.Equal(t, 640, *attachment.Width)
+}
+
+func TestMediaService_ListMedia(t *testing.T) {
+	ctx := context.Background()
+	storage := &MockMediaStorage{}
+	storage.On("GetURL", ctx, "user_3/a.png").Return("http://localhost/media/user_3/a.png", nil)
+	repo := &MockMediaRepository{}
+	repo.On("List", ctx, domain.MediaFilter{OwnerID: 3, Type: "image", Limit: 200}).
+		Return([]domain.Media{{ID: 1, OwnerID: 3, Path: "user_3/a.png"}}, int64(1), nil)
+	service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig})
+
+	page, err := service.ListMedia(ctx, domain.MediaFilter{OwnerID: 3, Type: "image", Limit: 1000, Offset: -1})
+	require.NoError(t, err)
+	assert.Equal(t, 200, page.Limit)
+	assert.Equal(t, int64(1), page.Total)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "http://localhost/media/user_3/a.png", page.Items[0].URL)
+}
+
+func TestMediaService_DeleteMedia(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	legacy := &domain.Media{ID: 7, OwnerID: 3, Path: "user_3/3_1_a.png", Size: 10}
+	thumbnails := domain.MediaThumbnails{{Size: 320, Path: jpegBlobPath + "_thumb320.png"}}
+	deduplicated := &domain.Media{ID: 8, OwnerID: 3, Path: jpegBlobPath, Size: int64(len(jpegHeader)), Thumbnails: thumbnails}
+
+	t.Run("LegacyOwner", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Delete", ctx, legacy.Path).Return(nil)
+		repo := &MockMediaRepository{}
+		repo.On("FindByID", ctx, uint(7)).Return(legacy, nil)
+		repo.On("Delete", ctx, uint(7)).Return(nil)
+		repo.On("AddUsage", ctx, uint(3), -legacy.Size, int64(0)).Return(nil)
+		repo.On("LockBlob", ctx, legacy.Path).Return(nil, shared.ErrRecordNotFound.WithDetails("media blob not found"))
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		require.NoError(t, service.DeleteMedia(ctx, 3, 7))
+		storage.AssertExpectations(t)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("LastReferenceRemovesBlob", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Delete", ctx, jpegBlobPath).Return(nil)
+		storage.On("Delete", ctx, jpegBlobPath+"_thumb320.png").Return(nil)
+		repo := &MockMediaRepository{}
+		repo.On("FindByID", ctx, uint(8)).Return(deduplicated, nil)
+		repo.On("Delete", ctx, uint(8)).Return(nil)
+		repo.On("AddUsage", ctx, uint(3), -deduplicated.Size, int64(0)).Return(nil)
+		repo.On("LockBlob", ctx, jpegBlobPath).Return(&domain.MediaBlob{Path: jpegBlobPath, Thumbnails: thumbnails, RefCount: 1}, nil)
+		repo.On("DeleteBlob", ctx, jpegBlobPath).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: afterCommit{t: t, repo: repo, storage: storage}})
+
+		require.NoError(t, service.DeleteMedia(ctx, 3, 8))
+		storage.AssertExpectations(t)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("FilesStayWhenCommitFails", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		repo := &MockMediaRepository{}
+		repo.On("FindByID", ctx, uint(8)).Return(deduplicated, nil)
+		repo.On("Delete", ctx, uint(8)).Return(nil)
+		repo.On("AddUsage", ctx, uint(3), -deduplicated.Size, int64(0)).Return(nil)
+		repo.On("LockBlob", ctx, jpegBlobPath).Return(&domain.MediaBlob{Path: jpegBlobPath, Thumbnails: thumbnails, RefCount: 1}, nil)
+		repo.On("DeleteBlob", ctx, jpegBlobPath).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: afterCommit{t: t, repo: repo, storage: storage, err: shared.ErrDatabaseOperation}})
+
+		assert.Error(t, service.DeleteMedia(ctx, 3, 8))
+		storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("SharedBlobIsKept", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		repo := &MockMediaRepository{}
+		repo.On("FindByID", ctx, uint(8)).Return(deduplicated, nil)
+		repo.On("Delete", ctx, uint(8)).Return(nil)
+		repo.On("AddUsage", ctx, uint(3), -deduplicated.Size, int64(0)).Return(nil)
+		repo.On("LockBlob", ctx, jpegBlobPath).Return(&domain.MediaBlob{Path: jpegBlobPath, RefCount: 2}, nil)
+		repo.On("SaveBlob", ctx, mock.MatchedBy(func(b *domain.MediaBlob) bool { return b.RefCount == 1 })).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		require.NoError(t, service.DeleteMedia(ctx, 3, 8))
+		storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("OtherUserSeesNotFound", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		repo := &MockMediaRepository{}
+		repo.On("FindByID", ctx, uint(7)).Return(legacy, nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		err := service.DeleteMedia(ctx, 4, 7)
+		var appErr shared.Error
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, shared.ErrRecordNotFound.Code, appErr.Code)
+		storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("MissingFileStillRemovesRecord", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Delete", ctx, jpegBlobPath).Return(fs.ErrNotExist)
+		storage.On("Delete", ctx, jpegBlobPath+"_thumb320.png").Return(fs.ErrNotExist)
+		repo := &MockMediaRepository{}
+		repo.On("FindByID", ctx, uint(8)).Return(deduplicated, nil)
+		repo.On("Delete", ctx, uint(8)).Return(nil)
+		repo.On("AddUsage", ctx, uint(3), -deduplicated.Size, int64(0)).Return(nil)
+		repo.On("LockBlob", ctx, jpegBlobPath).Return(&domain.MediaBlob{Path: jpegBlobPath, Thumbnails: thumbnails, RefCount: 1}, nil)
+		repo.On("DeleteBlob", ctx, jpegBlobPath).Return(nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		require.NoError(t, service.DeleteMedia(ctx, 3, 8))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("ByPathOnlyFindsOwnMedia", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		repo := &MockMediaRepository{}
+		repo.On("FindByPath", ctx, uint(4), legacy.Path).Return(nil, shared.ErrRecordNotFound.WithDetails("media not found"))
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		err := service.Delete(ctx, 4, "user_4/../user_3/3_1_a.png")
+		assert.Error(t, err)
+		storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMediaService_PurgeUnreferenced(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	storage := &MockMediaStorage{}
+	storage.On("Delete", ctx, testBlobPath).Return(nil)
+	repo := &MockMediaRepository{}
+	repo.On("FindUnreferencedBlobs", ctx).Return([]domain.MediaBlob{{Path: testBlobPath}, {Path: jpegBlobPath}}, nil)
+	repo.On("LockBlob", ctx, testBlobPath).Return(&domain.MediaBlob{Path: testBlobPath}, nil)
+	repo.On("DeleteBlob", ctx, testBlobPath).Return(nil)
+	// Uploaded again since it was found
+	repo.On("LockBlob", ctx, jpegBlobPath).Return(&domain.MediaBlob{Path: jpegBlobPath, RefCount: 1}, nil)
+	service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: afterCommit{t: t, repo: repo, storage: storage}})
+
+	purged, err := service.PurgeUnreferenced(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	storage.AssertExpectations(t)
+	repo.AssertNotCalled(t, "DeleteBlob", ctx, jpegBlobPath)
+}
@@ -0,0 +1,98 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const megabyte = 1024 * 1024
+
+func TestMediaService_UploadQuota(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	uploads := testUploadConfig
+	uploads.QuotaMB = 10
+	uploads.RoleQuotasMB = map[string]int{"admin": 0}
+	uploads.UserQuotasMB = map[uint]int{5: 20}
+
+	t.Run("OverQuotaIsNotStored", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		repo := &MockMediaRepository{}
+		repo.On("FindUsage", ctx, uint(3)).Return(int64(10*megabyte-2), nil)
+		users := &MockUserRepository{}
+		users.On("FindByID", ctx, uint(3)).Return(&domain.User{Role: domain.RoleUser}, nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: uploads, Transactions: mediaTransactions{repo}, Users: users})
+
+		_, err := service.Upload(ctx, 3, strings.NewReader("test"), "notes.txt", "text/plain", 4, 3)
+		assert.ErrorIs(t, err, shared.ErrStorageQuotaExceeded.WithDetails("upload would exceed your storage quota of 10MB"))
+		storage.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "AddUsage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("QuotaUsedMeanwhileDiscardsCopy", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, copyOf(testBlobPath, ""), "text/plain", int64(4)).Return(nil)
+		storage.On("Delete", ctx, copyOf(testBlobPath, "")).Return(nil)
+		repo := &MockMediaRepository{}
+		repo.On("FindUsage", ctx, uint(3)).Return(int64(0), nil)
+		// Another upload took the room between the check and the transaction
+		repo.On("AddUsage", ctx, uint(3), int64(4), int64(10*megabyte)).Return(shared.ErrStorageQuotaExceeded)
+		users := &MockUserRepository{}
+		users.On("FindByID", ctx, uint(3)).Return(&domain.User{Role: domain.RoleUser}, nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: uploads, Transactions: mediaTransactions{repo}, Users: users})
+
+		_, err := service.Upload(ctx, 3, strings.NewReader("test"), "notes.txt", "text/plain", 4, 3)
+		assert.ErrorIs(t, err, shared.ErrStorageQuotaExceeded.WithDetails("upload would exceed your storage quota of 10MB"))
+		storage.AssertExpectations(t)
+		repo.AssertNotCalled(t, "AcquireBlob", mock.Anything, mock.Anything)
+	})
+
+	t.Run("RoleWithoutLimit", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Put", ctx, mock.Anything, copyOf(testBlobPath, ""), "text/plain", int64(4)).Return(nil)
+		storage.On("GetURL", ctx, copyOf(testBlobPath, "")).Return("http://localhost/media/file", nil)
+		repo := newBlobRepo()
+		repo.On("Create", ctx, mock.Anything).Return(nil)
+		users := &MockUserRepository{}
+		users.On("FindByID", ctx, uint(1)).Return(&domain.User{Role: domain.RoleAdmin}, nil)
+		service := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: uploads, Transactions: mediaTransactions{repo}, Users: users})
+
+		_, err := service.Upload(ctx, 1, strings.NewReader("test"), "notes.txt", "text/plain", 4, 1)
+		require.NoError(t, err)
+		repo.AssertCalled(t, "AddUsage", ctx, uint(1), int64(4), int64(0))
+	})
+
+	t.Run("UsageAgainstOwnQuota", func(t *testing.T) {
+		repo := &MockMediaRepository{}
+		repo.On("FindUsage", ctx, uint(5)).Return(int64(3*megabyte), nil)
+		users := &MockUserRepository{}
+		users.On("FindByID", ctx, uint(5)).Return(&domain.User{Role: domain.RoleUser}, nil)
+		service := NewMediaService(MediaServiceDeps{Storage: &MockMediaStorage{}, Media: repo, Uploads: uploads, Transactions: mediaTransactions{repo}, Users: users})
+
+		usage, err := service.Usage(ctx, 5)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3*megabyte), usage.UsedBytes)
+		require.NotNil(t, usage.QuotaBytes)
+		assert.Equal(t, int64(20*megabyte), *usage.QuotaBytes)
+		assert.Equal(t, int64(17*megabyte), *usage.RemainingBytes)
+	})
+
+	t.Run("UsageWithoutLimit", func(t *testing.T) {
+		repo := &MockMediaRepository{}
+		repo.On("FindUsage", ctx, uint(3)).Return(int64(3*megabyte), nil)
+		service := NewMediaService(MediaServiceDeps{Storage: &MockMediaStorage{}, Media: repo, Uploads: testUploadConfig, Transactions: mediaTransactions{repo}})
+
+		usage, err := service.Usage(ctx, 3)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3*megabyte), usage.UsedBytes)
+		assert.Nil(t, usage.QuotaBytes)
+		assert.Nil(t, usage.RemainingBytes)
+	})
+}
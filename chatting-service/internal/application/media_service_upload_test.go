@@ -4,14 +4,44 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/AmeerHeiba/chatting-service/internal/shared"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+var testUploadConfig = config.UploadConfig{
+	MaxSizeMB: 10,
+	AllowedTypes: map[string]int{
+		"image/jpeg":      0,
+		"image/png":       2,
+		"application/pdf": 0,
+		"text/plain":      0,
+	},
+}
+
+// Leading bytes are enough for the sniffer
+var jpegHeader = "\xff\xd8\xff\xe0\x00\x10JFIF\x00"
+
+// jpegBlobPath is where jpegHeader was stored before
+var jpegBlobPath = domain.MediaBlobPath(sha256Of(jpegHeader), "0a1b2c3d4e5f6a7b", ".jpg")
+
+// copyOf matches the path of a new copy of the content stored at path, suffix is
+// appended for thumbnails
+func copyOf(path string, suffix string) interface{} {
+	prefix := path[:strings.LastIndex(path, "-")+1]
+	ext := filepath.Ext(path)
+	return mock.MatchedBy(func(p string) bool {
+		return p != path+suffix && strings.HasPrefix(p, prefix) && strings.HasSuffix(p, ext+suffix)
+	})
+}
+
 type MockMediaStorage struct {
 	mock.Mock
 }
@@ -21,6 +51,11 @@ func (m *MockMediaStorage) Upload(ctx context.Context, file io.Reader, filename
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockMediaStorage) Put(ctx context.Context, file io.Reader, path string, contentType string, size int64) error {
+	args := m.Called(ctx, file, path, contentType, size)
+	return args.Error(0)
+}
+
 func (m *MockMediaStorage) GetURL(ctx context.Context, path string) (string, error) {
 	args := m.Called(ctx, path)
 	return args.String(0), args.Error(1)
@@ -36,9 +71,38 @@ func (m *MockMediaStorage) GetSignedURL(ctx context.Context, path string, durati
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockMediaStorage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	args := m.Called(ctx, path)
+	file, _ := args.Get(0).(io.ReadCloser)
+	return file, args.Error(1)
+}
+
+func (m *MockMediaStorage) PathFromURL(url string) (string, bool) {
+	args := m.Called(url)
+	return args.String(0), args.Bool(1)
+}
+
+func (m *MockMediaStorage) DeleteUserFiles(ctx context.Context, userId uint) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+// Walk hands fn the files the expectation returns
+func (m *MockMediaStorage) Walk(ctx context.Context, fn func(file domain.StoredFile) error) error {
+	args := m.Called(ctx)
+	files, _ := args.Get(0).([]domain.StoredFile)
+	for _, file := range files {
+		if err := fn(file); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func TestMediaService_Upload(t *testing.T) {
 	tests := []struct {
 		name          string
+		content       string
 		fileSize      int64
 		contentType   string
 		mockSetup     func(*MockMediaStorage)
@@ -46,23 +110,62 @@ func TestMediaService_Upload(t *testing.T) {
 	}{
 		{
 			name:        "ValidUpload",
+			content:     jpegHeader,
 			fileSize:    5 * 1024 * 1024, // 5MB
 			contentType: "image/jpeg",
 			mockSetup: func(ms *MockMediaStorage) {
-				ms.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-					Return("user_1/test.jpg", nil)
-				ms.On("GetURL", mock.Anything, "user_1/test.jpg").
-					Return("http://example.com/user_1/test.jpg", nil)
+				ms.On("Put", mock.Anything, mock.Anything, copyOf(jpegBlobPath, ""), "image/jpeg", mock.Anything).
+					Return(nil)
+				ms.On("GetURL", mock.Anything, copyOf(jpegBlobPath, "")).
+					Return("http://example.com/media/file", nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:        "MissingTypeIsDetected",
+			content:     jpegHeader,
+			fileSize:    int64(len(jpegHeader)),
+			contentType: "",
+			mockSetup: func(ms *MockMediaStorage) {
+				ms.On("Put", mock.Anything, mock.Anything, copyOf(jpegBlobPath, ""), "image/jpeg", mock.Anything).
+					Return(nil)
+				ms.On("GetURL", mock.Anything, copyOf(jpegBlobPath, "")).
+					Return("http://example.com/media/file", nil)
 			},
 			expectedError: nil,
 		},
 		{
 			name:          "FileTooLarge",
+			content:       jpegHeader,
 			fileSize:      11 * 1024 * 1024, // 11MB
 			contentType:   "image/jpeg",
 			mockSetup:     func(ms *MockMediaStorage) {},
 			expectedError: shared.ErrValidation.WithDetails("file size exceeds 10MB limit"),
 		},
+		{
+			name:          "TypeLimitExceeded",
+			content:       "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 2*1024*1024),
+			fileSize:      2*1024*1024 + 8,
+			contentType:   "image/png",
+			mockSetup:     func(ms *MockMediaStorage) {},
+			expectedError: shared.ErrValidation.WithDetails("file size exceeds 2MB limit"),
+		},
+		{
+			name:          "HTMLPassedAsImage",
+			content:       "<!DOCTYPE html><script>alert(1)</script>",
+			fileSize:      40,
+			contentType:   "image/png",
+			mockSetup:     func(ms *MockMediaStorage) {},
+			expectedError: shared.ErrUnsupportedMediaType.WithDetails("file content is text/html, not image/png"),
+		},
+		{
+			name:          "TypeNotAllowed",
+			content:       "GIF89a",
+			fileSize:      6,
+			contentType:   "image/gif",
+			mockSetup:     func(ms *MockMediaStorage) {},
+			expectedError: shared.ErrUnsupportedMediaType.WithDetails("image/gif files are not allowed"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -72,8 +175,11 @@ func TestMediaService_Upload(t *testing.T) {
 				tt.mockSetup(storage)
 			}
 
-			service := NewMediaService(storage)
-			file := bytes.NewBufferString("test file content")
+			mediaRepo := newBlobRepo()
+			mediaRepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			service := NewMediaService(MediaServiceDeps{Storage: storage, Media: mediaRepo, Uploads: testUploadConfig, Transactions: mediaTransactions{mediaRepo}})
+			file := bytes.NewBufferString(tt.content)
 
 			_, err := service.Upload(
 				context.WithValue(context.Background(), "userID", uint(1)),
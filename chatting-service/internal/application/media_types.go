@@ -0,0 +1,97 @@
+package application
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Other names clients send for the types the sniffer reports
+var contentTypeAliases = map[string]string{
+	"image/jpg":   "image/jpeg",
+	"image/pjpeg": "image/jpeg",
+	"image/x-png": "image/png",
+	"audio/mp3":   "audio/mpeg",
+	"audio/wav":   "audio/wave",
+	"audio/x-wav": "audio/wave",
+	"image/x-bmp": "image/bmp",
+}
+
+// Extensions stored files get, so the type they are served with matches their content
+var canonicalExtensions = map[string]string{
+	"image/jpeg":       ".jpg",
+	"image/png":        ".png",
+	"image/gif":        ".gif",
+	"image/webp":       ".webp",
+	"image/bmp":        ".bmp",
+	"application/pdf":  ".pdf",
+	"text/plain":       ".txt",
+	"text/csv":         ".csv",
+	"text/markdown":    ".md",
+	"audio/mpeg":       ".mp3",
+	"audio/wave":       ".wav",
+	"audio/ogg":        ".ogg",
+	"application/ogg":  ".ogg",
+	"video/ogg":        ".ogv",
+	"application/json": ".json",
+	"video/mp4":        ".mp4",
+	"video/webm":       ".webm",
+	"application/zip":  ".zip",
+}
+
+// normalizeContentType drops parameters and resolves aliases, "" when unparsable
+func normalizeContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if alias, ok := contentTypeAliases[mediaType]; ok {
+		return alias
+	}
+	return mediaType
+}
+
+// sniffContentType detects the type from the file's leading bytes
+func sniffContentType(data []byte) string {
+	return normalizeContentType(http.DetectContentType(data))
+}
+
+// detectContentType decides the type of an upload from its content. The declared type
+// only counts when the content cannot tell more, e.g. CSV looks like plain text and
+// office documents like zip archives. Otherwise a declared type must match the content.
+func detectContentType(data []byte, declared string) (string, bool) {
+	sniffed := sniffContentType(data)
+	declared = normalizeContentType(declared)
+	switch {
+	case declared == "" || declared == "application/octet-stream" || declared == sniffed:
+		return sniffed, true
+	case refinesSniffedType(sniffed, declared):
+		return declared, true
+	}
+	return sniffed, false
+}
+
+func refinesSniffedType(sniffed, declared string) bool {
+	switch sniffed {
+	case "text/plain":
+		return declared == "text/csv" || declared == "text/markdown" || declared == "application/json"
+	case "application/zip":
+		return strings.HasPrefix(declared, "application/vnd.openxmlformats-officedocument.") ||
+			strings.HasPrefix(declared, "application/vnd.oasis.opendocument.") ||
+			declared == "application/epub+zip"
+	case "application/ogg":
+		return declared == "audio/ogg" || declared == "video/ogg"
+	}
+	return false
+}
+
+// mediaExtension is the extension stored files of the type get
+func mediaExtension(contentType string) string {
+	if ext, ok := canonicalExtensions[contentType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
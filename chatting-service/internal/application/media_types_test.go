@@ -0,0 +1,41 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		declared string
+		want     string
+		ok       bool
+	}{
+		{"Matches", "\x89PNG\r\n\x1a\n", "image/png", "image/png", true},
+		{"Alias", "\xff\xd8\xff\xe0", "image/jpg", "image/jpeg", true},
+		{"NoDeclaredType", "%PDF-1.7", "", "application/pdf", true},
+		{"OctetStream", "%PDF-1.7", "application/octet-stream", "application/pdf", true},
+		{"CSVLooksLikeText", "a,b\n1,2\n", "text/csv; charset=utf-8", "text/csv", true},
+		{"DocxLooksLikeZip", "PK\x03\x04", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", true},
+		{"HTMLAsImage", "<html><body>hi</body></html>", "image/png", "text/html", false},
+		{"TextAsImage", "hello", "image/png", "text/plain", false},
+		{"ImageAsPDF", "GIF89a", "application/pdf", "image/gif", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := detectContentType([]byte(tt.content), tt.declared)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestMediaExtension(t *testing.T) {
+	assert.Equal(t, ".jpg", mediaExtension("image/jpeg"))
+	assert.Equal(t, ".pdf", mediaExtension("application/pdf"))
+	assert.Equal(t, ".bin", mediaExtension("application/x-unknown-thing"))
+}
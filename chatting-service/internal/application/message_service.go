@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 
+	"github.com/AmeerHeiba/chatting-service/internal/config"
 	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/AmeerHeiba/chatting-service/internal/shared"
 	"go.uber.org/zap"
@@ -15,6 +16,11 @@ type MessageService struct {
 	userRepo             domain.UserRepository
 	notifier             domain.MessageNotifier // Optional for real-time
 	mediaUploader        domain.MediaUploader
+	policy               config.MessagingConfig
+	audit                *AuditLogger
+	blockRepo            domain.UserBlockRepository     // Optional, blocks are not enforced when nil
+	preferences          *NotificationPreferenceService // Optional, nothing is silenced when nil
+	contactRepo          domain.ContactRepository       // Needed unless the direct message policy is open
 }
 
 func NewMessageService(
@@ -23,6 +29,11 @@ func NewMessageService(
 	userRepo domain.UserRepository,
 	notifier domain.MessageNotifier,
 	mediaUploader domain.MediaUploader,
+	policy config.MessagingConfig,
+	audit *AuditLogger,
+	blockRepo domain.UserBlockRepository,
+	preferences *NotificationPreferenceService,
+	contactRepo domain.ContactRepository,
 ) *MessageService {
 	return &MessageService{
 		messageRepo:          messageRepo,
@@ -30,10 +41,37 @@ func NewMessageService(
 		userRepo:             userRepo,
 		notifier:             notifier,
 		mediaUploader:        mediaUploader,
+		policy:               policy,
+		audit:                audit,
+		blockRepo:            blockRepo,
+		preferences:          preferences,
+		contactRepo:          contactRepo,
 	}
 }
 
+// checkSenderAllowed applies server side sending policies to the sender
+func (s *MessageService) checkSenderAllowed(ctx context.Context, senderID uint) error {
+	if !s.policy.RequireVerifiedEmail {
+		return nil
+	}
+
+	sender, err := s.userRepo.FindByID(ctx, senderID)
+	if err != nil {
+		shared.Log.Error("find sender failed", zap.Uint("senderID", senderID), zap.Error(err))
+		return err
+	}
+	if !sender.EmailVerified {
+		shared.Log.Debug("sender email not verified", zap.Uint("senderID", senderID))
+		return shared.ErrEmailNotVerified.WithDetails("verify your email address before sending messages")
+	}
+	return nil
+}
+
 func (s *MessageService) SendDirectMessage(ctx context.Context, senderID, recipientID uint, content string, mediaURL string) (*domain.Message, error) {
+	if err := s.checkSenderAllowed(ctx, senderID); err != nil {
+		return nil, err
+	}
+
 	// Validate recipient exists
 	if exists, err := s.userRepo.Exists(ctx, recipientID); err != nil {
 		shared.Log.Error("user exists check failed",
@@ -49,6 +87,31 @@ func (s *MessageService) SendDirectMessage(ctx context.Context, senderID, recipi
 			zap.Uint("senderID", senderID))
 		return nil, err
 	}
+
+	if s.blockRepo != nil {
+		blocked, err := s.blockRepo.IsBlocked(ctx, recipientID, senderID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			shared.Log.Debug("recipient blocked sender",
+				zap.String("operation", "SendDirectMessage"),
+				zap.Uint("recipientID", recipientID),
+				zap.Uint("senderID", senderID))
+			return nil, shared.ErrBlocked
+		}
+	}
+
+	if denied, err := s.disallowedRecipients(ctx, senderID, []uint{recipientID}); err != nil {
+		return nil, err
+	} else if len(denied) > 0 {
+		shared.Log.Debug("direct message policy rejected recipient",
+			zap.String("operation", "SendDirectMessage"),
+			zap.Uint("recipientID", recipientID),
+			zap.Uint("senderID", senderID))
+		return nil, shared.ErrDirectMessageNotAllowed
+	}
+
 	// Validate content
 	if strings.TrimSpace(content) == "" && mediaURL == "" {
 		shared.Log.Debug("Invalid or empty message content",
@@ -58,6 +121,9 @@ func (s *MessageService) SendDirectMessage(ctx context.Context, senderID, recipi
 			zap.String("content", content))
 		return nil, shared.ErrValidation.WithDetails("Invalid or empty message content for direct message")
 	}
+	if err := s.checkMediaURL(ctx, senderID, mediaURL); err != nil {
+		return nil, err
+	}
 
 	msg, err := s.messageRepo.Create(ctx, senderID, content, mediaURL, domain.MessageDirect)
 	if err != nil {
@@ -84,9 +150,12 @@ func (s *MessageService) SendDirectMessage(ctx context.Context, senderID, recipi
 		return nil, shared.ErrNotFound.WithDetails("Could not find message")
 	}
 
+	s.attachMedia(ctx, []*domain.Message{fullMessage})
+	silent := s.silenceFor(ctx, fullMessage, []uint{recipientID})
+
 	// Notify recipient (if notifier is configured)
 	if s.notifier != nil {
-		if err := s.notifier.Notify(ctx, fullMessage); err != nil {
+		if err := s.notifier.Notify(ctx, fullMessage, silent[recipientID]); err != nil {
 			shared.Log.Error("notify recipient failed",
 				zap.String("operation", "SendDirectMessage"),
 				zap.Uint("messageID", msg.ID),
@@ -95,7 +164,7 @@ func (s *MessageService) SendDirectMessage(ctx context.Context, senderID, recipi
 		}
 	}
 
-	return msg, nil
+	return fullMessage, nil
 }
 
 func (s *MessageService) SendBroadcast(ctx context.Context, broadcasterID uint, content string, mediaURL string, recipientIDs []uint) (*domain.Message, error) {
@@ -104,6 +173,10 @@ func (s *MessageService) SendBroadcast(ctx context.Context, broadcasterID uint,
 		return nil, shared.ErrBadRequest.WithDetails("Invalid or empty recipient IDs")
 	}
 
+	if err := s.checkSenderAllowed(ctx, broadcasterID); err != nil {
+		return nil, err
+	}
+
 	// Validate all recipients exist first
 	for _, id := range recipientIDs {
 		if _, err := s.userRepo.FindByID(ctx, id); err != nil {
@@ -112,13 +185,33 @@ func (s *MessageService) SendBroadcast(ctx context.Context, broadcasterID uint,
 		}
 	}
 
+	// Recipients who blocked the broadcaster are dropped without telling the broadcaster
+	recipientIDs, err := s.withoutBlockers(ctx, broadcasterID, recipientIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipientIDs) == 0 {
+		return nil, shared.ErrBadRequest.WithDetails("No recipients available")
+	}
+
+	if denied, err := s.disallowedRecipients(ctx, broadcasterID, recipientIDs); err != nil {
+		return nil, err
+	} else if len(denied) > 0 {
+		return nil, shared.ErrDirectMessageNotAllowed.WithDetails(map[string][]uint{"recipient_ids": denied})
+	}
+
+	if err := s.checkMediaURL(ctx, broadcasterID, mediaURL); err != nil {
+		return nil, err
+	}
+
 	// Create message object (validation happens in CreateWithRecipients)
 	msg := &domain.Message{
-		SenderID:    broadcasterID,
-		Content:     content,
-		MediaURL:    mediaURL,
-		MessageType: domain.MessageBroadcast,
-		Status:      domain.StatusSent,
+		SenderID:      broadcasterID,
+		BroadcasterID: &broadcasterID,
+		Content:       content,
+		MediaURL:      mediaURL,
+		MessageType:   domain.MessageBroadcast,
+		Status:        domain.StatusSent,
 	}
 
 	// Create message with recipients in one transaction
@@ -135,14 +228,140 @@ func (s *MessageService) SendBroadcast(ctx context.Context, broadcasterID uint,
 		return nil, err
 	}
 
+	s.attachMedia(ctx, []*domain.Message{fullMessage})
+	silent := s.silenceFor(ctx, fullMessage, recipientIDs)
+
 	// Notify recipients
 	if s.notifier != nil {
-		if err := s.notifier.Broadcast(ctx, fullMessage, recipientIDs); err != nil {
+		if err := s.notifier.Broadcast(ctx, fullMessage, recipientIDs, silent); err != nil {
 		}
 	}
 
 	return fullMessage, nil
 }
+
+// silenceFor works out which recipients get the message without a notification. Those
+// who muted it are recorded so the message stays out of their unread totals. Quiet
+// hours are not recorded, the message counts as unread once they end. The message
+// itself is always kept.
+func (s *MessageService) silenceFor(ctx context.Context, message *domain.Message, recipientIDs []uint) map[uint]bool {
+	if s.preferences == nil {
+		return map[uint]bool{}
+	}
+	silent, muted := s.preferences.SilentRecipients(ctx, message, recipientIDs)
+	if len(muted) == 0 {
+		return silent
+	}
+
+	mutedIDs := make([]uint, 0, len(muted))
+	for id := range muted {
+		mutedIDs = append(mutedIDs, id)
+	}
+	if err := s.messageRepo.MarkSilent(ctx, message.ID, mutedIDs); err != nil {
+		shared.Log.Error("mark message silent failed", zap.Uint("messageID", message.ID), zap.Error(err))
+	}
+	return silent
+}
+
+// CountUnread is the unread badge total, muted messages are not counted
+func (s *MessageService) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	return s.messageRepo.CountUnread(ctx, userID)
+}
+
+// disallowedRecipients applies the direct message policy and returns the recipients
+// senderID may not reach. Accepted contacts are always reachable.
+func (s *MessageService) disallowedRecipients(ctx context.Context, senderID uint, recipientIDs []uint) ([]uint, error) {
+	policy := s.policy.DirectMessagePolicy
+	if policy == "" || policy == config.DMPolicyOpen {
+		return nil, nil
+	}
+
+	contacts := make(map[uint]struct{})
+	if s.contactRepo != nil {
+		ids, err := s.contactRepo.ContactIDsAmong(ctx, senderID, recipientIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			contacts[id] = struct{}{}
+		}
+	}
+
+	var senderOrg string
+	if policy == config.DMPolicySameOrg {
+		sender, err := s.userRepo.FindByID(ctx, senderID)
+		if err != nil {
+			return nil, err
+		}
+		if senderOrg, err = s.organizationOf(ctx, sender); err != nil {
+			return nil, err
+		}
+	}
+
+	var denied []uint
+	for _, id := range recipientIDs {
+		if _, ok := contacts[id]; ok {
+			continue
+		}
+		if policy == config.DMPolicySameOrg && senderOrg != "" {
+			recipient, err := s.userRepo.FindByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			recipientOrg, err := s.organizationOf(ctx, recipient)
+			if err != nil {
+				return nil, err
+			}
+			if recipientOrg == senderOrg {
+				continue
+			}
+		}
+		denied = append(denied, id)
+	}
+	return denied, nil
+}
+
+// organizationOf is the user's email domain. Bots belong to their owner's organization.
+// Anyone can type any address, so users without a verified email have no organization.
+func (s *MessageService) organizationOf(ctx context.Context, user *domain.User) (string, error) {
+	if user.IsBot && user.BotOwnerID != nil {
+		owner, err := s.userRepo.FindByID(ctx, *user.BotOwnerID)
+		if err != nil {
+			return "", err
+		}
+		user = owner
+	}
+	if !user.EmailVerified {
+		return "", nil
+	}
+	return user.EmailDomain(), nil
+}
+
+func (s *MessageService) withoutBlockers(ctx context.Context, senderID uint, recipientIDs []uint) ([]uint, error) {
+	if s.blockRepo == nil {
+		return recipientIDs, nil
+	}
+	blockers, err := s.blockRepo.FindBlockersOf(ctx, senderID, recipientIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(blockers) == 0 {
+		return recipientIDs, nil
+	}
+
+	skip := make(map[uint]struct{}, len(blockers))
+	for _, id := range blockers {
+		skip[id] = struct{}{}
+	}
+	allowed := make([]uint, 0, len(recipientIDs))
+	for _, id := range recipientIDs {
+		if _, ok := skip[id]; !ok {
+			allowed = append(allowed, id)
+		}
+	}
+	return allowed, nil
+}
+
 func (s *MessageService) GetConversation(ctx context.Context, user1ID, user2ID uint, query domain.MessageQuery) ([]domain.Message, error) {
 	// Validate both users exist
 	if _, err := s.userRepo.FindByID(ctx, user1ID); err != nil {
@@ -164,6 +383,7 @@ func (s *MessageService) GetConversation(ctx context.Context, user1ID, user2ID u
 		return nil, err
 	}
 
+	s.attachMedia(ctx, messagePointers(messages))
 	return messages, nil
 }
 
@@ -176,9 +396,68 @@ func (s *MessageService) GetMessageHistory(ctx context.Context, userID uint, que
 			zap.Error(err))
 		return nil, err
 	}
+	s.attachMedia(ctx, messagePointers(messages))
 	return messages, nil
 }
 
+// checkMediaURL refuses links to stored files the sender cannot open themselves, so a
+// message never hands out access to somebody else's upload
+func (s *MessageService) checkMediaURL(ctx context.Context, senderID uint, mediaURL string) error {
+	if mediaURL == "" || s.mediaUploader == nil {
+		return nil
+	}
+	sender, err := s.userRepo.FindByID(ctx, senderID)
+	if err != nil {
+		return err
+	}
+	allowed, err := s.mediaUploader.CanShare(ctx, senderID, sender.Role, mediaURL)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		shared.Log.Debug("media URL not accessible to sender",
+			zap.Uint("senderID", senderID),
+			zap.String("mediaURL", mediaURL))
+		return shared.ErrValidation.WithDetails("media_url must link to media you uploaded or received")
+	}
+	return nil
+}
+
+// attachMedia adds dimensions and thumbnails of linked media. Messages still work
+// without them, so failures are only logged.
+func (s *MessageService) attachMedia(ctx context.Context, messages []*domain.Message) {
+	if s.mediaUploader == nil {
+		return
+	}
+	var urls []string
+	for _, msg := range messages {
+		if msg.MediaURL != "" {
+			urls = append(urls, msg.MediaURL)
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+	attachments, err := s.mediaUploader.Attachments(ctx, urls)
+	if err != nil {
+		shared.Log.Warn("load media attachments failed", zap.Error(err))
+		return
+	}
+	for _, msg := range messages {
+		if attachment, ok := attachments[msg.MediaURL]; ok {
+			msg.Media = &attachment
+		}
+	}
+}
+
+func messagePointers(messages []domain.Message) []*domain.Message {
+	pointers := make([]*domain.Message, len(messages))
+	for i := range messages {
+		pointers[i] = &messages[i]
+	}
+	return pointers
+}
+
 func (s *MessageService) MarkAsDelivered(ctx context.Context, messageID uint) error {
 	if err := s.messageRepo.MarkAsDelivered(ctx, messageID); err != nil {
 		shared.Log.Error("mark message as delivered failed",
@@ -218,6 +497,13 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID uint, user
 			zap.String("operation", "DeleteMessage"),
 			zap.Uint("messageID", messageID),
 			zap.Uint("userID", userID))
+		s.audit.Record(ctx, domain.AuditEvent{
+			ActorID:    auditActor(userID),
+			Action:     domain.AuditMessageDelete,
+			Outcome:    domain.AuditDenied,
+			TargetType: "message",
+			TargetID:   auditID(messageID),
+		})
 		return shared.ErrInvalidCredentials
 	}
 
@@ -230,5 +516,11 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID uint, user
 		return err
 	}
 
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(userID),
+		Action:     domain.AuditMessageDelete,
+		TargetType: "message",
+		TargetID:   auditID(messageID),
+	})
 	return nil
 }
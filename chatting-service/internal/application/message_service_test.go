@@ -0,0 +1,140 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestMessageService_SendDirectMessageToBlocker(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	userRepo := &MockUserRepository{}
+	blockRepo := &MockUserBlockRepository{}
+	userRepo.On("Exists", mock.Anything, uint(2)).Return(true, nil)
+	blockRepo.On("IsBlocked", mock.Anything, uint(2), uint(1)).Return(true, nil)
+
+	service := NewMessageService(nil, nil, userRepo, nil, nil, config.MessagingConfig{}, nil, blockRepo, nil, nil)
+	_, err := service.SendDirectMessage(ctx, 1, 2, "hello", "")
+
+	var appErr shared.Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, shared.ErrBlocked.Code, appErr.Code)
+}
+
+func TestMessageService_DirectMessagePolicy(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("ContactsOnlyRejectsStranger", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		contactRepo := &MockContactRepository{}
+		userRepo.On("Exists", mock.Anything, uint(2)).Return(true, nil)
+		contactRepo.On("ContactIDsAmong", mock.Anything, uint(1), []uint{2}).Return([]uint{}, nil)
+
+		policy := config.MessagingConfig{DirectMessagePolicy: config.DMPolicyContactsOnly}
+		service := NewMessageService(nil, nil, userRepo, nil, nil, policy, nil, nil, nil, contactRepo)
+		_, err := service.SendDirectMessage(ctx, 1, 2, "hello", "")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrDirectMessageNotAllowed.Code, appErr.Code)
+	})
+
+	t.Run("SameOrgRejectsOtherDomain", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		contactRepo := &MockContactRepository{}
+		userRepo.On("Exists", mock.Anything, uint(2)).Return(true, nil)
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, Email: "ops@partner.example"}, nil)
+		userRepo.On("FindByID", mock.Anything, uint(2)).Return(&domain.User{Model: gorm.Model{ID: 2}, Email: "jane@Corp.example"}, nil)
+		contactRepo.On("ContactIDsAmong", mock.Anything, uint(1), []uint{2}).Return([]uint{}, nil)
+
+		policy := config.MessagingConfig{DirectMessagePolicy: config.DMPolicySameOrg}
+		service := NewMessageService(nil, nil, userRepo, nil, nil, policy, nil, nil, nil, contactRepo)
+		denied, err := service.disallowedRecipients(ctx, 1, []uint{2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{2}, denied)
+	})
+
+	t.Run("SameOrgAllowsColleagueAndBotOfColleague", func(t *testing.T) {
+		ownerID := uint(3)
+		userRepo := &MockUserRepository{}
+		contactRepo := &MockContactRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, Email: "ops@corp.example", EmailVerified: true}, nil)
+		userRepo.On("FindByID", mock.Anything, uint(2)).Return(&domain.User{Model: gorm.Model{ID: 2}, Email: "jane@CORP.example", EmailVerified: true}, nil)
+		userRepo.On("FindByID", mock.Anything, uint(4)).
+			Return(&domain.User{Model: gorm.Model{ID: 4}, Email: "ci@bots.chatting-service.local", IsBot: true, BotOwnerID: &ownerID}, nil)
+		userRepo.On("FindByID", mock.Anything, ownerID).Return(&domain.User{Model: gorm.Model{ID: 3}, Email: "dev@corp.example", EmailVerified: true}, nil)
+		contactRepo.On("ContactIDsAmong", mock.Anything, uint(1), []uint{2, 4}).Return([]uint{}, nil)
+
+		policy := config.MessagingConfig{DirectMessagePolicy: config.DMPolicySameOrg}
+		service := NewMessageService(nil, nil, userRepo, nil, nil, policy, nil, nil, nil, contactRepo)
+		denied, err := service.disallowedRecipients(ctx, 1, []uint{2, 4})
+
+		assert.NoError(t, err)
+		assert.Empty(t, denied)
+	})
+	t.Run("SameOrgIgnoresUnverifiedEmail", func(t *testing.T) {
+		ownerID := uint(5)
+		userRepo := &MockUserRepository{}
+		contactRepo := &MockContactRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, Email: "ops@corp.example", EmailVerified: true}, nil)
+		// Anyone can register with the company's domain
+		userRepo.On("FindByID", mock.Anything, uint(2)).Return(&domain.User{Model: gorm.Model{ID: 2}, Email: "ceo@corp.example"}, nil)
+		userRepo.On("FindByID", mock.Anything, uint(4)).
+			Return(&domain.User{Model: gorm.Model{ID: 4}, Email: "ci@bots.chatting-service.local", IsBot: true, BotOwnerID: &ownerID}, nil)
+		userRepo.On("FindByID", mock.Anything, ownerID).Return(&domain.User{Model: gorm.Model{ID: 5}, Email: "dev@corp.example"}, nil)
+		contactRepo.On("ContactIDsAmong", mock.Anything, uint(1), []uint{2, 4}).Return([]uint{}, nil)
+
+		policy := config.MessagingConfig{DirectMessagePolicy: config.DMPolicySameOrg}
+		service := NewMessageService(nil, nil, userRepo, nil, nil, policy, nil, nil, nil, contactRepo)
+		denied, err := service.disallowedRecipients(ctx, 1, []uint{2, 4})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{2, 4}, denied)
+	})
+
+	t.Run("SameOrgUnverifiedSenderHasNoOrganization", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		contactRepo := &MockContactRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, Email: "intruder@corp.example"}, nil)
+		userRepo.On("FindByID", mock.Anything, uint(2)).Return(&domain.User{Model: gorm.Model{ID: 2}, Email: "jane@corp.example", EmailVerified: true}, nil)
+		contactRepo.On("ContactIDsAmong", mock.Anything, uint(1), []uint{2}).Return([]uint{}, nil)
+
+		policy := config.MessagingConfig{DirectMessagePolicy: config.DMPolicySameOrg}
+		service := NewMessageService(nil, nil, userRepo, nil, nil, policy, nil, nil, nil, contactRepo)
+		denied, err := service.disallowedRecipients(ctx, 1, []uint{2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{2}, denied)
+	})
+}
+
+func TestMessageService_SendRefusesMediaOfOthers(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	guessed := "http://localhost/media/user_9/9_1_private.png"
+
+	storage := &MockMediaStorage{}
+	storage.On("PathFromURL", guessed).Return("user_9/9_1_private.png", true)
+	storage.On("GetURL", ctx, "user_9/9_1_private.png").Return(guessed, nil)
+	media := NewMediaService(MediaServiceDeps{Storage: storage, Messages: &stubMediaAccess{}})
+	userRepo := &MockUserRepository{}
+	userRepo.On("Exists", mock.Anything, uint(2)).Return(true, nil)
+	userRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, Role: domain.RoleUser}, nil)
+
+	service := NewMessageService(nil, nil, userRepo, nil, media, config.MessagingConfig{}, nil, nil, nil, nil)
+	_, err := service.SendDirectMessage(ctx, 1, 2, "", guessed)
+
+	var appErr shared.Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, shared.ErrValidation.Code, appErr.Code)
+}
@@ -0,0 +1,125 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+const maxMutesPerUser = 500
+
+type NotificationPreferenceService struct {
+	repo      domain.NotificationPreferenceRepository
+	txManager domain.TransactionManager
+	now       func() time.Time
+}
+
+func NewNotificationPreferenceService(repo domain.NotificationPreferenceRepository, txManager domain.TransactionManager) *NotificationPreferenceService {
+	return &NotificationPreferenceService{
+		repo:      repo,
+		txManager: txManager,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Get returns the user's preferences, or the defaults, with the mutes still in effect
+func (s *NotificationPreferenceService) Get(ctx context.Context, userID uint) (*domain.NotificationPreferences, []domain.Mute, error) {
+	prefs, err := s.repo.Find(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if prefs == nil {
+		prefs = domain.DefaultNotificationPreferences(userID)
+	}
+
+	mutes, err := s.repo.ListMutes(ctx, userID, s.now())
+	if err != nil {
+		return nil, nil, err
+	}
+	return prefs, mutes, nil
+}
+
+// Update replaces the user's preferences and mutes, both or neither are saved
+func (s *NotificationPreferenceService) Update(ctx context.Context, prefs *domain.NotificationPreferences, mutes []domain.Mute) error {
+	if err := prefs.Validate(); err != nil {
+		return shared.ErrValidation.WithDetails(err.Error())
+	}
+	if len(mutes) > maxMutesPerUser {
+		return shared.ErrValidation.WithDetails("too many mutes")
+	}
+
+	type muteKey struct {
+		scope  domain.MuteScope
+		target uint
+	}
+	now := s.now()
+	seen := make(map[muteKey]bool, len(mutes))
+	active := make([]domain.Mute, 0, len(mutes))
+	for _, mute := range mutes {
+		if !mute.Scope.IsValid() {
+			return shared.ErrValidation.WithDetails("mute scope must be conversation or sender")
+		}
+		if mute.TargetUserID == 0 || mute.TargetUserID == prefs.UserID {
+			return shared.ErrValidation.WithDetails("mute target must be another user")
+		}
+		key := muteKey{scope: mute.Scope, target: mute.TargetUserID}
+		if seen[key] {
+			return shared.ErrValidation.WithDetails("each conversation or sender can only be muted once")
+		}
+		seen[key] = true
+		if mute.ActiveAt(now) {
+			active = append(active, mute)
+		}
+	}
+
+	return s.txManager.WithTransaction(ctx, func(ctx context.Context, repos *domain.Repositories) error {
+		if err := repos.NotificationPreferences.Save(ctx, prefs); err != nil {
+			return err
+		}
+		return repos.NotificationPreferences.ReplaceMutes(ctx, prefs.UserID, active)
+	})
+}
+
+// SilentRecipients returns the recipients who should get message without a sound, and
+// among them those who muted it, whose unread badge leaves it out as well. Quiet hours
+// only silence. Lookup failures are logged and treated as not muted, a missed mute is
+// better than a missed message.
+func (s *NotificationPreferenceService) SilentRecipients(ctx context.Context, message *domain.Message, recipientIDs []uint) (silent, muted map[uint]bool) {
+	silent, muted = make(map[uint]bool), make(map[uint]bool)
+	if len(recipientIDs) == 0 {
+		return silent, muted
+	}
+
+	now := s.now()
+	saved, err := s.repo.FindMany(ctx, recipientIDs)
+	if err != nil {
+		shared.Log.Error("load notification preferences failed", zap.Uint("messageID", message.ID), zap.Error(err))
+		return silent, muted
+	}
+	mutes, err := s.repo.FindMutesOf(ctx, message.SenderID, recipientIDs, now)
+	if err != nil {
+		shared.Log.Error("load mutes failed", zap.Uint("messageID", message.ID), zap.Error(err))
+		return silent, muted
+	}
+
+	prefsByUser := make(map[uint]*domain.NotificationPreferences, len(saved))
+	for i := range saved {
+		prefsByUser[saved[i].UserID] = &saved[i]
+	}
+	for _, id := range recipientIDs {
+		prefs, ok := prefsByUser[id]
+		if !ok {
+			prefs = domain.DefaultNotificationPreferences(id)
+		}
+		if prefs.Mutes(message, mutes, now) {
+			muted[id] = true
+		}
+		if muted[id] || prefs.InQuietHours(now) {
+			silent[id] = true
+		}
+	}
+	return silent, muted
+}
@@ -0,0 +1,42 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationPreferenceService_Update(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("SavesInOneTransaction", func(t *testing.T) {
+		txManager := &stubTransactionManager{}
+		mutes := []domain.Mute{
+			{Scope: domain.MuteConversation, TargetUserID: 2},
+			{Scope: domain.MuteSender, TargetUserID: 2},
+		}
+
+		err := NewNotificationPreferenceService(nil, txManager).Update(ctx, domain.DefaultNotificationPreferences(1), mutes)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, txManager.calls)
+	})
+
+	t.Run("RejectsDuplicateMutes", func(t *testing.T) {
+		txManager := &stubTransactionManager{}
+		mutes := []domain.Mute{
+			{Scope: domain.MuteSender, TargetUserID: 2},
+			{Scope: domain.MuteSender, TargetUserID: 2},
+		}
+
+		err := NewNotificationPreferenceService(nil, txManager).Update(ctx, domain.DefaultNotificationPreferences(1), mutes)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrValidation.Code, appErr.Code)
+		assert.Zero(t, txManager.calls)
+	})
+}
@@ -0,0 +1,248 @@
+package application
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+const maxProvisionedUsernameLength = 40
+
+// OIDCLogin runs the authorization code + PKCE flow against the configured provider
+// and resolves the asserted identity to a local user
+type OIDCLogin struct {
+	provider     domain.IdentityProvider
+	stateRepo    domain.OIDCStateRepository
+	identityRepo domain.ExternalIdentityRepository
+	userRepo     domain.UserRepository
+	userService  *UserService
+	cfg          config.OIDCConfig
+}
+
+func NewOIDCLogin(
+	provider domain.IdentityProvider,
+	stateRepo domain.OIDCStateRepository,
+	identityRepo domain.ExternalIdentityRepository,
+	userRepo domain.UserRepository,
+	userService *UserService,
+	cfg config.OIDCConfig,
+) *OIDCLogin {
+	return &OIDCLogin{
+		provider:     provider,
+		stateRepo:    stateRepo,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		userService:  userService,
+		cfg:          cfg,
+	}
+}
+
+// Begin stores a fresh state, PKCE verifier and nonce and returns the provider URL to redirect to
+func (l *OIDCLogin) Begin(ctx context.Context) (string, error) {
+	state, err := generateRandomString(43)
+	if err != nil {
+		return "", shared.ErrInternalServer.WithDetails("generate login state failed")
+	}
+	verifier, err := generateRandomString(64)
+	if err != nil {
+		return "", shared.ErrInternalServer.WithDetails("generate code verifier failed")
+	}
+	nonce, err := generateRandomString(32)
+	if err != nil {
+		return "", shared.ErrInternalServer.WithDetails("generate nonce failed")
+	}
+
+	err = l.stateRepo.Create(ctx, &domain.OIDCLoginState{
+		StateHash:    hashLoginState(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().UTC().Add(l.cfg.StateTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return l.provider.AuthCodeURL(ctx, state, pkceChallenge(verifier), nonce)
+}
+
+// Complete redeems the callback parameters and returns the local user for the identity,
+// linking or provisioning an account on first login
+func (l *OIDCLogin) Complete(ctx context.Context, code, state string) (*domain.User, error) {
+	loginState, err := l.stateRepo.Consume(ctx, hashLoginState(state))
+	if err != nil {
+		var appErr shared.Error
+		if errors.As(err, &appErr) && appErr.Code == shared.ErrRecordNotFound.Code {
+			return nil, shared.ErrUnauthorized.WithDetails("unknown or already used login state")
+		}
+		return nil, err
+	}
+	if loginState.IsExpired() {
+		return nil, shared.ErrUnauthorized.WithDetails("login took too long, please start again")
+	}
+
+	claims, err := l.provider.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := l.identityRepo.FindBySubject(ctx, claims.Issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		if err := l.identityRepo.TouchLastLogin(ctx, identity.ID); err != nil {
+			shared.Log.Error("update external identity last login failed", zap.Error(err), zap.Uint("identityID", identity.ID))
+		}
+		return l.userRepo.FindByID(ctx, identity.UserID)
+	}
+
+	user, err := l.resolveUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = l.identityRepo.Create(ctx, &domain.ExternalIdentity{
+		UserID:      user.ID,
+		Issuer:      claims.Issuer,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shared.Log.Info("external identity linked",
+		zap.String("event", "sso_identity_linked"),
+		zap.Uint("userID", user.ID),
+		zap.String("issuer", claims.Issuer))
+	return user, nil
+}
+
+// resolveUser finds the account a new identity belongs to. Existing accounts are only
+// matched on an email the provider has verified, otherwise anyone could claim them, and
+// only when the account verified it too. Whoever registered an unverified address may
+// not own it, and their password would keep working on the linked account.
+func (l *OIDCLogin) resolveUser(ctx context.Context, claims *domain.ExternalClaims) (*domain.User, error) {
+	if claims.Email != "" && claims.EmailVerified {
+		user, err := l.userRepo.FindByEmail(ctx, claims.Email)
+		if err == nil {
+			if user.IsBot {
+				return nil, shared.ErrForbidden.WithDetails("bot accounts cannot use single sign-on")
+			}
+			if !user.EmailVerified {
+				shared.Log.Warn("refused to link identity to unverified account",
+					zap.String("event", "sso_link_refused"),
+					zap.Uint("userID", user.ID),
+					zap.String("issuer", claims.Issuer))
+				return nil, shared.ErrForbidden.WithDetails("an account with this email exists but has not verified it, sign in to that account and verify the email first")
+			}
+			return user, nil
+		}
+		var appErr shared.Error
+		if !errors.As(err, &appErr) || appErr.Code != shared.ErrRecordNotFound.Code {
+			return nil, err
+		}
+	}
+
+	if !l.cfg.AutoProvision {
+		return nil, shared.ErrForbidden.WithDetails("no account is linked to this identity")
+	}
+	return l.provision(ctx, claims)
+}
+
+func (l *OIDCLogin) provision(ctx context.Context, claims *domain.ExternalClaims) (*domain.User, error) {
+	if claims.Email == "" {
+		return nil, shared.ErrForbidden.WithDetails("identity provider did not share an email address")
+	}
+
+	username, err := l.availableUsername(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// SSO users never see this password, they can set one later through a reset
+	password, err := generateRandomString(32)
+	if err != nil {
+		return nil, shared.ErrInternalServer.WithDetails("generate password failed")
+	}
+
+	user, err := l.userService.CreateUser(ctx, username, claims.Email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.EmailVerified {
+		if err := l.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.EmailVerified = true
+	} else if err := l.userService.SendEmailVerification(ctx, user); err != nil {
+		shared.Log.Error("send verification email failed", zap.Error(err), zap.Uint("userID", user.ID))
+	}
+
+	shared.Log.Info("user provisioned from identity provider",
+		zap.String("event", "sso_user_provisioned"),
+		zap.Uint("userID", user.ID),
+		zap.String("issuer", claims.Issuer))
+	return user, nil
+}
+
+// availableUsername derives a username from the mapped claim (or the email local part)
+// and appends a numeric suffix until it does not collide with an existing account
+func (l *OIDCLogin) availableUsername(ctx context.Context, claims *domain.ExternalClaims) (string, error) {
+	base := sanitizeUsername(claims.Username)
+	if len(base) < 3 {
+		base = sanitizeUsername(strings.SplitN(claims.Email, "@", 2)[0])
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	for i := 0; i < 20; i++ {
+		candidate := base
+		if i > 0 {
+			candidate = fmt.Sprintf("%s%d", base, i+1)
+		}
+		exists, err := l.userRepo.ExistsByUsername(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return "", shared.ErrUserExists.WithDetails("could not find a free username")
+}
+
+func sanitizeUsername(raw string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			b.WriteRune(r)
+		}
+		if b.Len() == maxProvisionedUsernameLength {
+			break
+		}
+	}
+	return b.String()
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func hashLoginState(state string) string {
+	return hashVerificationToken(state)
+}
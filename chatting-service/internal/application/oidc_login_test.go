@@ -0,0 +1,204 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockIdentityProvider struct {
+	mock.Mock
+}
+
+func (m *MockIdentityProvider) AuthCodeURL(ctx context.Context, state, codeChallenge, nonce string) (string, error) {
+	args := m.Called(ctx, state, codeChallenge, nonce)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockIdentityProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*domain.ExternalClaims, error) {
+	args := m.Called(ctx, code, codeVerifier, nonce)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ExternalClaims), args.Error(1)
+}
+
+type MockOIDCStateRepository struct {
+	mock.Mock
+}
+
+func (m *MockOIDCStateRepository) Create(ctx context.Context, state *domain.OIDCLoginState) error {
+	args := m.Called(ctx, state)
+	return args.Error(0)
+}
+
+func (m *MockOIDCStateRepository) Consume(ctx context.Context, stateHash string) (*domain.OIDCLoginState, error) {
+	args := m.Called(ctx, stateHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.OIDCLoginState), args.Error(1)
+}
+
+type MockExternalIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockExternalIdentityRepository) FindBySubject(ctx context.Context, issuer, subject string) (*domain.ExternalIdentity, error) {
+	args := m.Called(ctx, issuer, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ExternalIdentity), args.Error(1)
+}
+
+func (m *MockExternalIdentityRepository) Create(ctx context.Context, identity *domain.ExternalIdentity) (*domain.ExternalIdentity, error) {
+	args := m.Called(ctx, identity)
+	return identity, args.Error(0)
+}
+
+func (m *MockExternalIdentityRepository) TouchLastLogin(ctx context.Context, identityID uint) error {
+	args := m.Called(ctx, identityID)
+	return args.Error(0)
+}
+
+func (m *MockExternalIdentityRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func TestOIDCLogin_Complete(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	cfg := config.OIDCConfig{AutoProvision: true, StateTTL: time.Minute}
+	pending := &domain.OIDCLoginState{CodeVerifier: "verifier", Nonce: "nonce", ExpiresAt: time.Now().UTC().Add(time.Minute)}
+
+	setup := func(claims *domain.ExternalClaims) (*OIDCLogin, *MockUserRepository, *MockExternalIdentityRepository) {
+		provider := &MockIdentityProvider{}
+		stateRepo := &MockOIDCStateRepository{}
+		identityRepo := &MockExternalIdentityRepository{}
+		userRepo := &MockUserRepository{}
+
+		stateRepo.On("Consume", mock.Anything, hashLoginState("state")).Return(pending, nil)
+		stateRepo.On("Consume", mock.Anything, mock.Anything).Return(nil, shared.ErrRecordNotFound)
+		provider.On("Exchange", mock.Anything, "code", "verifier", "nonce").Return(claims, nil)
+
+		login := NewOIDCLogin(provider, stateRepo, identityRepo, userRepo, NewUserService(userRepo, nil, nil, nil, nil, nil), cfg)
+		return login, userRepo, identityRepo
+	}
+
+	t.Run("ReturnsLinkedUser", func(t *testing.T) {
+		claims := &domain.ExternalClaims{Issuer: "https://idp", Subject: "abc"}
+		login, userRepo, identityRepo := setup(claims)
+		identityRepo.On("FindBySubject", mock.Anything, "https://idp", "abc").
+			Return(&domain.ExternalIdentity{Model: gorm.Model{ID: 3}, UserID: 7}, nil)
+		identityRepo.On("TouchLastLogin", mock.Anything, uint(3)).Return(nil)
+		userRepo.On("FindByID", mock.Anything, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}}, nil)
+
+		user, err := login.Complete(ctx, "code", "state")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(7), user.ID)
+		identityRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("PendingResetBlocksSingleSignOn", func(t *testing.T) {
+		claims := &domain.ExternalClaims{Issuer: "https://idp", Subject: "abc"}
+		login, userRepo, identityRepo := setup(claims)
+		identityRepo.On("FindBySubject", mock.Anything, "https://idp", "abc").
+			Return(&domain.ExternalIdentity{Model: gorm.Model{ID: 3}, UserID: 7}, nil)
+		identityRepo.On("TouchLastLogin", mock.Anything, uint(3)).Return(nil)
+		userRepo.On("FindByID", mock.Anything, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}, PasswordResetRequired: true}, nil)
+
+		_, err := NewAuthService(userRepo, nil, nil, nil, login, nil).CompleteOIDCLogin(ctx, "code", "state")
+
+		assert.ErrorIs(t, err, shared.ErrPasswordResetRequired)
+		userRepo.AssertNotCalled(t, "UpdateLastActiveAt", mock.Anything, mock.Anything)
+	})
+
+	t.Run("LinksExistingAccountByVerifiedEmail", func(t *testing.T) {
+		claims := &domain.ExternalClaims{Issuer: "https://idp", Subject: "abc", Email: "jane@corp.example", EmailVerified: true}
+		login, userRepo, identityRepo := setup(claims)
+		identityRepo.On("FindBySubject", mock.Anything, "https://idp", "abc").Return(nil, nil)
+		userRepo.On("FindByEmail", mock.Anything, "jane@corp.example").
+			Return(&domain.User{Model: gorm.Model{ID: 9}, Username: "jane", EmailVerified: true}, nil)
+		identityRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		user, err := login.Complete(ctx, "code", "state")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(9), user.ID)
+		linked := identityRepo.Calls[1].Arguments.Get(1).(*domain.ExternalIdentity)
+		assert.Equal(t, uint(9), linked.UserID)
+		userRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("DoesNotLinkAccountWithUnverifiedEmail", func(t *testing.T) {
+		claims := &domain.ExternalClaims{Issuer: "https://idp", Subject: "abc", Email: "jane@corp.example", EmailVerified: true}
+		login, userRepo, identityRepo := setup(claims)
+		identityRepo.On("FindBySubject", mock.Anything, "https://idp", "abc").Return(nil, nil)
+		// Registered by someone else before the owner of the address signed in
+		userRepo.On("FindByEmail", mock.Anything, "jane@corp.example").
+			Return(&domain.User{Model: gorm.Model{ID: 9}, Username: "squatter"}, nil)
+
+		_, err := login.Complete(ctx, "code", "state")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrForbidden.Code, appErr.Code)
+		userRepo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything)
+		identityRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ProvisionsWithFreeUsername", func(t *testing.T) {
+		claims := &domain.ExternalClaims{Issuer: "https://idp", Subject: "abc", Email: "jane@corp.example", EmailVerified: true, Username: "jane doe"}
+		login, userRepo, identityRepo := setup(claims)
+		identityRepo.On("FindBySubject", mock.Anything, "https://idp", "abc").Return(nil, nil)
+		userRepo.On("FindByEmail", mock.Anything, "jane@corp.example").Return(nil, shared.ErrRecordNotFound)
+		userRepo.On("ExistsByUsername", mock.Anything, "janedoe").Return(true, nil)
+		userRepo.On("ExistsByUsername", mock.Anything, "janedoe2").Return(false, nil)
+		userRepo.On("ExistsByEmail", mock.Anything, "jane@corp.example").Return(false, nil)
+		userRepo.On("Create", mock.Anything, "janedoe2", "jane@corp.example", mock.Anything).
+			Return(&domain.User{Model: gorm.Model{ID: 11}, Username: "janedoe2"}, nil)
+		userRepo.On("MarkEmailVerified", mock.Anything, uint(11)).Return(nil)
+		identityRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		user, err := login.Complete(ctx, "code", "state")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "janedoe2", user.Username)
+		assert.True(t, user.EmailVerified)
+	})
+
+	t.Run("UnverifiedEmailDoesNotMatchExistingAccount", func(t *testing.T) {
+		claims := &domain.ExternalClaims{Issuer: "https://idp", Subject: "abc", Email: "jane@corp.example", Username: "jane"}
+		login, userRepo, identityRepo := setup(claims)
+		identityRepo.On("FindBySubject", mock.Anything, "https://idp", "abc").Return(nil, nil)
+		userRepo.On("ExistsByUsername", mock.Anything, "jane").Return(false, nil)
+		userRepo.On("ExistsByEmail", mock.Anything, "jane@corp.example").Return(true, nil)
+
+		_, err := login.Complete(ctx, "code", "state")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrUserExists.Code, appErr.Code)
+		userRepo.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("RejectsUnknownState", func(t *testing.T) {
+		login, _, _ := setup(nil)
+
+		_, err := login.Complete(ctx, "code", "forged")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrUnauthorized.Code, appErr.Code)
+	})
+}
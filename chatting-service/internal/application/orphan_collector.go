@@ -0,0 +1,204 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+// unsentPageSize is how many media library entries are checked at a time
+const unsentPageSize = 500
+
+// OrphanCollector removes stored files nothing refers to anymore: uploads that were
+// never sent, files of deleted messages, and leftovers of failed cleanups. Files younger
+// than the grace period are kept, since an upload is stored before whatever refers to it
+// is saved.
+type OrphanCollector struct {
+	storage domain.MediaStorage
+	refs    domain.MediaReferenceRepository
+	grace   time.Duration
+	media   *MediaService // Optional, unsent media library entries are kept when nil
+}
+
+func NewOrphanCollector(storage domain.MediaStorage, refs domain.MediaReferenceRepository, grace time.Duration, media *MediaService) *OrphanCollector {
+	return &OrphanCollector{storage: storage, refs: refs, grace: grace, media: media}
+}
+
+// Collect looks for orphans and deletes them, a dry run only reports them
+func (c *OrphanCollector) Collect(ctx context.Context, dryRun bool) (*domain.OrphanReport, error) {
+	report := &domain.OrphanReport{DryRun: dryRun, Orphans: []domain.StoredFile{}, Unsent: []domain.StoredFile{}}
+	cutoff := time.Now().Add(-c.grace)
+
+	// Released first, files left without a reference go with the same pass
+	if err := c.releaseUnsent(ctx, cutoff, report); err != nil {
+		return nil, err
+	}
+
+	// Storage is listed before references are read, so anything referenced while
+	// listing is still seen as referenced
+	var candidates []domain.StoredFile
+	err := c.storage.Walk(ctx, func(file domain.StoredFile) error {
+		report.Scanned++
+		if file.ModifiedAt.Before(cutoff) {
+			candidates = append(candidates, file)
+		}
+		return nil
+	})
+	if err != nil {
+		shared.Log.Error("failed to list media storage", zap.Error(err))
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return report, nil
+	}
+
+	referenced, err := c.referenced(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range candidates {
+		if c.isReferenced(referenced, file.Path) {
+			continue
+		}
+		report.Orphans = append(report.Orphans, file)
+		report.Bytes += file.Size
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	for _, file := range report.Orphans {
+		if err := c.storage.Delete(ctx, file.Path); err != nil {
+			shared.Log.Warn("failed to delete orphaned media",
+				zap.String("path", file.Path),
+				zap.Error(err))
+			report.Failed++
+			continue
+		}
+		report.Deleted++
+	}
+	return report, nil
+}
+
+// releaseUnsent removes media library entries past the grace period that no message or
+// announcement links, the same way their owner would delete them. Content shared with
+// an entry that was sent stays, a link cannot tell whose upload it was.
+func (c *OrphanCollector) releaseUnsent(ctx context.Context, cutoff time.Time, report *domain.OrphanReport) error {
+	if c.media == nil {
+		return nil
+	}
+	var linked map[string]bool
+	var afterID uint
+	for {
+		page, err := c.refs.MediaUploadedBefore(ctx, cutoff, afterID, unsentPageSize)
+		if err != nil || len(page) == 0 {
+			return err
+		}
+		if linked == nil {
+			if linked, err = c.linked(ctx); err != nil {
+				return err
+			}
+		}
+
+		for i := range page {
+			c.releaseIfUnsent(ctx, &page[i], linked, report)
+		}
+		if len(page) < unsentPageSize {
+			return nil
+		}
+		// Released entries are gone, the ID still marks where the next page starts
+		afterID = page[len(page)-1].ID
+	}
+}
+
+func (c *OrphanCollector) releaseIfUnsent(ctx context.Context, record *domain.Media, linked map[string]bool, report *domain.OrphanReport) {
+	if linked[record.Path] {
+		return
+	}
+	report.Unsent = append(report.Unsent, domain.StoredFile{Path: record.Path, Size: record.Size, ModifiedAt: record.CreatedAt})
+	if report.DryRun {
+		return
+	}
+	if err := c.media.remove(ctx, record.OwnerID, record); err != nil {
+		shared.Log.Warn("failed to release unsent media",
+			zap.Uint("mediaID", record.ID),
+			zap.Error(err))
+		report.Failed++
+		return
+	}
+	report.Released++
+}
+
+func (c *OrphanCollector) referenced(ctx context.Context) (map[string]bool, error) {
+	paths, err := c.refs.ReferencedPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	referenced, err := c.linked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		referenced[path] = true
+	}
+	return referenced, nil
+}
+
+// linked are the storage paths messages and announcements link to
+func (c *OrphanCollector) linked(ctx context.Context) (map[string]bool, error) {
+	urls, err := c.refs.ReferencedURLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		// Links may be signed, the query is not part of the path
+		url, _, _ = strings.Cut(url, "?")
+		if path, ok := c.storage.PathFromURL(url); ok {
+			if cleaned, ok := cleanMediaPath(path); ok {
+				linked[cleaned] = true
+			}
+		}
+	}
+	return linked, nil
+}
+
+func (c *OrphanCollector) isReferenced(referenced map[string]bool, path string) bool {
+	if referenced[path] {
+		return true
+	}
+	// A thumbnail stays with its original, even where only the original is linked
+	if original, ok := thumbnailOriginal(path); ok {
+		return referenced[original]
+	}
+	return false
+}
+
+// RunJanitor collects orphans every interval until ctx is done
+func (c *OrphanCollector) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if report, err := c.Collect(ctx, false); err != nil {
+			shared.Log.Error("collect orphaned media failed", zap.Error(err))
+		} else if report.Deleted > 0 || report.Released > 0 || report.Failed > 0 {
+			shared.Log.Info("collected orphaned media",
+				zap.Int("released", report.Released),
+				zap.Int("deleted", report.Deleted),
+				zap.Int("failed", report.Failed),
+				zap.Int64("bytes", report.Bytes))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
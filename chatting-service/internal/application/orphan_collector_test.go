@@ -0,0 +1,163 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockMediaReferenceRepository struct {
+	mock.Mock
+}
+
+func (m *MockMediaReferenceRepository) ReferencedPaths(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	paths, _ := args.Get(0).([]string)
+	return paths, args.Error(1)
+}
+
+func (m *MockMediaReferenceRepository) ReferencedURLs(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	urls, _ := args.Get(0).([]string)
+	return urls, args.Error(1)
+}
+
+func (m *MockMediaReferenceRepository) MediaUploadedBefore(ctx context.Context, before time.Time, afterID uint, limit int) ([]domain.Media, error) {
+	args := m.Called(ctx, before, afterID, limit)
+	media, _ := args.Get(0).([]domain.Media)
+	return media, args.Error(1)
+}
+
+func TestOrphanCollector_Collect(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	old := time.Now().Add(-48 * time.Hour)
+	files := []domain.StoredFile{
+		{Path: "user_3/3_1_sent.png", Size: 10, ModifiedAt: old},
+		{Path: "user_3/3_1_sent.png_thumb320.png", Size: 2, ModifiedAt: old},
+		{Path: "user_3/3_1_library.pdf", Size: 10, ModifiedAt: old},
+		{Path: jpegBlobPath, Size: 10, ModifiedAt: old},
+		{Path: "user_3/upload_abc_0_x.part", Size: 10, ModifiedAt: old},
+		{Path: "user_3/avatar128_3_1_a.png", Size: 10, ModifiedAt: old},
+		{Path: "user_3/3_1_forgotten.png", Size: 7, ModifiedAt: old},
+		{Path: "user_3/3_1_deleted.pdf", Size: 5, ModifiedAt: old},
+		{Path: "user_3/3_1_new.png", Size: 10, ModifiedAt: time.Now()},
+	}
+	newCollector := func(storage *MockMediaStorage) *OrphanCollector {
+		storage.On("Walk", ctx).Return(files, nil)
+		storage.On("PathFromURL", "http://localhost/media/user_3/3_1_sent.png").Return("user_3/3_1_sent.png", true)
+		storage.On("PathFromURL", "https://elsewhere.example/a.png").Return("", false)
+		refs := &MockMediaReferenceRepository{}
+		refs.On("ReferencedPaths", ctx).Return([]string{"user_3/3_1_library.pdf", jpegBlobPath, "user_3/upload_abc_0_x.part", "user_3/avatar128_3_1_a.png"}, nil)
+		refs.On("ReferencedURLs", ctx).Return([]string{"http://localhost/media/user_3/3_1_sent.png?expires=1&signature=x", "https://elsewhere.example/a.png"}, nil)
+		return NewOrphanCollector(storage, refs, 24*time.Hour, nil)
+	}
+
+	t.Run("DryRun", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		report, err := newCollector(storage).Collect(ctx, true)
+		require.NoError(t, err)
+		assert.Equal(t, 9, report.Scanned)
+		require.Len(t, report.Orphans, 2)
+		assert.Equal(t, "user_3/3_1_forgotten.png", report.Orphans[0].Path)
+		assert.Equal(t, "user_3/3_1_deleted.pdf", report.Orphans[1].Path)
+		assert.Equal(t, int64(12), report.Bytes)
+		assert.Zero(t, report.Deleted)
+		storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("DeletesOrphans", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Delete", ctx, "user_3/3_1_forgotten.png").Return(nil)
+		storage.On("Delete", ctx, "user_3/3_1_deleted.pdf").Return(nil)
+		report, err := newCollector(storage).Collect(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Deleted)
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("ReferenceFailureDeletesNothing", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Walk", ctx).Return(files, nil)
+		refs := &MockMediaReferenceRepository{}
+		refs.On("ReferencedPaths", ctx).Return(nil, shared.ErrDatabaseOperation)
+		_, err := NewOrphanCollector(storage, refs, 24*time.Hour, nil).Collect(ctx, false)
+		assert.Error(t, err)
+		storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestOrphanCollector_ReleaseUnsent(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	old := time.Now().Add(-48 * time.Hour)
+	sent := domain.Media{ID: 1, OwnerID: 3, Path: jpegBlobPath, Size: 10, CreatedAt: old}
+	unsent := domain.Media{ID: 2, OwnerID: 4, Path: testBlobPath, Size: 4, CreatedAt: old}
+
+	newCollector := func(storage *MockMediaStorage, repo *MockMediaRepository) *OrphanCollector {
+		storage.On("Walk", ctx).Return([]domain.StoredFile{}, nil)
+		storage.On("PathFromURL", "http://localhost/media/"+jpegBlobPath).Return(jpegBlobPath, true)
+		refs := &MockMediaReferenceRepository{}
+		refs.On("MediaUploadedBefore", ctx, mock.AnythingOfType("time.Time"), uint(0), unsentPageSize).Return([]domain.Media{sent, unsent}, nil)
+		refs.On("ReferencedURLs", ctx).Return([]string{"http://localhost/media/" + jpegBlobPath}, nil)
+		media := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: afterCommit{t: t, repo: repo, storage: storage}})
+		return NewOrphanCollector(storage, refs, 24*time.Hour, media)
+	}
+
+	t.Run("DryRun", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		repo := &MockMediaRepository{}
+		report, err := newCollector(storage, repo).Collect(ctx, true)
+		require.NoError(t, err)
+		require.Len(t, report.Unsent, 1)
+		assert.Equal(t, testBlobPath, report.Unsent[0].Path)
+		assert.Zero(t, report.Released)
+		repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ReleasesLikeTheOwnerWould", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Delete", ctx, testBlobPath).Return(nil)
+		repo := &MockMediaRepository{}
+		repo.On("Delete", ctx, uint(2)).Return(nil)
+		repo.On("AddUsage", ctx, uint(4), int64(-4), int64(0)).Return(nil)
+		repo.On("LockBlob", ctx, testBlobPath).Return(&domain.MediaBlob{Path: testBlobPath, RefCount: 1}, nil)
+		repo.On("DeleteBlob", ctx, testBlobPath).Return(nil)
+
+		report, err := newCollector(storage, repo).Collect(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Released)
+		storage.AssertExpectations(t)
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "Delete", ctx, uint(1))
+	})
+
+	t.Run("PagesThroughTheLibrary", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Walk", ctx).Return([]domain.StoredFile{}, nil)
+		storage.On("PathFromURL", "http://localhost/media/"+jpegBlobPath).Return(jpegBlobPath, true)
+		full := make([]domain.Media, unsentPageSize)
+		for i := range full {
+			full[i] = domain.Media{ID: uint(i + 1), OwnerID: 3, Path: jpegBlobPath, CreatedAt: old}
+		}
+		last := domain.Media{ID: unsentPageSize + 1, OwnerID: 4, Path: testBlobPath, Size: 4, CreatedAt: old}
+		refs := &MockMediaReferenceRepository{}
+		refs.On("MediaUploadedBefore", ctx, mock.AnythingOfType("time.Time"), uint(0), unsentPageSize).Return(full, nil)
+		refs.On("MediaUploadedBefore", ctx, mock.AnythingOfType("time.Time"), uint(unsentPageSize), unsentPageSize).Return([]domain.Media{last}, nil)
+		refs.On("ReferencedURLs", ctx).Return([]string{"http://localhost/media/" + jpegBlobPath}, nil)
+		repo := &MockMediaRepository{}
+		media := NewMediaService(MediaServiceDeps{Storage: storage, Media: repo, Uploads: testUploadConfig, Transactions: afterCommit{t: t, repo: repo, storage: storage}})
+
+		report, err := NewOrphanCollector(storage, refs, 24*time.Hour, media).Collect(ctx, true)
+		require.NoError(t, err)
+		require.Len(t, report.Unsent, 1)
+		assert.Equal(t, testBlobPath, report.Unsent[0].Path)
+		refs.AssertNumberOfCalls(t, "ReferencedURLs", 1)
+	})
+}
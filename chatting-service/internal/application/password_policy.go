@@ -0,0 +1,93 @@
+package application
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+)
+
+// PasswordPolicy checks user chosen passwords against the configured rules.
+// Generated secrets (bots, SSO accounts) are not subject to it.
+type PasswordPolicy struct {
+	cfg    config.PasswordPolicyConfig
+	common map[string]struct{}
+}
+
+// NewPasswordPolicy loads the common password list, if configured, into memory
+func NewPasswordPolicy(cfg config.PasswordPolicyConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{cfg: cfg, common: map[string]struct{}{}}
+	if cfg.CommonListPath == "" {
+		return policy, nil
+	}
+
+	file, err := os.Open(cfg.CommonListPath)
+	if err != nil {
+		return nil, fmt.Errorf("open common password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		policy.common[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read common password list: %w", err)
+	}
+	return policy, nil
+}
+
+// Validate returns ErrPasswordTooWeak listing every rule the password breaks
+func (p *PasswordPolicy) Validate(password, username string) error {
+	var violations []string
+
+	if len([]rune(password)) < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.cfg.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.cfg.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.cfg.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.cfg.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if p.cfg.DisallowUsername && username != "" && strings.EqualFold(strings.TrimSpace(password), strings.TrimSpace(username)) {
+		violations = append(violations, "must not be the same as the username")
+	}
+	if _, found := p.common[strings.ToLower(password)]; found {
+		violations = append(violations, "is too common")
+	}
+
+	if len(violations) > 0 {
+		return shared.ErrPasswordTooWeak.WithDetails(violations)
+	}
+	return nil
+}
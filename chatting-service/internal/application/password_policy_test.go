@@ -0,0 +1,66 @@
+package application
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	listPath := filepath.Join(t.TempDir(), "common.txt")
+	require.NoError(t, os.WriteFile(listPath, []byte("# top passwords\nPassword123!\nqwertyuiop\n"), 0o600))
+
+	policy, err := NewPasswordPolicy(config.PasswordPolicyConfig{
+		MinLength:        10,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		CommonListPath:   listPath,
+		DisallowUsername: true,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		username string
+		valid    bool
+	}{
+		{"Strong", "Correct-Horse-42", "johndoe", true},
+		{"TooShort", "Ab1!", "johndoe", false},
+		{"MissingClasses", "alllowercaseletters", "johndoe", false},
+		{"CommonCaseInsensitive", "PASSWORD123!", "johndoe", false},
+		{"SameAsUsername", "JohnDoe-2024!", "johndoe-2024!", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, tt.username)
+			if tt.valid {
+				assert.NoError(t, err)
+				return
+			}
+			var appErr shared.Error
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, shared.ErrPasswordTooWeak.Code, appErr.Code)
+		})
+	}
+
+	t.Run("ListsEveryViolation", func(t *testing.T) {
+		err := policy.Validate("abc", "")
+		var appErr shared.Error
+		require.ErrorAs(t, err, &appErr)
+		assert.Len(t, appErr.Details, 4) // length, upper, digit, symbol
+	})
+
+	t.Run("MissingListFile", func(t *testing.T) {
+		_, err := NewPasswordPolicy(config.PasswordPolicyConfig{CommonListPath: filepath.Join(t.TempDir(), "missing.txt")})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,314 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+const sniffLength = 512 // What http.DetectContentType looks at
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ResumableUploadService takes large files in chunks. Every chunk is stored as a part
+// right away, so an interrupted upload continues where it stopped. Completing the upload
+// joins the parts into a regular library entry.
+type ResumableUploadService struct {
+	media      *MediaService
+	uploadRepo domain.MediaUploadRepository
+}
+
+func NewResumableUploadService(media *MediaService, uploadRepo domain.MediaUploadRepository) *ResumableUploadService {
+	return &ResumableUploadService{media: media, uploadRepo: uploadRepo}
+}
+
+// Create starts an upload of size bytes whose content must hash to checksum, a hex SHA-256
+func (s *ResumableUploadService) Create(ctx context.Context, userID uint, filename string, contentType string, size int64, checksum string) (*domain.MediaUploadSession, error) {
+	if size <= 0 {
+		return nil, shared.ErrValidation.WithDetails("size must be positive")
+	}
+	if !sha256Hex.MatchString(checksum) {
+		return nil, shared.ErrValidation.WithDetails("checksum must be a lowercase hex SHA-256")
+	}
+
+	// The content decides the type on completion, a declared type already tells the limit
+	limit := s.media.uploads.LargestResumableSize()
+	declared := normalizeContentType(contentType)
+	if declared != "" && declared != "application/octet-stream" {
+		var allowed bool
+		if limit, allowed = s.maxSize(declared); !allowed {
+			return nil, shared.ErrUnsupportedMediaType.WithDetails(fmt.Sprintf("%s files are not allowed", declared))
+		}
+	}
+	if size > limit {
+		return nil, fileTooLarge(limit)
+	}
+	// Only checked here so nobody sends gigabytes for nothing, completing counts the upload.
+	// Uploads still open count in full, or parallel sessions could add up past the quota.
+	open, err := s.uploadRepo.OpenSize(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.media.checkQuota(ctx, userID, open+size); err != nil {
+		return nil, err
+	}
+
+	uploadID, err := generateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	upload := &domain.MediaUploadSession{
+		ID:          uploadID,
+		OwnerID:     userID,
+		Filename:    originalFilename(filename),
+		ContentType: declared,
+		Size:        size,
+		Checksum:    checksum,
+		ExpiresAt:   time.Now().UTC().Add(s.media.uploads.ResumableTTL),
+	}
+	if err := s.uploadRepo.Create(ctx, upload); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// ChunkSize is the most a single chunk may carry
+func (s *ResumableUploadService) ChunkSize() int64 {
+	return s.media.uploads.ChunkSize()
+}
+
+// Get reports how far one of the user's uploads got
+func (s *ResumableUploadService) Get(ctx context.Context, userID uint, uploadID string) (*domain.MediaUploadSession, error) {
+	return s.find(ctx, userID, uploadID)
+}
+
+// WriteChunk stores the chunk starting at offset, which has to be where the upload
+// stopped. A client that lost track asks Get for the offset.
+func (s *ResumableUploadService) WriteChunk(ctx context.Context, userID uint, uploadID string, offset int64, chunk []byte) (*domain.MediaUploadSession, error) {
+	upload, err := s.find(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	length := int64(len(chunk))
+	switch {
+	case offset != upload.Received:
+		return nil, shared.ErrConflict.WithDetails(fmt.Sprintf("upload is at offset %d", upload.Received))
+	case length == 0:
+		return nil, shared.ErrValidation.WithDetails("chunk is empty")
+	case length > s.media.uploads.ChunkSize():
+		return nil, shared.ErrValidation.WithDetails(fmt.Sprintf("chunk exceeds %dMB limit", s.media.uploads.ChunkSizeMB))
+	case offset+length > upload.Size:
+		return nil, shared.ErrValidation.WithDetails(fmt.Sprintf("chunk runs past the upload size of %d bytes", upload.Size))
+	}
+
+	suffix, err := generateRandomString(8)
+	if err != nil {
+		return nil, err
+	}
+	filename := fmt.Sprintf("upload_%s_%d_%s.part", upload.ID, offset, suffix)
+	path, err := s.media.storage.Upload(ctx, bytes.NewReader(chunk), filename, "application/octet-stream", length, userID)
+	if err != nil {
+		shared.Log.Error("failed to store upload chunk",
+			zap.String("uploadID", upload.ID),
+			zap.Int64("offset", offset),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	upload.Received += length
+	upload.Parts = append(upload.Parts, path)
+	upload.ExpiresAt = time.Now().UTC().Add(s.media.uploads.ResumableTTL)
+	if err := s.uploadRepo.AppendPart(ctx, upload, offset); err != nil {
+		s.media.discard(ctx, path)
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Complete joins the parts of a fully received upload into a media library entry. Content
+// that does not match the checksum, or turns out not to be allowed, ends the upload.
+func (s *ResumableUploadService) Complete(ctx context.Context, userID uint, uploadID string) (*domain.MediaResponse, error) {
+	upload, err := s.find(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if !upload.Complete() {
+		return nil, shared.ErrValidation.WithDetails(fmt.Sprintf("upload is incomplete, %d of %d bytes received", upload.Received, upload.Size))
+	}
+
+	content := &partsReader{ctx: ctx, storage: s.media.storage, parts: upload.Parts}
+	defer content.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		shared.Log.Error("failed to read upload parts",
+			zap.String("uploadID", upload.ID),
+			zap.Error(err))
+		return nil, err
+	}
+	head = head[:n]
+	file := io.MultiReader(bytes.NewReader(head), content)
+
+	response, err := s.assemble(ctx, upload, head, file)
+	if err != nil {
+		var rejected shared.Error
+		if errors.As(err, &rejected) && (rejected.Code == shared.ErrValidation.Code || rejected.Code == shared.ErrUnsupportedMediaType.Code) {
+			s.remove(ctx, upload)
+		}
+		return nil, err
+	}
+	s.remove(ctx, upload)
+	return response, nil
+}
+
+func (s *ResumableUploadService) assemble(ctx context.Context, upload *domain.MediaUploadSession, head []byte, file io.Reader) (*domain.MediaResponse, error) {
+	contentType, err := s.media.checkContentType(head, upload.ContentType, upload.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	limit, _ := s.maxSize(contentType)
+	if upload.Size > limit {
+		return nil, fileTooLarge(limit)
+	}
+	if !resizableContentTypes[contentType] {
+		return s.media.storeStream(ctx, upload.OwnerID, upload.Filename, contentType, file, upload.Size, upload.Checksum)
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		shared.Log.Error("failed to read upload parts",
+			zap.String("uploadID", upload.ID),
+			zap.Error(err))
+		return nil, err
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != upload.Checksum {
+		return nil, errChecksumMismatch
+	}
+	return s.media.store(ctx, upload.OwnerID, upload.Filename, contentType, data)
+}
+
+// maxSize is the limit for a resumable upload of the type. Images are stripped and
+// thumbnailed in memory, so they keep the limit of direct uploads.
+func (s *ResumableUploadService) maxSize(contentType string) (int64, bool) {
+	if resizableContentTypes[contentType] {
+		return s.media.uploads.MaxSize(contentType)
+	}
+	return s.media.uploads.ResumableMaxSize(contentType)
+}
+
+// Cancel drops one of the user's uploads with everything received so far
+func (s *ResumableUploadService) Cancel(ctx context.Context, userID uint, uploadID string) error {
+	upload, err := s.find(ctx, userID, uploadID)
+	if err != nil {
+		return err
+	}
+	return s.remove(ctx, upload)
+}
+
+func (s *ResumableUploadService) find(ctx context.Context, userID uint, uploadID string) (*domain.MediaUploadSession, error) {
+	upload, err := s.uploadRepo.FindByID(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	// Uploads of other users and abandoned ones look like they do not exist
+	if upload.OwnerID != userID || !time.Now().UTC().Before(upload.ExpiresAt) {
+		return nil, shared.ErrRecordNotFound.WithDetails("upload not found")
+	}
+	return upload, nil
+}
+
+func (s *ResumableUploadService) remove(ctx context.Context, upload *domain.MediaUploadSession) error {
+	s.media.discard(ctx, upload.Parts...)
+	return s.uploadRepo.Delete(ctx, upload.ID)
+}
+
+// PurgeExpired removes uploads that got no chunk within the configured TTL
+func (s *ResumableUploadService) PurgeExpired(ctx context.Context) (int, error) {
+	uploads, err := s.uploadRepo.FindExpired(ctx, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range uploads {
+		if err := s.remove(ctx, &uploads[i]); err != nil {
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunJanitor purges abandoned uploads every interval until ctx is done
+func (s *ResumableUploadService) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := s.PurgeExpired(ctx); err != nil {
+			shared.Log.Error("purge abandoned uploads failed", zap.Error(err))
+		} else if purged > 0 {
+			shared.Log.Info("purged abandoned uploads", zap.Int("count", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// partsReader reads the stored parts of an upload one after another, opening each
+// only when it is reached
+type partsReader struct {
+	ctx     context.Context
+	storage domain.MediaStorage
+	parts   []string
+	current io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			file, err := r.storage.Open(r.ctx, r.parts[0])
+			if err != nil {
+				return 0, err
+			}
+			r.current, r.parts = file, r.parts[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
@@ -0,0 +1,353 @@
+package application
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockMediaUploadRepository struct {
+	mock.Mock
+}
+
+func (m *MockMediaUploadRepository) Create(ctx context.Context, upload *domain.MediaUploadSession) error {
+	args := m.Called(ctx, upload)
+	return args.Error(0)
+}
+
+func (m *MockMediaUploadRepository) FindByID(ctx context.Context, uploadID string) (*domain.MediaUploadSession, error) {
+	args := m.Called(ctx, uploadID)
+	upload, _ := args.Get(0).(*domain.MediaUploadSession)
+	return upload, args.Error(1)
+}
+
+func (m *MockMediaUploadRepository) AppendPart(ctx context.Context, upload *domain.MediaUploadSession, previousReceived int64) error {
+	args := m.Called(ctx, upload, previousReceived)
+	return args.Error(0)
+}
+
+func (m *MockMediaUploadRepository) Delete(ctx context.Context, uploadID string) error {
+	args := m.Called(ctx, uploadID)
+	return args.Error(0)
+}
+
+func (m *MockMediaUploadRepository) FindExpired(ctx context.Context, now time.Time) ([]domain.MediaUploadSession, error) {
+	args := m.Called(ctx, now)
+	uploads, _ := args.Get(0).([]domain.MediaUploadSession)
+	return uploads, args.Error(1)
+}
+
+func (m *MockMediaUploadRepository) OpenSize(ctx context.Context, ownerID uint, now time.Time) (int64, error) {
+	args := m.Called(ctx, ownerID, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockMediaUploadRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func newResumableTestService(storage *MockMediaStorage, mediaRepo *MockMediaRepository, uploadRepo *MockMediaUploadRepository) *ResumableUploadService {
+	cfg := testUploadConfig
+	cfg.ResumableMaxMB = 100
+	cfg.ChunkSizeMB = 1
+	cfg.ResumableTTL = time.Hour
+	return NewResumableUploadService(NewMediaService(MediaServiceDeps{Storage: storage, Media: mediaRepo, Uploads: cfg, Transactions: mediaTransactions{mediaRepo}}), uploadRepo)
+}
+
+func sha256Of(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// consumeUpload makes the mocked storage read the file like a real one would
+func consumeUpload(args mock.Arguments) {
+	_, _ = io.Copy(io.Discard, args.Get(1).(io.Reader))
+}
+
+func TestResumableUploadService_Create(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	checksum := sha256Of("hello")
+
+	t.Run("LargerThanDirectUploads", func(t *testing.T) {
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("OpenSize", ctx, uint(3), mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+		uploadRepo.On("Create", ctx, mock.Anything).Return(nil)
+		service := newResumableTestService(&MockMediaStorage{}, &MockMediaRepository{}, uploadRepo)
+
+		upload, err := service.Create(ctx, 3, "dir/notes.txt", "text/plain; charset=utf-8", 50*1024*1024, checksum)
+		require.NoError(t, err)
+		assert.NotEmpty(t, upload.ID)
+		assert.Equal(t, "notes.txt", upload.Filename)
+		assert.Equal(t, "text/plain", upload.ContentType)
+		assert.Equal(t, int64(0), upload.Received)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), upload.ExpiresAt, time.Minute)
+	})
+
+	t.Run("ImagesKeepTheirLimit", func(t *testing.T) {
+		service := newResumableTestService(&MockMediaStorage{}, &MockMediaRepository{}, &MockMediaUploadRepository{})
+
+		_, err := service.Create(ctx, 3, "photo.png", "image/png", 3*1024*1024, checksum)
+		assert.ErrorIs(t, err, shared.ErrValidation.WithDetails("file size exceeds 2MB limit"))
+	})
+
+	t.Run("TypeNotAllowed", func(t *testing.T) {
+		service := newResumableTestService(&MockMediaStorage{}, &MockMediaRepository{}, &MockMediaUploadRepository{})
+
+		_, err := service.Create(ctx, 3, "movie.mp4", "video/mp4", 1024, checksum)
+		assert.ErrorIs(t, err, shared.ErrUnsupportedMediaType.WithDetails("video/mp4 files are not allowed"))
+	})
+
+	t.Run("InvalidChecksum", func(t *testing.T) {
+		service := newResumableTestService(&MockMediaStorage{}, &MockMediaRepository{}, &MockMediaUploadRepository{})
+
+		_, err := service.Create(ctx, 3, "notes.txt", "text/plain", 1024, "not-a-checksum")
+		assert.ErrorIs(t, err, shared.ErrValidation.WithDetails("checksum must be a lowercase hex SHA-256"))
+	})
+
+	t.Run("OverQuota", func(t *testing.T) {
+		mediaRepo := &MockMediaRepository{}
+		mediaRepo.On("FindUsage", ctx, uint(3)).Return(int64(60*1024*1024), nil)
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("OpenSize", ctx, uint(3), mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+		cfg := testUploadConfig
+		cfg.ResumableMaxMB = 100
+		cfg.QuotaMB = 100
+		service := NewResumableUploadService(NewMediaService(MediaServiceDeps{Storage: &MockMediaStorage{}, Media: mediaRepo, Uploads: cfg, Transactions: mediaTransactions{mediaRepo}}), uploadRepo)
+
+		_, err := service.Create(ctx, 3, "notes.txt", "text/plain", 50*1024*1024, checksum)
+		assert.ErrorIs(t, err, shared.ErrStorageQuotaExceeded.WithDetails("upload would exceed your storage quota of 100MB"))
+	})
+
+	t.Run("OpenSessionsCountAgainstQuota", func(t *testing.T) {
+		mediaRepo := &MockMediaRepository{}
+		mediaRepo.On("FindUsage", ctx, uint(3)).Return(int64(20*1024*1024), nil)
+		uploadRepo := &MockMediaUploadRepository{}
+		// The second session is started while the first one is still open
+		uploadRepo.On("OpenSize", ctx, uint(3), mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
+		uploadRepo.On("OpenSize", ctx, uint(3), mock.AnythingOfType("time.Time")).Return(int64(50*1024*1024), nil).Once()
+		uploadRepo.On("Create", ctx, mock.Anything).Return(nil).Once()
+		cfg := testUploadConfig
+		cfg.ResumableMaxMB = 100
+		cfg.QuotaMB = 100
+		service := NewResumableUploadService(NewMediaService(MediaServiceDeps{Storage: &MockMediaStorage{}, Media: mediaRepo, Uploads: cfg, Transactions: mediaTransactions{mediaRepo}}), uploadRepo)
+
+		_, err := service.Create(ctx, 3, "first.txt", "text/plain", 50*1024*1024, checksum)
+		require.NoError(t, err)
+		_, err = service.Create(ctx, 3, "second.txt", "text/plain", 50*1024*1024, checksum)
+		assert.ErrorIs(t, err, shared.ErrStorageQuotaExceeded.WithDetails("upload would exceed your storage quota of 100MB"))
+		uploadRepo.AssertExpectations(t)
+	})
+}
+
+func TestResumableUploadService_WriteChunk(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	pending := func() *domain.MediaUploadSession {
+		return &domain.MediaUploadSession{
+			ID:        "up1",
+			OwnerID:   3,
+			Size:      10,
+			Received:  4,
+			Parts:     []string{"user_3/upload_up1_0_a.part"},
+			ExpiresAt: time.Now().Add(time.Minute),
+		}
+	}
+
+	t.Run("StoresPart", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Upload", ctx, mock.Anything, mock.MatchedBy(func(name string) bool {
+			return strings.HasPrefix(name, "upload_up1_4_") && strings.HasSuffix(name, ".part")
+		}), "application/octet-stream", int64(3)).Return("user_3/upload_up1_4_b.part", nil)
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("FindByID", ctx, "up1").Return(pending(), nil)
+		uploadRepo.On("AppendPart", ctx, mock.Anything, int64(4)).Return(nil)
+		service := newResumableTestService(storage, &MockMediaRepository{}, uploadRepo)
+
+		upload, err := service.WriteChunk(ctx, 3, "up1", 4, []byte("abc"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), upload.Received)
+		assert.Equal(t, []string{"user_3/upload_up1_0_a.part", "user_3/upload_up1_4_b.part"}, upload.Parts)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), upload.ExpiresAt, time.Minute)
+	})
+
+	t.Run("WrongOffset", func(t *testing.T) {
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("FindByID", ctx, "up1").Return(pending(), nil)
+		service := newResumableTestService(&MockMediaStorage{}, &MockMediaRepository{}, uploadRepo)
+
+		_, err := service.WriteChunk(ctx, 3, "up1", 0, []byte("abc"))
+		assert.ErrorIs(t, err, shared.ErrConflict.WithDetails("upload is at offset 4"))
+	})
+
+	t.Run("PastTheSize", func(t *testing.T) {
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("FindByID", ctx, "up1").Return(pending(), nil)
+		service := newResumableTestService(&MockMediaStorage{}, &MockMediaRepository{}, uploadRepo)
+
+		_, err := service.WriteChunk(ctx, 3, "up1", 4, []byte("abcdefg"))
+		assert.ErrorIs(t, err, shared.ErrValidation.WithDetails("chunk runs past the upload size of 10 bytes"))
+	})
+
+	t.Run("LostRaceDiscardsPart", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		storage.On("Upload", ctx, mock.Anything, mock.Anything, mock.Anything, int64(3)).Return("user_3/upload_up1_4_b.part", nil)
+		storage.On("Delete", ctx, "user_3/upload_up1_4_b.part").Return(nil)
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("FindByID", ctx, "up1").Return(pending(), nil)
+		conflict := shared.ErrConflict.WithDetails("upload was changed by another request")
+		uploadRepo.On("AppendPart", ctx, mock.Anything, int64(4)).Return(conflict)
+		service := newResumableTestService(storage, &MockMediaRepository{}, uploadRepo)
+
+		_, err := service.WriteChunk(ctx, 3, "up1", 4, []byte("abc"))
+		assert.ErrorIs(t, err, conflict)
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("OtherUsersUpload", func(t *testing.T) {
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("FindByID", ctx, "up1").Return(pending(), nil)
+		service := newResumableTestService(&MockMediaStorage{}, &MockMediaRepository{}, uploadRepo)
+
+		_, err := service.WriteChunk(ctx, 4, "up1", 4, []byte("abc"))
+		assert.ErrorIs(t, err, shared.ErrRecordNotFound.WithDetails("upload not found"))
+	})
+}
+
+func TestResumableUploadService_Complete(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	parts := []string{"user_3/upload_up1_0_a.part", "user_3/upload_up1_6_b.part"}
+	received := func(checksum string) *domain.MediaUploadSession {
+		return &domain.MediaUploadSession{
+			ID:          "up1",
+			OwnerID:     3,
+			Filename:    "notes.txt",
+			ContentType: "text/plain",
+			Size:        11,
+			Checksum:    checksum,
+			Received:    11,
+			Parts:       parts,
+			ExpiresAt:   time.Now().Add(time.Minute),
+		}
+	}
+	withParts := func(storage *MockMediaStorage) {
+		storage.On("Open", ctx, parts[0]).Return(io.NopCloser(strings.NewReader("hello ")), nil)
+		storage.On("Open", ctx, parts[1]).Return(io.NopCloser(strings.NewReader("world")), nil)
+		storage.On("Delete", ctx, parts[0]).Return(nil)
+		storage.On("Delete", ctx, parts[1]).Return(nil)
+	}
+
+	// blobPath is where "hello world" was stored before
+	blobPath := domain.MediaBlobPath(sha256Of("hello world"), "0011223344556677", ".txt")
+
+	t.Run("JoinsParts", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		withParts(storage)
+		storage.On("Put", ctx, mock.Anything, copyOf(blobPath, ""), "text/plain", int64(11)).
+			Run(consumeUpload).Return(nil)
+		storage.On("GetURL", ctx, copyOf(blobPath, "")).Return("http://localhost/media/file", nil)
+		mediaRepo := newBlobRepo()
+		mediaRepo.On("Create", ctx, mock.MatchedBy(func(m *domain.Media) bool {
+			return m.Size == 11 && m.Checksum == sha256Of("hello world") && m.OriginalFilename == "notes.txt"
+		})).Return(nil)
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("FindByID", ctx, "up1").Return(received(sha256Of("hello world")), nil)
+		uploadRepo.On("Delete", ctx, "up1").Return(nil)
+		service := newResumableTestService(storage, mediaRepo, uploadRepo)
+
+		resp, err := service.Complete(ctx, 3, "up1")
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost/media/file", resp.URL)
+		assert.Equal(t, "text/plain", resp.ContentType)
+		storage.AssertExpectations(t)
+		uploadRepo.AssertExpectations(t)
+	})
+
+	t.Run("ContentStoredBefore", func(t *testing.T) {
+		storage := &MockMediaStorage{}
+		withParts(storage)
+		storage.On("Put", ctx, mock.Anything, copyOf(blobPath, ""), "text/plain", int64(11)).
+			Run(consumeUpload).Return(nil)
+		storage.On("Delete", ctx, copyOf(blobPath, "")).Return(nil)
+		storage.On("GetURL", ctx, blobPath).Return("http://localhost/media/"+blobPath, nil)
+		mediaRepo := &MockMediaRepository{}
+		mediaRepo.On("AddUsage", ctx, uint(3), int64(11), int64(0)).Return(nil)
+		mediaRepo.On("AcquireBlob", ctx, mock.Anything).Return(&domain.MediaBlob{Path: blobPath, RefCount: 1}, nil)
+		mediaRepo.On("SaveBlob", ctx, mock.MatchedBy(func(b *domain.MediaBlob) bool { return b.RefCount == 2 })).Return(nil)
+		mediaRepo.On("Create", ctx, mock.Anything).Return(nil)
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("FindByID", ctx, "up1").Return(received(sha256Of("hello world")), nil)
+		uploadRepo.On("Delete", ctx, "up1").Return(nil)
+		service := newResumableTestService(storage, mediaRepo, uploadRepo)
+
+		_, err := service.Complete(ctx, 3, "up1")
+		require.NoError(t, err)
+		storage.AssertExpectations(t)
+		storage.AssertNotCalled(t, "Delete", ctx, blobPath)
+		mediaRepo.AssertExpectations(t)
+	})
+
+	t.Run("ChecksumMismatch", func(t *testing.T) {
+		otherPath := domain.MediaBlobPath(sha256Of("something else"), "0011223344556677", ".txt")
+		storage := &MockMediaStorage{}
+		withParts(storage)
+		storage.On("Put", ctx, mock.Anything, copyOf(otherPath, ""), "text/plain", int64(11)).
+			Run(consumeUpload).Return(nil)
+		storage.On("Delete", ctx, copyOf(otherPath, "")).Return(nil)
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("FindByID", ctx, "up1").Return(received(sha256Of("something else")), nil)
+		uploadRepo.On("Delete", ctx, "up1").Return(nil)
+		service := newResumableTestService(storage, newBlobRepo(), uploadRepo)
+
+		_, err := service.Complete(ctx, 3, "up1")
+		assert.ErrorIs(t, err, errChecksumMismatch)
+		storage.AssertExpectations(t)
+		uploadRepo.AssertExpectations(t)
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		upload := received(sha256Of("hello world"))
+		upload.Received = 6
+		uploadRepo := &MockMediaUploadRepository{}
+		uploadRepo.On("FindByID", ctx, "up1").Return(upload, nil)
+		service := newResumableTestService(&MockMediaStorage{}, &MockMediaRepository{}, uploadRepo)
+
+		_, err := service.Complete(ctx, 3, "up1")
+		assert.ErrorIs(t, err, shared.ErrValidation.WithDetails("upload is incomplete, 6 of 11 bytes received"))
+		uploadRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestResumableUploadService_PurgeExpired(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	storage := &MockMediaStorage{}
+	storage.On("Delete", ctx, "user_3/upload_up1_0_a.part").Return(nil)
+	uploadRepo := &MockMediaUploadRepository{}
+	uploadRepo.On("FindExpired", ctx, mock.Anything).Return([]domain.MediaUploadSession{
+		{ID: "up1", OwnerID: 3, Parts: []string{"user_3/upload_up1_0_a.part"}},
+		{ID: "up2", OwnerID: 4},
+	}, nil)
+	uploadRepo.On("Delete", ctx, "up1").Return(nil)
+	uploadRepo.On("Delete", ctx, "up2").Return(nil)
+	service := newResumableTestService(storage, &MockMediaRepository{}, uploadRepo)
+
+	purged, err := service.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	storage.AssertExpectations(t)
+	uploadRepo.AssertExpectations(t)
+}
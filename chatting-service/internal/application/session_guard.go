@@ -0,0 +1,39 @@
+package application
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+)
+
+// SessionGuard rejects otherwise valid tokens of accounts that no longer exist, are
+// suspended, or had their sessions revoked. JWTs are stateless, so this is what
+// revokes sessions when an account is erased, suspended or forced to reset its password.
+type SessionGuard struct {
+	userRepo domain.UserRepository
+}
+
+func NewSessionGuard(userRepo domain.UserRepository) *SessionGuard {
+	return &SessionGuard{userRepo: userRepo}
+}
+
+func (g *SessionGuard) ValidateSession(ctx context.Context, claims *domain.TokenClaims) error {
+	user, err := g.userRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		var appErr shared.Error
+		if errors.As(err, &appErr) && appErr.Code == shared.ErrRecordNotFound.Code {
+			return shared.ErrUnauthorized.WithDetails("account no longer exists")
+		}
+		return err
+	}
+	if user.IsSuspended() {
+		return shared.ErrAccountSuspended
+	}
+	// Personal access tokens are revoked outright, only JWTs carry an issue time
+	if !claims.IsAPIToken() && claims.IssuedAt != nil && user.SessionRevoked(claims.IssuedAt.Time) {
+		return shared.ErrUnauthorized.WithDetails("session has been revoked")
+	}
+	return nil
+}
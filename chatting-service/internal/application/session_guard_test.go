@@ -0,0 +1,59 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSessionGuard_ValidateSession(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	issuedAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	claims := &domain.TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(issuedAt)},
+		UserID:           7,
+	}
+
+	t.Run("ActiveAccount", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", ctx, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}}, nil)
+
+		assert.NoError(t, NewSessionGuard(userRepo).ValidateSession(ctx, claims))
+	})
+
+	t.Run("DeletedAccount", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", ctx, uint(7)).Return((*domain.User)(nil), shared.ErrRecordNotFound.WithDetails("user not found"))
+
+		var appErr shared.Error
+		assert.ErrorAs(t, NewSessionGuard(userRepo).ValidateSession(ctx, claims), &appErr)
+		assert.Equal(t, shared.ErrUnauthorized.Code, appErr.Code)
+	})
+
+	t.Run("SuspendedAccount", func(t *testing.T) {
+		suspendedAt := time.Now().UTC()
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", ctx, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}, SuspendedAt: &suspendedAt}, nil)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, NewSessionGuard(userRepo).ValidateSession(ctx, claims), &appErr)
+		assert.Equal(t, shared.ErrAccountSuspended.Code, appErr.Code)
+	})
+
+	t.Run("RevokedSession", func(t *testing.T) {
+		// Revocation in the same second as the token was issued still counts
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", ctx, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}, SessionsValidAfter: &issuedAt}, nil)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, NewSessionGuard(userRepo).ValidateSession(ctx, claims), &appErr)
+		assert.Equal(t, shared.ErrUnauthorized.Code, appErr.Code)
+	})
+}
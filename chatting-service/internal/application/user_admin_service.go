@@ -0,0 +1,228 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultUserListLimit = 50
+	maxUserListLimit     = 200
+	maxSuspensionReason  = 500
+)
+
+// UserAdminService lets admins find accounts, look at their activity and act on
+// compromised or abusive ones without touching the database by hand.
+type UserAdminService struct {
+	userRepo     domain.UserRepository
+	messageRepo  domain.MessageRepository
+	apiTokenRepo domain.APITokenRepository
+	mediaRepo    domain.MediaRepository  // Optional, storage usage reads as zero when nil
+	connections  domain.ConnectionCloser // Optional, open sockets are left alone when nil
+	audit        *AuditLogger
+	resets       *EmailVerificationService
+}
+
+func NewUserAdminService(userRepo domain.UserRepository, messageRepo domain.MessageRepository, apiTokenRepo domain.APITokenRepository, mediaRepo domain.MediaRepository, connections domain.ConnectionCloser, audit *AuditLogger, resets *EmailVerificationService) *UserAdminService {
+	return &UserAdminService{
+		userRepo:     userRepo,
+		messageRepo:  messageRepo,
+		apiTokenRepo: apiTokenRepo,
+		mediaRepo:    mediaRepo,
+		connections:  connections,
+		audit:        audit,
+		resets:       resets,
+	}
+}
+
+type UserPage struct {
+	Users  []*domain.User
+	Total  int64 // Matches across all pages
+	Limit  int
+	Offset int
+}
+
+// ListUsers returns one page of matching users
+func (s *UserAdminService) ListUsers(ctx context.Context, filter domain.UserListFilter) (*UserPage, error) {
+	switch filter.Role {
+	case "", domain.RoleUser, domain.RoleAdmin:
+	default:
+		return nil, shared.ErrBadRequest.WithDetails("role must be user or admin")
+	}
+	switch filter.Status {
+	case "", domain.AccountActive, domain.AccountSuspended:
+	default:
+		return nil, shared.ErrBadRequest.WithDetails("status must be active or suspended")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUserListLimit
+	}
+	if filter.Limit > maxUserListLimit {
+		filter.Limit = maxUserListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	users, total, err := s.userRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &UserPage{Users: users, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// GetUser returns the account with its activity summary
+func (s *UserAdminService) GetUser(ctx context.Context, userID uint) (*domain.User, *domain.UserActivity, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sent, err := s.messageRepo.CountSentBy(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	activity := &domain.UserActivity{
+		MessagesSent: sent,
+		LastActiveAt: user.LastActiveAt,
+	}
+	if s.mediaRepo != nil {
+		// Uploads are shared by content, the counted bytes are what the user uploaded
+		used, err := s.mediaRepo.FindUsage(ctx, userID)
+		if err != nil {
+			// Stats are informational, a database hiccup should not hide the account
+			shared.Log.Error("find storage usage failed", zap.Error(err), zap.Uint("userID", userID))
+		}
+		activity.StorageBytes = used
+	}
+	return user, activity, nil
+}
+
+// Suspend blocks sign-in and every existing session, and disconnects open sockets
+func (s *UserAdminService) Suspend(ctx context.Context, adminID, userID uint, reason string) (*domain.User, error) {
+	if adminID == userID {
+		return nil, shared.ErrBadRequest.WithDetails("you cannot suspend your own account")
+	}
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxSuspensionReason {
+		return nil, shared.ErrValidation.WithDetails("reason is too long")
+	}
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsSuspended() {
+		return nil, shared.ErrConflict.WithDetails("account is already suspended")
+	}
+
+	now := time.Now().UTC()
+	if err := s.userRepo.SetSuspension(ctx, userID, &now, reason); err != nil {
+		return nil, err
+	}
+	user.SuspendedAt = &now
+	user.SuspensionReason = reason
+
+	if s.connections != nil {
+		s.connections.CloseUserConnections(userID, "account suspended")
+	}
+
+	shared.Log.Info("account suspended", zap.Uint("adminID", adminID), zap.Uint("userID", userID))
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(adminID),
+		Action:     domain.AuditUserSuspend,
+		TargetType: "user",
+		TargetID:   auditID(userID),
+		Details:    reason,
+	})
+	return user, nil
+}
+
+// Reinstate lifts a suspension. Sessions issued before it stay valid if they have not expired.
+func (s *UserAdminService) Reinstate(ctx context.Context, adminID, userID uint) (*domain.User, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsSuspended() {
+		return nil, shared.ErrConflict.WithDetails("account is not suspended")
+	}
+
+	if err := s.userRepo.SetSuspension(ctx, userID, nil, ""); err != nil {
+		return nil, err
+	}
+	user.SuspendedAt = nil
+	user.SuspensionReason = ""
+
+	shared.Log.Info("account reinstated", zap.Uint("adminID", adminID), zap.Uint("userID", userID))
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(adminID),
+		Action:     domain.AuditUserReinstate,
+		TargetType: "user",
+		TargetID:   auditID(userID),
+	})
+	return user, nil
+}
+
+// ForcePasswordReset revokes every session and personal access token of the user.
+// Sign-in is refused until the user picks a new password with a single-use reset
+// code. The code is mailed to a verified address, otherwise it is returned so the
+// admin can hand it over.
+func (s *UserAdminService) ForcePasswordReset(ctx context.Context, adminID, userID uint) (string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user.IsBot {
+		return "", shared.ErrBadRequest.WithDetails("bot accounts have no password, revoke their tokens instead")
+	}
+	if s.resets == nil {
+		return "", shared.ErrServiceUnavailable.WithDetails("password reset codes are not enabled")
+	}
+
+	// JWT issue times have second precision, anything issued up to now is revoked
+	revokeBefore := time.Now().UTC().Truncate(time.Second)
+	if err := s.userRepo.RequirePasswordReset(ctx, userID, revokeBefore); err != nil {
+		return "", err
+	}
+	if err := s.apiTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return "", err
+	}
+	if s.connections != nil {
+		s.connections.CloseUserConnections(userID, "password reset required")
+	}
+
+	code, err := s.resets.IssuePasswordReset(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	shared.Log.Info("password reset forced", zap.Uint("adminID", adminID), zap.Uint("userID", userID))
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(adminID),
+		Action:     domain.AuditPasswordReset,
+		TargetType: "user",
+		TargetID:   auditID(userID),
+	})
+	return code, nil
+}
+
+func (s *UserAdminService) findUser(ctx context.Context, userID uint) (*domain.User, error) {
+	user, err := s.userRepo.FindProfileByID(ctx, userID)
+	if err != nil {
+		var appErr shared.Error
+		if errors.As(err, &appErr) && appErr.Code == shared.ErrRecordNotFound.Code {
+			return nil, shared.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,165 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestUserAdminService_Suspend(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("SuspendsAndDisconnects", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindProfileByID", ctx, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}, Username: "spammer"}, nil)
+		userRepo.On("SetSuspension", ctx, uint(7), mock.AnythingOfType("*time.Time"), "Sending spam").Return(nil)
+		connections := &MockConnectionCloser{}
+		connections.On("CloseUserConnections", uint(7), "account suspended").Return()
+
+		user, err := NewUserAdminService(userRepo, nil, nil, nil, connections, nil, nil).Suspend(ctx, 1, 7, "  Sending spam ")
+
+		assert.NoError(t, err)
+		assert.True(t, user.IsSuspended())
+		assert.Equal(t, "Sending spam", user.SuspensionReason)
+		userRepo.AssertExpectations(t)
+		connections.AssertExpectations(t)
+	})
+
+	t.Run("CannotSuspendSelf", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+
+		_, err := NewUserAdminService(userRepo, nil, nil, nil, nil, nil, nil).Suspend(ctx, 1, 1, "")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrBadRequest.Code, appErr.Code)
+		userRepo.AssertNotCalled(t, "SetSuspension", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("AlreadySuspended", func(t *testing.T) {
+		suspendedAt := time.Now().UTC()
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindProfileByID", ctx, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}, SuspendedAt: &suspendedAt}, nil)
+
+		_, err := NewUserAdminService(userRepo, nil, nil, nil, nil, nil, nil).Suspend(ctx, 1, 7, "")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrConflict.Code, appErr.Code)
+	})
+
+	t.Run("ReinstateRequiresSuspension", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindProfileByID", ctx, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}}, nil)
+
+		_, err := NewUserAdminService(userRepo, nil, nil, nil, nil, nil, nil).Reinstate(ctx, 1, 7)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrConflict.Code, appErr.Code)
+		userRepo.AssertNotCalled(t, "SetSuspension", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// sentMessageCounter answers only the sent message count of the activity summary
+type sentMessageCounter struct {
+	domain.MessageRepository
+	sent int64
+}
+
+func (c sentMessageCounter) CountSentBy(ctx context.Context, userID uint) (int64, error) {
+	return c.sent, nil
+}
+
+func TestUserAdminService_GetUser(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("StorageUsageFromDatabase", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindProfileByID", ctx, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}}, nil)
+		mediaRepo := &MockMediaRepository{}
+		mediaRepo.On("FindUsage", ctx, uint(7)).Return(int64(4096), nil)
+
+		_, activity, err := NewUserAdminService(userRepo, sentMessageCounter{sent: 3}, nil, mediaRepo, nil, nil, nil).GetUser(ctx, 7)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), activity.MessagesSent)
+		assert.Equal(t, int64(4096), activity.StorageBytes)
+		mediaRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserAdminService_ForcePasswordReset(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("RevokesSessionsAndTokens", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindProfileByID", ctx, uint(7)).Return(&domain.User{Model: gorm.Model{ID: 7}}, nil)
+		userRepo.On("RequirePasswordReset", ctx, uint(7), mock.AnythingOfType("time.Time")).Return(nil)
+		tokenRepo := &MockAPITokenRepository{}
+		tokenRepo.On("RevokeAllForUser", ctx, uint(7)).Return(nil)
+		connections := &MockConnectionCloser{}
+		connections.On("CloseUserConnections", uint(7), mock.Anything).Return()
+		verificationRepo := &MockEmailVerificationRepository{}
+		verificationRepo.On("InvalidateForUser", ctx, uint(7), domain.VerificationPasswordReset).Return(nil)
+		verificationRepo.On("Create", ctx, uint(7), mock.Anything, mock.Anything, domain.VerificationPasswordReset, mock.Anything).
+			Return(&domain.EmailVerification{}, nil)
+		resets := NewEmailVerificationService(userRepo, verificationRepo, &MockMailer{}, config.MailConfig{PasswordResetTTL: time.Hour})
+
+		code, err := NewUserAdminService(userRepo, nil, tokenRepo, nil, connections, nil, resets).ForcePasswordReset(ctx, 1, 7)
+
+		assert.NoError(t, err)
+		// The address is not verified, so the admin gets the code to hand over
+		assert.NotEmpty(t, code)
+		userRepo.AssertExpectations(t)
+		tokenRepo.AssertExpectations(t)
+		connections.AssertExpectations(t)
+		verificationRepo.AssertExpectations(t)
+	})
+
+	t.Run("RejectsBots", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindProfileByID", ctx, uint(5)).Return(&domain.User{Model: gorm.Model{ID: 5}, IsBot: true}, nil)
+
+		_, err := NewUserAdminService(userRepo, nil, nil, nil, nil, nil, nil).ForcePasswordReset(ctx, 1, 5)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrBadRequest.Code, appErr.Code)
+	})
+}
+
+func TestUserAdminService_ListUsers(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("ClampsLimit", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("List", ctx, domain.UserListFilter{Query: "john", Status: domain.AccountSuspended, Limit: maxUserListLimit}).
+			Return([]*domain.User{{Model: gorm.Model{ID: 7}}}, int64(1), nil)
+
+		page, err := NewUserAdminService(userRepo, nil, nil, nil, nil, nil, nil).ListUsers(ctx, domain.UserListFilter{Query: " john ", Status: domain.AccountSuspended, Limit: 5000})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), page.Total)
+		assert.Equal(t, maxUserListLimit, page.Limit)
+	})
+
+	t.Run("RejectsUnknownStatus", func(t *testing.T) {
+		_, err := NewUserAdminService(&MockUserRepository{}, nil, nil, nil, nil, nil, nil).ListUsers(ctx, domain.UserListFilter{Status: "banned"})
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrBadRequest.Code, appErr.Code)
+	})
+}
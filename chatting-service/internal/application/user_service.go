@@ -2,21 +2,48 @@ package application
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"io"
 	"strings"
 
 	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/AmeerHeiba/chatting-service/internal/shared"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultDirectoryPageSize = 20
+	maxDirectoryPageSize     = 100
 )
 
 type UserService struct {
-	userRepo domain.UserRepository
+	userRepo       domain.UserRepository
+	verifier       *EmailVerificationService // Optional, email verification is skipped when nil
+	passwordPolicy *PasswordPolicy           // Optional, only the minimum length is enforced when nil
+	audit          *AuditLogger
+	blockRepo      domain.UserBlockRepository // Optional, blocking is unavailable when nil
+	avatars        domain.AvatarUploader      // Optional, avatars are unavailable when nil
+}
+
+func NewUserService(repo domain.UserRepository, verifier *EmailVerificationService, passwordPolicy *PasswordPolicy, audit *AuditLogger, blockRepo domain.UserBlockRepository, avatars domain.AvatarUploader) *UserService {
+	return &UserService{
+		userRepo:       repo,
+		verifier:       verifier,
+		passwordPolicy: passwordPolicy,
+		audit:          audit,
+		blockRepo:      blockRepo,
+		avatars:        avatars,
+	}
 }
 
-func NewUserService(repo domain.UserRepository) *UserService {
-	return &UserService{userRepo: repo}
+// ValidatePassword applies the password policy to a password chosen by a user
+func (s *UserService) ValidatePassword(password, username string) error {
+	if s.passwordPolicy == nil {
+		return nil
+	}
+	return s.passwordPolicy.Validate(password, username)
 }
 
 func (s *UserService) CreateUser(ctx context.Context, username, email, password string) (*domain.User, error) {
@@ -82,6 +109,23 @@ func (s *UserService) GetUserProfile(ctx context.Context, id uint) (*domain.User
 	return profile, nil
 }
 
+// GetPublicProfile returns another user's profile, users on either side of a block
+// cannot see each other
+func (s *UserService) GetPublicProfile(ctx context.Context, viewerID, userID uint) (*domain.User, error) {
+	if s.blockRepo != nil && viewerID != userID {
+		hiddenIDs, err := s.blockRepo.HiddenFrom(ctx, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range hiddenIDs {
+			if id == userID {
+				return nil, shared.ErrUserNotFound
+			}
+		}
+	}
+	return s.GetUserProfile(ctx, userID)
+}
+
 func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
 	user, err := s.userRepo.FindByUsername(ctx, username)
 	if err != nil {
@@ -107,23 +151,31 @@ func (s *UserService) VerifyCredentials(ctx context.Context, username, password
 		shared.Log.Error("Failed to find user", zap.Error(err), zap.String("username", username))
 		return nil, err
 	}
+	// Bots authenticate only with API tokens
+	if user.IsBot {
+		shared.Log.Debug("password login attempted for bot", zap.String("username", username))
+		return nil, shared.ErrInvalidCredentials.WithDetails("bot accounts cannot log in with a password")
+	}
+
 	// Direct password check without validation
-	if err := bcrypt.CompareHashAndPassword(
-		[]byte(user.PasswordHash),
-		[]byte(password),
-	); err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			shared.Log.Error("Password comparison failed", zap.Error(err), zap.String("username", username))
-			return nil, shared.ErrInvalidCredentials.WithDetails("password comparison failed")
-		}
-		shared.Log.Error("Password comparison failed", zap.Error(err), zap.String("username", username))
+	if !user.CheckPassword(password) {
+		shared.Log.Error("Password comparison failed", zap.String("username", username))
 		return nil, shared.ErrInvalidCredentials.WithDetails("password comparison failed")
 	}
 
+	// Upgrade bcrypt or outdated hashes while we still have the plain password
+	if user.PasswordNeedsRehash() {
+		if err := user.SetPassword(password); err != nil {
+			shared.Log.Error("rehash password failed", zap.Error(err), zap.Uint("userID", user.ID))
+		} else if err := s.userRepo.UpdatePassword(ctx, user.ID, user.PasswordHash); err != nil {
+			shared.Log.Error("store rehashed password failed", zap.Error(err), zap.Uint("userID", user.ID))
+		}
+	}
+
 	return user, nil
 }
 
-func (s *UserService) UpdateProfile(ctx context.Context, userID uint, username, email string) (*domain.User, error) {
+func (s *UserService) UpdateProfile(ctx context.Context, userID uint, username, email string, details domain.ProfileDetails) (*domain.User, error) {
 	// Input validation (e.g., email format, username length)
 	if username != "" && len(username) < 3 {
 		shared.Log.Debug("username must be at least 3 characters long", zap.String("username", username))
@@ -133,12 +185,22 @@ func (s *UserService) UpdateProfile(ctx context.Context, userID uint, username,
 		shared.Log.Debug("invalid email format", zap.String("email", email))
 		return nil, shared.ErrValidation.WithDetails("invalid email format")
 	}
+	if err := details.Normalize(); err != nil {
+		shared.Log.Debug("invalid profile details", zap.Error(err))
+		return nil, shared.ErrValidation.WithDetails(err.Error())
+	}
 
 	// Delegate to repository
 	if err := s.userRepo.Update(ctx, userID, username, email); err != nil {
 		shared.Log.Error("update user failed", zap.Error(err), zap.Uint("userID", userID), zap.String("username", username), zap.String("email", email))
 		return nil, err
 	}
+	if !details.IsEmpty() {
+		if err := s.userRepo.UpdateDetails(ctx, userID, details); err != nil {
+			shared.Log.Error("update profile details failed", zap.Error(err), zap.Uint("userID", userID))
+			return nil, err
+		}
+	}
 
 	updatedProfile, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
@@ -146,16 +208,353 @@ func (s *UserService) UpdateProfile(ctx context.Context, userID uint, username,
 		return nil, err
 	}
 
+	changed := []string{}
+	if username != "" {
+		changed = append(changed, "username")
+	}
+	if email != "" {
+		changed = append(changed, "email")
+	}
+	if details.DisplayName != nil {
+		changed = append(changed, "display name")
+	}
+	if details.Bio != nil {
+		changed = append(changed, "bio")
+	}
+	if details.TimeZone != nil {
+		changed = append(changed, "time zone")
+	}
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(userID),
+		Action:     domain.AuditProfileUpdate,
+		TargetType: "user",
+		TargetID:   auditID(userID),
+		Details:    "changed " + strings.Join(changed, ", "),
+	})
+
+	// Changing the email resets verification, ask the owner to confirm the new address
+	if email != "" {
+		if err := s.SendEmailVerification(ctx, updatedProfile); err != nil {
+			shared.Log.Error("send verification email failed", zap.Error(err), zap.Uint("userID", userID))
+			//will continue even if the email could not be sent, user can request a new link
+		}
+	}
+
 	return updatedProfile, nil
 }
 
-func (s *UserService) GettAllUsers(ctx context.Context) ([]*domain.User, error) {
-	users, err := s.userRepo.GetAll(ctx)
+// SetAvatar replaces the user's avatar, the previous images are deleted once the new ones are saved
+func (s *UserService) SetAvatar(ctx context.Context, userID uint, file io.Reader, contentType string, size int64) (*domain.User, error) {
+	if s.avatars == nil {
+		return nil, shared.ErrServiceUnavailable.WithDetails("avatars are not available")
+	}
+
+	current, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return users, nil
+	avatar, err := s.avatars.UploadAvatar(ctx, userID, file, contentType, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateAvatar(ctx, userID, avatar); err != nil {
+		shared.Log.Error("save avatar failed", zap.Error(err), zap.Uint("userID", userID))
+		s.avatars.DeleteAvatar(ctx, userID, avatar)
+		return nil, err
+	}
+	s.avatars.DeleteAvatar(ctx, userID, current.Avatar)
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(userID),
+		Action:     domain.AuditProfileUpdate,
+		TargetType: "user",
+		TargetID:   auditID(userID),
+		Details:    "changed avatar",
+	})
+
+	return s.GetUserProfile(ctx, userID)
+}
+
+func (s *UserService) RemoveAvatar(ctx context.Context, userID uint) error {
+	if s.avatars == nil {
+		return shared.ErrServiceUnavailable.WithDetails("avatars are not available")
+	}
+
+	current, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(current.Avatar) == 0 {
+		return nil
+	}
+
+	if err := s.userRepo.UpdateAvatar(ctx, userID, nil); err != nil {
+		shared.Log.Error("clear avatar failed", zap.Error(err), zap.Uint("userID", userID))
+		return err
+	}
+	s.avatars.DeleteAvatar(ctx, userID, current.Avatar)
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(userID),
+		Action:     domain.AuditProfileUpdate,
+		TargetType: "user",
+		TargetID:   auditID(userID),
+		Details:    "removed avatar",
+	})
+	return nil
+}
+
+func (s *UserService) SendEmailVerification(ctx context.Context, user *domain.User) error {
+	if s.verifier == nil {
+		return nil
+	}
+	return s.verifier.SendVerification(ctx, user)
+}
+
+func (s *UserService) ResendEmailVerification(ctx context.Context, userID uint) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		shared.Log.Error("find user by ID failed", zap.Error(err), zap.Uint("userID", userID))
+		return err
+	}
+	if user.EmailVerified {
+		return shared.ErrBadRequest.WithDetails("email already verified")
+	}
+	return s.SendEmailVerification(ctx, user)
+}
+
+func (s *UserService) VerifyEmail(ctx context.Context, token string) (*domain.User, error) {
+	if s.verifier == nil {
+		return nil, shared.ErrServiceUnavailable.WithDetails("email verification is not enabled")
+	}
+
+	user, err := s.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(user.ID),
+		Action:     domain.AuditEmailVerified,
+		TargetType: "user",
+		TargetID:   auditID(user.ID),
+	})
+	return user, nil
+}
+
+// CompletePasswordReset replaces a password an admin forced to be reset. The reset
+// code proves the identity, the old password may be what an attacker knows.
+func (s *UserService) CompletePasswordReset(ctx context.Context, token, newPassword string) (*domain.User, error) {
+	if s.verifier == nil {
+		return nil, shared.ErrServiceUnavailable.WithDetails("password reset codes are not enabled")
+	}
+
+	user, reset, err := s.verifier.FindPasswordReset(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsSuspended() {
+		return nil, shared.ErrAccountSuspended
+	}
+	if !user.PasswordResetRequired {
+		return nil, shared.ErrInvalidVerificationToken.WithDetails("no password reset is pending")
+	}
+	if user.CheckPassword(newPassword) {
+		return nil, shared.ErrValidation.WithDetails("new password must differ from the current one")
+	}
+	if err := s.ValidatePassword(newPassword, user.Username); err != nil {
+		return nil, err
+	}
+	if err := user.SetPassword(newPassword); err != nil {
+		return nil, shared.ErrValidation.WithDetails("password must be at least 8 characters").WithDetails(err.Error())
+	}
+
+	// Spent before the password is stored, so a code cannot be used twice at once
+	if err := s.verifier.UsePasswordReset(ctx, reset); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.CompletePasswordReset(ctx, user.ID, user.PasswordHash); err != nil {
+		return nil, err
+	}
+	user.PasswordResetRequired = false
+	return user, nil
+}
+
+// CreateBot registers a bot account owned by ownerID. Bots get an unusable random
+// password and a placeholder address, they only authenticate with API tokens
+func (s *UserService) CreateBot(ctx context.Context, ownerID uint, username string) (*domain.User, error) {
+	username = strings.TrimSpace(username)
+	if len(username) < 3 {
+		shared.Log.Debug("username must be at least 3 characters long", zap.String("username", username))
+		return nil, shared.ErrUsernameTooShort
+	}
+
+	exists, err := s.userRepo.ExistsByUsername(ctx, username)
+	if err != nil {
+		shared.Log.Error("username check failed", zap.Error(err), zap.String("username", username))
+		return nil, err
+	}
+	if exists {
+		return nil, shared.ErrUserExists.WithDetails("username already exists")
+	}
+
+	secret, err := generateRandomString(32)
+	if err != nil {
+		shared.Log.Error("generate bot secret failed", zap.Error(err))
+		return nil, shared.ErrInternalServer.WithDetails("generate bot secret failed")
+	}
+
+	bot := &domain.User{Username: username}
+	if err := bot.SetPassword(secret); err != nil {
+		return nil, shared.ErrInternalServer.WithDetails("set bot password failed")
+	}
+
+	created, err := s.userRepo.CreateBot(ctx, username, strings.ToLower(username)+"@bots.chatting-service.local", bot.PasswordHash, ownerID)
+	if err != nil {
+		shared.Log.Error("create bot failed", zap.Error(err), zap.String("username", username), zap.Uint("ownerID", ownerID))
+		return nil, err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(ownerID),
+		Action:     domain.AuditBotCreate,
+		TargetType: "user",
+		TargetID:   auditID(created.ID),
+	})
+	return created, nil
+}
+
+func (s *UserService) ListBots(ctx context.Context, ownerID uint) ([]*domain.User, error) {
+	return s.userRepo.FindBotsByOwner(ctx, ownerID)
+}
+
+// DirectoryPage is one page of directory results. NextCursor is empty on the last page.
+type DirectoryPage struct {
+	Users      []*domain.User
+	NextCursor string
+}
+
+type directoryCursor struct {
+	Username string `json:"u"`
+	ID       uint   `json:"id"`
+}
+
+// SearchUsers pages through the user directory as seen by viewerID, leaving out
+// anyone on either side of a block
+func (s *UserService) SearchUsers(ctx context.Context, viewerID uint, query string, limit int, cursor string) (*DirectoryPage, error) {
+	if limit <= 0 {
+		limit = defaultDirectoryPageSize
+	}
+	if limit > maxDirectoryPageSize {
+		limit = maxDirectoryPageSize
+	}
+
+	search := domain.UserSearch{
+		Query: strings.TrimSpace(query),
+		Limit: limit + 1, // One extra row tells us whether there is a next page
+	}
+	if cursor != "" {
+		after, err := decodeDirectoryCursor(cursor)
+		if err != nil {
+			return nil, shared.ErrBadRequest.WithDetails("invalid cursor")
+		}
+		search.AfterUsername = after.Username
+		search.AfterID = after.ID
+	}
+
+	if s.blockRepo != nil {
+		hiddenIDs, err := s.blockRepo.HiddenFrom(ctx, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		search.ExcludeIDs = hiddenIDs
+	}
+
+	users, err := s.userRepo.Search(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &DirectoryPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		last := page.Users[limit-1]
+		page.NextCursor = encodeDirectoryCursor(directoryCursor{Username: last.Username, ID: last.ID})
+	}
+	return page, nil
+}
+
+func encodeDirectoryCursor(c directoryCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeDirectoryCursor(value string) (directoryCursor, error) {
+	var c directoryCursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, err
+	}
+	if c.Username == "" {
+		return c, errors.New("empty cursor")
+	}
+	return c, nil
+}
+
+// BlockUser stops blockedID from messaging blockerID and hides the two from each other
+func (s *UserService) BlockUser(ctx context.Context, blockerID, blockedID uint) error {
+	if s.blockRepo == nil {
+		return shared.ErrServiceUnavailable.WithDetails("blocking is not available")
+	}
+	if blockerID == blockedID {
+		return shared.ErrValidation.WithDetails(domain.ErrCannotBlockSelf.Error())
+	}
+	if exists, err := s.userRepo.Exists(ctx, blockedID); err != nil {
+		return err
+	} else if !exists {
+		return shared.ErrUserNotFound
+	}
+
+	if err := s.blockRepo.Block(ctx, blockerID, blockedID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(blockerID),
+		Action:     domain.AuditUserBlock,
+		TargetType: "user",
+		TargetID:   auditID(blockedID),
+	})
+	return nil
+}
+
+func (s *UserService) UnblockUser(ctx context.Context, blockerID, blockedID uint) error {
+	if s.blockRepo == nil {
+		return shared.ErrServiceUnavailable.WithDetails("blocking is not available")
+	}
+	if err := s.blockRepo.Unblock(ctx, blockerID, blockedID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, domain.AuditEvent{
+		ActorID:    auditActor(blockerID),
+		Action:     domain.AuditUserUnblock,
+		TargetType: "user",
+		TargetID:   auditID(blockedID),
+	})
+	return nil
+}
+
+func (s *UserService) ListBlockedUsers(ctx context.Context, blockerID uint) ([]domain.UserBlock, error) {
+	if s.blockRepo == nil {
+		return []domain.UserBlock{}, nil
+	}
+	return s.blockRepo.ListBlocked(ctx, blockerID)
 }
 
 func (s *UserService) GetMessageHistory(ctx context.Context, userID uint, limit, offset int) ([]domain.Message, int64, error) {
@@ -0,0 +1,273 @@
+package application
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type MockUserBlockRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserBlockRepository) Block(ctx context.Context, blockerID, blockedID uint) error {
+	args := m.Called(ctx, blockerID, blockedID)
+	return args.Error(0)
+}
+
+func (m *MockUserBlockRepository) Unblock(ctx context.Context, blockerID, blockedID uint) error {
+	args := m.Called(ctx, blockerID, blockedID)
+	return args.Error(0)
+}
+
+func (m *MockUserBlockRepository) IsBlocked(ctx context.Context, blockerID, blockedID uint) (bool, error) {
+	args := m.Called(ctx, blockerID, blockedID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserBlockRepository) ListBlocked(ctx context.Context, blockerID uint) ([]domain.UserBlock, error) {
+	args := m.Called(ctx, blockerID)
+	return args.Get(0).([]domain.UserBlock), args.Error(1)
+}
+
+func (m *MockUserBlockRepository) FindBlockersOf(ctx context.Context, blockedID uint, candidateIDs []uint) ([]uint, error) {
+	args := m.Called(ctx, blockedID, candidateIDs)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockUserBlockRepository) HiddenFrom(ctx context.Context, userID uint) ([]uint, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockUserBlockRepository) DeleteAllForUser(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+type MockAvatarUploader struct {
+	mock.Mock
+}
+
+func (m *MockAvatarUploader) UploadAvatar(ctx context.Context, userID uint, file io.Reader, contentType string, size int64) (domain.Avatar, error) {
+	args := m.Called(ctx, userID, file, contentType, size)
+	avatar, _ := args.Get(0).(domain.Avatar)
+	return avatar, args.Error(1)
+}
+
+func (m *MockAvatarUploader) DeleteAvatar(ctx context.Context, userID uint, avatar domain.Avatar) {
+	m.Called(ctx, userID, avatar)
+}
+
+func TestUserService_VerifyCredentials(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("UpgradesBcryptHash", func(t *testing.T) {
+		legacy, err := bcrypt.GenerateFromPassword([]byte("Password123"), bcrypt.MinCost)
+		assert.NoError(t, err)
+
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByUsername", mock.Anything, "johndoe").
+			Return(&domain.User{Model: gorm.Model{ID: 1}, Username: "johndoe", PasswordHash: string(legacy)}, nil)
+		userRepo.On("UpdatePassword", mock.Anything, uint(1), mock.MatchedBy(func(hash string) bool {
+			return strings.HasPrefix(hash, "$argon2id$")
+		})).Return(nil)
+
+		user, err := NewUserService(userRepo, nil, nil, nil, nil, nil).VerifyCredentials(ctx, "johndoe", "Password123")
+
+		assert.NoError(t, err)
+		assert.False(t, user.PasswordNeedsRehash())
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("KeepsCurrentHash", func(t *testing.T) {
+		current := &domain.User{Model: gorm.Model{ID: 1}, Username: "johndoe"}
+		assert.NoError(t, current.SetPassword("Password123"))
+
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByUsername", mock.Anything, "johndoe").Return(current, nil)
+
+		_, err := NewUserService(userRepo, nil, nil, nil, nil, nil).VerifyCredentials(ctx, "johndoe", "Password123")
+
+		assert.NoError(t, err)
+		userRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("WrongPasswordIsNotRehashed", func(t *testing.T) {
+		legacy, err := bcrypt.GenerateFromPassword([]byte("Password123"), bcrypt.MinCost)
+		assert.NoError(t, err)
+
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByUsername", mock.Anything, "johndoe").
+			Return(&domain.User{Model: gorm.Model{ID: 1}, Username: "johndoe", PasswordHash: string(legacy)}, nil)
+
+		_, err = NewUserService(userRepo, nil, nil, nil, nil, nil).VerifyCredentials(ctx, "johndoe", "wrong-password")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrInvalidCredentials.Code, appErr.Code)
+		userRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_Blocking(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("SearchExcludesBothSidesOfBlock", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		blockRepo := &MockUserBlockRepository{}
+		blockRepo.On("HiddenFrom", mock.Anything, uint(1)).Return([]uint{3}, nil)
+		userRepo.On("Search", mock.Anything, mock.MatchedBy(func(search domain.UserSearch) bool {
+			return len(search.ExcludeIDs) == 1 && search.ExcludeIDs[0] == 3
+		})).Return([]*domain.User{{Model: gorm.Model{ID: 2}, Username: "bob"}}, nil)
+
+		page, err := NewUserService(userRepo, nil, nil, nil, blockRepo, nil).SearchUsers(ctx, 1, "", 0, "")
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Users, 1)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("CannotBlockSelf", func(t *testing.T) {
+		blockRepo := &MockUserBlockRepository{}
+
+		err := NewUserService(&MockUserRepository{}, nil, nil, nil, blockRepo, nil).BlockUser(ctx, 1, 1)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrValidation.Code, appErr.Code)
+		blockRepo.AssertNotCalled(t, "Block", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("BlockUnknownUser", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		blockRepo := &MockUserBlockRepository{}
+		userRepo.On("Exists", mock.Anything, uint(9)).Return(false, nil)
+
+		err := NewUserService(userRepo, nil, nil, nil, blockRepo, nil).BlockUser(ctx, 1, 9)
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrUserNotFound.Code, appErr.Code)
+		blockRepo.AssertNotCalled(t, "Block", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_SearchUsers(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("PagesWithCursor", func(t *testing.T) {
+		userRepo := &MockUserRepository{}
+		userRepo.On("Search", mock.Anything, domain.UserSearch{Query: "an", Limit: 3}).Return([]*domain.User{
+			{Model: gorm.Model{ID: 4}, Username: "anna"},
+			{Model: gorm.Model{ID: 9}, Username: "dana"},
+			{Model: gorm.Model{ID: 2}, Username: "hannah"},
+		}, nil)
+		userRepo.On("Search", mock.Anything, domain.UserSearch{Query: "an", Limit: 3, AfterUsername: "dana", AfterID: 9}).
+			Return([]*domain.User{{Model: gorm.Model{ID: 2}, Username: "hannah"}}, nil)
+		service := NewUserService(userRepo, nil, nil, nil, nil, nil)
+
+		first, err := service.SearchUsers(ctx, 1, " an ", 2, "")
+		assert.NoError(t, err)
+		assert.Len(t, first.Users, 2)
+		assert.NotEmpty(t, first.NextCursor)
+
+		second, err := service.SearchUsers(ctx, 1, "an", 2, first.NextCursor)
+		assert.NoError(t, err)
+		assert.Len(t, second.Users, 1)
+		assert.Empty(t, second.NextCursor)
+	})
+
+	t.Run("RejectsGarbageCursor", func(t *testing.T) {
+		_, err := NewUserService(&MockUserRepository{}, nil, nil, nil, nil, nil).SearchUsers(ctx, 1, "", 10, "not-a-cursor")
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrBadRequest.Code, appErr.Code)
+	})
+}
+
+func TestUserService_Profile(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("RejectsUnknownTimeZone", func(t *testing.T) {
+		zone := "Mars/Olympus"
+		_, err := NewUserService(&MockUserRepository{}, nil, nil, nil, nil, nil).
+			UpdateProfile(ctx, 1, "", "", domain.ProfileDetails{TimeZone: &zone})
+
+		var appErr shared.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrValidation.Code, appErr.Code)
+	})
+
+	t.Run("SavesTrimmedDetails", func(t *testing.T) {
+		name, zone := "  Johnny  ", ""
+		userRepo := &MockUserRepository{}
+		userRepo.On("Update", mock.Anything, uint(1), "", "").Return(nil)
+		userRepo.On("UpdateDetails", mock.Anything, uint(1), mock.MatchedBy(func(d domain.ProfileDetails) bool {
+			return *d.DisplayName == "Johnny" && *d.TimeZone == "UTC" && d.Bio == nil
+		})).Return(nil)
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, DisplayName: "Johnny"}, nil)
+
+		profile, err := NewUserService(userRepo, nil, nil, nil, nil, nil).
+			UpdateProfile(ctx, 1, "", "", domain.ProfileDetails{DisplayName: &name, TimeZone: &zone})
+		assert.NoError(t, err)
+		assert.Equal(t, "Johnny", profile.DisplayName)
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("ReplacingAvatarDeletesOldImages", func(t *testing.T) {
+		oldAvatar := domain.Avatar{{Size: 64, Path: "user_1/old.png", URL: "http://media/user_1/old.png"}}
+		newAvatar := domain.Avatar{{Size: 64, Path: "user_1/new.png", URL: "http://media/user_1/new.png"}}
+		file := strings.NewReader("image")
+
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, Avatar: oldAvatar}, nil)
+		userRepo.On("UpdateAvatar", mock.Anything, uint(1), newAvatar).Return(nil)
+		userRepo.On("FindProfileByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, Avatar: newAvatar}, nil)
+		avatars := &MockAvatarUploader{}
+		avatars.On("UploadAvatar", mock.Anything, uint(1), file, "image/png", int64(5)).Return(newAvatar, nil)
+		avatars.On("DeleteAvatar", mock.Anything, uint(1), oldAvatar).Return()
+
+		profile, err := NewUserService(userRepo, nil, nil, nil, nil, avatars).SetAvatar(ctx, 1, file, "image/png", 5)
+		assert.NoError(t, err)
+		assert.Equal(t, "http://media/user_1/new.png", profile.Avatar.URL(128))
+		avatars.AssertExpectations(t)
+	})
+
+	t.Run("AvatarKeptWhenSaveFails", func(t *testing.T) {
+		oldAvatar := domain.Avatar{{Size: 64, Path: "user_1/old.png"}}
+		newAvatar := domain.Avatar{{Size: 64, Path: "user_1/new.png"}}
+
+		userRepo := &MockUserRepository{}
+		userRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.User{Model: gorm.Model{ID: 1}, Avatar: oldAvatar}, nil)
+		userRepo.On("UpdateAvatar", mock.Anything, uint(1), newAvatar).Return(shared.ErrDatabaseOperation)
+		avatars := &MockAvatarUploader{}
+		avatars.On("UploadAvatar", mock.Anything, uint(1), mock.Anything, "image/png", int64(5)).Return(newAvatar, nil)
+		avatars.On("DeleteAvatar", mock.Anything, uint(1), newAvatar).Return()
+
+		_, err := NewUserService(userRepo, nil, nil, nil, nil, avatars).SetAvatar(ctx, 1, strings.NewReader("image"), "image/png", 5)
+		assert.Error(t, err)
+		avatars.AssertNotCalled(t, "DeleteAvatar", mock.Anything, uint(1), oldAvatar)
+	})
+
+	t.Run("HidesBlockedProfiles", func(t *testing.T) {
+		blockRepo := &MockUserBlockRepository{}
+		blockRepo.On("HiddenFrom", mock.Anything, uint(1)).Return([]uint{7}, nil)
+
+		_, err := NewUserService(&MockUserRepository{}, nil, nil, nil, blockRepo, nil).GetPublicProfile(ctx, 1, 7)
+		assert.ErrorIs(t, err, shared.ErrUserNotFound)
+	})
+}
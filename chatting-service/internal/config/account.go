@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DeletedMessagePolicy decides what happens to messages sent by an erased account
+type DeletedMessagePolicy string
+
+const (
+	DeletedMessagesTombstone DeletedMessagePolicy = "tombstone" // Keep a placeholder so conversations stay readable
+	DeletedMessagesDelete    DeletedMessagePolicy = "delete"    // Remove the messages entirely
+)
+
+type AccountConfig struct {
+	DeletedMessagePolicy DeletedMessagePolicy
+
+	// Personal data exports
+	ExportStoragePath string        // Private directory for export archives
+	ExportLinkTTL     time.Duration // How long a finished export can be downloaded
+	ExportSigningKey  string        // Signs download links, falls back to JWT_SECRET
+	AppBaseURL        string        // Public URL used to build download links
+}
+
+func LoadAccountConfig() AccountConfig {
+	return AccountConfig{
+		DeletedMessagePolicy: DeletedMessagePolicy(strings.ToLower(getEnvWithDefault("DELETED_USER_MESSAGES", string(DeletedMessagesTombstone)))),
+		ExportStoragePath:    getEnvWithDefault("EXPORT_STORAGE_PATH", "./exports"),
+		ExportLinkTTL:        getDurationWithDefault("EXPORT_LINK_TTL", 24*time.Hour),
+		ExportSigningKey:     getEnvWithDefault("EXPORT_SIGNING_KEY", os.Getenv("JWT_SECRET")),
+		AppBaseURL:           getEnvWithDefault("APP_BASE_URL", "http://localhost:8080"),
+	}
+}
+
+func (c AccountConfig) Validate() error {
+	switch c.DeletedMessagePolicy {
+	case "", DeletedMessagesTombstone, DeletedMessagesDelete:
+	default:
+		return fmt.Errorf("unknown DELETED_USER_MESSAGES %q, expected tombstone or delete", c.DeletedMessagePolicy)
+	}
+	if c.ExportSigningKey == "" {
+		return fmt.Errorf("EXPORT_SIGNING_KEY or JWT_SECRET must be set to sign export links")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+type LoginProtectionConfig struct {
+	MaxAttemptsPerUser int           // Failures allowed per username before locking
+	MaxAttemptsPerIP   int           // Failures allowed per client IP before locking
+	AttemptWindow      time.Duration // Failures older than this are forgotten
+	BaseLockout        time.Duration // First lockout, doubled on every further failure
+	MaxLockout         time.Duration
+}
+
+func LoadLoginProtectionConfig() LoginProtectionConfig {
+	return LoginProtectionConfig{
+		MaxAttemptsPerUser: getIntWithDefault("LOGIN_MAX_ATTEMPTS_PER_USER", 5),
+		MaxAttemptsPerIP:   getIntWithDefault("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+		AttemptWindow:      getDurationWithDefault("LOGIN_ATTEMPT_WINDOW", time.Minute*15),
+		BaseLockout:        getDurationWithDefault("LOGIN_BASE_LOCKOUT", time.Minute),
+		MaxLockout:         getDurationWithDefault("LOGIN_MAX_LOCKOUT", time.Hour),
+	}
+}
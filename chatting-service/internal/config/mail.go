@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+type MailConfig struct {
+	SMTPHost             string
+	SMTPPort             int
+	SMTPUsername         string
+	SMTPPassword         string
+	From                 string
+	AppBaseURL           string        // Public URL used to build links sent by email
+	VerificationTokenTTL time.Duration // e.g., 24 hours
+	PasswordResetTTL     time.Duration // How long a forced reset code works
+}
+
+func LoadMailConfig() MailConfig {
+	return MailConfig{
+		SMTPHost:             os.Getenv("SMTP_HOST"),
+		SMTPPort:             getIntWithDefault("SMTP_PORT", 587),
+		SMTPUsername:         os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:         os.Getenv("SMTP_PASSWORD"),
+		From:                 getEnvWithDefault("MAIL_FROM", "no-reply@chatting-service.local"),
+		AppBaseURL:           getEnvWithDefault("APP_BASE_URL", "http://localhost:8080"),
+		VerificationTokenTTL: getDurationWithDefault("EMAIL_VERIFICATION_TTL", time.Hour*24),
+		PasswordResetTTL:     getDurationWithDefault("PASSWORD_RESET_TTL", time.Hour*24),
+	}
+}
+
+// SMTPEnabled reports whether enough settings exist to talk to a real mail server
+func (c MailConfig) SMTPEnabled() bool {
+	return c.SMTPHost != ""
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DirectMessagePolicy decides who may send a direct message to whom
+type DirectMessagePolicy string
+
+const (
+	DMPolicyOpen         DirectMessagePolicy = "open"     // Anyone can message anyone
+	DMPolicyContactsOnly DirectMessagePolicy = "contacts" // Only accepted contacts
+	DMPolicySameOrg      DirectMessagePolicy = "same_org" // Same email domain, or accepted contacts
+)
+
+type MessagingConfig struct {
+	RequireVerifiedEmail bool // Block sending until the sender confirmed their email
+	DirectMessagePolicy  DirectMessagePolicy
+}
+
+func LoadMessagingConfig() MessagingConfig {
+	return MessagingConfig{
+		RequireVerifiedEmail: getBoolWithDefault("REQUIRE_EMAIL_VERIFICATION", false),
+		DirectMessagePolicy:  DirectMessagePolicy(strings.ToLower(getEnvWithDefault("DM_POLICY", string(DMPolicyOpen)))),
+	}
+}
+
+func (c MessagingConfig) Validate() error {
+	switch c.DirectMessagePolicy {
+	case "", DMPolicyOpen, DMPolicyContactsOnly, DMPolicySameOrg:
+		return nil
+	}
+	return fmt.Errorf("unknown DM_POLICY %q, expected open, contacts or same_org", c.DirectMessagePolicy)
+}
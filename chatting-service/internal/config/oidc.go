@@ -0,0 +1,43 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string // Optional, public clients rely on PKCE alone
+	RedirectURL  string
+	Scopes       []string
+
+	// Claim mapping, names of the ID token claims read for a login
+	UsernameClaim      string
+	EmailClaim         string
+	EmailVerifiedClaim string
+
+	AutoProvision bool          // Create a local user on first login when no account matches
+	StateTTL      time.Duration // How long a started login may take before the callback
+}
+
+func LoadOIDCConfig() OIDCConfig {
+	return OIDCConfig{
+		IssuerURL:          strings.TrimSuffix(os.Getenv("OIDC_ISSUER_URL"), "/"),
+		ClientID:           os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:       os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:        getEnvWithDefault("OIDC_REDIRECT_URL", "http://localhost:8080/api/auth/oidc/callback"),
+		Scopes:             strings.Fields(getEnvWithDefault("OIDC_SCOPES", "openid profile email")),
+		UsernameClaim:      getEnvWithDefault("OIDC_USERNAME_CLAIM", "preferred_username"),
+		EmailClaim:         getEnvWithDefault("OIDC_EMAIL_CLAIM", "email"),
+		EmailVerifiedClaim: getEnvWithDefault("OIDC_EMAIL_VERIFIED_CLAIM", "email_verified"),
+		AutoProvision:      getBoolWithDefault("OIDC_AUTO_PROVISION", true),
+		StateTTL:           getDurationWithDefault("OIDC_STATE_TTL", time.Minute*10),
+	}
+}
+
+// Enabled reports whether single sign-on is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != ""
+}
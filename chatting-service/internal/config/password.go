@@ -0,0 +1,25 @@
+package config
+
+import "os"
+
+type PasswordPolicyConfig struct {
+	MinLength        int
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	CommonListPath   string // Optional file of breached/common passwords, one per line
+	DisallowUsername bool   // Reject passwords equal to the username
+}
+
+func LoadPasswordPolicyConfig() PasswordPolicyConfig {
+	return PasswordPolicyConfig{
+		MinLength:        getIntWithDefault("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:     getBoolWithDefault("PASSWORD_REQUIRE_UPPER", false),
+		RequireLower:     getBoolWithDefault("PASSWORD_REQUIRE_LOWER", false),
+		RequireDigit:     getBoolWithDefault("PASSWORD_REQUIRE_DIGIT", false),
+		RequireSymbol:    getBoolWithDefault("PASSWORD_REQUIRE_SYMBOL", false),
+		CommonListPath:   os.Getenv("PASSWORD_COMMON_LIST_PATH"),
+		DisallowUsername: getBoolWithDefault("PASSWORD_DISALLOW_USERNAME", true),
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// StorageDriver picks where uploaded media is kept
+type StorageDriver string
+
+const (
+	StorageLocal StorageDriver = "local" // Files on the local disk, only visible to this instance
+	StorageS3    StorageDriver = "s3"    // Any S3-compatible object store, shared by every instance
+)
+
+// S3 does not accept multipart parts below 5MB, except for the last one
+const minS3PartSizeMB = 5
+
+type StorageConfig struct {
+	Driver StorageDriver
+
+	// Local driver
+	LocalPath  string
+	BaseURL    string // URL the API serves the local files under
+	SigningKey string // Signs local media links, falls back to JWT_SECRET
+
+	SignedURLTTL time.Duration // Lifetime of signed media links handed out by the API
+
+	// Files nothing refers to are removed once they are older than OrphanGracePeriod,
+	// which covers uploads still on their way to being referenced
+	OrphanGracePeriod time.Duration
+	OrphanInterval    time.Duration // How often the API looks for orphans, 0 leaves it to cmd/media
+
+	S3 S3Config
+}
+
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.eu-central-1.amazonaws.com or http://minio:9000
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UsePathStyle    bool   // endpoint/bucket/key instead of bucket.endpoint/key, needed by most self-hosted stores
+	PublicURL       string // Optional CDN or public bucket URL used in media links, defaults to the object URL
+
+	MultipartThresholdMB int // Uploads larger than this are sent in parts
+	PartSizeMB           int
+}
+
+func LoadStorageConfig() StorageConfig {
+	return StorageConfig{
+		Driver:            StorageDriver(strings.ToLower(getEnvWithDefault("MEDIA_STORAGE_DRIVER", string(StorageLocal)))),
+		LocalPath:         getEnvWithDefault("MEDIA_STORAGE_PATH", "./uploads"),
+		BaseURL:           strings.TrimSuffix(getEnvWithDefault("MEDIA_BASE_URL", "/media"), "/"),
+		SigningKey:        getEnvWithDefault("MEDIA_SIGNING_KEY", os.Getenv("JWT_SECRET")),
+		SignedURLTTL:      getDurationWithDefault("MEDIA_URL_TTL", time.Hour),
+		OrphanGracePeriod: getDurationWithDefault("MEDIA_ORPHAN_GRACE_PERIOD", 7*24*time.Hour),
+		OrphanInterval:    getDurationWithDefault("MEDIA_ORPHAN_INTERVAL", 24*time.Hour),
+		S3: S3Config{
+			Endpoint:             strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/"),
+			Region:               getEnvWithDefault("S3_REGION", "us-east-1"),
+			Bucket:               os.Getenv("S3_BUCKET"),
+			AccessKeyID:          os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey:      os.Getenv("S3_SECRET_ACCESS_KEY"),
+			UsePathStyle:         getBoolWithDefault("S3_USE_PATH_STYLE", false),
+			PublicURL:            strings.TrimSuffix(os.Getenv("S3_PUBLIC_URL"), "/"),
+			MultipartThresholdMB: getIntWithDefault("S3_MULTIPART_THRESHOLD_MB", 16),
+			PartSizeMB:           getIntWithDefault("S3_PART_SIZE_MB", 8),
+		},
+	}
+}
+
+// FilesPath is the route the local files are served under, taken from BaseURL
+func (c StorageConfig) FilesPath() string {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil || u.Path == "" || u.Path == "/" {
+		return "/media"
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+func (c StorageConfig) Validate() error {
+	if c.SignedURLTTL <= 0 {
+		return fmt.Errorf("MEDIA_URL_TTL must be positive")
+	}
+	if c.OrphanGracePeriod < time.Hour {
+		return fmt.Errorf("MEDIA_ORPHAN_GRACE_PERIOD must be at least an hour")
+	}
+	if c.OrphanInterval < 0 {
+		return fmt.Errorf("MEDIA_ORPHAN_INTERVAL must not be negative")
+	}
+	switch c.Driver {
+	case StorageLocal:
+		if c.SigningKey == "" {
+			return fmt.Errorf("MEDIA_SIGNING_KEY or JWT_SECRET must be set to sign media links")
+		}
+		return nil
+	case StorageS3:
+		return c.S3.Validate()
+	default:
+		return fmt.Errorf("unknown MEDIA_STORAGE_DRIVER %q, expected local or s3", c.Driver)
+	}
+}
+
+func (c S3Config) Validate() error {
+	switch {
+	case c.Endpoint == "":
+		return fmt.Errorf("S3_ENDPOINT is required for the s3 storage driver")
+	case !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://"):
+		return fmt.Errorf("S3_ENDPOINT must start with http:// or https://")
+	case c.Bucket == "":
+		return fmt.Errorf("S3_BUCKET is required for the s3 storage driver")
+	case c.AccessKeyID == "" || c.SecretAccessKey == "":
+		return fmt.Errorf("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for the s3 storage driver")
+	case c.PartSizeMB < minS3PartSizeMB:
+		return fmt.Errorf("S3_PART_SIZE_MB must be at least %d", minS3PartSizeMB)
+	case c.MultipartThresholdMB < 0:
+		return fmt.Errorf("S3_MULTIPART_THRESHOLD_MB must not be negative")
+	}
+	return nil
+}
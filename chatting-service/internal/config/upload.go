@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const bytesPerMB = 1024 * 1024
+
+type UploadConfig struct {
+	MaxSizeMB    int            // Limit for allowed types without their own
+	AllowedTypes map[string]int // Content type to its limit in MB, 0 uses MaxSizeMB
+	KeepMetadata bool           // Store images with their EXIF data, including locations
+
+	ResumableMaxMB int           // Limit for resumable uploads of allowed types without their own
+	ChunkSizeMB    int           // Most a single chunk of a resumable upload may carry
+	ResumableTTL   time.Duration // Unfinished resumable uploads are removed after this long without a chunk
+
+	// Bytes of media each user may keep, 0 means no limit. A user's own quota wins
+	// over the one of their role, which wins over QuotaMB.
+	QuotaMB      int
+	RoleQuotasMB map[string]int
+	UserQuotasMB map[uint]int
+}
+
+// LoadUploadConfig reads MEDIA_ALLOWED_TYPES as a comma separated list of
+// type[:maxMB] entries, e.g. "image/jpeg,image/png,video/mp4:50". Quotas for roles
+// and users are listed the same way, e.g. "admin:0" or "42:5120".
+func LoadUploadConfig() UploadConfig {
+	cfg := UploadConfig{
+		MaxSizeMB:    getIntWithDefault("MEDIA_MAX_UPLOAD_MB", 10),
+		AllowedTypes: map[string]int{},
+		KeepMetadata: getBoolWithDefault("MEDIA_KEEP_METADATA", false),
+
+		ResumableMaxMB: getIntWithDefault("MEDIA_RESUMABLE_MAX_MB", 1024),
+		ChunkSizeMB:    getIntWithDefault("MEDIA_UPLOAD_CHUNK_MB", 8),
+		ResumableTTL:   getDurationWithDefault("MEDIA_RESUMABLE_TTL", 24*time.Hour),
+
+		QuotaMB:      getIntWithDefault("MEDIA_QUOTA_MB", 1024),
+		RoleQuotasMB: map[string]int{},
+		UserQuotasMB: map[uint]int{},
+	}
+	for role, quotaMB := range parseQuotas(os.Getenv("MEDIA_ROLE_QUOTAS_MB")) {
+		cfg.RoleQuotasMB[strings.ToLower(role)] = quotaMB
+	}
+	for user, quotaMB := range parseQuotas(os.Getenv("MEDIA_USER_QUOTAS_MB")) {
+		userID, err := strconv.ParseUint(user, 10, 0)
+		if err != nil || userID == 0 {
+			cfg.UserQuotasMB[0] = -1 // Rejected by Validate
+			continue
+		}
+		cfg.UserQuotasMB[uint(userID)] = quotaMB
+	}
+	for _, entry := range strings.Split(getEnvWithDefault("MEDIA_ALLOWED_TYPES", "image/jpeg,image/png,application/pdf"), ",") {
+		contentType, limit, hasLimit := strings.Cut(strings.TrimSpace(entry), ":")
+		if contentType == "" {
+			continue
+		}
+		sizeMB := 0
+		if hasLimit {
+			n, err := strconv.Atoi(strings.TrimSpace(limit))
+			if err != nil || n <= 0 {
+				n = -1 // Rejected by Validate
+			}
+			sizeMB = n
+		}
+		cfg.AllowedTypes[strings.ToLower(strings.TrimSpace(contentType))] = sizeMB
+	}
+	return cfg
+}
+
+// parseQuotas reads comma separated name:MB entries, sizes that are missing or not a
+// number come back negative
+func parseQuotas(value string) map[string]int {
+	quotas := map[string]int{}
+	for _, entry := range strings.Split(value, ",") {
+		name, quota, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		quotaMB, err := strconv.Atoi(strings.TrimSpace(quota))
+		if err != nil {
+			quotaMB = -1
+		}
+		quotas[name] = quotaMB
+	}
+	return quotas
+}
+
+func (c UploadConfig) Validate() error {
+	if c.MaxSizeMB <= 0 {
+		return fmt.Errorf("MEDIA_MAX_UPLOAD_MB must be positive")
+	}
+	if c.ResumableMaxMB < c.MaxSizeMB {
+		return fmt.Errorf("MEDIA_RESUMABLE_MAX_MB must be at least MEDIA_MAX_UPLOAD_MB")
+	}
+	if c.ChunkSizeMB <= 0 {
+		return fmt.Errorf("MEDIA_UPLOAD_CHUNK_MB must be positive")
+	}
+	if c.ResumableTTL <= 0 {
+		return fmt.Errorf("MEDIA_RESUMABLE_TTL must be positive")
+	}
+	if len(c.AllowedTypes) == 0 {
+		return fmt.Errorf("MEDIA_ALLOWED_TYPES must list at least one content type")
+	}
+	for contentType, sizeMB := range c.AllowedTypes {
+		if !strings.Contains(contentType, "/") {
+			return fmt.Errorf("MEDIA_ALLOWED_TYPES entry %q is not a content type", contentType)
+		}
+		if sizeMB < 0 {
+			return fmt.Errorf("MEDIA_ALLOWED_TYPES entry %q needs a positive size in MB", contentType)
+		}
+	}
+	if c.QuotaMB < 0 {
+		return fmt.Errorf("MEDIA_QUOTA_MB must not be negative")
+	}
+	for role, quotaMB := range c.RoleQuotasMB {
+		if quotaMB < 0 {
+			return fmt.Errorf("MEDIA_ROLE_QUOTAS_MB entry %q needs a size in MB, 0 for no limit", role)
+		}
+	}
+	for userID, quotaMB := range c.UserQuotasMB {
+		if userID == 0 {
+			return fmt.Errorf("MEDIA_USER_QUOTAS_MB entries must start with a user ID")
+		}
+		if quotaMB < 0 {
+			return fmt.Errorf("MEDIA_USER_QUOTAS_MB entry for user %d needs a size in MB, 0 for no limit", userID)
+		}
+	}
+	return nil
+}
+
+// Quota is how many bytes of media the user may keep, 0 when there is no limit
+func (c UploadConfig) Quota(userID uint, role string) int64 {
+	quotaMB, ok := c.UserQuotasMB[userID]
+	if !ok {
+		if quotaMB, ok = c.RoleQuotasMB[role]; !ok {
+			quotaMB = c.QuotaMB
+		}
+	}
+	return int64(quotaMB) * bytesPerMB
+}
+
+// MaxSize is the limit in bytes for an allowed type, false when the type is not allowed
+func (c UploadConfig) MaxSize(contentType string) (int64, bool) {
+	sizeMB, ok := c.AllowedTypes[contentType]
+	if !ok {
+		return 0, false
+	}
+	if sizeMB <= 0 {
+		sizeMB = c.MaxSizeMB
+	}
+	return int64(sizeMB) * bytesPerMB, true
+}
+
+// LargestSize is the most any upload may be, whatever its type
+func (c UploadConfig) LargestSize() int64 {
+	var largest int64
+	for contentType := range c.AllowedTypes {
+		if size, _ := c.MaxSize(contentType); size > largest {
+			largest = size
+		}
+	}
+	return largest
+}
+
+// ResumableMaxSize is the limit in bytes for a resumable upload of an allowed type. Types
+// with their own limit keep it, the others may go up to ResumableMaxMB.
+func (c UploadConfig) ResumableMaxSize(contentType string) (int64, bool) {
+	sizeMB, ok := c.AllowedTypes[contentType]
+	if !ok {
+		return 0, false
+	}
+	if sizeMB <= 0 {
+		sizeMB = c.ResumableMaxMB
+	}
+	return int64(sizeMB) * bytesPerMB, true
+}
+
+// LargestResumableSize is the most any resumable upload may be, whatever its type
+func (c UploadConfig) LargestResumableSize() int64 {
+	var largest int64
+	for contentType := range c.AllowedTypes {
+		if size, _ := c.ResumableMaxSize(contentType); size > largest {
+			largest = size
+		}
+	}
+	return largest
+}
+
+func (c UploadConfig) ChunkSize() int64 {
+	return int64(c.ChunkSizeMB) * bytesPerMB
+}
+
+// BodyLimit is the largest request body the server has to accept, a direct upload
+// with its multipart framing or a single chunk
+func (c UploadConfig) BodyLimit() int {
+	return int(max(c.LargestSize(), c.ChunkSize()) + bytesPerMB)
+}
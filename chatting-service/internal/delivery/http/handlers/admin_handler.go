@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/dto/admin"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type AdminHandler struct {
+	authService    *application.AuthService
+	audit          *application.AuditLogger
+	accountService *application.AccountService
+	userAdmin      *application.UserAdminService
+}
+
+func NewAdminHandler(authService *application.AuthService, audit *application.AuditLogger, accountService *application.AccountService, userAdmin *application.UserAdminService) *AdminHandler {
+	return &AdminHandler{
+		authService:    authService,
+		audit:          audit,
+		accountService: accountService,
+		userAdmin:      userAdmin,
+	}
+}
+
+// ListUsers godoc
+// @Summary      List users
+// @Description  Page through all accounts, newest first, filtered by text, role, status and bot flag (admin only)
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        q       query     string  false  "Username, email or display name contains"
+// @Param        role    query     string  false  "user or admin"
+// @Param        status  query     string  false  "active or suspended"
+// @Param        is_bot  query     bool    false  "Only bots or only humans"
+// @Param        limit   query     int     false  "Max users (default 50, max 200)"
+// @Param        offset  query     int     false  "Offset"
+// @Success      200  {object}  admin.UserListResponse
+// @Failure      400  {object}  shared.Error
+// @Failure      403  {object}  shared.Error
+// @Router       /api/admin/users [get]
+func (h *AdminHandler) ListUsers(c *fiber.Ctx) error {
+	var req admin.UserListRequest
+	if err := c.QueryParser(&req); err != nil {
+		shared.Log.Debug("Invalid user list query", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("Invalid query params").WithDetails(err.Error())
+	}
+
+	filter := domain.UserListFilter{
+		Query:  req.Query,
+		Role:   domain.UserRole(req.Role),
+		Status: domain.UserAccountStatus(req.Status),
+		IsBot:  req.IsBot,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	}
+	page, err := h.userAdmin.ListUsers(c.Context(), filter)
+	if err != nil {
+		shared.Log.Error("Failed to list users", zap.Error(err))
+		return err
+	}
+
+	response := admin.UserListResponse{
+		Users:  make([]admin.UserSummaryResponse, len(page.Users)),
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+	for i, u := range page.Users {
+		response.Users[i] = toUserSummary(u)
+	}
+	return c.JSON(response)
+}
+
+// GetUser godoc
+// @Summary      Get a user with activity stats
+// @Description  Account details plus messages sent, last activity and storage used (admin only)
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  admin.UserDetailResponse
+// @Failure      400  {object}  shared.Error
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/admin/users/{id} [get]
+func (h *AdminHandler) GetUser(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+
+	user, activity, err := h.userAdmin.GetUser(c.Context(), uint(userID))
+	if err != nil {
+		shared.Log.Error("Failed to get user", zap.Error(err), zap.Int("userID", userID))
+		return err
+	}
+
+	return c.JSON(admin.UserDetailResponse{
+		UserSummaryResponse: toUserSummary(user),
+		Activity: admin.UserActivityResponse{
+			MessagesSent: activity.MessagesSent,
+			LastActiveAt: activity.LastActiveAt,
+			StorageBytes: activity.StorageBytes,
+		},
+	})
+}
+
+// SuspendUser godoc
+// @Summary      Suspend a user account
+// @Description  Refuse sign-in, reject every existing session and token, and close open WebSocket connections (admin only)
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id    path      int                       true   "User ID"
+// @Param        body  body      admin.SuspendUserRequest  false  "Reason shown to other admins"
+// @Success      200   {object}  admin.UserSummaryResponse
+// @Failure      400   {object}  shared.Error
+// @Failure      403   {object}  shared.Error
+// @Failure      404   {object}  shared.Error
+// @Failure      409   {object}  shared.Error
+// @Router       /api/admin/users/{id}/suspend [post]
+func (h *AdminHandler) SuspendUser(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+
+	var body admin.SuspendUserRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return shared.ErrBadRequest.WithDetails("Invalid request body")
+		}
+	}
+
+	user, err := h.userAdmin.Suspend(c.Context(), claims.UserID, uint(userID), body.Reason)
+	if err != nil {
+		shared.Log.Error("Failed to suspend user", zap.Error(err), zap.Int("userID", userID))
+		return err
+	}
+
+	return c.JSON(toUserSummary(user))
+}
+
+// ReinstateUser godoc
+// @Summary      Reinstate a suspended account
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  admin.UserSummaryResponse
+// @Failure      400  {object}  shared.Error
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Failure      409  {object}  shared.Error
+// @Router       /api/admin/users/{id}/reinstate [post]
+func (h *AdminHandler) ReinstateUser(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+
+	user, err := h.userAdmin.Reinstate(c.Context(), claims.UserID, uint(userID))
+	if err != nil {
+		shared.Log.Error("Failed to reinstate user", zap.Error(err), zap.Int("userID", userID))
+		return err
+	}
+
+	return c.JSON(toUserSummary(user))
+}
+
+// ForcePasswordReset godoc
+// @Summary      Force a password reset
+// @Description  Revoke all sessions and API tokens of the user and require a new password before the next sign-in (admin only). The single-use reset code is mailed to a verified address, otherwise it is returned as reset_code to be handed over to the user in person.
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  shared.Error
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/admin/users/{id}/password-reset [post]
+func (h *AdminHandler) ForcePasswordReset(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+
+	code, err := h.userAdmin.ForcePasswordReset(c.Context(), claims.UserID, uint(userID))
+	if err != nil {
+		shared.Log.Error("Failed to force password reset", zap.Error(err), zap.Int("userID", userID))
+		return err
+	}
+
+	if code != "" {
+		return c.JSON(fiber.Map{
+			"message":    "Password reset required, all sessions revoked. The email address is not verified, hand the reset code to the user",
+			"reset_code": code,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Password reset required, all sessions revoked. The reset code was sent by email",
+	})
+}
+
+// UnlockUser godoc
+// @Summary      Unlock a user account
+// @Description  Clear a login lockout caused by repeated failed login attempts (admin only)
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  shared.Error
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/admin/users/{id}/unlock [post]
+func (h *AdminHandler) UnlockUser(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+
+	if err := h.authService.UnlockAccount(c.Context(), claims.UserID, uint(userID)); err != nil {
+		shared.Log.Error("Failed to unlock user", zap.Error(err), zap.Int("userID", userID))
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Account unlocked",
+	})
+}
+
+// DeleteUser godoc
+// @Summary      Erase a user account
+// @Description  Anonymize the account, erase its messages according to DELETED_USER_MESSAGES, delete its media and revoke its sessions (admin only)
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  shared.Error
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/admin/users/{id} [delete]
+func (h *AdminHandler) DeleteUser(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+
+	if err := h.accountService.DeleteAccount(c.UserContext(), claims.UserID, uint(userID)); err != nil {
+		shared.Log.Error("Failed to delete user", zap.Error(err), zap.Int("userID", userID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListAuditEvents godoc
+// @Summary      Query the audit log
+// @Description  List security audit events, newest first, filtered by actor, action and time range (admin only)
+// @Tags         Admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        actor_id  query     int     false  "Actor user ID"
+// @Param        action    query     string  false  "Action, e.g. auth.login"
+// @Param        from      query     string  false  "Start time (RFC3339)"
+// @Param        to        query     string  false  "End time, exclusive (RFC3339)"
+// @Param        limit     query     int     false  "Max events (default 100, max 1000)"
+// @Param        offset    query     int     false  "Offset"
+// @Success      200  {object}  admin.AuditEventListResponse
+// @Failure      400  {object}  shared.Error
+// @Failure      403  {object}  shared.Error
+// @Router       /api/admin/audit-events [get]
+func (h *AdminHandler) ListAuditEvents(c *fiber.Ctx) error {
+	var req admin.AuditQueryRequest
+	if err := c.QueryParser(&req); err != nil {
+		shared.Log.Debug("Invalid audit query", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("Invalid query params").WithDetails(err.Error())
+	}
+
+	query := domain.AuditQuery{
+		Action: domain.AuditAction(req.Action),
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	}
+	if req.ActorID != 0 {
+		query.ActorID = &req.ActorID
+	}
+	var err error
+	if query.From, err = parseQueryTime(req.From); err != nil {
+		return shared.ErrBadRequest.WithDetails("from must be an RFC3339 timestamp")
+	}
+	if query.To, err = parseQueryTime(req.To); err != nil {
+		return shared.ErrBadRequest.WithDetails("to must be an RFC3339 timestamp")
+	}
+
+	events, err := h.audit.Query(c.Context(), query)
+	if err != nil {
+		shared.Log.Error("Failed to query audit events", zap.Error(err))
+		return err
+	}
+
+	response := admin.AuditEventListResponse{
+		Events: make([]admin.AuditEventResponse, len(events)),
+		Count:  len(events),
+	}
+	for i, e := range events {
+		response.Events[i] = admin.AuditEventResponse{
+			ID:         e.ID,
+			CreatedAt:  e.CreatedAt,
+			ActorID:    e.ActorID,
+			Actor:      e.Actor,
+			Action:     string(e.Action),
+			TargetType: e.TargetType,
+			TargetID:   e.TargetID,
+			Outcome:    string(e.Outcome),
+			IP:         e.IP,
+			RequestID:  e.RequestID,
+			Details:    e.Details,
+		}
+	}
+	return c.JSON(response)
+}
+
+func parseQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func toUserSummary(u *domain.User) admin.UserSummaryResponse {
+	status := domain.AccountActive
+	if u.IsSuspended() {
+		status = domain.AccountSuspended
+	}
+	return admin.UserSummaryResponse{
+		ID:                    u.ID,
+		Username:              u.Username,
+		Email:                 u.Email,
+		DisplayName:           u.DisplayName,
+		Role:                  string(u.Role),
+		IsBot:                 u.IsBot,
+		EmailVerified:         u.EmailVerified,
+		Status:                string(status),
+		SuspendedAt:           u.SuspendedAt,
+		SuspensionReason:      u.SuspensionReason,
+		PasswordResetRequired: u.PasswordResetRequired,
+		LastActiveAt:          u.LastActiveAt,
+		CreatedAt:             u.CreatedAt,
+	}
+}
@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/dto/announcement"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type AnnouncementHandler struct {
+	announcementService *application.AnnouncementService
+}
+
+func NewAnnouncementHandler(announcementService *application.AnnouncementService) *AnnouncementHandler {
+	return &AnnouncementHandler{announcementService: announcementService}
+}
+
+// ListChannels godoc
+// @Summary      List announcement channels
+// @Description  Channels the authenticated user follows, with their read position and unread count
+// @Tags         Announcements
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   announcement.ChannelResponse
+// @Failure      401  {object}  shared.Error
+// @Router       /api/announcements/channels [get]
+func (h *AnnouncementHandler) ListChannels(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	summaries, err := h.announcementService.ListChannels(c.Context(), claims.UserID, claims.Role)
+	if err != nil {
+		shared.Log.Error("Failed to list announcement channels", zap.Error(err))
+		return err
+	}
+
+	response := make([]announcement.ChannelResponse, 0, len(summaries))
+	for _, summary := range summaries {
+		item := toChannelResponse(&summary.Channel)
+		item.LastReadID = summary.LastReadID
+		item.UnreadCount = summary.UnreadCount
+		response = append(response, item)
+	}
+	return c.JSON(response)
+}
+
+// CreateChannel godoc
+// @Summary      Create an announcement channel
+// @Description  Every user follows the new channel, or only users of the given audience role
+// @Tags         Announcements
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      announcement.CreateChannelRequest  true  "Channel"
+// @Success      201   {object}  announcement.ChannelResponse
+// @Failure      400   {object}  shared.Error
+// @Failure      403   {object}  shared.Error
+// @Failure      409   {object}  shared.Error
+// @Router       /api/announcements/channels [post]
+func (h *AnnouncementHandler) CreateChannel(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	var body announcement.CreateChannelRequest
+	if err := c.BodyParser(&body); err != nil {
+		shared.Log.Debug("Invalid channel body", zap.ByteString("body", c.Body()))
+		return shared.ErrBadRequest.WithDetails("Invalid request body")
+	}
+	var audience *domain.UserRole
+	if body.Audience != "" {
+		role := domain.UserRole(body.Audience)
+		audience = &role
+	}
+
+	channel, err := h.announcementService.CreateChannel(c.Context(), claims.UserID, body.Name, body.Description, audience)
+	if err != nil {
+		shared.Log.Error("Failed to create announcement channel", zap.Error(err))
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(toChannelResponse(channel))
+}
+
+// DeleteChannel godoc
+// @Summary      Delete an announcement channel
+// @Description  Removes the channel together with its posts
+// @Tags         Announcements
+// @Security     ApiKeyAuth
+// @Param        id   path  int  true  "Channel ID"
+// @Success      204
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/announcements/channels/{id} [delete]
+func (h *AnnouncementHandler) DeleteChannel(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	channelID, err := channelIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.announcementService.DeleteChannel(c.Context(), claims.UserID, channelID); err != nil {
+		shared.Log.Error("Failed to delete announcement channel", zap.Error(err), zap.Uint("channelID", channelID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListPosters godoc
+// @Summary      List channel posters
+// @Description  Non-admin users allowed to publish to the channel
+// @Tags         Announcements
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "Channel ID"
+// @Success      200  {array}   announcement.PosterResponse
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/announcements/channels/{id}/posters [get]
+func (h *AnnouncementHandler) ListPosters(c *fiber.Ctx) error {
+	channelID, err := channelIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	posters, err := h.announcementService.ListPosters(c.Context(), channelID)
+	if err != nil {
+		shared.Log.Error("Failed to list announcement posters", zap.Error(err), zap.Uint("channelID", channelID))
+		return err
+	}
+
+	response := make([]announcement.PosterResponse, 0, len(posters))
+	for _, poster := range posters {
+		response = append(response, announcement.PosterResponse{
+			UserID:    poster.UserID,
+			Username:  poster.User.Username,
+			GrantedAt: poster.CreatedAt,
+		})
+	}
+	return c.JSON(response)
+}
+
+// AddPoster godoc
+// @Summary      Allow a user to post
+// @Tags         Announcements
+// @Security     ApiKeyAuth
+// @Param        id      path  int  true  "Channel ID"
+// @Param        userID  path  int  true  "User ID"
+// @Success      204
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/announcements/channels/{id}/posters/{userID} [put]
+func (h *AnnouncementHandler) AddPoster(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	channelID, userID, err := posterParams(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.announcementService.AddPoster(c.Context(), claims.UserID, channelID, userID); err != nil {
+		shared.Log.Error("Failed to add announcement poster", zap.Error(err), zap.Uint("channelID", channelID), zap.Uint("userID", userID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RemovePoster godoc
+// @Summary      Revoke a user's right to post
+// @Tags         Announcements
+// @Security     ApiKeyAuth
+// @Param        id      path  int  true  "Channel ID"
+// @Param        userID  path  int  true  "User ID"
+// @Success      204
+// @Failure      403  {object}  shared.Error
+// @Router       /api/announcements/channels/{id}/posters/{userID} [delete]
+func (h *AnnouncementHandler) RemovePoster(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	channelID, userID, err := posterParams(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.announcementService.RemovePoster(c.Context(), claims.UserID, channelID, userID); err != nil {
+		shared.Log.Error("Failed to remove announcement poster", zap.Error(err), zap.Uint("channelID", channelID), zap.Uint("userID", userID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListPosts godoc
+// @Summary      List announcements
+// @Description  Newest first. Pass the smallest ID of the previous page as before to load older posts.
+// @Tags         Announcements
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id      path   int  true   "Channel ID"
+// @Param        before  query  int  false  "Only posts older than this ID"
+// @Param        limit   query  int  false  "Page size (max 100)"
+// @Success      200  {array}   announcement.PostResponse
+// @Failure      404  {object}  shared.Error
+// @Router       /api/announcements/channels/{id}/posts [get]
+func (h *AnnouncementHandler) ListPosts(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	channelID, err := channelIDParam(c)
+	if err != nil {
+		return err
+	}
+	before := c.QueryInt("before")
+	if before < 0 {
+		return shared.ErrBadRequest.WithDetails("before must be a post ID")
+	}
+
+	posts, err := h.announcementService.ListPosts(c.Context(), claims.Role, channelID, uint(before), c.QueryInt("limit"))
+	if err != nil {
+		shared.Log.Error("Failed to list announcements", zap.Error(err), zap.Uint("channelID", channelID))
+		return err
+	}
+	return c.JSON(toPostResponses(posts))
+}
+
+// ListPinned godoc
+// @Summary      List pinned announcements
+// @Tags         Announcements
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "Channel ID"
+// @Success      200  {array}   announcement.PostResponse
+// @Failure      404  {object}  shared.Error
+// @Router       /api/announcements/channels/{id}/pinned [get]
+func (h *AnnouncementHandler) ListPinned(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	channelID, err := channelIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	posts, err := h.announcementService.ListPinned(c.Context(), claims.Role, channelID)
+	if err != nil {
+		shared.Log.Error("Failed to list pinned announcements", zap.Error(err), zap.Uint("channelID", channelID))
+		return err
+	}
+	return c.JSON(toPostResponses(posts))
+}
+
+// Post godoc
+// @Summary      Publish an announcement
+// @Description  Only admins and users granted posting rights on the channel can publish
+// @Tags         Announcements
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id    path      int                       true  "Channel ID"
+// @Param        body  body      announcement.PostRequest  true  "Announcement"
+// @Success      201   {object}  announcement.PostResponse
+// @Failure      400   {object}  shared.Error
+// @Failure      403   {object}  shared.Error
+// @Failure      404   {object}  shared.Error
+// @Router       /api/announcements/channels/{id}/posts [post]
+func (h *AnnouncementHandler) Post(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	channelID, err := channelIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	var body announcement.PostRequest
+	if err := c.BodyParser(&body); err != nil {
+		shared.Log.Debug("Invalid announcement body", zap.ByteString("body", c.Body()))
+		return shared.ErrBadRequest.WithDetails("Invalid request body")
+	}
+
+	post, err := h.announcementService.Post(c.Context(), claims.UserID, claims.Role, channelID, body.Content, body.MediaURL)
+	if err != nil {
+		shared.Log.Error("Failed to post announcement", zap.Error(err), zap.Uint("channelID", channelID))
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(toPostResponse(post))
+}
+
+// MarkRead godoc
+// @Summary      Mark announcements as read
+// @Description  Everything up to and including the given post counts as read. The read position never moves back.
+// @Tags         Announcements
+// @Accept       json
+// @Security     ApiKeyAuth
+// @Param        id    path  int                           true  "Channel ID"
+// @Param        body  body  announcement.MarkReadRequest  true  "Newest post seen"
+// @Success      204
+// @Failure      400  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/announcements/channels/{id}/read [post]
+func (h *AnnouncementHandler) MarkRead(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	channelID, err := channelIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	var body announcement.MarkReadRequest
+	if err := c.BodyParser(&body); err != nil || body.PostID == 0 {
+		shared.Log.Debug("Invalid mark read body", zap.ByteString("body", c.Body()))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing post_id")
+	}
+
+	if err := h.announcementService.MarkRead(c.Context(), claims.UserID, claims.Role, channelID, body.PostID); err != nil {
+		shared.Log.Error("Failed to mark announcements read", zap.Error(err), zap.Uint("channelID", channelID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// PinPost godoc
+// @Summary      Pin an announcement
+// @Tags         Announcements
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "Announcement ID"
+// @Success      200  {object}  announcement.PostResponse
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/announcements/posts/{id}/pin [post]
+func (h *AnnouncementHandler) PinPost(c *fiber.Ctx) error {
+	return h.setPinned(c, true)
+}
+
+// UnpinPost godoc
+// @Summary      Unpin an announcement
+// @Tags         Announcements
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "Announcement ID"
+// @Success      200  {object}  announcement.PostResponse
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/announcements/posts/{id}/pin [delete]
+func (h *AnnouncementHandler) UnpinPost(c *fiber.Ctx) error {
+	return h.setPinned(c, false)
+}
+
+func (h *AnnouncementHandler) setPinned(c *fiber.Ctx, pinned bool) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	postID, err := c.ParamsInt("id")
+	if err != nil || postID <= 0 {
+		shared.Log.Debug("Invalid announcement ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing announcement ID")
+	}
+
+	post, err := h.announcementService.SetPinned(c.Context(), claims.UserID, claims.Role, uint(postID), pinned)
+	if err != nil {
+		shared.Log.Error("Failed to pin announcement", zap.Error(err), zap.Int("postID", postID), zap.Bool("pinned", pinned))
+		return err
+	}
+	return c.JSON(toPostResponse(post))
+}
+
+// DeletePost godoc
+// @Summary      Delete an announcement
+// @Description  Authors can delete their own posts, admins any post
+// @Tags         Announcements
+// @Security     ApiKeyAuth
+// @Param        id   path  int  true  "Announcement ID"
+// @Success      204
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/announcements/posts/{id} [delete]
+func (h *AnnouncementHandler) DeletePost(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	postID, err := c.ParamsInt("id")
+	if err != nil || postID <= 0 {
+		shared.Log.Debug("Invalid announcement ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing announcement ID")
+	}
+
+	if err := h.announcementService.DeletePost(c.Context(), claims.UserID, claims.Role, uint(postID)); err != nil {
+		shared.Log.Error("Failed to delete announcement", zap.Error(err), zap.Int("postID", postID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func channelIDParam(c *fiber.Ctx) (uint, error) {
+	channelID, err := c.ParamsInt("id")
+	if err != nil || channelID <= 0 {
+		shared.Log.Debug("Invalid channel ID", zap.String("id", c.Params("id")))
+		return 0, shared.ErrBadRequest.WithDetails("Invalid or missing channel ID")
+	}
+	return uint(channelID), nil
+}
+
+func posterParams(c *fiber.Ctx) (uint, uint, error) {
+	channelID, err := channelIDParam(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	userID, err := c.ParamsInt("userID")
+	if err != nil || userID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("userID", c.Params("userID")))
+		return 0, 0, shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+	return channelID, uint(userID), nil
+}
+
+func toChannelResponse(channel *domain.AnnouncementChannel) announcement.ChannelResponse {
+	response := announcement.ChannelResponse{
+		ID:          channel.ID,
+		Name:        channel.Name,
+		Description: channel.Description,
+		CreatedAt:   channel.CreatedAt,
+	}
+	if channel.AudienceRole != nil {
+		response.Audience = string(*channel.AudienceRole)
+	}
+	return response
+}
+
+func toPostResponse(post *domain.Announcement) announcement.PostResponse {
+	return announcement.PostResponse{
+		ID:        post.ID,
+		ChannelID: post.ChannelID,
+		Content:   post.Content,
+		MediaURL:  post.MediaURL,
+		Author:    post.Author.SenderInfo(),
+		Pinned:    post.Pinned(),
+		PinnedAt:  post.PinnedAt,
+		CreatedAt: post.CreatedAt,
+	}
+}
+
+func toPostResponses(posts []domain.Announcement) []announcement.PostResponse {
+	response := make([]announcement.PostResponse, 0, len(posts))
+	for i := range posts {
+		response = append(response, toPostResponse(&posts[i]))
+	}
+	return response
+}
@@ -29,6 +29,7 @@ func NewAuthHandler(authService *application.AuthService) *AuthHandler {
 // @Success      200   {object}  auth.AuthResponse
 // @Failure      400   {object}  shared.Error
 // @Failure      401   {object}  shared.Error
+// @Failure      429   {object}  shared.Error
 // @Failure      500   {object}  shared.Error
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -47,7 +48,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return shared.ErrBadRequest.WithDetails("Invalid request body must have all fields")
 	}
 
-	res, err := h.authService.Login(c.Context(), body.Username, body.Password)
+	res, err := h.authService.Login(c.Context(), body.Username, body.Password, c.IP())
 	if err != nil {
 		shared.Log.Error("Login failed", zap.Error(err))
 		return err
@@ -140,3 +141,149 @@ func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
 		"message": "Password updated successfully",
 	})
 }
+
+// ResetPassword godoc
+// @Summary      Complete a forced password reset
+// @Description  Replace a password an admin required to be reset, using the single-use reset code that was emailed or handed over by the admin. Login is refused with PASSWORD_RESET_REQUIRED until this is done. Returns fresh tokens.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      auth.ResetPasswordRequest  true  "Reset code and new password"
+// @Success      200   {object}  auth.AuthResponse
+// @Failure      400   {object}  shared.Error  "Invalid, used or expired reset code, or password rejected"
+// @Failure      403   {object}  shared.Error
+// @Router       /api/auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var body auth.ResetPasswordRequest
+	if err := c.BodyParser(&body); err != nil {
+		shared.Log.Debug("Invalid request body", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("Invalid request body")
+	}
+	if body.Token == "" || body.NewPassword == "" {
+		return shared.ErrBadRequest.WithDetails("token and new_password are required")
+	}
+
+	res, err := h.authService.ResetPassword(c.Context(), body.Token, body.NewPassword, c.IP())
+	if err != nil {
+		shared.Log.Error("Password reset failed", zap.Error(err))
+		return err
+	}
+
+	return c.JSON(res)
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email address
+// @Description  Confirm ownership of an email address using the token from the verification link
+// @Tags         Auth
+// @Produce      json
+// @Param        token  query     string  true  "Verification token"
+// @Success      200    {object}  map[string]string
+// @Failure      400    {object}  shared.Error
+// @Failure      500    {object}  shared.Error
+// @Router       /auth/verify-email [get]
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var query auth.VerifyEmailRequest
+	if err := c.QueryParser(&query); err != nil || query.Token == "" {
+		shared.Log.Debug("Missing verification token", zap.String("path", c.Path()))
+		return shared.ErrInvalidVerificationToken.WithDetails("verification token is required")
+	}
+
+	if err := h.authService.VerifyEmail(c.Context(), query.Token); err != nil {
+		shared.Log.Debug("Verify email failed", zap.Error(err))
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Email verified successfully",
+	})
+}
+
+// ResendVerification godoc
+// @Summary      Resend verification email
+// @Description  Send a new verification link to the authenticated user's email address
+// @Tags         Auth
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200   {object}  map[string]string
+// @Failure      400   {object}  shared.Error
+// @Failure      401   {object}  shared.Error
+// @Failure      500   {object}  shared.Error
+// @Router       /auth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	claims, ok := c.Locals("userClaims").(*domain.TokenClaims)
+	if !ok || claims == nil {
+		shared.Log.Debug("Invalid user claims", zap.ByteString("body", c.Body()))
+		return shared.ErrUnauthorized.WithDetails("Invalid user claims")
+	}
+
+	if err := h.authService.ResendVerification(c.Context(), claims.UserID); err != nil {
+		shared.Log.Error("Resend verification failed", zap.Error(err))
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Verification email sent",
+	})
+}
+
+// OIDCLogin godoc
+// @Summary      Start single sign-on
+// @Description  Redirect to the OpenID Connect provider. Pass redirect=false to get the URL as JSON instead
+// @Tags         Auth
+// @Produce      json
+// @Param        redirect  query     bool  false  "Redirect to the provider (default true)"
+// @Success      200       {object}  auth.OIDCLoginResponse
+// @Success      302
+// @Failure      503       {object}  shared.Error
+// @Router       /auth/oidc/login [get]
+func (h *AuthHandler) OIDCLogin(c *fiber.Ctx) error {
+	authorizationURL, err := h.authService.BeginOIDCLogin(c.Context())
+	if err != nil {
+		shared.Log.Error("Begin OIDC login failed", zap.Error(err))
+		return err
+	}
+
+	if c.Query("redirect") == "false" {
+		return c.JSON(auth.OIDCLoginResponse{AuthorizationURL: authorizationURL})
+	}
+	return c.Redirect(authorizationURL, fiber.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary      Complete single sign-on
+// @Description  Exchange the authorization code returned by the OpenID Connect provider for session tokens
+// @Tags         Auth
+// @Produce      json
+// @Param        code   query     string  true  "Authorization code"
+// @Param        state  query     string  true  "State returned by the provider"
+// @Success      200    {object}  auth.AuthResponse
+// @Failure      400    {object}  shared.Error
+// @Failure      401    {object}  shared.Error
+// @Failure      403    {object}  shared.Error
+// @Failure      503    {object}  shared.Error
+// @Router       /auth/oidc/callback [get]
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	var query auth.OIDCCallbackRequest
+	if err := c.QueryParser(&query); err != nil {
+		return shared.ErrBadRequest.WithDetails("Invalid callback parameters")
+	}
+
+	if query.Error != "" {
+		shared.Log.Debug("Identity provider returned an error",
+			zap.String("error", query.Error),
+			zap.String("description", query.ErrorDescription))
+		return shared.ErrUnauthorized.WithDetails("identity provider denied the login: " + query.Error)
+	}
+	if query.Code == "" || query.State == "" {
+		return shared.ErrBadRequest.WithDetails("code and state are required")
+	}
+
+	res, err := h.authService.CompleteOIDCLogin(c.Context(), query.Code, query.State)
+	if err != nil {
+		shared.Log.Error("OIDC login failed", zap.Error(err))
+		return err
+	}
+
+	return c.JSON(res)
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/dto/contact"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type ContactHandler struct {
+	contactService *application.ContactService
+}
+
+func NewContactHandler(contactService *application.ContactService) *ContactHandler {
+	return &ContactHandler{contactService: contactService}
+}
+
+// ListContacts godoc
+// @Summary      List contacts
+// @Description  List the accepted contacts of the authenticated user
+// @Tags         Contacts
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   contact.ContactResponse
+// @Failure      401  {object}  shared.Error
+// @Router       /api/contacts [get]
+func (h *ContactHandler) ListContacts(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	contacts, err := h.contactService.ListContacts(c.Context(), claims.UserID)
+	if err != nil {
+		shared.Log.Error("Failed to list contacts", zap.Error(err))
+		return err
+	}
+
+	response := make([]contact.ContactResponse, 0, len(contacts))
+	for i := range contacts {
+		other := contacts[i].Other(claims.UserID)
+		response = append(response, contact.ContactResponse{
+			UserID:     other.ID,
+			Username:   other.Username,
+			IsBot:      other.IsBot,
+			AcceptedAt: contacts[i].AcceptedAt,
+		})
+	}
+	return c.JSON(response)
+}
+
+// ListRequests godoc
+// @Summary      List pending contact requests
+// @Description  List incoming and outgoing contact requests that have not been answered yet
+// @Tags         Contacts
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   contact.RequestResponse
+// @Failure      401  {object}  shared.Error
+// @Router       /api/contacts/requests [get]
+func (h *ContactHandler) ListRequests(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	requests, err := h.contactService.ListRequests(c.Context(), claims.UserID)
+	if err != nil {
+		shared.Log.Error("Failed to list contact requests", zap.Error(err))
+		return err
+	}
+
+	response := make([]contact.RequestResponse, 0, len(requests))
+	for i := range requests {
+		response = append(response, toRequestResponse(&requests[i], claims.UserID))
+	}
+	return c.JSON(response)
+}
+
+// SendRequest godoc
+// @Summary      Send a contact request
+// @Description  Ask another user to become a contact. If they already asked you, the request is accepted instead.
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      contact.SendRequestRequest  true  "User to add"
+// @Success      201   {object}  contact.RequestResponse
+// @Failure      400   {object}  shared.Error
+// @Failure      404   {object}  shared.Error
+// @Failure      409   {object}  shared.Error
+// @Router       /api/contacts/requests [post]
+func (h *ContactHandler) SendRequest(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	var body contact.SendRequestRequest
+	if err := c.BodyParser(&body); err != nil || body.UserID == 0 {
+		shared.Log.Debug("Invalid contact request body", zap.ByteString("body", c.Body()))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user_id")
+	}
+
+	created, err := h.contactService.SendRequest(c.Context(), claims.UserID, body.UserID)
+	if err != nil {
+		shared.Log.Error("Failed to send contact request", zap.Error(err), zap.Uint("addresseeID", body.UserID))
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(toRequestResponse(created, claims.UserID))
+}
+
+// AcceptRequest godoc
+// @Summary      Accept a contact request
+// @Tags         Contacts
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "Request ID"
+// @Success      200  {object}  contact.RequestResponse
+// @Failure      404  {object}  shared.Error
+// @Failure      409  {object}  shared.Error
+// @Router       /api/contacts/requests/{id}/accept [post]
+func (h *ContactHandler) AcceptRequest(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	requestID, err := c.ParamsInt("id")
+	if err != nil || requestID <= 0 {
+		shared.Log.Debug("Invalid contact request ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing request ID")
+	}
+
+	accepted, err := h.contactService.Accept(c.Context(), claims.UserID, uint(requestID))
+	if err != nil {
+		shared.Log.Error("Failed to accept contact request", zap.Error(err), zap.Int("requestID", requestID))
+		return err
+	}
+
+	return c.JSON(toRequestResponse(accepted, claims.UserID))
+}
+
+// DeclineRequest godoc
+// @Summary      Decline or withdraw a contact request
+// @Description  The addressee declines a request, the requester withdraws it
+// @Tags         Contacts
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "Request ID"
+// @Success      204
+// @Failure      404  {object}  shared.Error
+// @Router       /api/contacts/requests/{id}/decline [post]
+func (h *ContactHandler) DeclineRequest(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	requestID, err := c.ParamsInt("id")
+	if err != nil || requestID <= 0 {
+		shared.Log.Debug("Invalid contact request ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing request ID")
+	}
+
+	if err := h.contactService.Decline(c.Context(), claims.UserID, uint(requestID)); err != nil {
+		shared.Log.Error("Failed to decline contact request", zap.Error(err), zap.Int("requestID", requestID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RemoveContact godoc
+// @Summary      Remove a contact
+// @Tags         Contacts
+// @Security     ApiKeyAuth
+// @Param        userID  path  int  true  "Contact's user ID"
+// @Success      204
+// @Failure      404  {object}  shared.Error
+// @Router       /api/contacts/{userID} [delete]
+func (h *ContactHandler) RemoveContact(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	otherID, err := c.ParamsInt("userID")
+	if err != nil || otherID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("userID", c.Params("userID")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+
+	if err := h.contactService.Remove(c.Context(), claims.UserID, uint(otherID)); err != nil {
+		shared.Log.Error("Failed to remove contact", zap.Error(err), zap.Int("otherID", otherID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func toRequestResponse(c *domain.Contact, viewerID uint) contact.RequestResponse {
+	other := c.Other(viewerID)
+	direction := "outgoing"
+	if c.AddresseeID == viewerID {
+		direction = "incoming"
+	}
+	return contact.RequestResponse{
+		ID:        c.ID,
+		UserID:    other.ID,
+		Username:  other.Username,
+		Direction: direction,
+		Status:    string(c.Status),
+		CreatedAt: c.CreatedAt,
+	}
+}
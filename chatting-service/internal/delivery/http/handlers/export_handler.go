@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/dto/export"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type ExportHandler struct {
+	exportService *application.ExportService
+}
+
+func NewExportHandler(exportService *application.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: exportService}
+}
+
+// RequestExport godoc
+// @Summary      Request a personal data export
+// @Description  Start building an archive of your profile, settings, messages and media. If an export is already running it is returned instead.
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      202  {object}  export.ExportResponse
+// @Failure      401  {object}  shared.Error
+// @Router       /api/users/me/export [post]
+func (h *ExportHandler) RequestExport(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	job, err := h.exportService.Request(c.Context(), claims.UserID)
+	if err != nil {
+		shared.Log.Error("Failed to request data export", zap.Error(err), zap.Uint("userID", claims.UserID))
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(h.toResponse(job))
+}
+
+// GetExport godoc
+// @Summary      Get the latest data export
+// @Description  Progress of the most recent export, with a time-limited download link once it is ready
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  export.ExportResponse
+// @Failure      401  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/users/me/export [get]
+func (h *ExportHandler) GetExport(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	job, err := h.exportService.Latest(c.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(h.toResponse(job))
+}
+
+// DownloadExport godoc
+// @Summary      Download a data export
+// @Description  Stream the export archive. The link from GET /api/users/me/export carries its own signature, no login is needed.
+// @Tags         Users
+// @Produce      application/zip
+// @Param        id         path   int     true  "Export ID"
+// @Param        expires    query  int     true  "Link expiry (unix seconds)"
+// @Param        signature  query  string  true  "Link signature"
+// @Success      200
+// @Failure      403  {object}  shared.Error
+// @Router       /api/exports/{id}/download [get]
+func (h *ExportHandler) DownloadExport(c *fiber.Ctx) error {
+	exportID, err := c.ParamsInt("id")
+	if err != nil || exportID <= 0 {
+		return shared.ErrBadRequest.WithDetails("Invalid or missing export ID")
+	}
+	expires := c.QueryInt("expires")
+	signature := c.Query("signature")
+	if expires <= 0 || signature == "" {
+		return shared.ErrForbidden.WithDetails("invalid download link")
+	}
+
+	archive, size, err := h.exportService.OpenDownload(c.Context(), uint(exportID), int64(expires), signature)
+	if err != nil {
+		shared.Log.Warn("Export download refused", zap.Error(err), zap.Int("exportID", exportID))
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="data-export-%d.zip"`, exportID))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	// Fiber closes the reader once the body is written
+	return c.SendStream(archive, int(size))
+}
+
+func (h *ExportHandler) toResponse(job *domain.DataExport) export.ExportResponse {
+	return export.ExportResponse{
+		ID:          job.ID,
+		Status:      string(job.Status),
+		Progress:    job.Progress,
+		Error:       job.Error,
+		Size:        job.Size,
+		DownloadURL: h.exportService.DownloadURL(job),
+		ExpiresAt:   job.ExpiresAt,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+}
@@ -1,33 +1,45 @@
 package handlers
 
 import (
+	"io"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strconv"
+
 	"github.com/AmeerHeiba/chatting-service/internal/application"
 	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/dto/media"
 	"github.com/AmeerHeiba/chatting-service/internal/shared"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
+const uploadOffsetHeader = "Upload-Offset"
+
 type MediaHandler struct {
-	mediaService *application.MediaService
+	mediaService  *application.MediaService
+	uploadService *application.ResumableUploadService
 }
 
-func NewMediaHandler(mediaService *application.MediaService) *MediaHandler {
-	return &MediaHandler{mediaService: mediaService}
+func NewMediaHandler(mediaService *application.MediaService, uploadService *application.ResumableUploadService) *MediaHandler {
+	return &MediaHandler{mediaService: mediaService, uploadService: uploadService}
 }
 
 // Upload handles media file upload for authenticated users.
 //
 // @Summary Upload a media file
-// @Description Upload a media file (JPEG, PNG, PDF) with a maximum size of 10MB.
+// @Description Upload a media file. The type is detected from the content and must be allowed by MEDIA_ALLOWED_TYPES (JPEG, PNG and PDF up to 10MB by default).
 // @Tags Media
 // @Accept multipart/form-data
 // @Produce json
 // @Security ApiKeyAuth
-// @Param file formData file true "Media File (JPEG, PNG, PDF)"
+// @Param file formData file true "Media file"
 // @Success 200 {object} domain.MediaResponse "Successfully uploaded"
 // @Failure 400 {object} shared.Error "Bad request (missing or invalid file)"
 // @Failure 401 {object} shared.Error "Unauthorized"
+// @Failure 413 {object} shared.Error "Storage quota exceeded"
+// @Failure 415 {object} shared.Error "File type not allowed or not matching its content"
 // @Failure 500 {object} shared.Error "Internal server error"
 // @Router /api/media/upload [post]
 func (h *MediaHandler) Upload(c *fiber.Ctx) error {
@@ -67,3 +79,325 @@ func (h *MediaHandler) Upload(c *fiber.Ctx) error {
 
 	return c.JSON(response)
 }
+
+// CreateUpload godoc
+// @Summary      Start a resumable upload
+// @Description  For files too large for a single request. Send the content with PATCH in chunks of at most chunk_size bytes, then complete the upload. Allowed types without their own limit may go up to MEDIA_RESUMABLE_MAX_MB, images keep the direct upload limit.
+// @Tags         Media
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request  body      media.CreateUploadRequest  true  "File to upload"
+// @Success      201      {object}  media.UploadResponse
+// @Failure      400      {object}  shared.Error
+// @Failure      401      {object}  shared.Error
+// @Failure      413      {object}  shared.Error  "Storage quota exceeded"
+// @Failure      415      {object}  shared.Error  "File type not allowed"
+// @Router       /api/media/uploads [post]
+func (h *MediaHandler) CreateUpload(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	var req media.CreateUploadRequest
+	if err := c.BodyParser(&req); err != nil {
+		shared.Log.Debug("Invalid upload request body", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("Invalid request body")
+	}
+
+	upload, err := h.uploadService.Create(c.Context(), claims.UserID, req.Filename, req.ContentType, req.Size, req.Checksum)
+	if err != nil {
+		shared.Log.Error("Failed to create upload", zap.Error(err), zap.Uint("userID", claims.UserID))
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(h.toUploadResponse(c, upload))
+}
+
+// GetUpload godoc
+// @Summary      Check a resumable upload
+// @Description  Tells where the next chunk starts, e.g. after a dropped connection
+// @Tags         Media
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Upload ID"
+// @Success      200  {object}  media.UploadResponse
+// @Failure      401  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/media/uploads/{id} [get]
+func (h *MediaHandler) GetUpload(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	upload, err := h.uploadService.Get(c.Context(), claims.UserID, c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(h.toUploadResponse(c, upload))
+}
+
+// UploadChunk godoc
+// @Summary      Send a chunk of a resumable upload
+// @Description  The raw bytes go in the body, Upload-Offset must be the offset the upload is at
+// @Tags         Media
+// @Accept       application/offset+octet-stream
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id             path      string  true  "Upload ID"
+// @Param        Upload-Offset  header    int     true  "Where the chunk starts"
+// @Success      200  {object}  media.UploadResponse
+// @Failure      400  {object}  shared.Error
+// @Failure      401  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Failure      409  {object}  shared.Error  "Offset does not match the upload"
+// @Router       /api/media/uploads/{id} [patch]
+func (h *MediaHandler) UploadChunk(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	offset, err := strconv.ParseInt(c.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		shared.Log.Debug("Invalid upload offset", zap.String("offset", c.Get(uploadOffsetHeader)))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing Upload-Offset header")
+	}
+
+	upload, err := h.uploadService.WriteChunk(c.Context(), claims.UserID, c.Params("id"), offset, c.Body())
+	if err != nil {
+		shared.Log.Debug("Failed to write upload chunk", zap.Error(err), zap.Int64("offset", offset))
+		return err
+	}
+	return c.JSON(h.toUploadResponse(c, upload))
+}
+
+// CompleteUpload godoc
+// @Summary      Finish a resumable upload
+// @Description  Joins the chunks into a media library entry once every byte arrived. A checksum mismatch or a type that is not allowed ends the upload.
+// @Tags         Media
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Upload ID"
+// @Success      200  {object}  domain.MediaResponse
+// @Failure      400  {object}  shared.Error  "Upload incomplete or checksum mismatch"
+// @Failure      401  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Failure      413  {object}  shared.Error  "Storage quota exceeded"
+// @Failure      415  {object}  shared.Error  "File type not allowed or not matching its content"
+// @Router       /api/media/uploads/{id}/complete [post]
+func (h *MediaHandler) CompleteUpload(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	response, err := h.uploadService.Complete(c.UserContext(), claims.UserID, c.Params("id"))
+	if err != nil {
+		shared.Log.Error("Failed to complete upload", zap.Error(err), zap.Uint("userID", claims.UserID))
+		return err
+	}
+	return c.JSON(response)
+}
+
+// CancelUpload godoc
+// @Summary      Abort a resumable upload
+// @Description  Drops the chunks received so far
+// @Tags         Media
+// @Security     ApiKeyAuth
+// @Param        id   path  string  true  "Upload ID"
+// @Success      204
+// @Failure      401  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/media/uploads/{id} [delete]
+func (h *MediaHandler) CancelUpload(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	if err := h.uploadService.Cancel(c.Context(), claims.UserID, c.Params("id")); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// toUploadResponse also sets Upload-Offset, so clients can resume from the headers alone
+func (h *MediaHandler) toUploadResponse(c *fiber.Ctx, upload *domain.MediaUploadSession) media.UploadResponse {
+	c.Set(uploadOffsetHeader, strconv.FormatInt(upload.Received, 10))
+	return media.UploadResponse{
+		ID:          upload.ID,
+		Filename:    upload.Filename,
+		ContentType: upload.ContentType,
+		Size:        upload.Size,
+		Offset:      upload.Received,
+		ChunkSize:   h.uploadService.ChunkSize(),
+		ExpiresAt:   upload.ExpiresAt,
+	}
+}
+
+// Usage godoc
+// @Summary      My storage usage
+// @Description  Bytes taken by the caller's media library and what is left of their quota. Every upload counts in full, also when its content is shared. Quota and remaining are null without a limit.
+// @Tags         Media
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  domain.StorageUsage
+// @Failure      401  {object}  shared.Error
+// @Router       /api/media/usage [get]
+func (h *MediaHandler) Usage(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	usage, err := h.mediaService.Usage(c.Context(), claims.UserID)
+	if err != nil {
+		shared.Log.Error("Failed to get media usage", zap.Error(err), zap.Uint("userID", claims.UserID))
+		return err
+	}
+	return c.JSON(usage)
+}
+
+// ListMedia godoc
+// @Summary      List my media
+// @Description  Pages through the caller's uploads, newest first
+// @Tags         Media
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        type    query     string  false  "Content type (image/png) or top-level type (image)"
+// @Param        limit   query     int     false  "Max items (default 50, max 200)"
+// @Param        offset  query     int     false  "Offset"
+// @Success      200  {object}  media.MediaListResponse
+// @Failure      400  {object}  shared.Error
+// @Failure      401  {object}  shared.Error
+// @Router       /api/media [get]
+func (h *MediaHandler) ListMedia(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	var req media.MediaListRequest
+	if err := c.QueryParser(&req); err != nil {
+		shared.Log.Debug("Invalid media list query", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("Invalid query params").WithDetails(err.Error())
+	}
+
+	page, err := h.mediaService.ListMedia(c.Context(), domain.MediaFilter{
+		OwnerID: claims.UserID,
+		Type:    req.Type,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+	})
+	if err != nil {
+		shared.Log.Error("Failed to list media", zap.Error(err))
+		return err
+	}
+
+	response := media.MediaListResponse{
+		Media:  make([]media.MediaItemResponse, len(page.Items)),
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+	for i, item := range page.Items {
+		response.Media[i] = media.MediaItemResponse{
+			ID:               item.ID,
+			URL:              item.URL,
+			OriginalFilename: item.OriginalFilename,
+			ContentType:      item.ContentType,
+			Size:             item.Size,
+			Checksum:         item.Checksum,
+			Width:            item.Width,
+			Height:           item.Height,
+			CreatedAt:        item.CreatedAt,
+		}
+		for _, thumb := range item.Thumbnails {
+			response.Media[i].Thumbnails = append(response.Media[i].Thumbnails, media.ThumbnailResponse{
+				Size:   thumb.Size,
+				Width:  thumb.Width,
+				Height: thumb.Height,
+				URL:    thumb.URL,
+			})
+		}
+	}
+	return c.JSON(response)
+}
+
+// DeleteMedia godoc
+// @Summary      Delete one of my uploads
+// @Description  Removes the file and its library entry. Messages that link it stop showing the media.
+// @Tags         Media
+// @Security     ApiKeyAuth
+// @Param        id   path  int  true  "Media ID"
+// @Success      204
+// @Failure      400  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/media/{id} [delete]
+func (h *MediaHandler) DeleteMedia(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	mediaID, err := c.ParamsInt("id")
+	if err != nil || mediaID <= 0 {
+		shared.Log.Debug("Invalid media ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing media ID")
+	}
+
+	if err := h.mediaService.DeleteMedia(c.Context(), claims.UserID, uint(mediaID)); err != nil {
+		shared.Log.Error("Failed to delete media", zap.Error(err), zap.Int("mediaID", mediaID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ServeSigned streams a file for a signed link. Requests without a signature go on
+// to the authenticated ServeFile.
+//
+// @Summary Download media with a signed link
+// @Description Links come from the signed_url of an upload and stop working at expires_at. No login is needed.
+// @Tags Media
+// @Param path path string true "Media path, e.g. user_1/1_1700000000_abcd.png"
+// @Param expires query int true "Link expiry (unix seconds)"
+// @Param signature query string true "Link signature"
+// @Success 200
+// @Failure 403 {object} shared.Error "Invalid or expired link"
+// @Failure 404 {object} shared.Error "Not found"
+// @Router /media/{path} [get]
+func (h *MediaHandler) ServeSigned(c *fiber.Ctx) error {
+	signature := c.Query("signature")
+	if signature == "" {
+		return c.Next()
+	}
+	path, err := mediaPathParam(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := h.mediaService.OpenSigned(c.UserContext(), path, int64(c.QueryInt("expires")), signature)
+	if err != nil {
+		return err
+	}
+	return sendMedia(c, path, file)
+}
+
+// ServeFile streams a file the caller may see: their own uploads, avatars, and media
+// of messages and announcements they can read.
+//
+// @Summary Download media
+// @Tags Media
+// @Security ApiKeyAuth
+// @Param path path string true "Media path, e.g. user_1/1_1700000000_abcd.png"
+// @Success 200
+// @Failure 401 {object} shared.Error "Unauthorized"
+// @Failure 404 {object} shared.Error "Not found or not shared with you"
+// @Router /media/{path} [get]
+func (h *MediaHandler) ServeFile(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	path, err := mediaPathParam(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := h.mediaService.OpenForUser(c.UserContext(), claims.UserID, claims.Role, path)
+	if err != nil {
+		return err
+	}
+	return sendMedia(c, path, file)
+}
+
+func mediaPathParam(c *fiber.Ctx) (string, error) {
+	path, err := url.PathUnescape(c.Params("*"))
+	if err != nil || path == "" {
+		shared.Log.Debug("Invalid media path", zap.String("path", c.Params("*")))
+		return "", shared.ErrBadRequest.WithDetails("Invalid or missing media path")
+	}
+	return path, nil
+}
+
+// sendMedia streams the file with the type its extension names. Extensions come from the
+// sniffed content type, the response is still sandboxed in case a file predates that.
+func sendMedia(c *fiber.Ctx, path string, file io.ReadCloser) error {
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = fiber.MIMEOctetStream
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; sandbox")
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return c.SendStream(file)
+}
@@ -212,6 +212,28 @@ func (h *MessageHandler) MarkAsRead(c *fiber.Ctx) error {
 	})
 }
 
+// GetUnreadCount returns the unread badge total
+// @Summary Unread message count
+// @Description Count unread direct messages and broadcasts for the logged-in user. Messages that arrived muted are not counted.
+// @Tags Messages
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} message.UnreadCountResponse
+// @Failure 401 {object} shared.Error
+// @Failure 500 {object} shared.Error
+// @Router /api/messages/unread-count [get]
+func (h *MessageHandler) GetUnreadCount(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	count, err := h.messageService.CountUnread(c.Context(), claims.UserID)
+	if err != nil {
+		shared.Log.Error("Failed to count unread messages", zap.Error(err))
+		return err
+	}
+
+	return c.JSON(message.UnreadCountResponse{Unread: count})
+}
+
 // DeleteMessage deletes a message
 // @Summary Delete message
 // @Description Delete a message by ID (only by the sender)
@@ -269,8 +291,32 @@ func toMessageResponse(m *domain.Message) message.MessageResponse {
 		Status:   string(m.Status),
 		SenderID: m.SenderID,
 		SentAt:   m.SentAt,
+		Erased:   m.Erased,
 	}
 
+	if m.Media != nil {
+		resp.Media = &message.Media{
+			ContentType: m.Media.ContentType,
+			Width:       m.Media.Width,
+			Height:      m.Media.Height,
+		}
+		for _, thumb := range m.Media.Thumbnails {
+			resp.Media.Thumbnails = append(resp.Media.Thumbnails, message.Thumbnail{
+				Size:   thumb.Size,
+				Width:  thumb.Width,
+				Height: thumb.Height,
+				URL:    thumb.URL,
+			})
+		}
+	}
+	if sender := m.Sender.SenderInfo(); sender != nil {
+		resp.Sender = &message.Sender{
+			ID:          sender.ID,
+			Username:    sender.Username,
+			DisplayName: sender.DisplayName,
+			AvatarURL:   sender.AvatarURL,
+		}
+	}
 	if m.RecipientID != nil {
 		resp.RecipientID = *m.RecipientID
 	}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/dto/token"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type TokenHandler struct {
+	tokenService *application.APITokenService
+	userService  *application.UserService
+}
+
+func NewTokenHandler(tokenService *application.APITokenService, userService *application.UserService) *TokenHandler {
+	return &TokenHandler{
+		tokenService: tokenService,
+		userService:  userService,
+	}
+}
+
+// CreateToken godoc
+// @Summary      Create a personal access token
+// @Description  Create a long-lived, scoped API token for the authenticated user. The token is only shown once.
+// @Tags         Tokens
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      token.CreateTokenRequest  true  "Token details"
+// @Success      200   {object}  token.CreatedTokenResponse
+// @Failure      400   {object}  shared.Error
+// @Failure      401   {object}  shared.Error
+// @Router       /api/tokens [post]
+func (h *TokenHandler) CreateToken(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	return h.createToken(c, claims.UserID, claims.UserID)
+}
+
+// ListTokens godoc
+// @Summary      List personal access tokens
+// @Description  List API tokens of the authenticated user (token values are never returned)
+// @Tags         Tokens
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   token.TokenResponse
+// @Failure      401  {object}  shared.Error
+// @Router       /api/tokens [get]
+func (h *TokenHandler) ListTokens(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	return h.listTokens(c, claims.UserID, claims.UserID)
+}
+
+// RevokeToken godoc
+// @Summary      Revoke a personal access token
+// @Description  Revoke one of your tokens or a token of a bot you own
+// @Tags         Tokens
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "Token ID"
+// @Success      200  {object}  map[string]string
+// @Failure      403  {object}  shared.Error
+// @Failure      404  {object}  shared.Error
+// @Router       /api/tokens/{id} [delete]
+func (h *TokenHandler) RevokeToken(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	tokenID, err := c.ParamsInt("id")
+	if err != nil || tokenID <= 0 {
+		shared.Log.Debug("Invalid token ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing token ID")
+	}
+
+	if err := h.tokenService.Revoke(c.Context(), claims.UserID, uint(tokenID)); err != nil {
+		shared.Log.Error("Failed to revoke token", zap.Error(err), zap.Int("tokenID", tokenID))
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Token revoked",
+	})
+}
+
+// CreateBot godoc
+// @Summary      Create a bot account
+// @Description  Create a bot user owned by the authenticated user. Bots authenticate with API tokens only.
+// @Tags         Tokens
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      token.CreateBotRequest  true  "Bot details"
+// @Success      200   {object}  token.BotResponse
+// @Failure      400   {object}  shared.Error
+// @Failure      409   {object}  shared.Error
+// @Router       /api/bots [post]
+func (h *TokenHandler) CreateBot(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	var body token.CreateBotRequest
+	if err := c.BodyParser(&body); err != nil {
+		shared.Log.Debug("Invalid request body", zap.Error(err), zap.ByteString("body", c.Body()))
+		return shared.ErrBadRequest.WithDetails("Invalid request body").WithDetails(err.Error())
+	}
+
+	bot, err := h.userService.CreateBot(c.Context(), claims.UserID, body.Username)
+	if err != nil {
+		shared.Log.Error("Failed to create bot", zap.Error(err))
+		return err
+	}
+
+	return c.JSON(toBotResponse(bot))
+}
+
+// ListBots godoc
+// @Summary      List bot accounts
+// @Description  List bots owned by the authenticated user
+// @Tags         Tokens
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   token.BotResponse
+// @Failure      401  {object}  shared.Error
+// @Router       /api/bots [get]
+func (h *TokenHandler) ListBots(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	bots, err := h.userService.ListBots(c.Context(), claims.UserID)
+	if err != nil {
+		shared.Log.Error("Failed to list bots", zap.Error(err))
+		return err
+	}
+
+	response := make([]token.BotResponse, len(bots))
+	for i, bot := range bots {
+		response[i] = toBotResponse(bot)
+	}
+	return c.JSON(response)
+}
+
+// CreateBotToken godoc
+// @Summary      Create a token for a bot
+// @Description  Create a scoped API token for a bot you own. The token is only shown once.
+// @Tags         Tokens
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id    path      int                       true  "Bot user ID"
+// @Param        body  body      token.CreateTokenRequest  true  "Token details"
+// @Success      200   {object}  token.CreatedTokenResponse
+// @Failure      400   {object}  shared.Error
+// @Failure      403   {object}  shared.Error
+// @Router       /api/bots/{id}/tokens [post]
+func (h *TokenHandler) CreateBotToken(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	botID, err := c.ParamsInt("id")
+	if err != nil || botID <= 0 {
+		shared.Log.Debug("Invalid bot ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing bot ID")
+	}
+	return h.createToken(c, claims.UserID, uint(botID))
+}
+
+// ListBotTokens godoc
+// @Summary      List tokens of a bot
+// @Description  List API tokens of a bot you own
+// @Tags         Tokens
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "Bot user ID"
+// @Success      200  {array}   token.TokenResponse
+// @Failure      403  {object}  shared.Error
+// @Router       /api/bots/{id}/tokens [get]
+func (h *TokenHandler) ListBotTokens(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	botID, err := c.ParamsInt("id")
+	if err != nil || botID <= 0 {
+		shared.Log.Debug("Invalid bot ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing bot ID")
+	}
+	return h.listTokens(c, claims.UserID, uint(botID))
+}
+
+func (h *TokenHandler) createToken(c *fiber.Ctx, callerID, userID uint) error {
+	var body token.CreateTokenRequest
+	if err := c.BodyParser(&body); err != nil {
+		shared.Log.Debug("Invalid request body", zap.Error(err), zap.ByteString("body", c.Body()))
+		return shared.ErrBadRequest.WithDetails("Invalid request body").WithDetails(err.Error())
+	}
+
+	scopes := make([]domain.TokenScope, len(body.Scopes))
+	for i, s := range body.Scopes {
+		scopes[i] = domain.TokenScope(s)
+	}
+	expiresIn := time.Duration(body.ExpiresInDays) * 24 * time.Hour
+
+	plain, created, err := h.tokenService.Create(c.Context(), callerID, userID, body.Name, scopes, expiresIn)
+	if err != nil {
+		shared.Log.Error("Failed to create token", zap.Error(err), zap.Uint("userID", userID))
+		return err
+	}
+
+	return c.JSON(token.CreatedTokenResponse{
+		TokenResponse: toTokenResponse(created),
+		Token:         plain,
+	})
+}
+
+func (h *TokenHandler) listTokens(c *fiber.Ctx, callerID, userID uint) error {
+	tokens, err := h.tokenService.List(c.Context(), callerID, userID)
+	if err != nil {
+		shared.Log.Error("Failed to list tokens", zap.Error(err), zap.Uint("userID", userID))
+		return err
+	}
+
+	response := make([]token.TokenResponse, len(tokens))
+	for i := range tokens {
+		response[i] = toTokenResponse(&tokens[i])
+	}
+	return c.JSON(response)
+}
+
+func toTokenResponse(t *domain.APIToken) token.TokenResponse {
+	scopes := t.ScopeList()
+	resp := token.TokenResponse{
+		ID:         t.ID,
+		UserID:     t.UserID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     make([]string, len(scopes)),
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		RevokedAt:  t.RevokedAt,
+	}
+	for i, s := range scopes {
+		resp.Scopes[i] = string(s)
+	}
+	return resp
+}
+
+func toBotResponse(u *domain.User) token.BotResponse {
+	resp := token.BotResponse{
+		ID:        u.ID,
+		Username:  u.Username,
+		IsBot:     u.IsBot,
+		CreatedAt: u.CreatedAt,
+	}
+	if u.BotOwnerID != nil {
+		resp.OwnerID = *u.BotOwnerID
+	}
+	return resp
+}
@@ -11,11 +11,13 @@ import (
 )
 
 type UserHandler struct {
-	userService *application.UserService
+	userService       *application.UserService
+	preferenceService *application.NotificationPreferenceService
+	accountService    *application.AccountService
 }
 
-func NewUserHandler(userService *application.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService *application.UserService, preferenceService *application.NotificationPreferenceService, accountService *application.AccountService) *UserHandler {
+	return &UserHandler{userService: userService, preferenceService: preferenceService, accountService: accountService}
 }
 
 func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
@@ -28,13 +30,7 @@ func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
 		return err
 	}
 
-	return c.JSON(user.ProfileResponse{
-		ID:         profile.ID,
-		Username:   profile.Username,
-		Email:      profile.Email,
-		LastActive: profile.LastActiveAt,
-		Status:     string(profile.Status),
-	})
+	return c.JSON(toProfileResponse(profile))
 }
 
 func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
@@ -48,19 +44,89 @@ func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
 		return shared.ErrBadRequest.WithDetails("Invalid request body failed to parse request body").WithDetails(err.Error())
 	}
 
-	updatedUser, err := h.userService.UpdateProfile(c.Context(), claims.UserID, body.Username, body.Email)
+	details := domain.ProfileDetails{
+		DisplayName: body.DisplayName,
+		Bio:         body.Bio,
+		TimeZone:    body.TimeZone,
+	}
+	updatedUser, err := h.userService.UpdateProfile(c.Context(), claims.UserID, body.Username, body.Email, details)
 	if err != nil {
 		shared.Log.Error("Failed to update user profile", zap.Error(err))
 		return err
 	}
 
-	return c.JSON(user.ProfileResponse{
-		ID:         updatedUser.ID,
-		Username:   updatedUser.Username,
-		Email:      updatedUser.Email,
-		LastActive: updatedUser.LastActiveAt,
-		Status:     string(updatedUser.Status),
-	})
+	return c.JSON(toProfileResponse(updatedUser))
+}
+
+func (h *UserHandler) GetPublicProfile(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+
+	profile, err := h.userService.GetPublicProfile(c.Context(), claims.UserID, uint(userID))
+	if err != nil {
+		shared.Log.Debug("Failed to get public profile", zap.Error(err), zap.Int("userID", userID))
+		return err
+	}
+
+	showEmail := claims.Role == domain.RoleAdmin || profile.ID == claims.UserID
+	return c.JSON(toPublicProfileResponse(profile, showEmail))
+}
+
+func (h *UserHandler) UploadAvatar(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		shared.Log.Debug("failed to get avatar from form", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("file is required")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		shared.Log.Error("failed to open uploaded avatar", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("invalid file")
+	}
+	defer src.Close()
+
+	profile, err := h.userService.SetAvatar(c.UserContext(), claims.UserID, src, file.Header.Get("Content-Type"), file.Size)
+	if err != nil {
+		shared.Log.Error("Failed to set avatar", zap.Error(err))
+		return err
+	}
+
+	return c.JSON(toProfileResponse(profile))
+}
+
+func (h *UserHandler) DeleteAvatar(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	if err := h.userService.RemoveAvatar(c.UserContext(), claims.UserID); err != nil {
+		shared.Log.Error("Failed to remove avatar", zap.Error(err))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *UserHandler) DeleteAccount(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	var body user.DeleteAccountRequest
+	if err := c.BodyParser(&body); err != nil || body.Password == "" {
+		shared.Log.Debug("Invalid delete account request body", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("password is required to delete the account")
+	}
+
+	if err := h.accountService.DeleteOwnAccount(c.UserContext(), claims.UserID, body.Password); err != nil {
+		shared.Log.Error("Failed to delete account", zap.Error(err))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
 }
 
 func (h *UserHandler) GetMessageHistory(c *fiber.Ctx) error {
@@ -102,13 +168,197 @@ func (h *UserHandler) GetMessageHistory(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
-func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
+func (h *UserHandler) SearchUsers(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
 
-	users, err := h.userService.GettAllUsers(c.Context())
+	var query user.SearchUsersRequest
+	if err := c.QueryParser(&query); err != nil {
+		shared.Log.Debug("Invalid user search query", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("Invalid query params").WithDetails(err.Error())
+	}
+
+	page, err := h.userService.SearchUsers(c.Context(), claims.UserID, query.Q, query.Limit, query.Cursor)
 	if err != nil {
+		shared.Log.Error("Failed to search users", zap.Error(err))
 		return err
 	}
 
-	return c.JSON(users)
+	response := user.DirectoryResponse{
+		Users:      make([]user.PublicProfileResponse, 0, len(page.Users)),
+		NextCursor: page.NextCursor,
+	}
+	for _, u := range page.Users {
+		// Addresses are personal data, only admins see other people's
+		showEmail := claims.Role == domain.RoleAdmin || u.ID == claims.UserID
+		response.Users = append(response.Users, toPublicProfileResponse(u, showEmail))
+	}
+	return c.JSON(response)
+}
+
+func (h *UserHandler) BlockUser(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	blockedID, err := c.ParamsInt("id")
+	if err != nil || blockedID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
+
+	if err := h.userService.BlockUser(c.Context(), claims.UserID, uint(blockedID)); err != nil {
+		shared.Log.Error("Failed to block user", zap.Error(err), zap.Int("blockedID", blockedID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *UserHandler) UnblockUser(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+	blockedID, err := c.ParamsInt("id")
+	if err != nil || blockedID <= 0 {
+		shared.Log.Debug("Invalid user ID", zap.String("id", c.Params("id")))
+		return shared.ErrBadRequest.WithDetails("Invalid or missing user ID")
+	}
 
+	if err := h.userService.UnblockUser(c.Context(), claims.UserID, uint(blockedID)); err != nil {
+		shared.Log.Error("Failed to unblock user", zap.Error(err), zap.Int("blockedID", blockedID))
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *UserHandler) ListBlockedUsers(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	blocks, err := h.userService.ListBlockedUsers(c.Context(), claims.UserID)
+	if err != nil {
+		shared.Log.Error("Failed to list blocked users", zap.Error(err))
+		return err
+	}
+
+	response := make([]user.BlockedUserResponse, 0, len(blocks))
+	for _, block := range blocks {
+		response = append(response, user.BlockedUserResponse{
+			UserID:    block.BlockedID,
+			Username:  block.Blocked.Username,
+			BlockedAt: block.CreatedAt,
+		})
+	}
+	return c.JSON(response)
+}
+
+func (h *UserHandler) GetPreferences(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	prefs, mutes, err := h.preferenceService.Get(c.Context(), claims.UserID)
+	if err != nil {
+		shared.Log.Error("Failed to get notification preferences", zap.Error(err))
+		return err
+	}
+
+	return c.JSON(toPreferencesResponse(prefs, mutes))
+}
+
+func (h *UserHandler) UpdatePreferences(c *fiber.Ctx) error {
+	claims := c.Locals("userClaims").(*domain.TokenClaims)
+
+	var body user.UpdatePreferencesRequest
+	if err := c.BodyParser(&body); err != nil {
+		shared.Log.Debug("Invalid update preferences request body", zap.Error(err))
+		return shared.ErrBadRequest.WithDetails("Invalid request body failed to parse request body").WithDetails(err.Error())
+	}
+
+	prefs := domain.DefaultNotificationPreferences(claims.UserID)
+	prefs.MuteBroadcasts = body.MuteBroadcasts
+	prefs.QuietHoursEnabled = body.QuietHoursEnabled
+	if body.QuietHoursStart != "" {
+		prefs.QuietHoursStart = body.QuietHoursStart
+	}
+	if body.QuietHoursEnd != "" {
+		prefs.QuietHoursEnd = body.QuietHoursEnd
+	}
+	if body.TimeZone != "" {
+		prefs.TimeZone = body.TimeZone
+	}
+
+	mutes := make([]domain.Mute, 0, len(body.Mutes))
+	for _, m := range body.Mutes {
+		mutes = append(mutes, domain.Mute{
+			Scope:        domain.MuteScope(m.Scope),
+			TargetUserID: m.TargetUserID,
+			Until:        m.Until,
+		})
+	}
+
+	if err := h.preferenceService.Update(c.Context(), prefs, mutes); err != nil {
+		shared.Log.Error("Failed to update notification preferences", zap.Error(err))
+		return err
+	}
+
+	saved, active, err := h.preferenceService.Get(c.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(toPreferencesResponse(saved, active))
+}
+
+func toPreferencesResponse(prefs *domain.NotificationPreferences, mutes []domain.Mute) user.PreferencesResponse {
+	response := user.PreferencesResponse{
+		MuteBroadcasts:    prefs.MuteBroadcasts,
+		QuietHoursEnabled: prefs.QuietHoursEnabled,
+		QuietHoursStart:   prefs.QuietHoursStart,
+		QuietHoursEnd:     prefs.QuietHoursEnd,
+		TimeZone:          prefs.TimeZone,
+		Mutes:             make([]user.MuteResponse, 0, len(mutes)),
+	}
+	for _, m := range mutes {
+		response.Mutes = append(response.Mutes, user.MuteResponse{
+			Scope:        string(m.Scope),
+			TargetUserID: m.TargetUserID,
+			Until:        m.Until,
+		})
+	}
+	return response
+}
+
+func toPublicProfileResponse(u *domain.User, showEmail bool) user.PublicProfileResponse {
+	profile := user.PublicProfileResponse{
+		ID:          u.ID,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		TimeZone:    u.TimeZone,
+		Avatar:      toAvatarResponse(u.Avatar),
+		LastActive:  u.LastActiveAt,
+		Status:      string(u.Status),
+		IsBot:       u.IsBot,
+	}
+	if showEmail {
+		profile.Email = u.Email
+	}
+	return profile
+}
+
+func toProfileResponse(u *domain.User) user.ProfileResponse {
+	return user.ProfileResponse{
+		ID:            u.ID,
+		Username:      u.Username,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		DisplayName:   u.DisplayName,
+		Bio:           u.Bio,
+		TimeZone:      u.TimeZone,
+		Avatar:        toAvatarResponse(u.Avatar),
+		LastActive:    u.LastActiveAt,
+		Status:        string(u.Status),
+		IsBot:         u.IsBot,
+	}
+}
+
+func toAvatarResponse(avatar domain.Avatar) []user.AvatarResponse {
+	response := make([]user.AvatarResponse, 0, len(avatar))
+	for _, v := range avatar {
+		response = append(response, user.AvatarResponse{Size: v.Size, URL: v.URL})
+	}
+	return response
 }
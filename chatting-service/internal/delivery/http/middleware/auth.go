@@ -7,7 +7,10 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func NewAuthMiddleware(provider domain.TokenProvider) fiber.Handler {
+// NewAuthMiddleware validates JWTs, and personal access tokens as well when
+// apiTokens is set. Routes reachable with API tokens should declare a RequireScope.
+// When sessions is set the account behind the token is checked on every request.
+func NewAuthMiddleware(provider domain.TokenProvider, apiTokens domain.APITokenValidator, sessions domain.SessionValidator) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Check Authorization header first
 		authHeader := c.Get("Authorization")
@@ -26,13 +29,30 @@ func NewAuthMiddleware(provider domain.TokenProvider) fiber.Handler {
 			})
 		}
 
-		claims, err := provider.ValidateToken(c.Context(), token)
+		var claims *domain.TokenClaims
+		var err error
+		if domain.IsAPIToken(token) {
+			if apiTokens == nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "API tokens are not accepted on this endpoint",
+				})
+			}
+			claims, err = apiTokens.ValidateAPIToken(c.Context(), token)
+		} else {
+			claims, err = provider.ValidateToken(c.Context(), token)
+		}
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid token",
 			})
 		}
 
+		if sessions != nil {
+			if err := sessions.ValidateSession(c.Context(), claims); err != nil {
+				return err
+			}
+		}
+
 		c.Locals("userID", claims.UserID)
 		c.Locals("userClaims", claims)
 
@@ -22,6 +22,15 @@ func RequestContext() fiber.Handler {
 		)
 		c.Locals("logger", logger)
 
+		// Make request details available to services for the audit trail
+		meta := shared.RequestMeta{
+			RequestID: requestID,
+			ClientIP:  c.IP(),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+		}
+		c.Locals(shared.RequestMetaKey, meta)
+		c.SetUserContext(shared.WithRequestMeta(c.UserContext(), meta))
+
 		return c.Next()
 	}
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"errors"
+	"strconv"
 
 	"github.com/AmeerHeiba/chatting-service/internal/shared"
 	"github.com/gofiber/fiber/v2"
@@ -38,5 +39,9 @@ func ErrorHandler(ctx *fiber.Ctx) error {
 			zap.Any("details", appErr.Details))
 	}
 
+	if retry, ok := appErr.Details.(shared.RetryAfterDetails); ok {
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(retry.RetryAfterSeconds))
+	}
+
 	return ctx.Status(appErr.Status).JSON(appErr)
 }
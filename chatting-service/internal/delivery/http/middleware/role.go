@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireRole must run after NewAuthMiddleware, it rejects callers whose token
+// does not carry one of the allowed roles
+func RequireRole(roles ...domain.UserRole) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("userClaims").(*domain.TokenClaims)
+		if !ok || claims == nil {
+			return shared.ErrUnauthorized.WithDetails("Invalid user claims")
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				return c.Next()
+			}
+		}
+
+		return shared.ErrForbidden.WithDetails("insufficient role")
+	}
+}
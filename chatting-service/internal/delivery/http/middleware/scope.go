@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireScope lets interactive sessions through and limits API tokens to the given scope
+func RequireScope(scope domain.TokenScope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("userClaims").(*domain.TokenClaims)
+		if !ok || claims == nil {
+			return shared.ErrUnauthorized.WithDetails("Invalid user claims")
+		}
+
+		if !claims.HasScope(scope) {
+			return shared.ErrInsufficientScope.WithDetails("missing scope " + string(scope))
+		}
+		return c.Next()
+	}
+}
+
+// SessionOnly rejects API tokens on routes meant for interactive users only
+func SessionOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("userClaims").(*domain.TokenClaims)
+		if !ok || claims == nil {
+			return shared.ErrUnauthorized.WithDetails("Invalid user claims")
+		}
+
+		if claims.IsAPIToken() {
+			return shared.ErrInsufficientScope.WithDetails("endpoint requires an interactive session")
+		}
+		return c.Next()
+	}
+}
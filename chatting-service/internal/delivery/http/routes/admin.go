@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/middleware"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAdminRoutes(app *fiber.App, handler *handlers.AdminHandler, authMiddleware fiber.Handler) {
+	admin := app.Group("/api/admin", authMiddleware, middleware.RequireRole(domain.RoleAdmin))
+	admin.Get("/users", handler.ListUsers)
+	admin.Get("/users/:id", handler.GetUser)
+	admin.Post("/users/:id/suspend", handler.SuspendUser)
+	admin.Post("/users/:id/reinstate", handler.ReinstateUser)
+	admin.Post("/users/:id/password-reset", handler.ForcePasswordReset)
+	admin.Post("/users/:id/unlock", handler.UnlockUser)
+	admin.Delete("/users/:id", handler.DeleteUser)
+	admin.Get("/audit-events", handler.ListAuditEvents)
+}
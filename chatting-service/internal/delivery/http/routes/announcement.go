@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/middleware"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAnnouncementRoutes(app *fiber.App, handler *handlers.AnnouncementHandler, authMiddleware fiber.Handler) {
+	announcements := app.Group("/api/announcements", authMiddleware)
+
+	read := middleware.RequireScope(domain.ScopeMessagesRead)
+	send := middleware.RequireScope(domain.ScopeMessagesSend)
+	admin := middleware.RequireRole(domain.RoleAdmin)
+
+	announcements.Get("/channels", read, handler.ListChannels)
+	announcements.Post("/channels", middleware.SessionOnly(), admin, handler.CreateChannel)
+	announcements.Delete("/channels/:id", middleware.SessionOnly(), admin, handler.DeleteChannel)
+	announcements.Get("/channels/:id/posters", middleware.SessionOnly(), admin, handler.ListPosters)
+	announcements.Put("/channels/:id/posters/:userID", middleware.SessionOnly(), admin, handler.AddPoster)
+	announcements.Delete("/channels/:id/posters/:userID", middleware.SessionOnly(), admin, handler.RemovePoster)
+
+	announcements.Get("/channels/:id/posts", read, handler.ListPosts)
+	announcements.Get("/channels/:id/pinned", read, handler.ListPinned)
+	announcements.Post("/channels/:id/posts", send, handler.Post)
+	announcements.Post("/channels/:id/read", read, handler.MarkRead)
+
+	announcements.Post("/posts/:id/pin", send, handler.PinPost)
+	announcements.Delete("/posts/:id/pin", send, handler.UnpinPost)
+	announcements.Delete("/posts/:id", send, handler.DeletePost)
+}
@@ -9,8 +9,13 @@ func SetupAuthRoutes(app *fiber.App, handler *handlers.AuthHandler, authMiddlewa
 	auth := app.Group("/api/auth")
 	auth.Post("/login", handler.Login)
 	auth.Post("/register", handler.Register)
+	auth.Get("/verify-email", handler.VerifyEmail)
+	auth.Post("/password/reset", handler.ResetPassword)
+	auth.Get("/oidc/login", handler.OIDCLogin)
+	auth.Get("/oidc/callback", handler.OIDCCallback)
 
 	// protected
 	protected := auth.Group("", authMiddleware)
 	protected.Put("/password", handler.ChangePassword)
+	protected.Post("/verify-email/resend", handler.ResendVerification)
 }
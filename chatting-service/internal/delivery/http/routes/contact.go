@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/middleware"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupContactRoutes(app *fiber.App, handler *handlers.ContactHandler, authMiddleware fiber.Handler) {
+	contacts := app.Group("/api/contacts", authMiddleware)
+
+	read := middleware.RequireScope(domain.ScopeUsersRead)
+
+	contacts.Get("/", read, handler.ListContacts)
+	contacts.Get("/requests", read, handler.ListRequests)
+	contacts.Post("/requests", middleware.SessionOnly(), handler.SendRequest)
+	contacts.Post("/requests/:id/accept", middleware.SessionOnly(), handler.AcceptRequest)
+	contacts.Post("/requests/:id/decline", middleware.SessionOnly(), handler.DeclineRequest)
+	contacts.Delete("/:userID", middleware.SessionOnly(), handler.RemoveContact)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/middleware"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupExportRoutes(app *fiber.App, handler *handlers.ExportHandler, authMiddleware fiber.Handler) {
+	app.Post("/api/users/me/export", authMiddleware, middleware.SessionOnly(), handler.RequestExport)
+	app.Get("/api/users/me/export", authMiddleware, middleware.RequireScope(domain.ScopeUsersRead), handler.GetExport)
+
+	// Signed links, no auth
+	app.Get("/api/exports/:id/download", handler.DownloadExport)
+}
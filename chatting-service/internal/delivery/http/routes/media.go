@@ -2,10 +2,27 @@ package routes
 
 import (
 	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/middleware"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupMediaRoutes(app *fiber.App, handler *handlers.MediaHandler, authMiddleware fiber.Handler) {
+func SetupMediaRoutes(app *fiber.App, handler *handlers.MediaHandler, authMiddleware fiber.Handler, filesPath string) {
 	media := app.Group("/api/media", authMiddleware)
-	media.Post("/upload", handler.Upload)
+	media.Post("/upload", middleware.RequireScope(domain.ScopeMediaUpload), handler.Upload)
+	media.Get("/", middleware.RequireScope(domain.ScopeMediaUpload), handler.ListMedia)
+	media.Get("/usage", middleware.RequireScope(domain.ScopeMediaUpload), handler.Usage)
+	media.Delete("/:id", middleware.RequireScope(domain.ScopeMediaUpload), handler.DeleteMedia)
+
+	// Resumable uploads for files too large for a single request
+	media.Post("/uploads", middleware.RequireScope(domain.ScopeMediaUpload), handler.CreateUpload)
+	media.Get("/uploads/:id", middleware.RequireScope(domain.ScopeMediaUpload), handler.GetUpload)
+	media.Patch("/uploads/:id", middleware.RequireScope(domain.ScopeMediaUpload), handler.UploadChunk)
+	media.Post("/uploads/:id/complete", middleware.RequireScope(domain.ScopeMediaUpload), handler.CompleteUpload)
+	media.Delete("/uploads/:id", middleware.RequireScope(domain.ScopeMediaUpload), handler.CancelUpload)
+
+	// Files kept on local disk are served here, signed links skip the login
+	if filesPath != "" {
+		app.Get(filesPath+"/*", handler.ServeSigned, authMiddleware, middleware.RequireScope(domain.ScopeMessagesRead), handler.ServeFile)
+	}
 }
@@ -2,18 +2,24 @@ package routes
 
 import (
 	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/middleware"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupMessageRoutes(app *fiber.App, handler *handlers.MessageHandler, wsHandler *handlers.WebSocketHandler, authMiddleware fiber.Handler) {
 	messageGroup := app.Group("/api/messages", authMiddleware)
 
-	messageGroup.Post("/", handler.SendMessage)
-	messageGroup.Post("/broadcast", handler.SendBroadcast)
-	messageGroup.Get("/conversations", handler.GetLoggedInUserConversations)
-	messageGroup.Get("/conversation/:userID", handler.GetConversation)
-	messageGroup.Put("/:id/read", handler.MarkAsRead)
-	messageGroup.Delete("/:id", handler.DeleteMessage)
+	send := middleware.RequireScope(domain.ScopeMessagesSend)
+	read := middleware.RequireScope(domain.ScopeMessagesRead)
+
+	messageGroup.Post("/", send, handler.SendMessage)
+	messageGroup.Post("/broadcast", send, handler.SendBroadcast)
+	messageGroup.Get("/conversations", read, handler.GetLoggedInUserConversations)
+	messageGroup.Get("/conversation/:userID", read, handler.GetConversation)
+	messageGroup.Get("/unread-count", read, handler.GetUnreadCount)
+	messageGroup.Put("/:id/read", read, handler.MarkAsRead)
+	messageGroup.Delete("/:id", send, handler.DeleteMessage)
 
 	// Todo get all conversation for signed in user
 
@@ -9,31 +9,59 @@ import (
 )
 
 type Dependencies struct {
-	DB             *gorm.DB
-	UserHandler    *handlers.UserHandler
-	AuthHandler    *handlers.AuthHandler
-	MessageHandler *handlers.MessageHandler
-	MediaHandler   *handlers.MediaHandler
-	WSHandler      *handlers.WebSocketHandler
-	JWTProvider    domain.TokenProvider
+	DB                  *gorm.DB
+	UserHandler         *handlers.UserHandler
+	AuthHandler         *handlers.AuthHandler
+	MessageHandler      *handlers.MessageHandler
+	MediaHandler        *handlers.MediaHandler
+	MediaFilesPath      string // Route serving local media files, empty when an object store serves them
+	WSHandler           *handlers.WebSocketHandler
+	AdminHandler        *handlers.AdminHandler
+	TokenHandler        *handlers.TokenHandler
+	ContactHandler      *handlers.ContactHandler
+	ExportHandler       *handlers.ExportHandler
+	AnnouncementHandler *handlers.AnnouncementHandler
+	JWTProvider         domain.TokenProvider
+	APITokenValidator   domain.APITokenValidator
+	SessionValidator    domain.SessionValidator
 }
 
 func SetupRoutes(app *fiber.App, deps Dependencies) {
+	// Session-only routes accept JWTs, API-enabled routes also accept personal access tokens
+	sessionAuth := middleware.NewAuthMiddleware(deps.JWTProvider, nil, deps.SessionValidator)
+	apiAuth := middleware.NewAuthMiddleware(deps.JWTProvider, deps.APITokenValidator, deps.SessionValidator)
+
 	// Health route (no auth)
 	SetupHealthRoutes(app, deps.DB)
 
 	// Auth routes (no auth)
-	SetupAuthRoutes(app, deps.AuthHandler, middleware.NewAuthMiddleware(deps.JWTProvider))
+	SetupAuthRoutes(app, deps.AuthHandler, sessionAuth)
+
+	// Data export routes (protected, downloads use signed links)
+	// Registered before the user routes so the /api/users group middleware is not run twice
+	SetupExportRoutes(app, deps.ExportHandler, apiAuth)
 
 	// Profile routes (protected)
-	SetupUserRoutes(app, deps.UserHandler, middleware.NewAuthMiddleware(deps.JWTProvider))
+	SetupUserRoutes(app, deps.UserHandler, apiAuth)
+
+	// Contact routes (protected)
+	SetupContactRoutes(app, deps.ContactHandler, apiAuth)
 
 	// Message routes (protected)
-	SetupMessageRoutes(app, deps.MessageHandler, deps.WSHandler, middleware.NewAuthMiddleware(deps.JWTProvider))
+	SetupMessageRoutes(app, deps.MessageHandler, deps.WSHandler, apiAuth)
+
+	// Announcement channels (protected, publishing and channel management checked per route)
+	SetupAnnouncementRoutes(app, deps.AnnouncementHandler, apiAuth)
 
 	// Media routes (protected)
-	SetupMediaRoutes(app, deps.MediaHandler, middleware.NewAuthMiddleware(deps.JWTProvider))
+	SetupMediaRoutes(app, deps.MediaHandler, apiAuth, deps.MediaFilesPath)
+
+	// API token and bot management (protected, interactive sessions only)
+	SetupTokenRoutes(app, deps.TokenHandler, sessionAuth)
+
+	// Admin routes (protected, admin role)
+	SetupAdminRoutes(app, deps.AdminHandler, sessionAuth)
 
 	// WebSocket routes	(protected)
-	SetupWebSocketRoutes(app, deps.WSHandler, middleware.NewAuthMiddleware(deps.JWTProvider))
+	SetupWebSocketRoutes(app, deps.WSHandler, apiAuth)
 }
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupTokenRoutes(app *fiber.App, handler *handlers.TokenHandler, authMiddleware fiber.Handler) {
+	tokens := app.Group("/api/tokens", authMiddleware)
+	tokens.Post("/", handler.CreateToken)
+	tokens.Get("/", handler.ListTokens)
+	tokens.Delete("/:id", handler.RevokeToken)
+
+	bots := app.Group("/api/bots", authMiddleware)
+	bots.Post("/", handler.CreateBot)
+	bots.Get("/", handler.ListBots)
+	bots.Post("/:id/tokens", handler.CreateBotToken)
+	bots.Get("/:id/tokens", handler.ListBotTokens)
+}
@@ -2,14 +2,25 @@ package routes
 
 import (
 	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/middleware"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupUserRoutes(app *fiber.App, handler *handlers.UserHandler, authMiddleware fiber.Handler) {
 	user := app.Group("/api/users", authMiddleware)
-	user.Get("/profile", handler.GetUserProfile)
-	user.Put("/profile", handler.UpdateProfile)
-	user.Get("/messages", handler.GetMessageHistory)
-	user.Get("/all", handler.GetAllUsers)
+	user.Get("/profile", middleware.RequireScope(domain.ScopeUsersRead), handler.GetUserProfile)
+	user.Put("/profile", middleware.SessionOnly(), handler.UpdateProfile)
+	user.Put("/profile/avatar", middleware.SessionOnly(), handler.UploadAvatar)
+	user.Delete("/profile/avatar", middleware.SessionOnly(), handler.DeleteAvatar)
+	user.Get("/messages", middleware.RequireScope(domain.ScopeMessagesRead), handler.GetMessageHistory)
+	user.Get("/", middleware.RequireScope(domain.ScopeUsersRead), handler.SearchUsers)
+	user.Get("/preferences", middleware.RequireScope(domain.ScopeUsersRead), handler.GetPreferences)
+	user.Put("/preferences", middleware.SessionOnly(), handler.UpdatePreferences)
+	user.Get("/blocks", middleware.RequireScope(domain.ScopeUsersRead), handler.ListBlockedUsers)
+	user.Post("/:id/block", middleware.SessionOnly(), handler.BlockUser)
+	user.Delete("/:id/block", middleware.SessionOnly(), handler.UnblockUser)
+	user.Delete("/me", middleware.SessionOnly(), handler.DeleteAccount)
+	user.Get("/:id", middleware.RequireScope(domain.ScopeUsersRead), handler.GetPublicProfile)
 
 }
@@ -2,6 +2,8 @@ package routes
 
 import (
 	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/handlers"
+	"github.com/AmeerHeiba/chatting-service/internal/delivery/http/middleware"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 )
@@ -9,6 +11,7 @@ import (
 func SetupWebSocketRoutes(app *fiber.App, wsHandler *handlers.WebSocketHandler, authMiddleware fiber.Handler) {
 	app.Get("/ws",
 		authMiddleware,
+		middleware.RequireScope(domain.ScopeMessagesRead),
 		wsHandler.Upgrade,
 		websocket.New(wsHandler.HandleConnection),
 	)
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MaxAnnouncementLength       = 5000
+	MaxChannelNameLength        = 100
+	MaxChannelDescriptionLength = 500
+)
+
+// AnnouncementChannel is a read-only feed every user of the audience follows without
+// subscribing. A nil AudienceRole means everyone.
+type AnnouncementChannel struct {
+	gorm.Model
+	Name         string    `gorm:"size:100;not null;uniqueIndex:idx_announcement_channels_name,where:deleted_at IS NULL"`
+	Description  string    `gorm:"size:500"`
+	AudienceRole *UserRole `gorm:"type:user_role"`
+	CreatedByID  uint      `gorm:"not null"`
+}
+
+// VisibleTo reports whether a user with the given role follows the channel. Admins see every channel.
+func (c *AnnouncementChannel) VisibleTo(role UserRole) bool {
+	return c.AudienceRole == nil || *c.AudienceRole == role || role == RoleAdmin
+}
+
+// Validate checks the name and description before the channel is stored
+func (c *AnnouncementChannel) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidChannel)
+	case utf8.RuneCountInString(c.Name) > MaxChannelNameLength:
+		return fmt.Errorf("%w: name is longer than %d characters", ErrInvalidChannel, MaxChannelNameLength)
+	case utf8.RuneCountInString(c.Description) > MaxChannelDescriptionLength:
+		return fmt.Errorf("%w: description is longer than %d characters", ErrInvalidChannel, MaxChannelDescriptionLength)
+	}
+	if c.AudienceRole != nil && *c.AudienceRole != RoleUser && *c.AudienceRole != RoleAdmin {
+		return fmt.Errorf("%w: unknown audience role %q", ErrInvalidChannel, *c.AudienceRole)
+	}
+	return nil
+}
+
+// AnnouncementPoster grants a non-admin user the right to publish to a channel
+type AnnouncementPoster struct {
+	ChannelID uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"primaryKey;index"`
+	CreatedAt time.Time
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// Announcement is a single post. Posts are stored once per channel, readers are
+// never fanned out into rows of their own.
+type Announcement struct {
+	gorm.Model
+	ChannelID uint   `gorm:"not null;index"`
+	AuthorID  uint   `gorm:"not null;index"`
+	Author    User   `gorm:"foreignKey:AuthorID" json:"-"`
+	Content   string `gorm:"type:text"`
+	MediaURL  string `gorm:"type:varchar(255)"`
+	PinnedAt  *time.Time
+}
+
+func (a *Announcement) Pinned() bool {
+	return a.PinnedAt != nil
+}
+
+// Validate applies the message content rules to a post
+func (a *Announcement) Validate() error {
+	if strings.TrimSpace(a.Content) == "" && a.MediaURL == "" {
+		return fmt.Errorf("%w: %w", ErrInvalidAnnouncement, ErrEmptyMessage)
+	}
+	if utf8.RuneCountInString(a.Content) > MaxAnnouncementLength {
+		return fmt.Errorf("%w: content is longer than %d characters", ErrInvalidAnnouncement, MaxAnnouncementLength)
+	}
+	if a.MediaURL != "" {
+		if _, err := url.ParseRequestURI(a.MediaURL); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidAnnouncement, ErrInvalidMediaURL)
+		}
+	}
+	return nil
+}
+
+// AnnouncementReadState is the per-user read position in a channel. Everything up to
+// and including LastReadID counts as read, so one row covers any number of posts.
+type AnnouncementReadState struct {
+	ChannelID  uint `gorm:"primaryKey"`
+	UserID     uint `gorm:"primaryKey;index"`
+	LastReadID uint `gorm:"not null;default:0"`
+	UpdatedAt  time.Time
+}
+
+// ChannelSummary is a channel as seen by one reader
+type ChannelSummary struct {
+	Channel     AnnouncementChannel
+	LastReadID  uint
+	UnreadCount int64
+}
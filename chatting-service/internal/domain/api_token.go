@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APITokenPrefix marks personal access tokens so they can be told apart from JWTs
+const APITokenPrefix = "cst_"
+
+type TokenScope string
+
+const (
+	ScopeMessagesSend TokenScope = "messages:send"
+	ScopeMessagesRead TokenScope = "messages:read"
+	ScopeMediaUpload  TokenScope = "media:upload"
+	ScopeUsersRead    TokenScope = "users:read"
+)
+
+var AllTokenScopes = []TokenScope{ScopeMessagesSend, ScopeMessagesRead, ScopeMediaUpload, ScopeUsersRead}
+
+func (s TokenScope) IsValid() bool {
+	for _, known := range AllTokenScopes {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a long-lived personal access token, only the SHA-256 hash is stored
+type APIToken struct {
+	gorm.Model
+	UserID      uint       `gorm:"index;not null"`
+	CreatedByID uint       `gorm:"not null"` // Owner of the bot when issued for a bot account
+	Name        string     `gorm:"size:100;not null"`
+	TokenHash   string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	Prefix      string     `gorm:"size:16;not null"` // First characters, helps users recognize a token
+	Scopes      string     `gorm:"type:text;not null"`
+	ExpiresAt   *time.Time `gorm:"index"`
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (t *APIToken) ScopeList() []TokenScope {
+	if t.Scopes == "" {
+		return nil
+	}
+	parts := strings.Split(t.Scopes, ",")
+	scopes := make([]TokenScope, 0, len(parts))
+	for _, p := range parts {
+		scopes = append(scopes, TokenScope(p))
+	}
+	return scopes
+}
+
+func (t *APIToken) SetScopes(scopes []TokenScope) error {
+	if len(scopes) == 0 {
+		return ErrInvalidTokenScope
+	}
+	seen := make(map[TokenScope]bool, len(scopes))
+	parts := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !s.IsValid() {
+			return ErrInvalidTokenScope
+		}
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		parts = append(parts, string(s))
+	}
+	t.Scopes = strings.Join(parts, ",")
+	return nil
+}
+
+func (t *APIToken) IsActive(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
@@ -0,0 +1,67 @@
+package domain
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditRegister           AuditAction = "auth.register"
+	AuditLogin              AuditAction = "auth.login"
+	AuditLoginBlocked       AuditAction = "auth.login_blocked"
+	AuditLockout            AuditAction = "auth.lockout"
+	AuditUnlock             AuditAction = "auth.unlock"
+	AuditSSOLogin           AuditAction = "auth.sso_login"
+	AuditPasswordChange     AuditAction = "auth.password_change"
+	AuditEmailVerified      AuditAction = "user.email_verified"
+	AuditProfileUpdate      AuditAction = "user.profile_update"
+	AuditTokenCreate        AuditAction = "token.create"
+	AuditTokenRevoke        AuditAction = "token.revoke"
+	AuditBotCreate          AuditAction = "bot.create"
+	AuditUserBlock          AuditAction = "user.block"
+	AuditUserUnblock        AuditAction = "user.unblock"
+	AuditMessageDelete      AuditAction = "message.delete"
+	AuditAccountDelete      AuditAction = "user.account_delete"
+	AuditDataExport         AuditAction = "user.data_export"
+	AuditUserSuspend        AuditAction = "user.suspend"
+	AuditUserReinstate      AuditAction = "user.reinstate"
+	AuditPasswordReset      AuditAction = "auth.password_reset_forced"
+	AuditChannelCreate      AuditAction = "announcement.channel_create"
+	AuditChannelDelete      AuditAction = "announcement.channel_delete"
+	AuditPosterGrant        AuditAction = "announcement.poster_grant"
+	AuditPosterRevoke       AuditAction = "announcement.poster_revoke"
+	AuditAnnouncementDelete AuditAction = "announcement.delete"
+)
+
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+	AuditDenied  AuditOutcome = "denied"
+)
+
+// AuditEvent is a single security relevant action. Rows are only ever inserted,
+// the migration installs a trigger rejecting updates and deletes.
+type AuditEvent struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time    `gorm:"index;not null" json:"created_at"`
+	ActorID    *uint        `gorm:"index" json:"actor_id,omitempty"`
+	Actor      string       `gorm:"size:100" json:"actor,omitempty"` // Username as typed, kept for failed logins without an actor ID
+	Action     AuditAction  `gorm:"size:50;index;not null" json:"action"`
+	TargetType string       `gorm:"size:30" json:"target_type,omitempty"`
+	TargetID   string       `gorm:"size:100" json:"target_id,omitempty"`
+	Outcome    AuditOutcome `gorm:"size:20;not null" json:"outcome"`
+	IP         string       `gorm:"size:64" json:"ip,omitempty"`
+	RequestID  string       `gorm:"size:64;index" json:"request_id,omitempty"`
+	Details    string       `gorm:"type:text" json:"details,omitempty"`
+}
+
+// AuditQuery filters the audit trail, zero values are ignored
+type AuditQuery struct {
+	ActorID *uint
+	Action  AuditAction
+	From    time.Time
+	To      time.Time
+	Limit   int
+	Offset  int
+}
@@ -4,9 +4,32 @@ import jwt "github.com/golang-jwt/jwt/v5"
 
 type TokenClaims struct {
 	jwt.RegisteredClaims
-	UserID    uint   `json:"user_id"`
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	SessionID string `json:"sid,omitempty"`
-	IsRefresh bool   `json:"is_refresh,omitempty"` // Distinguish refresh tokens
+	UserID    uint     `json:"user_id"`
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	Role      UserRole `json:"role,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	IsRefresh bool     `json:"is_refresh,omitempty"` // Distinguish refresh tokens
+
+	// Set only when authenticated with a personal access token
+	APITokenID uint         `json:"-"`
+	Scopes     []TokenScope `json:"-"`
+	IsBot      bool         `json:"-"`
+}
+
+func (c *TokenClaims) IsAPIToken() bool {
+	return c.APITokenID != 0
+}
+
+// HasScope is always true for interactive sessions, API tokens are limited to their scopes
+func (c *TokenClaims) HasScope(scope TokenScope) bool {
+	if !c.IsAPIToken() {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
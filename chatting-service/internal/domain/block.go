@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// UserBlock records that BlockerID does not want to hear from BlockedID
+type UserBlock struct {
+	ID        uint      `gorm:"primaryKey"`
+	BlockerID uint      `gorm:"not null;uniqueIndex:idx_user_blocks_pair"`
+	BlockedID uint      `gorm:"not null;uniqueIndex:idx_user_blocks_pair;index"`
+	CreatedAt time.Time `gorm:"not null"`
+
+	Blocked User `gorm:"foreignKey:BlockedID" json:"-"`
+}
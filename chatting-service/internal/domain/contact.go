@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+type ContactStatus string
+
+const (
+	ContactPending  ContactStatus = "pending"
+	ContactAccepted ContactStatus = "accepted"
+)
+
+// Contact is a contact request from RequesterID to AddresseeID. Once accepted the
+// relationship is symmetric; declined requests are deleted so they can be sent again.
+type Contact struct {
+	ID          uint          `gorm:"primaryKey"`
+	RequesterID uint          `gorm:"not null;uniqueIndex:idx_contacts_pair"`
+	AddresseeID uint          `gorm:"not null;uniqueIndex:idx_contacts_pair;index"`
+	Status      ContactStatus `gorm:"size:20;not null;default:'pending';index"`
+	CreatedAt   time.Time
+	AcceptedAt  *time.Time
+
+	Requester User `gorm:"foreignKey:RequesterID" json:"-"`
+	Addressee User `gorm:"foreignKey:AddresseeID" json:"-"`
+}
+
+// Other returns the user on the other side of the contact from userID
+func (c *Contact) Other(userID uint) User {
+	if c.RequesterID == userID {
+		return c.Addressee
+	}
+	return c.Requester
+}
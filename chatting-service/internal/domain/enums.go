@@ -23,3 +23,25 @@ const (
 	MessageDirect    MessageType = "direct"
 	MessageBroadcast MessageType = "broadcast"
 )
+
+type UserRole string
+
+const (
+	RoleUser  UserRole = "user"
+	RoleAdmin UserRole = "admin"
+)
+
+func (r UserRole) IsValid() bool {
+	switch r {
+	case RoleUser, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+type LoginAttemptScope string
+
+const (
+	LoginScopeUsername LoginAttemptScope = "username"
+	LoginScopeIP       LoginAttemptScope = "ip"
+)
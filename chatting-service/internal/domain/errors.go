@@ -25,4 +25,19 @@ var (
 	ErrInvalidSenderID      = errors.New("invalid sender ID")
 	ErrEmailExists          = errors.New("email already exists")
 	ErrUserAlreadyExists    = errors.New("user already exists")
+	ErrEmailNotVerified     = errors.New("email address is not verified")
+	ErrVerificationExpired  = errors.New("verification link has expired")
+	ErrVerificationUsed     = errors.New("verification link has already been used")
+	ErrVerificationMismatch = errors.New("verification link does not match the current email")
+	ErrInvalidTokenScope    = errors.New("invalid token scope")
+	ErrInvalidPasswordHash  = errors.New("invalid password hash")
+	ErrCannotBlockSelf      = errors.New("users cannot block themselves")
+	ErrInvalidPreferences   = errors.New("invalid notification preferences")
+	ErrInvalidProfile       = errors.New("invalid profile")
+	ErrInvalidImage         = errors.New("file is not a supported image")
+	ErrImageTooLarge        = errors.New("image dimensions are too large")
+	ErrInvalidChannel       = errors.New("invalid announcement channel")
+	ErrInvalidAnnouncement  = errors.New("invalid announcement")
+	ErrInvalidMediaLink     = errors.New("invalid media link")
+	ErrMediaLinkExpired     = errors.New("media link has expired")
 )
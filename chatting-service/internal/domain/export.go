@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportReady   ExportStatus = "ready"
+	ExportFailed  ExportStatus = "failed"
+	ExportExpired ExportStatus = "expired" // Archive was removed after ExpiresAt
+)
+
+// DataExport tracks one personal data archive requested by a user
+type DataExport struct {
+	ID          uint         `gorm:"primaryKey"`
+	UserID      uint         `gorm:"not null;index"`
+	Status      ExportStatus `gorm:"size:20;not null;default:'pending';index"`
+	Progress    int          `gorm:"not null;default:0"` // Percent complete
+	Error       string       `gorm:"size:255"`
+	ArchiveName string       `gorm:"size:255"` // Name in the archive store, set once ready
+	Size        int64
+	ExpiresAt   *time.Time `gorm:"index"` // Download link and archive expire together
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// InProgress is true while the archive is still being built
+func (e *DataExport) InProgress() bool {
+	return e.Status == ExportPending || e.Status == ExportRunning
+}
+
+func (e *DataExport) DownloadableAt(now time.Time) bool {
+	return e.Status == ExportReady && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
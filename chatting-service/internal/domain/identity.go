@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExternalIdentity links a local user to an account at an OpenID Connect provider.
+// The issuer and subject pair is the stable key, emails at the provider may change.
+type ExternalIdentity struct {
+	gorm.Model
+	UserID      uint   `gorm:"index;not null"`
+	Issuer      string `gorm:"size:255;not null;uniqueIndex:idx_external_identities_issuer_subject"`
+	Subject     string `gorm:"size:255;not null;uniqueIndex:idx_external_identities_issuer_subject"`
+	Email       string `gorm:"size:100"`
+	LastLoginAt time.Time
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// OIDCLoginState keeps the PKCE verifier and nonce of a started login until the provider
+// redirects back. Only the hash of the state parameter is stored, like verification tokens.
+type OIDCLoginState struct {
+	StateHash    string    `gorm:"primaryKey;size:64"`
+	CodeVerifier string    `gorm:"size:128;not null"`
+	Nonce        string    `gorm:"size:64;not null"`
+	ExpiresAt    time.Time `gorm:"index;not null"`
+	CreatedAt    time.Time
+}
+
+func (s *OIDCLoginState) IsExpired() bool {
+	return time.Now().UTC().After(s.ExpiresAt)
+}
+
+// ExternalClaims is the identity asserted by a verified ID token, already mapped
+// through the configured claim names
+type ExternalClaims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+}
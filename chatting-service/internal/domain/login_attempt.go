@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// LoginAttempt tracks consecutive failed logins for a username or a client IP
+type LoginAttempt struct {
+	ID           uint              `gorm:"primaryKey"`
+	Scope        LoginAttemptScope `gorm:"size:20;not null;uniqueIndex:idx_login_attempts_scope_key"`
+	Key          string            `gorm:"size:255;not null;uniqueIndex:idx_login_attempts_scope_key"`
+	FailedCount  int               `gorm:"not null;default:0"`
+	LastFailedAt time.Time         `gorm:"not null"`
+	LockedUntil  *time.Time        `gorm:"index"`
+	UpdatedAt    time.Time
+}
+
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// RetryAfter returns how long the caller has to wait before trying again
+func (a *LoginAttempt) RetryAfter(now time.Time) time.Duration {
+	if !a.IsLocked(now) {
+		return 0
+	}
+	return a.LockedUntil.Sub(now)
+}
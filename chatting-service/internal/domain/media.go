@@ -1,21 +1,230 @@
 package domain
 
 import (
-	"io"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
-type MediaUpload struct {
-	File        io.Reader `validate:"required"`
-	Filename    string    `validate:"required,min=1,max=255"`
-	ContentType string    `validate:"required,oneof=image/jpeg image/png application/pdf"`
-	Size        int64     `validate:"required,min=1,max=10485760"` // 10MB max
-	UserID      uint      `validate:"required"`
-}
+// MediaThumbnailSizes bound the longest edge, in pixels, of the thumbnails kept for
+// uploaded images. Images that already fit a size get no thumbnail for it.
+var MediaThumbnailSizes = []int{320, 800}
+
 type MediaResponse struct {
-	URL         string    `json:"url"`
-	Size        int64     `json:"size"`
-	ContentType string    `json:"content_type"`
-	UploadedAt  time.Time `json:"uploaded_at"`
-	ExpiresAt   time.Time `json:"expires_at,omitempty"` // For temporary URLs "future enhancment"
+	ID          uint            `json:"id"`  // Media library entry
+	URL         string          `json:"url"` // Permanent reference to put in messages, fetching it needs a login
+	SignedURL   string          `json:"signed_url,omitempty"`
+	Size        int64           `json:"size"`
+	ContentType string          `json:"content_type"`
+	Width       *int            `json:"width,omitempty"`
+	Height      *int            `json:"height,omitempty"`
+	Thumbnails  MediaThumbnails `json:"thumbnails,omitempty"`
+	UploadedAt  time.Time       `json:"uploaded_at"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"` // When SignedURL stops working
+}
+
+// MediaThumbnail is one scaled down copy of an uploaded image
+type MediaThumbnail struct {
+	Size   int    `json:"size"` // Bound of the longest edge
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Path   string `json:"-"` // Storage path, kept so the file can be removed later
+	URL    string `json:"url"`
+}
+
+// MediaThumbnails are kept smallest first
+type MediaThumbnails []MediaThumbnail
+
+// Paths lists the storage paths of all thumbnails
+func (t MediaThumbnails) Paths() []string {
+	paths := make([]string, 0, len(t))
+	for _, thumb := range t {
+		paths = append(paths, thumb.Path)
+	}
+	return paths
+}
+
+// storedThumbnail is how a thumbnail is saved in the database. Unlike MediaThumbnail
+// it keeps the storage path, which is never sent to clients.
+type storedThumbnail struct {
+	Size   int    `json:"size"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Path   string `json:"path"`
+	URL    string `json:"url"`
+}
+
+// Value stores the thumbnails as JSON, storage paths included
+func (t MediaThumbnails) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	stored := make([]storedThumbnail, len(t))
+	for i, thumb := range t {
+		stored[i] = storedThumbnail(thumb)
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads thumbnails saved by Value
+func (t *MediaThumbnails) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported thumbnails value %T", value)
+	}
+
+	var stored []storedThumbnail
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	if stored == nil {
+		*t = nil
+		return nil
+	}
+	thumbnails := make(MediaThumbnails, len(stored))
+	for i, thumb := range stored {
+		thumbnails[i] = MediaThumbnail(thumb)
+	}
+	*t = thumbnails
+	return nil
+}
+
+// MediaAttachment describes the media linked from a message
+type MediaAttachment struct {
+	ContentType string          `json:"content_type"`
+	Width       *int            `json:"width,omitempty"`
+	Height      *int            `json:"height,omitempty"`
+	Thumbnails  MediaThumbnails `json:"thumbnails,omitempty"`
+}
+
+// Media records one file uploaded through MediaService.Upload. Avatars are kept on the
+// user and are not recorded here. Uploads of the same content share one MediaBlob, each
+// upload is a reference to it.
+type Media struct {
+	ID               uint            `gorm:"primaryKey"`
+	OwnerID          uint            `gorm:"not null;index"`
+	Path             string          `gorm:"size:512;not null;index"` // Location in MediaStorage, the blob for newer uploads
+	OriginalFilename string          `gorm:"size:255;not null"`
+	ContentType      string          `gorm:"size:127;not null;index"`
+	Size             int64           `gorm:"not null"`
+	Checksum         string          `gorm:"size:64;not null;index"` // Hex SHA-256 of the content
+	Width            *int            // Images only
+	Height           *int            // Images only
+	Thumbnails       MediaThumbnails `gorm:"type:text"`
+	CreatedAt        time.Time       `gorm:"index"`
+}
+
+// MediaBlobDir holds content-addressed files, outside of every user's directory
+const MediaBlobDir = "blobs"
+
+// MediaBlob is one stored file shared by every upload of the same content. The file is
+// removed with the last reference.
+type MediaBlob struct {
+	Path        string          `gorm:"primaryKey;size:512"` // See MediaBlobPath
+	Checksum    string          `gorm:"size:64;not null;index;uniqueIndex:idx_media_blobs_content"`
+	ContentType string          `gorm:"size:127;not null;uniqueIndex:idx_media_blobs_content"`
+	Size        int64           `gorm:"not null"`
+	Width       *int            // Images only
+	Height      *int            // Images only
+	Thumbnails  MediaThumbnails `gorm:"type:text"`
+	RefCount    int             `gorm:"not null;default:0;index"` // Media rows pointing here
+	CreatedAt   time.Time
+}
+
+// MediaBlobPath is where one copy of content with the checksum is stored. Every copy
+// written gets its own copyID, so a copy being written never shares a path with one being
+// removed. The extension keeps the type files are served with, the first two characters
+// spread blobs over directories.
+func MediaBlobPath(checksum string, copyID string, extension string) string {
+	return fmt.Sprintf("%s/%s/%s-%s%s", MediaBlobDir, checksum[:2], checksum, copyID, extension)
+}
+
+// MediaFilter pages through one user's media library
+type MediaFilter struct {
+	OwnerID uint
+	Type    string // Exact content type (image/png) or a top-level type (image)
+	Limit   int
+	Offset  int
+}
+
+// MediaPage is one page of a media library, URL is filled in from the storage
+type MediaPage struct {
+	Items  []MediaItem
+	Total  int64
+	Limit  int
+	Offset int
+}
+
+type MediaItem struct {
+	Media
+	URL string
+}
+
+// MediaUsage counts the bytes of a user's media library. Every upload counts in full,
+// also when its content is shared with other uploads.
+type MediaUsage struct {
+	OwnerID   uint  `gorm:"primaryKey;autoIncrement:false"`
+	Bytes     int64 `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+// StorageUsage tells a user how much of their quota is taken
+type StorageUsage struct {
+	UsedBytes      int64  `json:"used_bytes"`
+	QuotaBytes     *int64 `json:"quota_bytes"`     // Null when there is no limit
+	RemainingBytes *int64 `json:"remaining_bytes"` // Null when there is no limit
+}
+
+// MediaUploadSession tracks a resumable upload while its chunks arrive. Each chunk is
+// kept in MediaStorage as its own part until the upload is completed or abandoned.
+type MediaUploadSession struct {
+	ID          string    `gorm:"primaryKey;size:64"`
+	OwnerID     uint      `gorm:"not null;index"`
+	Filename    string    `gorm:"size:255;not null"`
+	ContentType string    `gorm:"size:127"`                  // As declared, checked against the content on completion
+	Size        int64     `gorm:"not null"`                  // Total bytes the client will send
+	Checksum    string    `gorm:"size:64;not null"`          // Hex SHA-256 the assembled content must match
+	Received    int64     `gorm:"not null;default:0"`        // Bytes stored so far, where the next chunk starts
+	Parts       []string  `gorm:"serializer:json;type:text"` // Storage paths of the chunks, in order
+	ExpiresAt   time.Time `gorm:"not null;index"`            // Pushed back by every chunk
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Complete reports whether every byte has arrived
+func (u *MediaUploadSession) Complete() bool {
+	return u.Received == u.Size
+}
+
+// StoredFile is a file as MediaStorage lists it
+type StoredFile struct {
+	Path       string
+	Size       int64
+	ModifiedAt time.Time
+}
+
+// OrphanReport describes one pass over MediaStorage looking for files nothing refers to
+type OrphanReport struct {
+	DryRun  bool         `json:"dry_run"`
+	Scanned int          `json:"scanned"` // Files in storage
+	Orphans []StoredFile `json:"orphans"` // Unreferenced and past the grace period
+	Bytes   int64        `json:"bytes"`   // Total size of the orphans
+	Deleted int          `json:"deleted"` // Zero on a dry run
+	Failed  int          `json:"failed"`  // Orphans that could not be deleted, unsent entries that could not be released
+
+	Unsent   []StoredFile `json:"unsent"`   // Media library entries past the grace period that were never sent
+	Released int          `json:"released"` // Unsent entries removed, zero on a dry run
 }
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMediaThumbnails(t *testing.T) {
+	thumbnails := MediaThumbnails{{Size: 320, Width: 320, Height: 240, Path: "blobs/ab/abc_thumb320.png", URL: "http://localhost/media/file"}}
+
+	t.Run("PathIsNotSentToClients", func(t *testing.T) {
+		data, err := json.Marshal(thumbnails)
+		require.NoError(t, err)
+
+		assert.NotContains(t, string(data), "blobs/ab")
+		assert.Contains(t, string(data), "http://localhost/media/file")
+	})
+
+	t.Run("PathIsStored", func(t *testing.T) {
+		value, err := thumbnails.Value()
+		require.NoError(t, err)
+
+		var scanned MediaThumbnails
+		require.NoError(t, scanned.Scan(value))
+		assert.Equal(t, thumbnails, scanned)
+	})
+
+	t.Run("ReadsRowsSavedBefore", func(t *testing.T) {
+		var scanned MediaThumbnails
+		require.NoError(t, scanned.Scan([]byte(`[{"size":800,"width":800,"height":600,"path":"1/photo_thumb800.jpg","url":"u"}]`)))
+
+		assert.Equal(t, []string{"1/photo_thumb800.jpg"}, scanned.Paths())
+	})
+
+	t.Run("NoThumbnailsStoreNull", func(t *testing.T) {
+		var none MediaThumbnails
+		value, err := none.Value()
+		require.NoError(t, err)
+		assert.Nil(t, value)
+
+		scanned := thumbnails
+		require.NoError(t, scanned.Scan(nil))
+		assert.Nil(t, scanned)
+	})
+}
@@ -12,10 +12,11 @@ import (
 
 type Message struct {
 	gorm.Model
-	Content     string        `gorm:"type:text" json:"content"`
-	MediaURL    string        `gorm:"type:varchar(255)" json:"media_url,omitempty"`
-	MessageType MessageType   `gorm:"type:message_type;default:'direct'" json:"message_type"`
-	Status      MessageStatus `gorm:"type:message_status;default:'sent'" json:"status"`
+	Content     string           `gorm:"type:text" json:"content"`
+	MediaURL    string           `gorm:"type:varchar(255)" json:"media_url,omitempty"`
+	Media       *MediaAttachment `gorm:"-" json:"media,omitempty"` // Filled in for library media
+	MessageType MessageType      `gorm:"type:message_type;default:'direct'" json:"message_type"`
+	Status      MessageStatus    `gorm:"type:message_status;default:'sent'" json:"status"`
 
 	// Relationships
 	//Using foreign keys and gorm models to allow eager/lazy loading
@@ -35,6 +36,12 @@ type Message struct {
 	SentAt      time.Time  `gorm:"index;default:CURRENT_TIMESTAMP" json:"sent_at"`
 	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
 	ReadAt      *time.Time `json:"read_at,omitempty"`
+
+	// Silent is set when the direct recipient had the message muted on arrival
+	Silent bool `gorm:"not null;default:false" json:"-"`
+
+	// Erased marks a tombstone left behind when the sender deleted their account
+	Erased bool `gorm:"not null;default:false" json:"erased,omitempty"`
 }
 
 // MessageRecipient join table for broadcasts
@@ -43,6 +50,7 @@ type MessageRecipient struct {
 	UserID     uint      `gorm:"primaryKey"`
 	ReceivedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	ReadAt     *time.Time
+	Silent     bool `gorm:"not null;default:false"` // Recipient had the broadcast muted on arrival
 }
 
 //Key Business Rules
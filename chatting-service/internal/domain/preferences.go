@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type MuteScope string
+
+const (
+	MuteConversation MuteScope = "conversation" // Direct messages with the target user
+	MuteSender       MuteScope = "sender"       // Everything the target user sends, broadcasts included
+)
+
+func (s MuteScope) IsValid() bool {
+	return s == MuteConversation || s == MuteSender
+}
+
+// NotificationPreferences controls which real-time events reach a user silently.
+// Silenced messages are still stored, they just do not ring or count as unread.
+type NotificationPreferences struct {
+	UserID            uint   `gorm:"primaryKey"`
+	MuteBroadcasts    bool   `gorm:"not null;default:false"`
+	QuietHoursEnabled bool   `gorm:"not null;default:false"`
+	QuietHoursStart   string `gorm:"size:5;not null;default:'22:00'"` // HH:MM in TimeZone
+	QuietHoursEnd     string `gorm:"size:5;not null;default:'07:00'"`
+	TimeZone          string `gorm:"size:64;not null;default:'UTC'"`
+	UpdatedAt         time.Time
+}
+
+// Mute silences a conversation or sender for one user, until Until or forever when nil
+type Mute struct {
+	ID           uint       `gorm:"primaryKey"`
+	UserID       uint       `gorm:"not null;uniqueIndex:idx_mutes_target"`
+	Scope        MuteScope  `gorm:"size:20;not null;uniqueIndex:idx_mutes_target"`
+	TargetUserID uint       `gorm:"not null;uniqueIndex:idx_mutes_target;index"`
+	Until        *time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
+
+func (m Mute) ActiveAt(now time.Time) bool {
+	return m.Until == nil || now.Before(*m.Until)
+}
+
+// DefaultNotificationPreferences is what users get before saving anything
+func DefaultNotificationPreferences(userID uint) *NotificationPreferences {
+	return &NotificationPreferences{
+		UserID:          userID,
+		QuietHoursStart: "22:00",
+		QuietHoursEnd:   "07:00",
+		TimeZone:        "UTC",
+	}
+}
+
+func (p *NotificationPreferences) Validate() error {
+	if _, err := time.LoadLocation(p.TimeZone); err != nil {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidPreferences, p.TimeZone)
+	}
+	if _, err := parseClock(p.QuietHoursStart); err != nil {
+		return fmt.Errorf("%w: quiet hours start must be HH:MM", ErrInvalidPreferences)
+	}
+	if _, err := parseClock(p.QuietHoursEnd); err != nil {
+		return fmt.Errorf("%w: quiet hours end must be HH:MM", ErrInvalidPreferences)
+	}
+	return nil
+}
+
+// InQuietHours reports whether now falls in the do-not-disturb window. Windows
+// that end before they start wrap past midnight (22:00-07:00).
+func (p *NotificationPreferences) InQuietHours(now time.Time) bool {
+	if !p.QuietHoursEnabled {
+		return false
+	}
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, err := parseClock(p.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(p.QuietHoursEnd)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// Silences decides whether message reaches the user silently given their preferences
+// and mutes. Mutes that are not active at now are ignored.
+func (p *NotificationPreferences) Silences(message *Message, mutes []Mute, now time.Time) bool {
+	return p.InQuietHours(now) || p.Mutes(message, mutes, now)
+}
+
+// Mutes decides whether the user muted message for good, unlike quiet hours which only
+// hold back the notification while they last
+func (p *NotificationPreferences) Mutes(message *Message, mutes []Mute, now time.Time) bool {
+	if message.IsBroadcast() && p.MuteBroadcasts {
+		return true
+	}
+	for _, mute := range mutes {
+		if mute.UserID != p.UserID || mute.TargetUserID != message.SenderID || !mute.ActiveAt(now) {
+			continue
+		}
+		if mute.Scope == MuteSender || (mute.Scope == MuteConversation && !message.IsBroadcast()) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock returns minutes since midnight for an HH:MM string
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestNotificationPreferences(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, _ := time.Parse("15:04", clock)
+		return time.Date(2024, 3, 1, parsed.Hour(), parsed.Minute(), 0, 0, time.UTC)
+	}
+
+	t.Run("QuietHoursWrapMidnight", func(t *testing.T) {
+		prefs := DefaultNotificationPreferences(1)
+		prefs.QuietHoursEnabled = true
+
+		assert.True(t, prefs.InQuietHours(at("03:00")))
+		assert.True(t, prefs.InQuietHours(at("22:00")))
+		assert.False(t, prefs.InQuietHours(at("07:00")))
+		assert.False(t, prefs.InQuietHours(at("12:30")))
+	})
+
+	t.Run("QuietHoursUseTimeZone", func(t *testing.T) {
+		prefs := DefaultNotificationPreferences(1)
+		prefs.QuietHoursEnabled = true
+		prefs.TimeZone = "Asia/Tokyo" // UTC+9, no daylight saving
+
+		assert.True(t, prefs.InQuietHours(at("18:00")))  // 03:00 in Tokyo
+		assert.False(t, prefs.InQuietHours(at("03:00"))) // 12:00 in Tokyo
+	})
+
+	t.Run("QuietHoursDoNotMute", func(t *testing.T) {
+		prefs := DefaultNotificationPreferences(1)
+		prefs.QuietHoursEnabled = true
+		msg := &Message{SenderID: 2, MessageType: MessageDirect}
+
+		assert.True(t, prefs.Silences(msg, nil, at("03:00")))
+		assert.False(t, prefs.Mutes(msg, nil, at("03:00")))
+	})
+
+	t.Run("ConversationMuteDoesNotCoverBroadcasts", func(t *testing.T) {
+		prefs := DefaultNotificationPreferences(1)
+		mutes := []Mute{{UserID: 1, Scope: MuteConversation, TargetUserID: 2}}
+		direct := &Message{SenderID: 2, MessageType: MessageDirect}
+		broadcast := &Message{SenderID: 2, MessageType: MessageBroadcast}
+
+		assert.True(t, prefs.Silences(direct, mutes, at("12:00")))
+		assert.False(t, prefs.Silences(broadcast, mutes, at("12:00")))
+	})
+
+	t.Run("ExpiredMuteIsIgnored", func(t *testing.T) {
+		prefs := DefaultNotificationPreferences(1)
+		until := at("11:00")
+		mutes := []Mute{{UserID: 1, Scope: MuteSender, TargetUserID: 2, Until: &until}}
+		msg := &Message{Model: gorm.Model{ID: 5}, SenderID: 2, MessageType: MessageDirect}
+
+		assert.True(t, prefs.Silences(msg, mutes, at("10:59")))
+		assert.False(t, prefs.Silences(msg, mutes, at("11:00")))
+	})
+
+	t.Run("MuteAllBroadcasts", func(t *testing.T) {
+		prefs := DefaultNotificationPreferences(1)
+		prefs.MuteBroadcasts = true
+
+		assert.True(t, prefs.Silences(&Message{SenderID: 3, MessageType: MessageBroadcast}, nil, at("12:00")))
+		assert.False(t, prefs.Silences(&Message{SenderID: 3, MessageType: MessageDirect}, nil, at("12:00")))
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		prefs := DefaultNotificationPreferences(1)
+		assert.NoError(t, prefs.Validate())
+
+		prefs.QuietHoursStart = "25:00"
+		assert.ErrorIs(t, prefs.Validate(), ErrInvalidPreferences)
+
+		prefs = DefaultNotificationPreferences(1)
+		prefs.TimeZone = "Mars/Olympus"
+		assert.ErrorIs(t, prefs.Validate(), ErrInvalidPreferences)
+	})
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxDisplayNameLength = 100
+	MaxBioLength         = 500
+)
+
+// AvatarSizes are the square edge lengths, in pixels, every avatar is stored at
+var AvatarSizes = []int{64, 128, 256}
+
+// AvatarVariant is one stored rendition of a user's avatar
+type AvatarVariant struct {
+	Size int    `json:"size"`
+	Path string `json:"path"` // Storage path, kept so the file can be removed later
+	URL  string `json:"url"`
+}
+
+// Avatar holds every rendition of a profile picture, smallest first. Empty means no avatar.
+type Avatar []AvatarVariant
+
+// URL returns the smallest variant at least size pixels wide, or the largest one available
+func (a Avatar) URL(size int) string {
+	if len(a) == 0 {
+		return ""
+	}
+	for _, v := range a {
+		if v.Size >= size {
+			return v.URL
+		}
+	}
+	return a[len(a)-1].URL
+}
+
+// Paths lists the storage paths of all variants
+func (a Avatar) Paths() []string {
+	paths := make([]string, 0, len(a))
+	for _, v := range a {
+		paths = append(paths, v.Path)
+	}
+	return paths
+}
+
+// ProfileDetails carries optional profile fields. Nil fields are left unchanged,
+// empty strings clear them (the time zone falls back to UTC).
+type ProfileDetails struct {
+	DisplayName *string
+	Bio         *string
+	TimeZone    *string
+}
+
+func (d ProfileDetails) IsEmpty() bool {
+	return d.DisplayName == nil && d.Bio == nil && d.TimeZone == nil
+}
+
+// Normalize trims whitespace and validates the lengths and time zone
+func (d *ProfileDetails) Normalize() error {
+	if d.DisplayName != nil {
+		name := strings.TrimSpace(*d.DisplayName)
+		if utf8.RuneCountInString(name) > MaxDisplayNameLength {
+			return fmt.Errorf("%w: display name is longer than %d characters", ErrInvalidProfile, MaxDisplayNameLength)
+		}
+		d.DisplayName = &name
+	}
+	if d.Bio != nil {
+		bio := strings.TrimSpace(*d.Bio)
+		if utf8.RuneCountInString(bio) > MaxBioLength {
+			return fmt.Errorf("%w: bio is longer than %d characters", ErrInvalidProfile, MaxBioLength)
+		}
+		d.Bio = &bio
+	}
+	if d.TimeZone != nil {
+		zone := strings.TrimSpace(*d.TimeZone)
+		if zone == "" {
+			zone = "UTC"
+		}
+		if _, err := time.LoadLocation(zone); err != nil {
+			return fmt.Errorf("%w: unknown time zone %q", ErrInvalidProfile, zone)
+		}
+		d.TimeZone = &zone
+	}
+	return nil
+}
+
+// SenderInfo is the slice of a profile shown next to messages
+type SenderInfo struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
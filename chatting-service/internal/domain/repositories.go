@@ -22,14 +22,25 @@ type UserRepository interface {
 	Create(ctx context.Context, userName, email, passwordHash string) (*User, error)
 	FindByID(ctx context.Context, userID uint) (*User, error)
 	FindByUsername(ctx context.Context, username string) (*User, error)
+	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindProfileByID(ctx context.Context, userID uint) (*User, error)
 	Update(ctx context.Context, userID uint, username, email string) error
+	UpdateDetails(ctx context.Context, userID uint, details ProfileDetails) error
+	UpdateAvatar(ctx context.Context, userID uint, avatar Avatar) error
 	UpdatePassword(ctx context.Context, userID uint, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID uint) error
 	UpdateLastActiveAt(ctx context.Context, userID uint) error
 	Exists(ctx context.Context, userID uint) (bool, error)
 	ExistsByUsername(ctx context.Context, username string) (bool, error)
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
-	GetAll(ctx context.Context) ([]*User, error)
+	Search(ctx context.Context, search UserSearch) ([]*User, error)
+	CreateBot(ctx context.Context, username, email, passwordHash string, ownerID uint) (*User, error)
+	FindBotsByOwner(ctx context.Context, ownerID uint) ([]*User, error)
+	Anonymize(ctx context.Context, userID uint) error // Scrubs personal data and soft deletes the account
+	List(ctx context.Context, filter UserListFilter) ([]*User, int64, error)
+	SetSuspension(ctx context.Context, userID uint, suspendedAt *time.Time, reason string) error
+	RequirePasswordReset(ctx context.Context, userID uint, sessionsValidAfter time.Time) error
+	CompletePasswordReset(ctx context.Context, userID uint, passwordHash string) error
 }
 
 type MessageRepository interface {
@@ -43,6 +54,13 @@ type MessageRepository interface {
 	MarkAsRead(ctx context.Context, messageID uint, recipientID uint) error
 	Update(ctx context.Context, messageID uint, recipientID *uint, broadcasterID *uint) error
 	Delete(ctx context.Context, messageID uint, userID uint) error
+	MarkSilent(ctx context.Context, messageID uint, recipientIDs []uint) error // Recipients who muted the message
+	CountUnread(ctx context.Context, userID uint) (int64, error)               // Muted messages are not counted
+	EraseFromSender(ctx context.Context, senderID uint, tombstone bool) error
+	FindForExport(ctx context.Context, userID uint, afterID uint, limit int) ([]Message, error) // Sent, received and broadcast, oldest first
+	CountForExport(ctx context.Context, userID uint) (int64, error)
+	CountSentBy(ctx context.Context, senderID uint) (int64, error)
+	HasMediaAccess(ctx context.Context, userID uint, mediaURL string) (bool, error) // Recipient of a message with the media, sending it grants nothing
 }
 
 type MessageRecipientRepository interface {
@@ -50,6 +68,163 @@ type MessageRecipientRepository interface {
 	CreateBulk(ctx context.Context, messageID uint, recipientIDs []uint) error
 }
 
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, userID uint, email, tokenHash string, purpose VerificationPurpose, expiresAt time.Time) (*EmailVerification, error)
+	FindByTokenHash(ctx context.Context, tokenHash string) (*EmailVerification, error)
+	MarkVerified(ctx context.Context, verificationID uint) error // Fails when the token was already used
+	InvalidateForUser(ctx context.Context, userID uint, purpose VerificationPurpose) error
+	DeleteForUser(ctx context.Context, userID uint) error
+}
+
+type LoginAttemptRepository interface {
+	Find(ctx context.Context, scope LoginAttemptScope, key string) (*LoginAttempt, error)
+	RegisterFailure(ctx context.Context, scope LoginAttemptScope, key string, window time.Duration) (*LoginAttempt, error)
+	Lock(ctx context.Context, scope LoginAttemptScope, key string, until time.Time) error
+	Reset(ctx context.Context, scope LoginAttemptScope, key string) error
+}
+
+type APITokenRepository interface {
+	Create(ctx context.Context, token *APIToken) (*APIToken, error)
+	FindByID(ctx context.Context, tokenID uint) (*APIToken, error)
+	FindByHash(ctx context.Context, tokenHash string) (*APIToken, error)
+	ListByUser(ctx context.Context, userID uint) ([]APIToken, error)
+	Revoke(ctx context.Context, tokenID uint) error
+	TouchLastUsed(ctx context.Context, tokenID uint) error
+	RevokeAllForUser(ctx context.Context, userID uint) error
+}
+
+type ExternalIdentityRepository interface {
+	FindBySubject(ctx context.Context, issuer, subject string) (*ExternalIdentity, error)
+	Create(ctx context.Context, identity *ExternalIdentity) (*ExternalIdentity, error)
+	TouchLastLogin(ctx context.Context, identityID uint) error
+	DeleteForUser(ctx context.Context, userID uint) error
+}
+
+type OIDCStateRepository interface {
+	Create(ctx context.Context, state *OIDCLoginState) error
+	Consume(ctx context.Context, stateHash string) (*OIDCLoginState, error) // Deletes the state so it can be used once
+}
+
+type UserBlockRepository interface {
+	Block(ctx context.Context, blockerID, blockedID uint) error // Blocking twice is not an error
+	Unblock(ctx context.Context, blockerID, blockedID uint) error
+	IsBlocked(ctx context.Context, blockerID, blockedID uint) (bool, error)
+	ListBlocked(ctx context.Context, blockerID uint) ([]UserBlock, error)
+	FindBlockersOf(ctx context.Context, blockedID uint, candidateIDs []uint) ([]uint, error) // Which candidates blocked blockedID
+	HiddenFrom(ctx context.Context, userID uint) ([]uint, error)                             // Users blocked by or blocking userID
+	DeleteAllForUser(ctx context.Context, userID uint) error                                 // Both directions
+}
+
+type ContactRepository interface {
+	Create(ctx context.Context, contact *Contact) (*Contact, error)
+	FindByID(ctx context.Context, contactID uint) (*Contact, error)
+	FindBetween(ctx context.Context, userA, userB uint) (*Contact, error) // Either direction, nil, nil when none
+	Accept(ctx context.Context, contactID uint) error
+	Delete(ctx context.Context, contactID uint) error
+	ListAccepted(ctx context.Context, userID uint) ([]Contact, error)
+	ListPending(ctx context.Context, userID uint) ([]Contact, error) // Incoming and outgoing
+	ContactIDsAmong(ctx context.Context, userID uint, candidateIDs []uint) ([]uint, error)
+	DeleteAllForUser(ctx context.Context, userID uint) error
+}
+
+type AnnouncementRepository interface {
+	CreateChannel(ctx context.Context, channel *AnnouncementChannel) error
+	FindChannel(ctx context.Context, channelID uint) (*AnnouncementChannel, error)
+	ListChannels(ctx context.Context) ([]AnnouncementChannel, error)
+	DeleteChannel(ctx context.Context, channelID uint) error
+	AddPoster(ctx context.Context, channelID, userID uint) error // Adding twice is not an error
+	RemovePoster(ctx context.Context, channelID, userID uint) error
+	IsPoster(ctx context.Context, channelID, userID uint) (bool, error)
+	ListPosters(ctx context.Context, channelID uint) ([]AnnouncementPoster, error)
+	CreatePost(ctx context.Context, post *Announcement) error
+	FindPost(ctx context.Context, postID uint) (*Announcement, error)
+	ListPosts(ctx context.Context, channelID, beforeID uint, limit int) ([]Announcement, error) // Newest first, beforeID 0 starts at the latest
+	ListPinned(ctx context.Context, channelID uint) ([]Announcement, error)
+	SetPinned(ctx context.Context, postID uint, pinnedAt *time.Time) error
+	DeletePost(ctx context.Context, postID uint) error
+	MarkRead(ctx context.Context, channelID, userID, lastReadID uint) error // Never moves the read position back
+	ReadSummaries(ctx context.Context, userID uint, channelIDs []uint) (map[uint]ChannelSummary, error)
+	DeleteForUser(ctx context.Context, userID uint) error
+	HasMedia(ctx context.Context, mediaURL string, role UserRole) (bool, error) // A post visible to the role links the media
+}
+
+type NotificationPreferenceRepository interface {
+	Find(ctx context.Context, userID uint) (*NotificationPreferences, error) // nil, nil when never saved
+	FindMany(ctx context.Context, userIDs []uint) ([]NotificationPreferences, error)
+	Save(ctx context.Context, prefs *NotificationPreferences) error
+	ListMutes(ctx context.Context, userID uint, now time.Time) ([]Mute, error)
+	FindMutesOf(ctx context.Context, senderID uint, userIDs []uint, now time.Time) ([]Mute, error)
+	ReplaceMutes(ctx context.Context, userID uint, mutes []Mute) error
+	DeleteForUser(ctx context.Context, userID uint) error // Preferences, own mutes and mutes targeting the user
+}
+
+type DataExportRepository interface {
+	Create(ctx context.Context, export *DataExport) error
+	FindByID(ctx context.Context, exportID uint) (*DataExport, error)
+	FindLatestByUser(ctx context.Context, userID uint) (*DataExport, error) // nil, nil when none
+	Save(ctx context.Context, export *DataExport) error
+	UpdateProgress(ctx context.Context, exportID uint, progress int) error
+	FindExpired(ctx context.Context, now time.Time) ([]DataExport, error) // Ready exports past ExpiresAt
+	FailStale(ctx context.Context, startedBefore time.Time) error         // Jobs lost to a restart
+	ExpireForUser(ctx context.Context, userID uint, now time.Time) error  // Lets the janitor remove the archives
+}
+
+type MediaRepository interface {
+	Create(ctx context.Context, media *Media) error
+	FindByID(ctx context.Context, mediaID uint) (*Media, error)
+	FindByPath(ctx context.Context, ownerID uint, path string) (*Media, error) // The owner's upload of the file at path
+	FindByPaths(ctx context.Context, paths []string) ([]Media, error)
+	List(ctx context.Context, filter MediaFilter) ([]Media, int64, error) // Newest first
+	Delete(ctx context.Context, mediaID uint) error
+	// DeleteForUser drops the user's references, blobs left without any are purged by
+	// MediaService.PurgeUnreferenced. Files of older uploads go with MediaStorage.DeleteUserFiles.
+	DeleteForUser(ctx context.Context, userID uint) error
+	IsReferencedBy(ctx context.Context, path string, userID uint) (bool, error) // The user uploaded the blob at path
+
+	// Blobs are locked until the surrounding transaction ends, so a file is never
+	// removed while another upload of the same content takes a reference to it
+	AcquireBlob(ctx context.Context, blob *MediaBlob) (*MediaBlob, error) // Creates it with no references unless a blob of the same content exists
+	LockBlob(ctx context.Context, path string) (*MediaBlob, error)
+	SaveBlob(ctx context.Context, blob *MediaBlob) error
+	DeleteBlob(ctx context.Context, path string) error
+	FindUnreferencedBlobs(ctx context.Context) ([]MediaBlob, error)
+
+	// AddUsage changes the bytes counted for the owner, shared.ErrStorageQuotaExceeded
+	// when that would go over limit. A limit of 0 is no limit. The count stays locked
+	// until the surrounding transaction ends.
+	AddUsage(ctx context.Context, ownerID uint, delta int64, limit int64) error
+	FindUsage(ctx context.Context, ownerID uint) (int64, error)
+}
+
+type MediaUploadRepository interface {
+	Create(ctx context.Context, upload *MediaUploadSession) error
+	FindByID(ctx context.Context, uploadID string) (*MediaUploadSession, error)
+	// AppendPart saves the grown upload, shared.ErrConflict when another chunk was
+	// stored since it was read at previousReceived
+	AppendPart(ctx context.Context, upload *MediaUploadSession, previousReceived int64) error
+	Delete(ctx context.Context, uploadID string) error
+	FindExpired(ctx context.Context, now time.Time) ([]MediaUploadSession, error)
+	OpenSize(ctx context.Context, ownerID uint, now time.Time) (int64, error) // Declared bytes of the owner's uploads that have not expired
+	DeleteForUser(ctx context.Context, userID uint) error                     // Records only, parts are removed by MediaStorage.DeleteUserFiles
+}
+
+// MediaReferenceRepository finds what keeps stored files in use
+type MediaReferenceRepository interface {
+	// ReferencedPaths are storage paths recorded by media library entries, shared blobs,
+	// their thumbnails, avatars and resumable uploads in progress
+	ReferencedPaths(ctx context.Context) ([]string, error)
+	// ReferencedURLs are media links of messages and announcements that were not deleted
+	ReferencedURLs(ctx context.Context) ([]string, error)
+	// MediaUploadedBefore pages through media library entries older than before, sent or
+	// not, ordered by ID
+	MediaUploadedBefore(ctx context.Context, before time.Time, afterID uint, limit int) ([]Media, error)
+}
+
+type AuditEventRepository interface {
+	Create(ctx context.Context, event *AuditEvent) error
+	Find(ctx context.Context, query AuditQuery) ([]AuditEvent, error)
+}
+
 type MessageService interface {
 	SendText(ctx context.Context, senderID, recipientID uint, content string) (*Message, error)
 	SendMedia(ctx context.Context, senderID, recipientID uint, content string, mediaURL string) (*Message, error)
@@ -69,46 +244,134 @@ type TokenProvider interface {
 	GetRefreshExpiry() time.Duration
 }
 
+type APITokenValidator interface {
+	ValidateAPIToken(ctx context.Context, token string) (*TokenClaims, error)
+}
+
+// IdentityProvider talks to an external OpenID Connect provider
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state, codeChallenge, nonce string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalClaims, error)
+}
+
 type AuthService interface {
 	Login(ctx context.Context, username, password string) (interface{}, error)
 	Refresh(ctx context.Context, refreshToken string) (interface{}, error)
 	Logout(ctx context.Context, token string) error
 }
 
+//Mail Interfaces
+
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
 //Media Interfaces
 
 type MediaStorage interface {
 	Upload(ctx context.Context, file io.Reader, filename string, contentType string, size int64, userId uint) (string, error)
+	Put(ctx context.Context, file io.Reader, path string, contentType string, size int64) error // Stores at exactly path, such as a MediaBlobPath
 	GetURL(ctx context.Context, path string) (string, error)
 	Delete(ctx context.Context, path string) error
-	GetSignedURL(ctx context.Context, path string, expires time.Duration) (string, error) // For enabling signed URLs use in cloud storage if cloud storage is used later
+	GetSignedURL(ctx context.Context, path string, expires time.Duration) (string, error) // Time-limited link that works without the file being public
+	DeleteUserFiles(ctx context.Context, userID uint) error                               // Everything uploaded by the user
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
+	PathFromURL(url string) (string, bool)                          // Reverses GetURL for files this storage holds
+	Walk(ctx context.Context, fn func(file StoredFile) error) error // Every stored file, stops at the first error fn returns
+}
+
+// SignedURLVerifier is implemented by storages whose files are served by the API itself,
+// it checks links made by GetSignedURL
+type SignedURLVerifier interface {
+	VerifySignedURL(path string, expires int64, signature string) error
+}
+
+// ArchiveStore keeps generated archives such as data exports, away from public media
+type ArchiveStore interface {
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+	Open(ctx context.Context, name string) (io.ReadCloser, int64, error)
+	Delete(ctx context.Context, name string) error
 }
 
 type MediaService interface {
 	Upload(ctx context.Context, userID uint, file io.Reader, filename string, contentType string, size int64) (*MediaResponse, error)
-	GetByUser(ctx context.Context, userID uint) ([]MediaResponse, error)
 	Delete(ctx context.Context, userID uint, path string) error
 }
 
+// AvatarUploader is what the user service needs from media to manage profile pictures
+type AvatarUploader interface {
+	UploadAvatar(ctx context.Context, userID uint, file io.Reader, contentType string, size int64) (Avatar, error)
+	DeleteAvatar(ctx context.Context, userID uint, avatar Avatar) // Best effort, failures are only logged
+}
+
+// ResizedImage is one rendition produced by an ImageResizer
+type ResizedImage struct {
+	Size        int // Requested bound in pixels
+	Width       int
+	Height      int
+	ContentType string
+	Data        []byte
+}
+
+// ImageResizer produces scaled copies of an uploaded image. With crop the image is
+// center-cropped to a square of exactly each size, otherwise it is fitted inside
+// the size without upscaling.
+type ImageResizer interface {
+	Resize(ctx context.Context, src io.Reader, sizes []int, crop bool) ([]ResizedImage, error)
+	Dimensions(ctx context.Context, src io.Reader) (width, height int, err error) // Reads the header only
+}
+
+// MetadataStripper removes metadata such as EXIF GPS coordinates from uploaded images
+type MetadataStripper interface {
+	StripMetadata(ctx context.Context, data []byte, contentType string) ([]byte, error) // Types it does not handle come back unchanged
+}
+
 //Media uploader is only used to define what message services needs from the media operations to avoid circular dependency
 
 type MediaUploader interface {
 	Upload(ctx context.Context, userID uint, file io.Reader, filename string, contentType string, size int64, userId uint) (*MediaResponse, error)
+	Attachments(ctx context.Context, mediaURLs []string) (map[string]MediaAttachment, error) // Keyed by URL, unknown URLs are left out
+	CanShare(ctx context.Context, userID uint, role UserRole, mediaURL string) (bool, error) // Links to stored files need access to them already
 }
 
 //Real Time Interfaces
 
 type MessageNotifier interface {
-	Notify(ctx context.Context, message *Message) error
-	Broadcast(ctx context.Context, message *Message, recipientIDs []uint) error
+	Notify(ctx context.Context, message *Message, silent bool) error
+	Broadcast(ctx context.Context, message *Message, recipientIDs []uint, silent map[uint]bool) error
+}
+
+// AnnouncementNotifier pushes a new post to the connected members of the channel audience
+type AnnouncementNotifier interface {
+	Announce(ctx context.Context, channel *AnnouncementChannel, post *Announcement) error
+}
+
+// ConnectionCloser drops a user's live connections, used when their sessions are revoked
+type ConnectionCloser interface {
+	CloseUserConnections(userID uint, reason string)
+}
+
+// SessionValidator checks that the account behind a valid token may still use it
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, claims *TokenClaims) error
 }
 
 // Transaction Manager for repositories
 
 type Repositories struct {
-	Users             UserRepository
-	Messages          MessageRepository
-	MessageRecipients MessageRecipientRepository
+	Users                   UserRepository
+	Messages                MessageRepository
+	MessageRecipients       MessageRecipientRepository
+	APITokens               APITokenRepository
+	ExternalIdentities      ExternalIdentityRepository
+	EmailVerifications      EmailVerificationRepository
+	Contacts                ContactRepository
+	Blocks                  UserBlockRepository
+	NotificationPreferences NotificationPreferenceRepository
+	DataExports             DataExportRepository
+	Announcements           AnnouncementRepository
+	Media                   MediaRepository
+	MediaUploads            MediaUploadRepository
 }
 
 type TransactionManager interface {
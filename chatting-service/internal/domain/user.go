@@ -4,8 +4,6 @@ import (
 	"strings"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
-
 	"gorm.io/gorm"
 )
 
@@ -13,9 +11,30 @@ type User struct {
 	gorm.Model
 	Username     string     `gorm:"uniqueIndex;size:50;not null"`
 	Email        string     `gorm:"uniqueIndex;size:100;not null"`
-	PasswordHash string     `gorm:"type:text;not null";json:"-"`
+	PasswordHash string     `gorm:"type:text;not null" json:"-"`
 	LastActiveAt time.Time  `gorm:"index"`
 	Status       UserStatus `gorm:"type:user_status;default:'offline'"`
+	Role         UserRole   `gorm:"type:user_role;default:'user'"`
+
+	// Profile
+	DisplayName string `gorm:"size:100"`
+	Bio         string `gorm:"size:500"`
+	TimeZone    string `gorm:"size:64;not null;default:'UTC'"`
+	Avatar      Avatar `gorm:"serializer:json;type:text"`
+
+	// Bot accounts authenticate only with API tokens and are managed by their owner
+	IsBot      bool  `gorm:"default:false;not null"`
+	BotOwnerID *uint `gorm:"index" json:",omitempty"`
+
+	// Email verification
+	EmailVerified   bool       `gorm:"default:false;not null"`
+	EmailVerifiedAt *time.Time `json:",omitempty"`
+
+	// Moderation. Tokens issued at or before SessionsValidAfter are rejected.
+	SuspendedAt           *time.Time `gorm:"index" json:",omitempty"`
+	SuspensionReason      string     `gorm:"size:500"`
+	PasswordResetRequired bool       `gorm:"default:false;not null"`
+	SessionsValidAfter    *time.Time `json:",omitempty"`
 
 	// Relationships
 	SentMessages     []Message `gorm:"foreignKey:SenderID"`
@@ -60,24 +79,109 @@ func (u *User) ValidateRegistration() error {
 	return nil
 }
 
-// SetPassword securely hashes and stores password
+// SetPassword securely hashes and stores password using Argon2id.
+// Only the minimum length is checked here, the configurable policy lives in the application layer.
 func (u *User) SetPassword(plainText string) error {
 	if len(plainText) < 8 {
 		return ErrWeakPassword
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(plainText), bcrypt.DefaultCost)
+	hash, err := hashPassword(plainText)
 	if err != nil {
 		return err
 	}
-	u.PasswordHash = string(hash)
+	u.PasswordHash = hash
 	return nil
 }
 
-// CheckPassword verifies against stored hash
+// CheckPassword verifies against stored hash, legacy bcrypt hashes are still accepted
 func (u *User) CheckPassword(plainText string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(plainText))
-	return err == nil
+	return verifyPassword(u.PasswordHash, plainText)
 }
+
+// PasswordNeedsRehash is true when the stored hash predates the current hashing settings
+func (u *User) PasswordNeedsRehash() bool {
+	return passwordNeedsRehash(u.PasswordHash)
+}
+func (u *User) IsOwnedBy(userID uint) bool {
+	return u.IsBot && u.BotOwnerID != nil && *u.BotOwnerID == userID
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// SessionRevoked reports whether a token issued at issuedAt predates the last session revocation
+func (u *User) SessionRevoked(issuedAt time.Time) bool {
+	return u.SessionsValidAfter != nil && !issuedAt.After(*u.SessionsValidAfter)
+}
+
 func (u *User) UpdateLastActive() {
 	u.LastActiveAt = time.Now().UTC()
 }
+
+// MarkEmailVerified flags the current email address as confirmed by its owner
+func (u *User) MarkEmailVerified() {
+	now := time.Now().UTC()
+	u.EmailVerified = true
+	u.EmailVerifiedAt = &now
+}
+
+// SenderInfo summarizes the user for message payloads
+func (u *User) SenderInfo() *SenderInfo {
+	if u.ID == 0 {
+		return nil // Sender was not loaded
+	}
+	return &SenderInfo{
+		ID:          u.ID,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		AvatarURL:   u.Avatar.URL(64),
+	}
+}
+
+// EmailDomain is the organization part of the user's address, lower cased
+func (u *User) EmailDomain() string {
+	at := strings.LastIndex(u.Email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(u.Email[at+1:])
+}
+
+// UserSearch pages through the user directory ordered by username. Results start
+// after (AfterUsername, AfterID) when a cursor is given.
+type UserSearch struct {
+	Query         string // Case-insensitive substring of the username or display name, empty lists everyone
+	Limit         int
+	AfterUsername string
+	AfterID       uint
+	ExcludeIDs    []uint
+}
+
+type UserAccountStatus string
+
+const (
+	AccountActive    UserAccountStatus = "active"
+	AccountSuspended UserAccountStatus = "suspended"
+)
+
+// UserListFilter selects users for the admin listing, newest first
+type UserListFilter struct {
+	Query  string // Case-insensitive substring of the username, email or display name
+	Role   UserRole
+	Status UserAccountStatus
+	IsBot  *bool
+	Limit  int
+	Offset int
+}
+
+// UserActivity is the per-user usage summary shown to admins
+type UserActivity struct {
+	MessagesSent int64
+	LastActiveAt time.Time
+	StorageBytes int64
+}
@@ -1,10 +1,12 @@
 package domain
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestUserModel(t *testing.T) {
@@ -83,6 +85,24 @@ func TestUserModel(t *testing.T) {
 			assert.False(t, u.CheckPassword("wrongpassword"))
 			assert.False(t, u.CheckPassword(""))
 		})
+
+		t.Run("UsesArgon2id", func(t *testing.T) {
+			u := baseUser
+			assert.NoError(t, u.SetPassword("testpassword"))
+			assert.True(t, strings.HasPrefix(u.PasswordHash, "$argon2id$"))
+			assert.False(t, u.PasswordNeedsRehash())
+		})
+
+		t.Run("LegacyBcryptHash", func(t *testing.T) {
+			hash, err := bcrypt.GenerateFromPassword([]byte("testpassword"), bcrypt.MinCost)
+			assert.NoError(t, err)
+
+			u := baseUser
+			u.PasswordHash = string(hash)
+			assert.True(t, u.CheckPassword("testpassword"))
+			assert.False(t, u.CheckPassword("wrongpassword"))
+			assert.True(t, u.PasswordNeedsRehash())
+		})
 	})
 
 	t.Run("ActivityTracking", func(t *testing.T) {
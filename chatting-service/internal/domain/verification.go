@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// VerificationPurpose keeps tokens of one kind from being used for another
+type VerificationPurpose string
+
+const (
+	VerificationEmail         VerificationPurpose = "email"          // Confirms the address
+	VerificationPasswordReset VerificationPurpose = "password_reset" // Completes a forced password reset
+)
+
+// EmailVerification is a single-use token that proves ownership of an email address.
+// Only the SHA-256 hash of the token is stored, the raw value travels in the emailed link.
+type EmailVerification struct {
+	gorm.Model
+	UserID     uint                `gorm:"index;not null"`
+	Email      string              `gorm:"size:100;not null"`
+	TokenHash  string              `gorm:"uniqueIndex;size:64;not null"`
+	Purpose    VerificationPurpose `gorm:"size:20;not null;default:'email'"`
+	ExpiresAt  time.Time           `gorm:"index;not null"`
+	VerifiedAt *time.Time          `json:"verified_at,omitempty"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (v *EmailVerification) IsExpired() bool {
+	return time.Now().UTC().After(v.ExpiresAt)
+}
+
+func (v *EmailVerification) IsUsed() bool {
+	return v.VerifiedAt != nil
+}
+
+// IsUsable checks the token is still valid for the address currently on the account,
+// a verification sent before an email change must not confirm the new address
+func (v *EmailVerification) IsUsable(currentEmail string) error {
+	switch {
+	case v.IsUsed():
+		return ErrVerificationUsed
+	case v.IsExpired():
+		return ErrVerificationExpired
+	case v.Email != currentEmail:
+		return ErrVerificationMismatch
+	}
+	return nil
+}
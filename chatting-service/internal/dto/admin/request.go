@@ -0,0 +1,23 @@
+package admin
+
+type AuditQueryRequest struct {
+	ActorID uint   `query:"actor_id" example:"1"`
+	Action  string `query:"action" example:"auth.login"`
+	From    string `query:"from" example:"2025-01-01T00:00:00Z"` // RFC3339
+	To      string `query:"to" example:"2025-02-01T00:00:00Z"`   // RFC3339, exclusive
+	Limit   int    `query:"limit" validate:"omitempty,min=1,max=1000" example:"100"`
+	Offset  int    `query:"offset" validate:"omitempty,min=0" example:"0"`
+}
+
+type UserListRequest struct {
+	Query  string `query:"q" example:"john"` // Matches username, email or display name
+	Role   string `query:"role" example:"user"`
+	Status string `query:"status" example:"suspended"` // active or suspended
+	IsBot  *bool  `query:"is_bot" example:"false"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=200" example:"50"`
+	Offset int    `query:"offset" validate:"omitempty,min=0" example:"0"`
+}
+
+type SuspendUserRequest struct {
+	Reason string `json:"reason" validate:"max=500" example:"Sending spam"`
+}
@@ -0,0 +1,56 @@
+package admin
+
+import "time"
+
+type AuditEventResponse struct {
+	ID         uint      `json:"id" example:"42"`
+	CreatedAt  time.Time `json:"created_at"`
+	ActorID    *uint     `json:"actor_id,omitempty" example:"1"`
+	Actor      string    `json:"actor,omitempty" example:"johndoe"`
+	Action     string    `json:"action" example:"auth.login"`
+	TargetType string    `json:"target_type,omitempty" example:"user"`
+	TargetID   string    `json:"target_id,omitempty" example:"7"`
+	Outcome    string    `json:"outcome" example:"success"`
+	IP         string    `json:"ip,omitempty" example:"10.0.0.1"`
+	RequestID  string    `json:"request_id,omitempty" example:"4f6c2d1e-..."`
+	Details    string    `json:"details,omitempty"`
+}
+
+type AuditEventListResponse struct {
+	Events []AuditEventResponse `json:"events"`
+	Count  int                  `json:"count" example:"1"`
+}
+
+type UserSummaryResponse struct {
+	ID                    uint       `json:"id" example:"7"`
+	Username              string     `json:"username" example:"johndoe"`
+	Email                 string     `json:"email" example:"john@example.com"`
+	DisplayName           string     `json:"display_name,omitempty" example:"John Doe"`
+	Role                  string     `json:"role" example:"user"`
+	IsBot                 bool       `json:"is_bot"`
+	EmailVerified         bool       `json:"email_verified"`
+	Status                string     `json:"status" example:"active"` // active or suspended
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason      string     `json:"suspension_reason,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	LastActiveAt          time.Time  `json:"last_active_at"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+type UserListResponse struct {
+	Users  []UserSummaryResponse `json:"users"`
+	Total  int64                 `json:"total" example:"120"`
+	Limit  int                   `json:"limit" example:"50"`
+	Offset int                   `json:"offset" example:"0"`
+}
+
+type UserActivityResponse struct {
+	MessagesSent int64     `json:"messages_sent" example:"314"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	StorageBytes int64     `json:"storage_bytes" example:"1048576"`
+}
+
+type UserDetailResponse struct {
+	UserSummaryResponse
+	Activity UserActivityResponse `json:"activity"`
+}
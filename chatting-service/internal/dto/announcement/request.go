@@ -0,0 +1,16 @@
+package announcement
+
+type CreateChannelRequest struct {
+	Name        string `json:"name" validate:"required,max=100" example:"Company news"`
+	Description string `json:"description,omitempty" validate:"max=500"`
+	Audience    string `json:"audience,omitempty" example:"user"` // Empty for everyone, otherwise a role
+}
+
+type PostRequest struct {
+	Content  string `json:"content" validate:"max=5000" example:"The office is closed on Friday"`
+	MediaURL string `json:"media_url,omitempty"`
+}
+
+type MarkReadRequest struct {
+	PostID uint `json:"post_id" validate:"required" example:"120"` // Newest post the user has seen
+}
@@ -0,0 +1,34 @@
+package announcement
+
+import (
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+)
+
+type ChannelResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Audience    string    `json:"audience,omitempty" example:"user"` // Empty when everyone follows the channel
+	LastReadID  uint      `json:"last_read_id"`
+	UnreadCount int64     `json:"unread_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type PostResponse struct {
+	ID        uint               `json:"id"`
+	ChannelID uint               `json:"channel_id"`
+	Content   string             `json:"content"`
+	MediaURL  string             `json:"media_url,omitempty"`
+	Author    *domain.SenderInfo `json:"author,omitempty"`
+	Pinned    bool               `json:"pinned"`
+	PinnedAt  *time.Time         `json:"pinned_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type PosterResponse struct {
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	GrantedAt time.Time `json:"granted_at"`
+}
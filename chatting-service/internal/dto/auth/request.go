@@ -19,3 +19,19 @@ type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" validate:"required,min=8" example:"Password123"`
 	NewPassword     string `json:"new_password" validate:"required,min=8" example:"NewPassword123"`
 }
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" query:"token" validate:"required"`
+}
+
+type OIDCCallbackRequest struct {
+	Code             string `query:"code"`
+	State            string `query:"state"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"` // Reset code from the email or the admin
+	NewPassword string `json:"new_password" validate:"required,min=8" example:"NewPassword123"`
+}
@@ -1,11 +1,16 @@
 package auth
 
 type AuthResponse struct {
-	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsIn..."`
-	RefreshToken string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsIn..."`
-	ExpiresIn    int    `json:"expires_in" example:"3600"` // seconds
-	TokenType    string `json:"token_type" example:"Bearer"`
-	UserID       uint   `json:"user_id" example:"1"`
-	Username     string `json:"username" example:"johndoe"`
-	Email        string `json:"email" example:"john@example.com"`
+	AccessToken   string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsIn..."`
+	RefreshToken  string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsIn..."`
+	ExpiresIn     int    `json:"expires_in" example:"3600"` // seconds
+	TokenType     string `json:"token_type" example:"Bearer"`
+	UserID        uint   `json:"user_id" example:"1"`
+	Username      string `json:"username" example:"johndoe"`
+	Email         string `json:"email" example:"john@example.com"`
+	EmailVerified bool   `json:"email_verified" example:"false"`
+}
+
+type OIDCLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url" example:"https://idp.example.com/authorize?client_id=..."`
 }
@@ -0,0 +1,5 @@
+package contact
+
+type SendRequestRequest struct {
+	UserID uint `json:"user_id" validate:"required" example:"42"`
+}
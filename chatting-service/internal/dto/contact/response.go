@@ -0,0 +1,19 @@
+package contact
+
+import "time"
+
+type ContactResponse struct {
+	UserID     uint       `json:"user_id"`
+	Username   string     `json:"username"`
+	IsBot      bool       `json:"is_bot"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+type RequestResponse struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"` // The other party
+	Username  string    `json:"username"`
+	Direction string    `json:"direction" example:"incoming"` // incoming or outgoing
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package export
+
+import "time"
+
+type ExportResponse struct {
+	ID          uint       `json:"id"`
+	Status      string     `json:"status" example:"running"` // pending, running, ready, failed or expired
+	Progress    int        `json:"progress" example:"40"`    // Percent complete
+	Error       string     `json:"error,omitempty"`
+	Size        int64      `json:"size,omitempty"` // Archive size in bytes once ready
+	DownloadURL string     `json:"download_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,14 @@
+package media
+
+type MediaListRequest struct {
+	Type   string `query:"type" example:"image"` // Exact content type (image/png) or top-level type (image)
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=200" example:"50"`
+	Offset int    `query:"offset" validate:"omitempty,min=0" example:"0"`
+}
+
+type CreateUploadRequest struct {
+	Filename    string `json:"filename" example:"holiday.mp4"`
+	ContentType string `json:"content_type" example:"video/mp4"`                                                    // Optional, the content decides on completion
+	Size        int64  `json:"size" example:"104857600"`                                                            // Total bytes that will be sent
+	Checksum    string `json:"checksum" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"` // Hex SHA-256 of the whole file
+}
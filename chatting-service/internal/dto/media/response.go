@@ -0,0 +1,40 @@
+package media
+
+import "time"
+
+type MediaItemResponse struct {
+	ID               uint                `json:"id" example:"12"`
+	URL              string              `json:"url" example:"http://localhost:8080/media/user_1/1_1700000000_abcd1234.png"`
+	OriginalFilename string              `json:"original_filename" example:"holiday.png"`
+	ContentType      string              `json:"content_type" example:"image/png"`
+	Size             int64               `json:"size" example:"48213"`
+	Checksum         string              `json:"checksum" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"` // Hex SHA-256
+	Width            *int                `json:"width,omitempty" example:"800"`
+	Height           *int                `json:"height,omitempty" example:"600"`
+	Thumbnails       []ThumbnailResponse `json:"thumbnails,omitempty"`
+	CreatedAt        time.Time           `json:"created_at"`
+}
+
+type ThumbnailResponse struct {
+	Size   int    `json:"size" example:"320"` // Bound of the longest edge
+	Width  int    `json:"width" example:"320"`
+	Height int    `json:"height" example:"240"`
+	URL    string `json:"url" example:"http://localhost:8080/media/user_1/1_1700000000_abcd1234.jpg_thumb320.jpg"`
+}
+
+type MediaListResponse struct {
+	Media  []MediaItemResponse `json:"media"`
+	Total  int64               `json:"total" example:"1"`
+	Limit  int                 `json:"limit" example:"50"`
+	Offset int                 `json:"offset" example:"0"`
+}
+
+type UploadResponse struct {
+	ID          string    `json:"id" example:"q3Xv0bT9x1Lr8yZ2c4Vn6mP0aS5dF7gH"`
+	Filename    string    `json:"filename" example:"holiday.mp4"`
+	ContentType string    `json:"content_type,omitempty" example:"video/mp4"`
+	Size        int64     `json:"size" example:"104857600"`
+	Offset      int64     `json:"offset" example:"8388608"`     // Bytes received, where the next chunk starts
+	ChunkSize   int64     `json:"chunk_size" example:"8388608"` // Most a single chunk may carry
+	ExpiresAt   time.Time `json:"expires_at"`                   // Dropped unless a chunk arrives before then
+}
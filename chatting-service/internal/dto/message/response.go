@@ -6,16 +6,45 @@ type MessageResponse struct {
 	ID          uint      `json:"id"`
 	Content     string    `json:"content"`
 	MediaURL    string    `json:"media_url,omitempty"`
+	Media       *Media    `json:"media,omitempty"` // Dimensions and thumbnails of library media
 	Type        string    `json:"type"`
 	Status      string    `json:"status"`
 	SenderID    uint      `json:"sender_id"`
+	Sender      *Sender   `json:"sender,omitempty"`
 	RecipientID uint      `json:"recipient_id,omitempty"`
 	SentAt      time.Time `json:"sent_at"`
 	DeliveredAt time.Time `json:"delivered_at,omitempty"`
 	ReadAt      time.Time `json:"read_at,omitempty"`
+	Erased      bool      `json:"erased,omitempty"` // Sender deleted their account, content was removed
+}
+
+type Media struct {
+	ContentType string      `json:"content_type"`
+	Width       *int        `json:"width,omitempty"`
+	Height      *int        `json:"height,omitempty"`
+	Thumbnails  []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+type Thumbnail struct {
+	Size   int    `json:"size"` // Bound of the longest edge
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url"`
+}
+
+// Sender is the author's public profile summary
+type Sender struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
 }
 
 type ConversationResponse struct {
 	Messages []MessageResponse `json:"messages"`
 	Total    int64             `json:"total"`
 }
+
+type UnreadCountResponse struct {
+	Unread int64 `json:"unread"`
+}
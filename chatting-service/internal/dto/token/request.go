@@ -0,0 +1,11 @@
+package token
+
+type CreateTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100" example:"ci-notifier"`
+	Scopes        []string `json:"scopes" validate:"required,min=1" example:"messages:send"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1" example:"90"` // 0 = never expires
+}
+
+type CreateBotRequest struct {
+	Username string `json:"username" validate:"required,min=3" example:"ci-bot"`
+}
@@ -0,0 +1,29 @@
+package token
+
+import "time"
+
+type TokenResponse struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" example:"cst_a1b2c3"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreatedTokenResponse is the only time the plain token is shown
+type CreatedTokenResponse struct {
+	TokenResponse
+	Token string `json:"token" example:"cst_..."`
+}
+
+type BotResponse struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	IsBot     bool      `json:"is_bot"`
+	OwnerID   uint      `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,11 +1,41 @@
 package user
 
+import "time"
+
 type UpdateProfileRequest struct {
-	Username string `json:"username" validate:"omitempty,min=3"`
-	Email    string `json:"email" validate:"omitempty,email"`
+	Username    string  `json:"username" validate:"omitempty,min=3"`
+	Email       string  `json:"email" validate:"omitempty,email"`
+	DisplayName *string `json:"display_name,omitempty" validate:"omitempty,max=100"` // Omit to keep, empty string clears
+	Bio         *string `json:"bio,omitempty" validate:"omitempty,max=500"`
+	TimeZone    *string `json:"time_zone,omitempty" example:"Europe/Berlin"`
 }
 
 type MessageHistoryRequest struct {
 	Limit  int `json:"limit" validate:"omitempty,min=1,max=100"`
 	Offset int `json:"offset" validate:"omitempty,min=0"`
 }
+
+type MuteRequest struct {
+	Scope        string     `json:"scope" validate:"required,oneof=conversation sender"`
+	TargetUserID uint       `json:"target_user_id" validate:"required"`
+	Until        *time.Time `json:"until,omitempty"` // Omit to mute until unmuted
+}
+
+type UpdatePreferencesRequest struct {
+	MuteBroadcasts    bool          `json:"mute_broadcasts"`
+	QuietHoursEnabled bool          `json:"quiet_hours_enabled"`
+	QuietHoursStart   string        `json:"quiet_hours_start" example:"22:00"`
+	QuietHoursEnd     string        `json:"quiet_hours_end" example:"07:00"`
+	TimeZone          string        `json:"time_zone" example:"Europe/Berlin"`
+	Mutes             []MuteRequest `json:"mutes"`
+}
+
+type SearchUsersRequest struct {
+	Q      string `query:"q"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"` // Confirms the erasure
+}
@@ -2,12 +2,23 @@ package user
 
 import "time"
 
+type AvatarResponse struct {
+	Size int    `json:"size"`
+	URL  string `json:"url"`
+}
+
 type ProfileResponse struct {
-	ID         uint      `json:"id"`
-	Username   string    `json:"username"`
-	Email      string    `json:"email"`
-	LastActive time.Time `json:"last_active"`
-	Status     string    `json:"status"`
+	ID            uint             `json:"id"`
+	Username      string           `json:"username"`
+	Email         string           `json:"email"`
+	EmailVerified bool             `json:"email_verified"`
+	DisplayName   string           `json:"display_name"`
+	Bio           string           `json:"bio"`
+	TimeZone      string           `json:"time_zone"`
+	Avatar        []AvatarResponse `json:"avatar"` // One entry per standard size, empty without an avatar
+	LastActive    time.Time        `json:"last_active"`
+	Status        string           `json:"status"`
+	IsBot         bool             `json:"is_bot"`
 }
 
 type MessageHistoryResponse struct {
@@ -22,3 +33,43 @@ type MessageResponse struct {
 	Status    string    `json:"status"`
 	Type      string    `json:"type"`
 }
+
+type BlockedUserResponse struct {
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+type MuteResponse struct {
+	Scope        string     `json:"scope"`
+	TargetUserID uint       `json:"target_user_id"`
+	Until        *time.Time `json:"until,omitempty"`
+}
+
+type PreferencesResponse struct {
+	MuteBroadcasts    bool           `json:"mute_broadcasts"`
+	QuietHoursEnabled bool           `json:"quiet_hours_enabled"`
+	QuietHoursStart   string         `json:"quiet_hours_start"`
+	QuietHoursEnd     string         `json:"quiet_hours_end"`
+	TimeZone          string         `json:"time_zone"`
+	Mutes             []MuteResponse `json:"mutes"`
+}
+
+// PublicProfileResponse is what other users see in the directory
+type PublicProfileResponse struct {
+	ID          uint             `json:"id"`
+	Username    string           `json:"username"`
+	Email       string           `json:"email,omitempty"` // Only for admins and the user themselves
+	DisplayName string           `json:"display_name,omitempty"`
+	Bio         string           `json:"bio,omitempty"`
+	TimeZone    string           `json:"time_zone,omitempty"`
+	Avatar      []AvatarResponse `json:"avatar,omitempty"`
+	LastActive  time.Time        `json:"last_active"`
+	Status      string           `json:"status"`
+	IsBot       bool             `json:"is_bot"`
+}
+
+type DirectoryResponse struct {
+	Users      []PublicProfileResponse `json:"users"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
@@ -44,6 +44,7 @@ func (p *JWTProvider) generateToken(user *domain.User, isRefresh bool) (string,
 		UserID:    user.ID,
 		Username:  user.Username,
 		Email:     user.Email,
+		Role:      user.Role,
 		SessionID: sessionID,
 		IsRefresh: isRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
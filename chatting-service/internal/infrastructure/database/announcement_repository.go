@@ -0,0 +1,338 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type announcementRepository struct {
+	db *gorm.DB
+}
+
+func NewAnnouncementRepository(db *gorm.DB) domain.AnnouncementRepository {
+	return &announcementRepository{db: db}
+}
+
+func withAnnouncementAuthor(db *gorm.DB) *gorm.DB {
+	return db.Preload("Author", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Select("id", "username", "display_name", "avatar")
+	})
+}
+
+func (r *announcementRepository) CreateChannel(ctx context.Context, channel *domain.AnnouncementChannel) error {
+	if err := r.db.WithContext(ctx).Create(channel).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return shared.ErrConflict.WithDetails("a channel with this name already exists")
+		}
+		shared.Log.Error("create announcement channel failed",
+			zap.String("operation", "CreateChannel"),
+			zap.String("name", channel.Name),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("create announcement channel failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *announcementRepository) FindChannel(ctx context.Context, channelID uint) (*domain.AnnouncementChannel, error) {
+	var channel domain.AnnouncementChannel
+	err := r.db.WithContext(ctx).First(&channel, channelID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("announcement channel not found")
+	}
+	if err != nil {
+		shared.Log.Error("find announcement channel failed",
+			zap.String("operation", "FindChannel"),
+			zap.Uint("channelID", channelID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find announcement channel failed").WithDetails(err.Error())
+	}
+	return &channel, nil
+}
+
+func (r *announcementRepository) ListChannels(ctx context.Context) ([]domain.AnnouncementChannel, error) {
+	var channels []domain.AnnouncementChannel
+	if err := r.db.WithContext(ctx).Order("name").Find(&channels).Error; err != nil {
+		shared.Log.Error("list announcement channels failed",
+			zap.String("operation", "ListChannels"),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("list announcement channels failed").WithDetails(err.Error())
+	}
+	return channels, nil
+}
+
+// DeleteChannel soft deletes the channel and its posts, posters and read positions are dropped
+func (r *announcementRepository) DeleteChannel(ctx context.Context, channelID uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("channel_id = ?", channelID).Delete(&domain.Announcement{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("channel_id = ?", channelID).Delete(&domain.AnnouncementPoster{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("channel_id = ?", channelID).Delete(&domain.AnnouncementReadState{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.AnnouncementChannel{}, channelID).Error
+	})
+	if err != nil {
+		shared.Log.Error("delete announcement channel failed",
+			zap.String("operation", "DeleteChannel"),
+			zap.Uint("channelID", channelID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete announcement channel failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *announcementRepository) AddPoster(ctx context.Context, channelID, userID uint) error {
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain.AnnouncementPoster{ChannelID: channelID, UserID: userID}).Error
+	if err != nil {
+		shared.Log.Error("add announcement poster failed",
+			zap.String("operation", "AddPoster"),
+			zap.Uint("channelID", channelID),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("add announcement poster failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *announcementRepository) RemovePoster(ctx context.Context, channelID, userID uint) error {
+	err := r.db.WithContext(ctx).
+		Where("channel_id = ? AND user_id = ?", channelID, userID).
+		Delete(&domain.AnnouncementPoster{}).Error
+	if err != nil {
+		shared.Log.Error("remove announcement poster failed",
+			zap.String("operation", "RemovePoster"),
+			zap.Uint("channelID", channelID),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("remove announcement poster failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *announcementRepository) IsPoster(ctx context.Context, channelID, userID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.AnnouncementPoster{}).
+		Where("channel_id = ? AND user_id = ?", channelID, userID).
+		Count(&count).Error
+	if err != nil {
+		shared.Log.Error("check announcement poster failed",
+			zap.String("operation", "IsPoster"),
+			zap.Uint("channelID", channelID),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return false, shared.ErrDatabaseOperation.WithDetails("check announcement poster failed").WithDetails(err.Error())
+	}
+	return count > 0, nil
+}
+
+func (r *announcementRepository) ListPosters(ctx context.Context, channelID uint) ([]domain.AnnouncementPoster, error) {
+	var posters []domain.AnnouncementPoster
+	err := r.db.WithContext(ctx).
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "username", "display_name")
+		}).
+		Where("channel_id = ?", channelID).
+		Order("created_at").
+		Find(&posters).Error
+	if err != nil {
+		shared.Log.Error("list announcement posters failed",
+			zap.String("operation", "ListPosters"),
+			zap.Uint("channelID", channelID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("list announcement posters failed").WithDetails(err.Error())
+	}
+	return posters, nil
+}
+
+func (r *announcementRepository) CreatePost(ctx context.Context, post *domain.Announcement) error {
+	if err := r.db.WithContext(ctx).Create(post).Error; err != nil {
+		shared.Log.Error("create announcement failed",
+			zap.String("operation", "CreatePost"),
+			zap.Uint("channelID", post.ChannelID),
+			zap.Uint("authorID", post.AuthorID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("create announcement failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *announcementRepository) FindPost(ctx context.Context, postID uint) (*domain.Announcement, error) {
+	var post domain.Announcement
+	err := withAnnouncementAuthor(r.db.WithContext(ctx)).First(&post, postID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("announcement not found")
+	}
+	if err != nil {
+		shared.Log.Error("find announcement failed",
+			zap.String("operation", "FindPost"),
+			zap.Uint("postID", postID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find announcement failed").WithDetails(err.Error())
+	}
+	return &post, nil
+}
+
+func (r *announcementRepository) ListPosts(ctx context.Context, channelID, beforeID uint, limit int) ([]domain.Announcement, error) {
+	var posts []domain.Announcement
+	q := withAnnouncementAuthor(r.db.WithContext(ctx)).Where("channel_id = ?", channelID)
+	if beforeID > 0 {
+		q = q.Where("id < ?", beforeID)
+	}
+	if err := q.Order("id DESC").Limit(limit).Find(&posts).Error; err != nil {
+		shared.Log.Error("list announcements failed",
+			zap.String("operation", "ListPosts"),
+			zap.Uint("channelID", channelID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("list announcements failed").WithDetails(err.Error())
+	}
+	return posts, nil
+}
+
+func (r *announcementRepository) ListPinned(ctx context.Context, channelID uint) ([]domain.Announcement, error) {
+	var posts []domain.Announcement
+	err := withAnnouncementAuthor(r.db.WithContext(ctx)).
+		Where("channel_id = ? AND pinned_at IS NOT NULL", channelID).
+		Order("pinned_at DESC").
+		Find(&posts).Error
+	if err != nil {
+		shared.Log.Error("list pinned announcements failed",
+			zap.String("operation", "ListPinned"),
+			zap.Uint("channelID", channelID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("list pinned announcements failed").WithDetails(err.Error())
+	}
+	return posts, nil
+}
+
+func (r *announcementRepository) SetPinned(ctx context.Context, postID uint, pinnedAt *time.Time) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.Announcement{}).
+		Where("id = ?", postID).
+		Update("pinned_at", pinnedAt).Error
+	if err != nil {
+		shared.Log.Error("pin announcement failed",
+			zap.String("operation", "SetPinned"),
+			zap.Uint("postID", postID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("pin announcement failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *announcementRepository) DeletePost(ctx context.Context, postID uint) error {
+	if err := r.db.WithContext(ctx).Delete(&domain.Announcement{}, postID).Error; err != nil {
+		shared.Log.Error("delete announcement failed",
+			zap.String("operation", "DeletePost"),
+			zap.Uint("postID", postID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete announcement failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *announcementRepository) MarkRead(ctx context.Context, channelID, userID, lastReadID uint) error {
+	state := domain.AnnouncementReadState{ChannelID: channelID, UserID: userID, LastReadID: lastReadID}
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "channel_id"}, {Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"last_read_id": gorm.Expr("GREATEST(announcement_read_states.last_read_id, EXCLUDED.last_read_id)"),
+				"updated_at":   gorm.Expr("EXCLUDED.updated_at"),
+			}),
+		}).
+		Create(&state).Error
+	if err != nil {
+		shared.Log.Error("mark announcements read failed",
+			zap.String("operation", "MarkRead"),
+			zap.Uint("channelID", channelID),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("mark announcements read failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+// ReadSummaries returns the read position and unread count of each channel in one query
+func (r *announcementRepository) ReadSummaries(ctx context.Context, userID uint, channelIDs []uint) (map[uint]domain.ChannelSummary, error) {
+	summaries := make(map[uint]domain.ChannelSummary, len(channelIDs))
+	if len(channelIDs) == 0 {
+		return summaries, nil
+	}
+
+	var rows []struct {
+		ChannelID   uint
+		LastReadID  uint
+		UnreadCount int64
+	}
+	err := r.db.WithContext(ctx).
+		Table("announcement_channels AS c").
+		Select(`c.id AS channel_id,
+			COALESCE(s.last_read_id, 0) AS last_read_id,
+			(SELECT COUNT(*) FROM announcements a
+				WHERE a.channel_id = c.id AND a.deleted_at IS NULL AND a.id > COALESCE(s.last_read_id, 0)) AS unread_count`).
+		Joins("LEFT JOIN announcement_read_states s ON s.channel_id = c.id AND s.user_id = ?", userID).
+		Where("c.id IN ?", channelIDs).
+		Scan(&rows).Error
+	if err != nil {
+		shared.Log.Error("read announcement summaries failed",
+			zap.String("operation", "ReadSummaries"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("read announcement summaries failed").WithDetails(err.Error())
+	}
+	for _, row := range rows {
+		summaries[row.ChannelID] = domain.ChannelSummary{LastReadID: row.LastReadID, UnreadCount: row.UnreadCount}
+	}
+	return summaries, nil
+}
+
+// DeleteForUser drops the read positions and posting rights of one user, their posts stay
+func (r *announcementRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Where("user_id = ?", userID).Delete(&domain.AnnouncementReadState{}).Error; err != nil {
+		shared.Log.Error("delete announcement read states failed",
+			zap.String("operation", "DeleteForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete announcement read states failed").WithDetails(err.Error())
+	}
+	if err := db.Where("user_id = ?", userID).Delete(&domain.AnnouncementPoster{}).Error; err != nil {
+		shared.Log.Error("delete announcement posters failed",
+			zap.String("operation", "DeleteForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete announcement posters failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *announcementRepository) HasMedia(ctx context.Context, mediaURL string, role domain.UserRole) (bool, error) {
+	var count int64
+	q := r.db.WithContext(ctx).
+		Model(&domain.Announcement{}).
+		Joins("JOIN announcement_channels c ON c.id = announcements.channel_id AND c.deleted_at IS NULL").
+		Where("announcements.media_url = ?", mediaURL)
+	if role != domain.RoleAdmin {
+		q = q.Where("c.audience_role IS NULL OR c.audience_role = ?", role)
+	}
+	if err := q.Limit(1).Count(&count).Error; err != nil {
+		shared.Log.Error("check announcement media failed",
+			zap.String("operation", "HasMedia"),
+			zap.Error(err))
+		return false, shared.ErrDatabaseOperation.WithDetails("check announcement media failed").WithDetails(err.Error())
+	}
+	return count > 0, nil
+}
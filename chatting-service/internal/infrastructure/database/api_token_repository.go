@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type apiTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) domain.APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+func (r *apiTokenRepository) Create(ctx context.Context, token *domain.APIToken) (*domain.APIToken, error) {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		shared.Log.Error("create api token failed",
+			zap.String("operation", "Create"),
+			zap.Uint("userID", token.UserID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("create api token failed").WithDetails(err.Error())
+	}
+	return token, nil
+}
+
+func (r *apiTokenRepository) FindByID(ctx context.Context, tokenID uint) (*domain.APIToken, error) {
+	var token domain.APIToken
+	err := r.db.WithContext(ctx).First(&token, tokenID).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("api token not found")
+	}
+	if err != nil {
+		shared.Log.Error("find api token failed",
+			zap.String("operation", "FindByID"),
+			zap.Uint("tokenID", tokenID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find api token failed").WithDetails(err.Error())
+	}
+	return &token, nil
+}
+
+func (r *apiTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error) {
+	var token domain.APIToken
+	err := r.db.WithContext(ctx).
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "username", "email", "role", "is_bot", "suspended_at")
+		}).
+		Where("token_hash = ?", tokenHash).
+		First(&token).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("api token not found")
+	}
+	if err != nil {
+		shared.Log.Error("find api token by hash failed",
+			zap.String("operation", "FindByHash"),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find api token failed").WithDetails(err.Error())
+	}
+	return &token, nil
+}
+
+func (r *apiTokenRepository) ListByUser(ctx context.Context, userID uint) ([]domain.APIToken, error) {
+	var tokens []domain.APIToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		shared.Log.Error("list api tokens failed",
+			zap.String("operation", "ListByUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("list api tokens failed").WithDetails(err.Error())
+	}
+	return tokens, nil
+}
+
+func (r *apiTokenRepository) Revoke(ctx context.Context, tokenID uint) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.APIToken{}).
+		Where("id = ? AND revoked_at IS NULL", tokenID).
+		Update("revoked_at", time.Now().UTC()).Error
+	if err != nil {
+		shared.Log.Error("revoke api token failed",
+			zap.String("operation", "Revoke"),
+			zap.Uint("tokenID", tokenID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("revoke api token failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *apiTokenRepository) TouchLastUsed(ctx context.Context, tokenID uint) error {
+	return r.db.WithContext(ctx).Exec(
+		"UPDATE api_tokens SET last_used_at = ? WHERE id = ?",
+		time.Now().UTC(),
+		tokenID,
+	).Error
+}
+
+func (r *apiTokenRepository) RevokeAllForUser(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now().UTC()).Error
+	if err != nil {
+		shared.Log.Error("revoke api tokens failed",
+			zap.String("operation", "RevokeAllForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("revoke api tokens failed").WithDetails(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type auditEventRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditEventRepository(db *gorm.DB) domain.AuditEventRepository {
+	return &auditEventRepository{db: db}
+}
+
+func (r *auditEventRepository) Create(ctx context.Context, event *domain.AuditEvent) error {
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		shared.Log.Error("create audit event failed",
+			zap.String("operation", "Create"),
+			zap.String("action", string(event.Action)),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("create audit event failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *auditEventRepository) Find(ctx context.Context, query domain.AuditQuery) ([]domain.AuditEvent, error) {
+	db := r.db.WithContext(ctx).Model(&domain.AuditEvent{})
+
+	if query.ActorID != nil {
+		db = db.Where("actor_id = ?", *query.ActorID)
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if !query.From.IsZero() {
+		db = db.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		db = db.Where("created_at < ?", query.To)
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+	if query.Offset > 0 {
+		db = db.Offset(query.Offset)
+	}
+
+	var events []domain.AuditEvent
+	if err := db.Order("created_at DESC, id DESC").Find(&events).Error; err != nil {
+		shared.Log.Error("find audit events failed",
+			zap.String("operation", "Find"),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find audit events failed").WithDetails(err.Error())
+	}
+	return events, nil
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type contactRepository struct {
+	db *gorm.DB
+}
+
+func NewContactRepository(db *gorm.DB) domain.ContactRepository {
+	return &contactRepository{db: db}
+}
+
+func withContactUsers(db *gorm.DB) *gorm.DB {
+	selectUser := func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "is_bot")
+	}
+	return db.Preload("Requester", selectUser).Preload("Addressee", selectUser)
+}
+
+func (r *contactRepository) Create(ctx context.Context, contact *domain.Contact) (*domain.Contact, error) {
+	if err := r.db.WithContext(ctx).Create(contact).Error; err != nil {
+		shared.Log.Error("create contact request failed",
+			zap.String("operation", "Create"),
+			zap.Uint("requesterID", contact.RequesterID),
+			zap.Uint("addresseeID", contact.AddresseeID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("create contact request failed").WithDetails(err.Error())
+	}
+	return contact, nil
+}
+
+func (r *contactRepository) FindByID(ctx context.Context, contactID uint) (*domain.Contact, error) {
+	var contact domain.Contact
+	err := withContactUsers(r.db.WithContext(ctx)).First(&contact, contactID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("contact request not found")
+	}
+	if err != nil {
+		shared.Log.Error("find contact failed",
+			zap.String("operation", "FindByID"),
+			zap.Uint("contactID", contactID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find contact failed").WithDetails(err.Error())
+	}
+	return &contact, nil
+}
+
+func (r *contactRepository) FindBetween(ctx context.Context, userA, userB uint) (*domain.Contact, error) {
+	var contact domain.Contact
+	err := r.db.WithContext(ctx).
+		Where("(requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)", userA, userB, userB, userA).
+		First(&contact).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		shared.Log.Error("find contact failed",
+			zap.String("operation", "FindBetween"),
+			zap.Uint("userA", userA),
+			zap.Uint("userB", userB),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find contact failed").WithDetails(err.Error())
+	}
+	return &contact, nil
+}
+
+func (r *contactRepository) Accept(ctx context.Context, contactID uint) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.Contact{}).
+		Where("id = ?", contactID).
+		Updates(map[string]interface{}{
+			"status":      domain.ContactAccepted,
+			"accepted_at": time.Now().UTC(),
+		}).Error
+	if err != nil {
+		shared.Log.Error("accept contact request failed",
+			zap.String("operation", "Accept"),
+			zap.Uint("contactID", contactID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("accept contact request failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *contactRepository) Delete(ctx context.Context, contactID uint) error {
+	if err := r.db.WithContext(ctx).Delete(&domain.Contact{}, contactID).Error; err != nil {
+		shared.Log.Error("delete contact failed",
+			zap.String("operation", "Delete"),
+			zap.Uint("contactID", contactID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete contact failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *contactRepository) ListAccepted(ctx context.Context, userID uint) ([]domain.Contact, error) {
+	return r.list(ctx, "ListAccepted", userID, domain.ContactAccepted)
+}
+
+func (r *contactRepository) ListPending(ctx context.Context, userID uint) ([]domain.Contact, error) {
+	return r.list(ctx, "ListPending", userID, domain.ContactPending)
+}
+
+func (r *contactRepository) list(ctx context.Context, operation string, userID uint, status domain.ContactStatus) ([]domain.Contact, error) {
+	var contacts []domain.Contact
+	err := withContactUsers(r.db.WithContext(ctx)).
+		Where("(requester_id = ? OR addressee_id = ?) AND status = ?", userID, userID, status).
+		Order("created_at DESC").
+		Find(&contacts).Error
+	if err != nil {
+		shared.Log.Error("list contacts failed",
+			zap.String("operation", operation),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("list contacts failed").WithDetails(err.Error())
+	}
+	return contacts, nil
+}
+
+func (r *contactRepository) ContactIDsAmong(ctx context.Context, userID uint, candidateIDs []uint) ([]uint, error) {
+	var ids []uint
+	if len(candidateIDs) == 0 {
+		return ids, nil
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT addressee_id FROM contacts WHERE requester_id = ? AND status = ? AND addressee_id IN ?
+		UNION
+		SELECT requester_id FROM contacts WHERE addressee_id = ? AND status = ? AND requester_id IN ?`,
+		userID, domain.ContactAccepted, candidateIDs,
+		userID, domain.ContactAccepted, candidateIDs).
+		Scan(&ids).Error
+	if err != nil {
+		shared.Log.Error("find contacts failed",
+			zap.String("operation", "ContactIDsAmong"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find contacts failed").WithDetails(err.Error())
+	}
+	return ids, nil
+}
+
+func (r *contactRepository) DeleteAllForUser(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).
+		Where("requester_id = ? OR addressee_id = ?", userID, userID).
+		Delete(&domain.Contact{}).Error
+	if err != nil {
+		shared.Log.Error("delete contacts failed",
+			zap.String("operation", "DeleteAllForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete contacts failed").WithDetails(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type dataExportRepository struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) domain.DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+func (r *dataExportRepository) Create(ctx context.Context, export *domain.DataExport) error {
+	if err := r.db.WithContext(ctx).Create(export).Error; err != nil {
+		shared.Log.Error("create data export failed",
+			zap.String("operation", "Create"),
+			zap.Uint("userID", export.UserID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("create data export failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *dataExportRepository) FindByID(ctx context.Context, exportID uint) (*domain.DataExport, error) {
+	var export domain.DataExport
+	err := r.db.WithContext(ctx).First(&export, exportID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("data export not found")
+	}
+	if err != nil {
+		shared.Log.Error("find data export failed",
+			zap.String("operation", "FindByID"),
+			zap.Uint("exportID", exportID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find data export failed").WithDetails(err.Error())
+	}
+	return &export, nil
+}
+
+func (r *dataExportRepository) FindLatestByUser(ctx context.Context, userID uint) (*domain.DataExport, error) {
+	var export domain.DataExport
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		First(&export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		shared.Log.Error("find latest data export failed",
+			zap.String("operation", "FindLatestByUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find data export failed").WithDetails(err.Error())
+	}
+	return &export, nil
+}
+
+func (r *dataExportRepository) Save(ctx context.Context, export *domain.DataExport) error {
+	if err := r.db.WithContext(ctx).Save(export).Error; err != nil {
+		shared.Log.Error("save data export failed",
+			zap.String("operation", "Save"),
+			zap.Uint("exportID", export.ID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("save data export failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *dataExportRepository) UpdateProgress(ctx context.Context, exportID uint, progress int) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.DataExport{}).
+		Where("id = ?", exportID).
+		Update("progress", progress).Error
+	if err != nil {
+		shared.Log.Error("update export progress failed",
+			zap.String("operation", "UpdateProgress"),
+			zap.Uint("exportID", exportID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("update export progress failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *dataExportRepository) FindExpired(ctx context.Context, now time.Time) ([]domain.DataExport, error) {
+	var exports []domain.DataExport
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", domain.ExportReady, now).
+		Find(&exports).Error
+	if err != nil {
+		shared.Log.Error("find expired exports failed",
+			zap.String("operation", "FindExpired"),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find expired exports failed").WithDetails(err.Error())
+	}
+	return exports, nil
+}
+
+func (r *dataExportRepository) FailStale(ctx context.Context, startedBefore time.Time) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.DataExport{}).
+		Where("status IN ? AND updated_at < ?", []domain.ExportStatus{domain.ExportPending, domain.ExportRunning}, startedBefore).
+		Updates(map[string]interface{}{
+			"status": domain.ExportFailed,
+			"error":  "export was interrupted",
+		}).Error
+	if err != nil {
+		shared.Log.Error("fail stale exports failed",
+			zap.String("operation", "FailStale"),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("fail stale exports failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *dataExportRepository) ExpireForUser(ctx context.Context, userID uint, now time.Time) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.DataExport{}).
+		Where("user_id = ? AND status = ?", userID, domain.ExportReady).
+		Update("expires_at", now).Error
+	if err != nil {
+		shared.Log.Error("expire user exports failed",
+			zap.String("operation", "ExpireForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("expire user exports failed").WithDetails(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type emailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) domain.EmailVerificationRepository {
+	return &emailVerificationRepository{db: db}
+}
+
+func (r *emailVerificationRepository) Create(ctx context.Context, userID uint, email, tokenHash string, purpose domain.VerificationPurpose, expiresAt time.Time) (*domain.EmailVerification, error) {
+	verification := &domain.EmailVerification{
+		UserID:    userID,
+		Email:     email,
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+		ExpiresAt: expiresAt,
+	}
+
+	if err := r.db.WithContext(ctx).Create(verification).Error; err != nil {
+		shared.Log.Error("create email verification failed",
+			zap.String("operation", "Create"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("create email verification failed").WithDetails(err.Error())
+	}
+	return verification, nil
+}
+
+func (r *emailVerificationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*domain.EmailVerification, error) {
+	var verification domain.EmailVerification
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ?", tokenHash).
+		First(&verification).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("verification not found")
+	}
+	if err != nil {
+		shared.Log.Error("find email verification failed",
+			zap.String("operation", "FindByTokenHash"),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find email verification failed").WithDetails(err.Error())
+	}
+	return &verification, nil
+}
+
+// MarkVerified uses up the token, only one of several concurrent uses gets through
+func (r *emailVerificationRepository) MarkVerified(ctx context.Context, verificationID uint) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.EmailVerification{}).
+		Where("id = ? AND verified_at IS NULL", verificationID).
+		Update("verified_at", time.Now().UTC())
+	if result.Error != nil {
+		shared.Log.Error("mark email verification used failed",
+			zap.String("operation", "MarkVerified"),
+			zap.Uint("verificationID", verificationID),
+			zap.Error(result.Error))
+		return shared.ErrDatabaseOperation.WithDetails("mark email verification failed").WithDetails(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrInvalidVerificationToken.WithDetails(domain.ErrVerificationUsed.Error())
+	}
+	return nil
+}
+
+// InvalidateForUser removes pending tokens of one purpose so only the latest one works
+func (r *emailVerificationRepository) InvalidateForUser(ctx context.Context, userID uint, purpose domain.VerificationPurpose) error {
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND purpose = ? AND verified_at IS NULL", userID, purpose).
+		Delete(&domain.EmailVerification{}).Error
+	if err != nil {
+		shared.Log.Error("invalidate email verifications failed",
+			zap.String("operation", "InvalidateForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("invalidate email verifications failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+// DeleteForUser removes all verification records, including the addresses they were sent to
+func (r *emailVerificationRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("user_id = ?", userID).
+		Delete(&domain.EmailVerification{}).Error
+	if err != nil {
+		shared.Log.Error("delete email verifications failed",
+			zap.String("operation", "DeleteForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete email verifications failed").WithDetails(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type externalIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewExternalIdentityRepository(db *gorm.DB) domain.ExternalIdentityRepository {
+	return &externalIdentityRepository{db: db}
+}
+
+func (r *externalIdentityRepository) FindBySubject(ctx context.Context, issuer, subject string) (*domain.ExternalIdentity, error) {
+	var identity domain.ExternalIdentity
+	err := r.db.WithContext(ctx).
+		Where("issuer = ? AND subject = ?", issuer, subject).
+		First(&identity).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil // Not linked yet
+	}
+	if err != nil {
+		shared.Log.Error("find external identity failed",
+			zap.String("operation", "FindBySubject"),
+			zap.String("issuer", issuer),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find external identity failed").WithDetails(err.Error())
+	}
+	return &identity, nil
+}
+
+func (r *externalIdentityRepository) Create(ctx context.Context, identity *domain.ExternalIdentity) (*domain.ExternalIdentity, error) {
+	if err := r.db.WithContext(ctx).Create(identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, shared.ErrDuplicateEntry.WithDetails("external identity already linked")
+		}
+		shared.Log.Error("create external identity failed",
+			zap.String("operation", "Create"),
+			zap.Uint("userID", identity.UserID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("create external identity failed").WithDetails(err.Error())
+	}
+	return identity, nil
+}
+
+func (r *externalIdentityRepository) TouchLastLogin(ctx context.Context, identityID uint) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.ExternalIdentity{}).
+		Where("id = ?", identityID).
+		Update("last_login_at", time.Now().UTC()).Error
+	if err != nil {
+		shared.Log.Error("update external identity last login failed",
+			zap.String("operation", "TouchLastLogin"),
+			zap.Uint("identityID", identityID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("update external identity failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *externalIdentityRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	// Unscoped, a soft-deleted row keeps the subject and its slot in the unique index
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("user_id = ?", userID).
+		Delete(&domain.ExternalIdentity{}).Error
+	if err != nil {
+		shared.Log.Error("delete external identities failed",
+			zap.String("operation", "DeleteForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete external identities failed").WithDetails(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) domain.LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Find(ctx context.Context, scope domain.LoginAttemptScope, key string) (*domain.LoginAttempt, error) {
+	var attempt domain.LoginAttempt
+	err := r.db.WithContext(ctx).
+		Where("scope = ? AND key = ?", scope, key).
+		First(&attempt).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil // No failures recorded
+	}
+	if err != nil {
+		shared.Log.Error("find login attempt failed",
+			zap.String("operation", "Find"),
+			zap.String("scope", string(scope)),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find login attempt failed").WithDetails(err.Error())
+	}
+	return &attempt, nil
+}
+
+// RegisterFailure atomically increments the failure counter, restarting it when the
+// previous failure is older than the window so old typos do not count forever
+func (r *loginAttemptRepository) RegisterFailure(ctx context.Context, scope domain.LoginAttemptScope, key string, window time.Duration) (*domain.LoginAttempt, error) {
+	now := time.Now().UTC()
+	var attempt domain.LoginAttempt
+
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO login_attempts (scope, key, failed_count, last_failed_at, updated_at)
+		VALUES (?, ?, 1, ?, ?)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failed_count = CASE
+				WHEN login_attempts.last_failed_at < ? AND (login_attempts.locked_until IS NULL OR login_attempts.locked_until < ?)
+				THEN 1
+				ELSE login_attempts.failed_count + 1
+			END,
+			last_failed_at = EXCLUDED.last_failed_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING *`,
+		scope, key, now, now, now.Add(-window), now,
+	).Scan(&attempt).Error
+
+	if err != nil {
+		shared.Log.Error("register login failure failed",
+			zap.String("operation", "RegisterFailure"),
+			zap.String("scope", string(scope)),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("register login failure failed").WithDetails(err.Error())
+	}
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, scope domain.LoginAttemptScope, key string, until time.Time) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.LoginAttempt{}).
+		Where("scope = ? AND key = ?", scope, key).
+		Updates(map[string]interface{}{
+			"locked_until": until,
+			"updated_at":   time.Now().UTC(),
+		}).Error
+	if err != nil {
+		shared.Log.Error("lock login attempt failed",
+			zap.String("operation", "Lock"),
+			zap.String("scope", string(scope)),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("lock login attempt failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, scope domain.LoginAttemptScope, key string) error {
+	err := r.db.WithContext(ctx).
+		Where("scope = ? AND key = ?", scope, key).
+		Delete(&domain.LoginAttempt{}).Error
+	if err != nil {
+		shared.Log.Error("reset login attempts failed",
+			zap.String("operation", "Reset"),
+			zap.String("scope", string(scope)),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("reset login attempts failed").WithDetails(err.Error())
+	}
+	return nil
+}
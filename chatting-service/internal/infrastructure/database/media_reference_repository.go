@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const referenceBatchSize = 1000
+
+type mediaReferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewMediaReferenceRepository(db *gorm.DB) domain.MediaReferenceRepository {
+	return &mediaReferenceRepository{db: db}
+}
+
+func (r *mediaReferenceRepository) ReferencedPaths(ctx context.Context) ([]string, error) {
+	var paths []string
+	db := r.db.WithContext(ctx)
+
+	var media []domain.Media
+	err := db.Select("id", "path", "thumbnails").FindInBatches(&media, referenceBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, m := range media {
+			paths = append(append(paths, m.Path), m.Thumbnails.Paths()...)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, r.failed("media", err)
+	}
+
+	var blobs []domain.MediaBlob
+	err = db.Select("path", "thumbnails").FindInBatches(&blobs, referenceBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, b := range blobs {
+			paths = append(append(paths, b.Path), b.Thumbnails.Paths()...)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, r.failed("media blobs", err)
+	}
+
+	// Deleted users are included, their files go with MediaStorage.DeleteUserFiles
+	var users []domain.User
+	err = db.Unscoped().Select("id", "avatar").Where("avatar IS NOT NULL AND avatar NOT IN ('', 'null', '[]')").
+		FindInBatches(&users, referenceBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, u := range users {
+				paths = append(paths, u.Avatar.Paths()...)
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, r.failed("avatars", err)
+	}
+
+	var uploads []domain.MediaUploadSession
+	err = db.Select("id", "parts").FindInBatches(&uploads, referenceBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, u := range uploads {
+			paths = append(paths, u.Parts...)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, r.failed("media upload parts", err)
+	}
+	return paths, nil
+}
+
+func (r *mediaReferenceRepository) ReferencedURLs(ctx context.Context) ([]string, error) {
+	var messageURLs, announcementURLs []string
+	db := r.db.WithContext(ctx)
+
+	if err := db.Model(&domain.Message{}).Where("media_url <> ''").Distinct().Pluck("media_url", &messageURLs).Error; err != nil {
+		return nil, r.failed("message media", err)
+	}
+	if err := db.Model(&domain.Announcement{}).Where("media_url <> ''").Distinct().Pluck("media_url", &announcementURLs).Error; err != nil {
+		return nil, r.failed("announcement media", err)
+	}
+	return append(messageURLs, announcementURLs...), nil
+}
+
+func (r *mediaReferenceRepository) MediaUploadedBefore(ctx context.Context, before time.Time, afterID uint, limit int) ([]domain.Media, error) {
+	var media []domain.Media
+	err := r.db.WithContext(ctx).
+		Select("id", "owner_id", "path", "size", "thumbnails", "created_at").
+		Where("created_at < ? AND id > ?", before, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&media).Error
+	if err != nil {
+		return nil, r.failed("media library", err)
+	}
+	return media, nil
+}
+
+func (r *mediaReferenceRepository) failed(what string, err error) error {
+	shared.Log.Error("find media references failed",
+		zap.String("operation", "FindReferences"),
+		zap.String("source", what),
+		zap.Error(err))
+	return shared.ErrDatabaseOperation.WithDetails("find " + what + " references failed").WithDetails(err.Error())
+}
@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type mediaRepository struct {
+	db *gorm.DB
+}
+
+func NewMediaRepository(db *gorm.DB) domain.MediaRepository {
+	return &mediaRepository{db: db}
+}
+
+func (r *mediaRepository) Create(ctx context.Context, media *domain.Media) error {
+	if err := r.db.WithContext(ctx).Create(media).Error; err != nil {
+		shared.Log.Error("create media failed",
+			zap.String("operation", "Create"),
+			zap.String("path", media.Path),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("create media failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *mediaRepository) FindByID(ctx context.Context, mediaID uint) (*domain.Media, error) {
+	return r.findOne(ctx, "FindByID", "id = ?", mediaID)
+}
+
+func (r *mediaRepository) FindByPath(ctx context.Context, ownerID uint, path string) (*domain.Media, error) {
+	return r.findOne(ctx, "FindByPath", "owner_id = ? AND path = ?", ownerID, path)
+}
+
+func (r *mediaRepository) FindByPaths(ctx context.Context, paths []string) ([]domain.Media, error) {
+	var media []domain.Media
+	if len(paths) == 0 {
+		return media, nil
+	}
+	if err := r.db.WithContext(ctx).Where("path IN ?", paths).Find(&media).Error; err != nil {
+		shared.Log.Error("find media failed",
+			zap.String("operation", "FindByPaths"),
+			zap.Int("count", len(paths)),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find media failed").WithDetails(err.Error())
+	}
+	return media, nil
+}
+
+func (r *mediaRepository) findOne(ctx context.Context, operation string, query string, args ...interface{}) (*domain.Media, error) {
+	var media domain.Media
+	err := r.db.WithContext(ctx).Where(query, args...).First(&media).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("media not found")
+	}
+	if err != nil {
+		shared.Log.Error("find media failed",
+			zap.String("operation", operation),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find media failed").WithDetails(err.Error())
+	}
+	return &media, nil
+}
+
+func (r *mediaRepository) List(ctx context.Context, filter domain.MediaFilter) ([]domain.Media, int64, error) {
+	q := r.db.WithContext(ctx).Model(&domain.Media{}).Where("owner_id = ?", filter.OwnerID)
+	if filter.Type != "" {
+		if strings.Contains(filter.Type, "/") {
+			q = q.Where("content_type = ?", filter.Type)
+		} else {
+			q = q.Where("content_type LIKE ?", escapeLike(filter.Type)+"/%")
+		}
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		shared.Log.Error("count media failed",
+			zap.String("operation", "List"),
+			zap.Uint("ownerID", filter.OwnerID),
+			zap.Error(err))
+		return nil, 0, shared.ErrDatabaseOperation.WithDetails("list media failed").WithDetails(err.Error())
+	}
+
+	var media []domain.Media
+	err := q.Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&media).Error
+	if err != nil {
+		shared.Log.Error("list media failed",
+			zap.String("operation", "List"),
+			zap.Uint("ownerID", filter.OwnerID),
+			zap.Error(err))
+		return nil, 0, shared.ErrDatabaseOperation.WithDetails("list media failed").WithDetails(err.Error())
+	}
+	return media, total, nil
+}
+
+func (r *mediaRepository) Delete(ctx context.Context, mediaID uint) error {
+	result := r.db.WithContext(ctx).Delete(&domain.Media{}, mediaID)
+	if result.Error != nil {
+		shared.Log.Error("delete media failed",
+			zap.String("operation", "Delete"),
+			zap.Uint("mediaID", mediaID),
+			zap.Error(result.Error))
+		return shared.ErrDatabaseOperation.WithDetails("delete media failed").WithDetails(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrRecordNotFound.WithDetails("media not found")
+	}
+	return nil
+}
+
+func (r *mediaRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Every upload of the user is one reference less on its blob
+		err := tx.Exec(`UPDATE media_blobs SET ref_count = GREATEST(media_blobs.ref_count - refs.count, 0)
+			FROM (SELECT path, COUNT(*) AS count FROM media WHERE owner_id = ? GROUP BY path) AS refs
+			WHERE media_blobs.path = refs.path`, userID).Error
+		if err != nil {
+			shared.Log.Error("release user media blobs failed",
+				zap.String("operation", "DeleteForUser"),
+				zap.Uint("userID", userID),
+				zap.Error(err))
+			return shared.ErrDatabaseOperation.WithDetails("delete user media failed").WithDetails(err.Error())
+		}
+		if err := tx.Where("owner_id = ?", userID).Delete(&domain.Media{}).Error; err != nil {
+			shared.Log.Error("delete user media failed",
+				zap.String("operation", "DeleteForUser"),
+				zap.Uint("userID", userID),
+				zap.Error(err))
+			return shared.ErrDatabaseOperation.WithDetails("delete user media failed").WithDetails(err.Error())
+		}
+		if err := tx.Where("owner_id = ?", userID).Delete(&domain.MediaUsage{}).Error; err != nil {
+			shared.Log.Error("delete user media usage failed",
+				zap.String("operation", "DeleteForUser"),
+				zap.Uint("userID", userID),
+				zap.Error(err))
+			return shared.ErrDatabaseOperation.WithDetails("delete user media failed").WithDetails(err.Error())
+		}
+		return nil
+	})
+}
+
+func (r *mediaRepository) IsReferencedBy(ctx context.Context, path string, userID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Media{}).
+		Where("path = ? AND owner_id = ?", path, userID).
+		Limit(1).
+		Count(&count).Error
+	if err != nil {
+		shared.Log.Error("check media reference failed",
+			zap.String("operation", "IsReferencedBy"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return false, shared.ErrDatabaseOperation.WithDetails("check media reference failed").WithDetails(err.Error())
+	}
+	return count > 0, nil
+}
+
+func (r *mediaRepository) AcquireBlob(ctx context.Context, blob *domain.MediaBlob) (*domain.MediaBlob, error) {
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(blob).Error; err != nil {
+		shared.Log.Error("create media blob failed",
+			zap.String("operation", "AcquireBlob"),
+			zap.String("path", blob.Path),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("create media blob failed").WithDetails(err.Error())
+	}
+
+	// The content may be stored at another path already
+	var existing domain.MediaBlob
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("checksum = ? AND content_type = ?", blob.Checksum, blob.ContentType).
+		First(&existing).Error
+	if err != nil {
+		shared.Log.Error("lock media blob failed",
+			zap.String("operation", "AcquireBlob"),
+			zap.String("path", blob.Path),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("lock media blob failed").WithDetails(err.Error())
+	}
+	return &existing, nil
+}
+
+func (r *mediaRepository) LockBlob(ctx context.Context, path string) (*domain.MediaBlob, error) {
+	var blob domain.MediaBlob
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("path = ?", path).
+		First(&blob).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("media blob not found")
+	}
+	if err != nil {
+		shared.Log.Error("lock media blob failed",
+			zap.String("operation", "LockBlob"),
+			zap.String("path", path),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("lock media blob failed").WithDetails(err.Error())
+	}
+	return &blob, nil
+}
+
+func (r *mediaRepository) SaveBlob(ctx context.Context, blob *domain.MediaBlob) error {
+	if err := r.db.WithContext(ctx).Save(blob).Error; err != nil {
+		shared.Log.Error("save media blob failed",
+			zap.String("operation", "SaveBlob"),
+			zap.String("path", blob.Path),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("save media blob failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *mediaRepository) DeleteBlob(ctx context.Context, path string) error {
+	if err := r.db.WithContext(ctx).Where("path = ?", path).Delete(&domain.MediaBlob{}).Error; err != nil {
+		shared.Log.Error("delete media blob failed",
+			zap.String("operation", "DeleteBlob"),
+			zap.String("path", path),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete media blob failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *mediaRepository) FindUnreferencedBlobs(ctx context.Context) ([]domain.MediaBlob, error) {
+	var blobs []domain.MediaBlob
+	if err := r.db.WithContext(ctx).Where("ref_count = 0").Find(&blobs).Error; err != nil {
+		shared.Log.Error("find unreferenced media blobs failed",
+			zap.String("operation", "FindUnreferencedBlobs"),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find unreferenced media blobs failed").WithDetails(err.Error())
+	}
+	return blobs, nil
+}
+
+func (r *mediaRepository) AddUsage(ctx context.Context, ownerID uint, delta int64, limit int64) error {
+	if limit > 0 && delta > limit {
+		return shared.ErrStorageQuotaExceeded
+	}
+	// The conditional update checks and counts in one statement, so concurrent uploads
+	// cannot both squeeze under the limit. Untyped parameters would be taken as integer,
+	// which fails for limits of 2GB and more.
+	result := r.db.WithContext(ctx).Exec(`INSERT INTO media_usages (owner_id, bytes, updated_at) VALUES (CAST(? AS bigint), CAST(? AS bigint), ?)
+		ON CONFLICT (owner_id) DO UPDATE SET bytes = GREATEST(media_usages.bytes + CAST(? AS bigint), 0), updated_at = EXCLUDED.updated_at
+		WHERE CAST(? AS bigint) <= 0 OR CAST(? AS bigint) <= 0 OR media_usages.bytes + CAST(? AS bigint) <= CAST(? AS bigint)`,
+		ownerID, max(delta, 0), time.Now().UTC(), delta, limit, delta, delta, limit)
+	if result.Error != nil {
+		shared.Log.Error("update media usage failed",
+			zap.String("operation", "AddUsage"),
+			zap.Uint("ownerID", ownerID),
+			zap.Error(result.Error))
+		return shared.ErrDatabaseOperation.WithDetails("update media usage failed").WithDetails(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrStorageQuotaExceeded
+	}
+	return nil
+}
+
+func (r *mediaRepository) FindUsage(ctx context.Context, ownerID uint) (int64, error) {
+	var usage domain.MediaUsage
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).First(&usage).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil // Nothing uploaded yet
+	}
+	if err != nil {
+		shared.Log.Error("find media usage failed",
+			zap.String("operation", "FindUsage"),
+			zap.Uint("ownerID", ownerID),
+			zap.Error(err))
+		return 0, shared.ErrDatabaseOperation.WithDetails("find media usage failed").WithDetails(err.Error())
+	}
+	return usage.Bytes, nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type mediaUploadRepository struct {
+	db *gorm.DB
+}
+
+func NewMediaUploadRepository(db *gorm.DB) domain.MediaUploadRepository {
+	return &mediaUploadRepository{db: db}
+}
+
+func (r *mediaUploadRepository) Create(ctx context.Context, upload *domain.MediaUploadSession) error {
+	if err := r.db.WithContext(ctx).Create(upload).Error; err != nil {
+		shared.Log.Error("create media upload failed",
+			zap.String("operation", "Create"),
+			zap.Uint("ownerID", upload.OwnerID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("create media upload failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *mediaUploadRepository) FindByID(ctx context.Context, uploadID string) (*domain.MediaUploadSession, error) {
+	var upload domain.MediaUploadSession
+	err := r.db.WithContext(ctx).Where("id = ?", uploadID).First(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, shared.ErrRecordNotFound.WithDetails("upload not found")
+	}
+	if err != nil {
+		shared.Log.Error("find media upload failed",
+			zap.String("operation", "FindByID"),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find media upload failed").WithDetails(err.Error())
+	}
+	return &upload, nil
+}
+
+func (r *mediaUploadRepository) AppendPart(ctx context.Context, upload *domain.MediaUploadSession, previousReceived int64) error {
+	// Two chunks for the same offset may race, only the first one is kept
+	result := r.db.WithContext(ctx).
+		Model(upload).
+		Where("received = ?", previousReceived).
+		Select("Received", "Parts", "ExpiresAt", "UpdatedAt").
+		Updates(upload)
+	if result.Error != nil {
+		shared.Log.Error("append media upload part failed",
+			zap.String("operation", "AppendPart"),
+			zap.Uint("ownerID", upload.OwnerID),
+			zap.Error(result.Error))
+		return shared.ErrDatabaseOperation.WithDetails("append media upload part failed").WithDetails(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrConflict.WithDetails("upload was changed by another request")
+	}
+	return nil
+}
+
+func (r *mediaUploadRepository) Delete(ctx context.Context, uploadID string) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", uploadID).Delete(&domain.MediaUploadSession{}).Error; err != nil {
+		shared.Log.Error("delete media upload failed",
+			zap.String("operation", "Delete"),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete media upload failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *mediaUploadRepository) FindExpired(ctx context.Context, now time.Time) ([]domain.MediaUploadSession, error) {
+	var uploads []domain.MediaUploadSession
+	if err := r.db.WithContext(ctx).Where("expires_at <= ?", now).Find(&uploads).Error; err != nil {
+		shared.Log.Error("find expired media uploads failed",
+			zap.String("operation", "FindExpired"),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find expired media uploads failed").WithDetails(err.Error())
+	}
+	return uploads, nil
+}
+
+func (r *mediaUploadRepository) OpenSize(ctx context.Context, ownerID uint, now time.Time) (int64, error) {
+	var size int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.MediaUploadSession{}).
+		Where("owner_id = ? AND expires_at > ?", ownerID, now).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&size).Error
+	if err != nil {
+		shared.Log.Error("sum open media uploads failed",
+			zap.String("operation", "OpenSize"),
+			zap.Uint("ownerID", ownerID),
+			zap.Error(err))
+		return 0, shared.ErrDatabaseOperation.WithDetails("sum open media uploads failed").WithDetails(err.Error())
+	}
+	return size, nil
+}
+
+func (r *mediaUploadRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("owner_id = ?", userID).Delete(&domain.MediaUploadSession{}).Error; err != nil {
+		shared.Log.Error("delete user media uploads failed",
+			zap.String("operation", "DeleteForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete user media uploads failed").WithDetails(err.Error())
+	}
+	return nil
+}
@@ -78,10 +78,10 @@ func (r *messageRepository) FindByID(ctx context.Context, messageID uint) (*doma
 	var message domain.Message
 	err := r.db.WithContext(ctx).
 		Preload("Sender", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id", "username", "email", "status", "last_active_at")
+			return db.Select("id", "username", "email", "status", "last_active_at", "display_name", "avatar")
 		}).
 		Preload("Recipients", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id", "username", "email", "status", "last_active_at")
+			return db.Select("id", "username", "email", "status", "last_active_at", "display_name", "avatar")
 		}).
 		First(&message, messageID).Error
 
@@ -258,6 +258,65 @@ func (r *messageRepository) Delete(ctx context.Context, messageID uint, userID u
 	})
 }
 
+// MarkSilent flags the message as muted for the given recipients so it is left out of unread totals
+func (r *messageRepository) MarkSilent(ctx context.Context, messageID uint, recipientIDs []uint) error {
+	if len(recipientIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Message{}).
+			Where("id = ? AND recipient_id IN ?", messageID, recipientIDs).
+			Update("silent", true).Error; err != nil {
+			return shared.ErrDatabaseOperation.WithDetails("mark message silent failed").WithDetails(err.Error())
+		}
+		if err := tx.Model(&domain.MessageRecipient{}).
+			Where("message_id = ? AND user_id IN ?", messageID, recipientIDs).
+			Update("silent", true).Error; err != nil {
+			return shared.ErrDatabaseOperation.WithDetails("mark recipients silent failed").WithDetails(err.Error())
+		}
+		return nil
+	})
+}
+
+func (r *messageRepository) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	var direct, broadcast int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.Message{}).
+		Where("recipient_id = ? AND message_type = ? AND read_at IS NULL AND NOT silent", userID, domain.MessageDirect).
+		Count(&direct).Error
+	if err != nil {
+		shared.Log.Error("count unread direct messages failed", zap.Uint("userID", userID), zap.Error(err))
+		return 0, shared.ErrDatabaseOperation.WithDetails("count unread messages failed").WithDetails(err.Error())
+	}
+
+	err = r.db.WithContext(ctx).
+		Table("message_recipients").
+		Joins("JOIN messages ON messages.id = message_recipients.message_id AND messages.deleted_at IS NULL").
+		Where("message_recipients.user_id = ? AND message_recipients.read_at IS NULL AND NOT message_recipients.silent", userID).
+		Count(&broadcast).Error
+	if err != nil {
+		shared.Log.Error("count unread broadcasts failed", zap.Uint("userID", userID), zap.Error(err))
+		return 0, shared.ErrDatabaseOperation.WithDetails("count unread messages failed").WithDetails(err.Error())
+	}
+	return direct + broadcast, nil
+}
+
+func (r *messageRepository) CountSentBy(ctx context.Context, senderID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.Message{}).
+		Where("sender_id = ?", senderID).
+		Count(&count).Error
+	if err != nil {
+		shared.Log.Error("count sent messages failed",
+			zap.String("operation", "CountSentBy"),
+			zap.Uint("senderID", senderID),
+			zap.Error(err))
+		return 0, shared.ErrDatabaseOperation.WithDetails("count sent messages failed").WithDetails(err.Error())
+	}
+	return count, nil
+}
+
 // Helper function to apply query filters
 func applyMessageQuery(q *gorm.DB, query domain.MessageQuery) *gorm.DB {
 	if query.Limit > 0 {
@@ -295,3 +354,99 @@ func applyMessageQuery(q *gorm.DB, query domain.MessageQuery) *gorm.DB {
 
 	return q
 }
+
+// EraseFromSender scrubs everything senderID wrote. Tombstones keep the rows (and
+// conversation order) with the content removed, otherwise the messages are deleted.
+func (r *messageRepository) EraseFromSender(ctx context.Context, senderID uint, tombstone bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if tombstone {
+			if err := tx.Unscoped().Model(&domain.Message{}).
+				Where("sender_id = ?", senderID).
+				Updates(map[string]interface{}{
+					"content":   "",
+					"media_url": "",
+					"erased":    true,
+				}).Error; err != nil {
+				shared.Log.Error("tombstone messages failed",
+					zap.String("operation", "EraseFromSender"),
+					zap.Uint("senderID", senderID),
+					zap.Error(err))
+				return shared.ErrDatabaseOperation.WithDetails("tombstone messages failed").WithDetails(err.Error())
+			}
+			return nil
+		}
+
+		sent := tx.Unscoped().Model(&domain.Message{}).Select("id").Where("sender_id = ?", senderID)
+		if err := tx.Where("message_id IN (?)", sent).Delete(&domain.MessageRecipient{}).Error; err != nil {
+			return shared.ErrDatabaseOperation.WithDetails("delete message recipients failed").WithDetails(err.Error())
+		}
+		if err := tx.Unscoped().Where("sender_id = ?", senderID).Delete(&domain.Message{}).Error; err != nil {
+			shared.Log.Error("delete messages failed",
+				zap.String("operation", "EraseFromSender"),
+				zap.Uint("senderID", senderID),
+				zap.Error(err))
+			return shared.ErrDatabaseOperation.WithDetails("delete messages failed").WithDetails(err.Error())
+		}
+		return nil
+	})
+}
+
+// FindForExport pages through every message the user sent or received, including
+// broadcasts delivered through message_recipients, ordered by ID
+func (r *messageRepository) FindForExport(ctx context.Context, userID uint, afterID uint, limit int) ([]domain.Message, error) {
+	var messages []domain.Message
+	received := r.db.Model(&domain.MessageRecipient{}).Select("message_id").Where("user_id = ?", userID)
+	err := r.db.WithContext(ctx).
+		Preload("Sender", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().Select("id", "username", "display_name")
+		}).
+		Where("id > ?", afterID).
+		Where("sender_id = ? OR recipient_id = ? OR id IN (?)", userID, userID, received).
+		Order("id ASC").
+		Limit(limit).
+		Find(&messages).Error
+	if err != nil {
+		shared.Log.Error("find messages for export failed",
+			zap.String("operation", "FindForExport"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find messages for export failed").WithDetails(err.Error())
+	}
+	return messages, nil
+}
+
+func (r *messageRepository) CountForExport(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	received := r.db.Model(&domain.MessageRecipient{}).Select("message_id").Where("user_id = ?", userID)
+	err := r.db.WithContext(ctx).
+		Model(&domain.Message{}).
+		Where("sender_id = ? OR recipient_id = ? OR id IN (?)", userID, userID, received).
+		Count(&count).Error
+	if err != nil {
+		shared.Log.Error("count messages for export failed",
+			zap.String("operation", "CountForExport"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return 0, shared.ErrDatabaseOperation.WithDetails("count messages for export failed").WithDetails(err.Error())
+	}
+	return count, nil
+}
+
+func (r *messageRepository) HasMediaAccess(ctx context.Context, userID uint, mediaURL string) (bool, error) {
+	var count int64
+	received := r.db.Model(&domain.MessageRecipient{}).Select("message_id").Where("user_id = ?", userID)
+	err := r.db.WithContext(ctx).
+		Model(&domain.Message{}).
+		Where("media_url = ?", mediaURL).
+		Where("recipient_id = ? OR id IN (?)", userID, received).
+		Limit(1).
+		Count(&count).Error
+	if err != nil {
+		shared.Log.Error("check media access failed",
+			zap.String("operation", "HasMediaAccess"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return false, shared.ErrDatabaseOperation.WithDetails("check media access failed").WithDetails(err.Error())
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type notificationPreferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationPreferenceRepository(db *gorm.DB) domain.NotificationPreferenceRepository {
+	return &notificationPreferenceRepository{db: db}
+}
+
+func (r *notificationPreferenceRepository) Find(ctx context.Context, userID uint) (*domain.NotificationPreferences, error) {
+	var prefs domain.NotificationPreferences
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&prefs).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		shared.Log.Error("find notification preferences failed",
+			zap.String("operation", "Find"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find notification preferences failed").WithDetails(err.Error())
+	}
+	return &prefs, nil
+}
+
+func (r *notificationPreferenceRepository) FindMany(ctx context.Context, userIDs []uint) ([]domain.NotificationPreferences, error) {
+	var prefs []domain.NotificationPreferences
+	if len(userIDs) == 0 {
+		return prefs, nil
+	}
+	if err := r.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&prefs).Error; err != nil {
+		shared.Log.Error("find notification preferences failed",
+			zap.String("operation", "FindMany"),
+			zap.Int("users", len(userIDs)),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find notification preferences failed").WithDetails(err.Error())
+	}
+	return prefs, nil
+}
+
+func (r *notificationPreferenceRepository) Save(ctx context.Context, prefs *domain.NotificationPreferences) error {
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"mute_broadcasts", "quiet_hours_enabled", "quiet_hours_start", "quiet_hours_end", "time_zone", "updated_at"}),
+		}).
+		Create(prefs).Error
+	if err != nil {
+		shared.Log.Error("save notification preferences failed",
+			zap.String("operation", "Save"),
+			zap.Uint("userID", prefs.UserID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("save notification preferences failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *notificationPreferenceRepository) ListMutes(ctx context.Context, userID uint, now time.Time) ([]domain.Mute, error) {
+	var mutes []domain.Mute
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND (until IS NULL OR until > ?)", userID, now).
+		Order("created_at").
+		Find(&mutes).Error
+	if err != nil {
+		shared.Log.Error("list mutes failed",
+			zap.String("operation", "ListMutes"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("list mutes failed").WithDetails(err.Error())
+	}
+	return mutes, nil
+}
+
+func (r *notificationPreferenceRepository) FindMutesOf(ctx context.Context, senderID uint, userIDs []uint, now time.Time) ([]domain.Mute, error) {
+	var mutes []domain.Mute
+	if len(userIDs) == 0 {
+		return mutes, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("target_user_id = ? AND user_id IN ? AND (until IS NULL OR until > ?)", senderID, userIDs, now).
+		Find(&mutes).Error
+	if err != nil {
+		shared.Log.Error("find mutes failed",
+			zap.String("operation", "FindMutesOf"),
+			zap.Uint("senderID", senderID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find mutes failed").WithDetails(err.Error())
+	}
+	return mutes, nil
+}
+
+// ReplaceMutes swaps the user's mutes for the given set, expired ones are dropped on the way
+func (r *notificationPreferenceRepository) ReplaceMutes(ctx context.Context, userID uint, mutes []domain.Mute) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.Mute{}).Error; err != nil {
+			return err
+		}
+		if len(mutes) == 0 {
+			return nil
+		}
+		for i := range mutes {
+			mutes[i].UserID = userID
+		}
+		return tx.Create(&mutes).Error
+	})
+	if err != nil {
+		shared.Log.Error("replace mutes failed",
+			zap.String("operation", "ReplaceMutes"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("replace mutes failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *notificationPreferenceRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? OR target_user_id = ?", userID, userID).Delete(&domain.Mute{}).Error; err != nil {
+			shared.Log.Error("delete mutes failed",
+				zap.String("operation", "DeleteForUser"),
+				zap.Uint("userID", userID),
+				zap.Error(err))
+			return shared.ErrDatabaseOperation.WithDetails("delete mutes failed").WithDetails(err.Error())
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.NotificationPreferences{}).Error; err != nil {
+			return shared.ErrDatabaseOperation.WithDetails("delete notification preferences failed").WithDetails(err.Error())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type oidcStateRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCStateRepository(db *gorm.DB) domain.OIDCStateRepository {
+	return &oidcStateRepository{db: db}
+}
+
+func (r *oidcStateRepository) Create(ctx context.Context, state *domain.OIDCLoginState) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Abandoned logins are never consumed, clear them out while we are here
+		if err := tx.Where("expires_at < ?", time.Now().UTC()).Delete(&domain.OIDCLoginState{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(state).Error
+	})
+	if err != nil {
+		shared.Log.Error("create oidc state failed",
+			zap.String("operation", "Create"),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("create oidc state failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+// Consume deletes and returns the state in one statement so a callback cannot be replayed
+func (r *oidcStateRepository) Consume(ctx context.Context, stateHash string) (*domain.OIDCLoginState, error) {
+	var states []domain.OIDCLoginState
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("state_hash = ?", stateHash).
+		Delete(&states).Error
+	if err != nil {
+		shared.Log.Error("consume oidc state failed",
+			zap.String("operation", "Consume"),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("consume oidc state failed").WithDetails(err.Error())
+	}
+	if len(states) == 0 {
+		return nil, shared.ErrRecordNotFound.WithDetails("login state not found")
+	}
+	return &states[0], nil
+}
@@ -27,10 +27,19 @@ func (m *GormTransactionManager) WithTransaction(
 	// Start transaction with context
 	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		repos := &domain.Repositories{
-			//TODO
-			// Users:             NewGormUserRepository(tx),
-			// Messages:          NewGormMessageRepository(tx),
-			// MessageRecipients: NewGormMessageRecipientRepository(tx),
+			Users:                   NewUserRepository(tx),
+			Messages:                NewMessageRepository(tx),
+			MessageRecipients:       NewMessageRecipientRepository(tx),
+			APITokens:               NewAPITokenRepository(tx),
+			ExternalIdentities:      NewExternalIdentityRepository(tx),
+			EmailVerifications:      NewEmailVerificationRepository(tx),
+			Contacts:                NewContactRepository(tx),
+			Blocks:                  NewUserBlockRepository(tx),
+			NotificationPreferences: NewNotificationPreferenceRepository(tx),
+			DataExports:             NewDataExportRepository(tx),
+			Announcements:           NewAnnouncementRepository(tx),
+			Media:                   NewMediaRepository(tx),
+			MediaUploads:            NewMediaUploadRepository(tx),
 		}
 		return fn(ctx, repos) // Propagate context to the callback
 	})
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type userBlockRepository struct {
+	db *gorm.DB
+}
+
+func NewUserBlockRepository(db *gorm.DB) domain.UserBlockRepository {
+	return &userBlockRepository{db: db}
+}
+
+func (r *userBlockRepository) Block(ctx context.Context, blockerID, blockedID uint) error {
+	block := &domain.UserBlock{BlockerID: blockerID, BlockedID: blockedID}
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(block).Error
+	if err != nil {
+		shared.Log.Error("block user failed",
+			zap.String("operation", "Block"),
+			zap.Uint("blockerID", blockerID),
+			zap.Uint("blockedID", blockedID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("block user failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *userBlockRepository) Unblock(ctx context.Context, blockerID, blockedID uint) error {
+	err := r.db.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&domain.UserBlock{}).Error
+	if err != nil {
+		shared.Log.Error("unblock user failed",
+			zap.String("operation", "Unblock"),
+			zap.Uint("blockerID", blockerID),
+			zap.Uint("blockedID", blockedID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("unblock user failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *userBlockRepository) IsBlocked(ctx context.Context, blockerID, blockedID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.UserBlock{}).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Count(&count).Error
+	if err != nil {
+		shared.Log.Error("block check failed",
+			zap.String("operation", "IsBlocked"),
+			zap.Uint("blockerID", blockerID),
+			zap.Uint("blockedID", blockedID),
+			zap.Error(err))
+		return false, shared.ErrDatabaseOperation.WithDetails("block check failed").WithDetails(err.Error())
+	}
+	return count > 0, nil
+}
+
+func (r *userBlockRepository) ListBlocked(ctx context.Context, blockerID uint) ([]domain.UserBlock, error) {
+	var blocks []domain.UserBlock
+	err := r.db.WithContext(ctx).
+		Preload("Blocked", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "username", "is_bot")
+		}).
+		Where("blocker_id = ?", blockerID).
+		Order("created_at DESC").
+		Find(&blocks).Error
+	if err != nil {
+		shared.Log.Error("list blocked users failed",
+			zap.String("operation", "ListBlocked"),
+			zap.Uint("blockerID", blockerID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("list blocked users failed").WithDetails(err.Error())
+	}
+	return blocks, nil
+}
+
+func (r *userBlockRepository) FindBlockersOf(ctx context.Context, blockedID uint, candidateIDs []uint) ([]uint, error) {
+	var blockers []uint
+	if len(candidateIDs) == 0 {
+		return blockers, nil
+	}
+	err := r.db.WithContext(ctx).
+		Model(&domain.UserBlock{}).
+		Where("blocked_id = ? AND blocker_id IN ?", blockedID, candidateIDs).
+		Pluck("blocker_id", &blockers).Error
+	if err != nil {
+		shared.Log.Error("find blockers failed",
+			zap.String("operation", "FindBlockersOf"),
+			zap.Uint("blockedID", blockedID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find blockers failed").WithDetails(err.Error())
+	}
+	return blockers, nil
+}
+
+func (r *userBlockRepository) HiddenFrom(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT blocked_id FROM user_blocks WHERE blocker_id = ?
+		UNION
+		SELECT blocker_id FROM user_blocks WHERE blocked_id = ?`, userID, userID).
+		Scan(&ids).Error
+	if err != nil {
+		shared.Log.Error("find hidden users failed",
+			zap.String("operation", "HiddenFrom"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find hidden users failed").WithDetails(err.Error())
+	}
+	return ids, nil
+}
+
+func (r *userBlockRepository) DeleteAllForUser(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).
+		Where("blocker_id = ? OR blocked_id = ?", userID, userID).
+		Delete(&domain.UserBlock{}).Error
+	if err != nil {
+		shared.Log.Error("delete blocks failed",
+			zap.String("operation", "DeleteAllForUser"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("delete blocks failed").WithDetails(err.Error())
+	}
+	return nil
+}
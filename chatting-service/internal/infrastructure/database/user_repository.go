@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -94,7 +95,10 @@ func (r *userRepository) Update(ctx context.Context, userID uint, username, emai
 			updates["username"] = username
 		}
 		if email != "" {
+			// A new address has to be confirmed again
 			updates["email"] = email
+			updates["email_verified"] = false
+			updates["email_verified_at"] = nil
 		}
 
 		if len(updates) > 0 {
@@ -114,6 +118,47 @@ func (r *userRepository) Update(ctx context.Context, userID uint, username, emai
 	})
 }
 
+func (r *userRepository) UpdateDetails(ctx context.Context, userID uint, details domain.ProfileDetails) error {
+	updates := map[string]interface{}{}
+	if details.DisplayName != nil {
+		updates["display_name"] = *details.DisplayName
+	}
+	if details.Bio != nil {
+		updates["bio"] = *details.Bio
+	}
+	if details.TimeZone != nil {
+		updates["time_zone"] = *details.TimeZone
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if err := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ?", userID).
+		Updates(updates).Error; err != nil {
+		shared.Log.Error("update profile details failed",
+			zap.String("operation", "UpdateDetails"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("update profile details failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *userRepository) UpdateAvatar(ctx context.Context, userID uint, avatar domain.Avatar) error {
+	// Select forces the write even when clearing the avatar
+	if err := r.db.WithContext(ctx).Model(&domain.User{Model: gorm.Model{ID: userID}}).
+		Select("avatar").
+		Updates(&domain.User{Avatar: avatar}).Error; err != nil {
+		shared.Log.Error("update avatar failed",
+			zap.String("operation", "UpdateAvatar"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("update avatar failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
 func (r *userRepository) UpdateLastActiveAt(ctx context.Context, userID uint) error {
 	err := r.db.WithContext(ctx).Exec(
 		"UPDATE users SET last_active_at = ? WHERE id = ?",
@@ -135,7 +180,8 @@ func (r *userRepository) UpdateLastActiveAt(ctx context.Context, userID uint) er
 func (r *userRepository) FindByID(ctx context.Context, userID uint) (*domain.User, error) {
 	var user domain.User
 	err := r.db.WithContext(ctx).
-		Select("id", "username", "email", "password_hash", "last_active_at").
+		Select("id", "username", "email", "password_hash", "last_active_at", "email_verified", "email_verified_at", "role", "is_bot", "bot_owner_id", "display_name", "bio", "time_zone", "avatar",
+			"suspended_at", "suspension_reason", "password_reset_required", "sessions_valid_after").
 		First(&user, userID).Error
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -150,7 +196,8 @@ func (r *userRepository) FindByID(ctx context.Context, userID uint) (*domain.Use
 func (r *userRepository) FindProfileByID(ctx context.Context, userID uint) (*domain.User, error) {
 	var user domain.User
 	err := r.db.WithContext(ctx).
-		Select("id", "username", "email", "last_active_at", "status").
+		Select("id", "created_at", "username", "email", "last_active_at", "status", "email_verified", "email_verified_at", "role", "is_bot", "bot_owner_id", "display_name", "bio", "time_zone", "avatar",
+			"suspended_at", "suspension_reason", "password_reset_required").
 		First(&user, userID).Error
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -172,7 +219,8 @@ func (r *userRepository) FindProfileByID(ctx context.Context, userID uint) (*dom
 func (r *userRepository) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
 	var user domain.User
 	err := r.db.WithContext(ctx).
-		Select("id", "username", "email", "password_hash", "last_active_at").
+		Select("id", "username", "email", "password_hash", "last_active_at", "email_verified", "email_verified_at", "role", "is_bot", "bot_owner_id",
+			"suspended_at", "password_reset_required").
 		Where("username = ?", username).
 		First(&user).Error
 
@@ -190,6 +238,27 @@ func (r *userRepository) FindByUsername(ctx context.Context, username string) (*
 	return &user, nil
 }
 
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).
+		Select("id", "username", "email", "last_active_at", "email_verified", "email_verified_at", "role", "is_bot", "bot_owner_id", "suspended_at").
+		Where("LOWER(email) = LOWER(?)", email).
+		First(&user).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		shared.Log.Debug("user not found", zap.String("email", email))
+		return nil, shared.ErrRecordNotFound.WithDetails("user not found")
+	}
+	if err != nil {
+		shared.Log.Error("find user by email failed",
+			zap.String("operation", "FindByEmail"),
+			zap.String("email", email),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find user by email failed").WithDetails(err.Error())
+	}
+	return &user, nil
+}
+
 func (r *userRepository) Exists(ctx context.Context, userID uint) (bool, error) {
 	var count int64
 	err := r.db.WithContext(ctx).
@@ -255,18 +324,237 @@ func (r *userRepository) UpdatePassword(ctx context.Context, userID uint, passwo
 	})
 }
 
-func (r *userRepository) GetAll(ctx context.Context) ([]*domain.User, error) {
+func (r *userRepository) Search(ctx context.Context, search domain.UserSearch) ([]*domain.User, error) {
 	var users []*domain.User
 
-	err := r.db.WithContext(ctx).
-		Select("id", "username", "email", "last_active_at", "status").
-		Find(&users).Error
+	q := r.db.WithContext(ctx).
+		Select("id", "username", "email", "last_active_at", "status", "is_bot", "display_name", "avatar")
+	if search.Query != "" {
+		// Served by the idx_users_username_trgm and idx_users_display_name_trgm trigram indexes
+		pattern := "%" + escapeLike(search.Query) + "%"
+		q = q.Where("(username ILIKE ? OR display_name ILIKE ?)", pattern, pattern)
+	}
+	if search.AfterUsername != "" {
+		q = q.Where("(username, id) > (?, ?)", search.AfterUsername, search.AfterID)
+	}
+	if len(search.ExcludeIDs) > 0 {
+		q = q.Where("id NOT IN ?", search.ExcludeIDs)
+	}
 
+	err := q.Order("username, id").Limit(search.Limit).Find(&users).Error
 	if err != nil {
-		shared.Log.Error("get all users failed",
-			zap.String("operation", "GetAll"),
+		shared.Log.Error("search users failed",
+			zap.String("operation", "Search"),
+			zap.String("query", search.Query),
 			zap.Error(err))
-		return nil, shared.ErrDatabaseOperation.WithDetails("get all users failed").WithDetails(err.Error())
+		return nil, shared.ErrDatabaseOperation.WithDetails("search users failed").WithDetails(err.Error())
 	}
 	return users, nil
 }
+
+// escapeLike makes user input match literally inside a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"email_verified":    true,
+			"email_verified_at": time.Now().UTC(),
+		}).Error
+	if err != nil {
+		shared.Log.Error("mark email verified failed",
+			zap.String("operation", "MarkEmailVerified"),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		return shared.ErrDatabaseOperation.WithDetails("mark email verified failed").WithDetails(err.Error())
+	}
+	return nil
+}
+
+func (r *userRepository) CreateBot(ctx context.Context, username, email, passwordHash string, ownerID uint) (*domain.User, error) {
+	now := time.Now().UTC()
+	bot := &domain.User{
+		Username:        username,
+		Email:           email,
+		PasswordHash:    passwordHash,
+		IsBot:           true,
+		BotOwnerID:      &ownerID,
+		EmailVerified:   true, // Bots have no mailbox, the owner vouches for them
+		EmailVerifiedAt: &now,
+	}
+
+	if err := r.db.WithContext(ctx).Create(bot).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			shared.Log.Debug("bot username already exists", zap.String("username", username))
+			return nil, shared.ErrDuplicateEntry.WithDetails("username already exists")
+		}
+		shared.Log.Error("create bot failed",
+			zap.String("operation", "CreateBot"),
+			zap.String("username", username),
+			zap.Uint("ownerID", ownerID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("create bot failed").WithDetails(err.Error())
+	}
+	return bot, nil
+}
+
+func (r *userRepository) FindBotsByOwner(ctx context.Context, ownerID uint) ([]*domain.User, error) {
+	var bots []*domain.User
+	err := r.db.WithContext(ctx).
+		Select("id", "username", "email", "last_active_at", "status", "is_bot", "bot_owner_id", "created_at").
+		Where("is_bot = ? AND bot_owner_id = ?", true, ownerID).
+		Order("created_at ASC").
+		Find(&bots).Error
+	if err != nil {
+		shared.Log.Error("find bots by owner failed",
+			zap.String("operation", "FindBotsByOwner"),
+			zap.Uint("ownerID", ownerID),
+			zap.Error(err))
+		return nil, shared.ErrDatabaseOperation.WithDetails("find bots failed").WithDetails(err.Error())
+	}
+	return bots, nil
+}
+
+// Anonymize replaces every personal field with placeholders and soft deletes the
+// account. The row is kept so messages and audit events still point somewhere.
+func (r *userRepository) Anonymize(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"username":          fmt.Sprintf("deleted_user_%d", userID),
+			"email":             fmt.Sprintf("deleted_user_%d@deleted.invalid", userID),
+			"password_hash":     "!", // Matches no password
+			"display_name":      "",
+			"bio":               "",
+			"time_zone":         "UTC",
+			"avatar":            nil,
+			"email_verified":    false,
+			"email_verified_at": nil,
+			"status":            domain.UserOffline,
+			"suspension_reason": "",
+		}
+		if err := tx.Model(&domain.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			shared.Log.Error("anonymize user failed",
+				zap.String("operation", "Anonymize"),
+				zap.Uint("userID", userID),
+				zap.Error(err))
+			return shared.ErrDatabaseOperation.WithDetails("anonymize user failed").WithDetails(err.Error())
+		}
+		if err := tx.Delete(&domain.User{}, userID).Error; err != nil {
+			return shared.ErrDatabaseOperation.WithDetails("delete user failed").WithDetails(err.Error())
+		}
+		return nil
+	})
+}
+
+func (r *userRepository) List(ctx context.Context, filter domain.UserListFilter) ([]*domain.User, int64, error) {
+	q := r.db.WithContext(ctx).Model(&domain.User{})
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		q = q.Where("username ILIKE ? OR email ILIKE ? OR display_name ILIKE ?", pattern, pattern, pattern)
+	}
+	if filter.Role != "" {
+		q = q.Where("role = ?", filter.Role)
+	}
+	switch filter.Status {
+	case domain.AccountActive:
+		q = q.Where("suspended_at IS NULL")
+	case domain.AccountSuspended:
+		q = q.Where("suspended_at IS NOT NULL")
+	}
+	if filter.IsBot != nil {
+		q = q.Where("is_bot = ?", *filter.IsBot)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		shared.Log.Error("count users failed",
+			zap.String("operation", "List"),
+			zap.Error(err))
+		return nil, 0, shared.ErrDatabaseOperation.WithDetails("list users failed").WithDetails(err.Error())
+	}
+
+	var users []*domain.User
+	err := q.Select("id", "created_at", "username", "email", "last_active_at", "status", "role", "is_bot", "bot_owner_id",
+		"email_verified", "display_name", "suspended_at", "suspension_reason", "password_reset_required").
+		Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&users).Error
+	if err != nil {
+		shared.Log.Error("list users failed",
+			zap.String("operation", "List"),
+			zap.Error(err))
+		return nil, 0, shared.ErrDatabaseOperation.WithDetails("list users failed").WithDetails(err.Error())
+	}
+	return users, total, nil
+}
+
+// SetSuspension suspends the account when suspendedAt is set and reinstates it when nil
+func (r *userRepository) SetSuspension(ctx context.Context, userID uint, suspendedAt *time.Time, reason string) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"suspended_at":      suspendedAt,
+			"suspension_reason": reason,
+		})
+	if result.Error != nil {
+		shared.Log.Error("update suspension failed",
+			zap.String("operation", "SetSuspension"),
+			zap.Uint("userID", userID),
+			zap.Error(result.Error))
+		return shared.ErrDatabaseOperation.WithDetails("update suspension failed").WithDetails(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrUserNotFound
+	}
+	return nil
+}
+
+// CompletePasswordReset stores the new password and clears a forced reset
+func (r *userRepository) CompletePasswordReset(ctx context.Context, userID uint, passwordHash string) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"password_hash":           passwordHash,
+			"password_reset_required": false,
+		})
+	if result.Error != nil {
+		shared.Log.Error("complete password reset failed",
+			zap.String("operation", "CompletePasswordReset"),
+			zap.Uint("userID", userID),
+			zap.Error(result.Error))
+		return shared.ErrDatabaseOperation.WithDetails("complete password reset failed").WithDetails(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrUserNotFound
+	}
+	return nil
+}
+
+// RequirePasswordReset flags the account and revokes every session issued until sessionsValidAfter
+func (r *userRepository) RequirePasswordReset(ctx context.Context, userID uint, sessionsValidAfter time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"password_reset_required": true,
+			"sessions_valid_after":    sessionsValidAfter,
+		})
+	if result.Error != nil {
+		shared.Log.Error("require password reset failed",
+			zap.String("operation", "RequirePasswordReset"),
+			zap.Uint("userID", userID),
+			zap.Error(result.Error))
+		return shared.ErrDatabaseOperation.WithDetails("require password reset failed").WithDetails(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+)
+
+const (
+	jpegQuality    = 90 // Used for JPEG thumbnails and when a JPEG has to be re-encoded to apply its orientation
+	tagOrientation = 0x0112
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// MetadataStripper removes EXIF (including GPS), XMP, IPTC, comments and text chunks
+// from JPEG and PNG files. The pixels are left untouched unless an EXIF orientation
+// has to be applied, then the image is re-encoded upright.
+type MetadataStripper struct {
+	maxPixels int
+}
+
+func NewMetadataStripper(maxPixels int) *MetadataStripper {
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	return &MetadataStripper{maxPixels: maxPixels}
+}
+
+func (s *MetadataStripper) StripMetadata(ctx context.Context, data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		stripped, orientation, err := stripJPEG(data)
+		if err != nil {
+			return nil, err
+		}
+		return s.applyOrientation(stripped, orientation, func(img image.Image) ([]byte, error) {
+			var buf bytes.Buffer
+			err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+			return buf.Bytes(), err
+		})
+	case "image/png":
+		stripped, orientation, err := stripPNG(data)
+		if err != nil {
+			return nil, err
+		}
+		return s.applyOrientation(stripped, orientation, func(img image.Image) ([]byte, error) {
+			var buf bytes.Buffer
+			err := png.Encode(&buf, img)
+			return buf.Bytes(), err
+		})
+	}
+	return data, nil
+}
+
+func (s *MetadataStripper) applyOrientation(data []byte, orientation int, encode func(image.Image) ([]byte, error)) ([]byte, error) {
+	if orientation < 2 || orientation > 8 {
+		return data, nil
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidImage, err)
+	}
+	if cfg.Width*cfg.Height > s.maxPixels {
+		return nil, domain.ErrImageTooLarge
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidImage, err)
+	}
+	return encode(orient(toRGBA(decoded), orientation))
+}
+
+// stripJPEG drops every APPn segment except JFIF, ICC profiles and Adobe color
+// information, and all comments, also between the scans of progressive images. The
+// output ends with the primary image, phones append secondary images and vendor
+// trailers after it that carry their own EXIF.
+func stripJPEG(data []byte) ([]byte, int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, 0, fmt.Errorf("%w: missing JPEG start marker", domain.ErrInvalidImage)
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	orientation := 1
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xFF {
+			return nil, 0, fmt.Errorf("%w: malformed JPEG segment", domain.ErrInvalidImage)
+		}
+		// Markers may be padded with any number of 0xFF bytes
+		for pos < len(data) && data[pos] == 0xFF {
+			pos++
+		}
+		if pos >= len(data) {
+			break
+		}
+		marker := data[pos]
+		pos++
+
+		switch {
+		case marker == 0xD9: // End of image
+			return append(out, 0xFF, marker), orientation, nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7): // No payload
+			out = append(out, 0xFF, marker)
+			continue
+		}
+
+		if pos+2 > len(data) {
+			return nil, 0, fmt.Errorf("%w: truncated JPEG segment", domain.ErrInvalidImage)
+		}
+		length := int(binary.BigEndian.Uint16(data[pos:]))
+		if length < 2 || pos+length > len(data) {
+			return nil, 0, fmt.Errorf("%w: truncated JPEG segment", domain.ErrInvalidImage)
+		}
+		payload := data[pos+2 : pos+length]
+		segment := data[pos-2 : pos+length]
+		pos += length
+
+		if marker == 0xDA { // Start of scan, the compressed data follows
+			out = append(out, segment...)
+			end := scanEnd(data, pos)
+			out = append(out, data[pos:end]...)
+			if end == len(data) {
+				// Image data without an end marker, decoders cope with it
+				return out, orientation, nil
+			}
+			pos = end
+			continue
+		}
+		if marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			orientation = exifOrientation(payload[6:])
+		}
+		if keepJPEGSegment(marker, payload) {
+			out = append(out, segment...)
+		}
+	}
+	return nil, 0, fmt.Errorf("%w: missing JPEG image data", domain.ErrInvalidImage)
+}
+
+// scanEnd finds the marker ending the compressed data that starts at pos. Stuffed
+// 0xFF00 bytes and restart markers are part of the data.
+func scanEnd(data []byte, pos int) int {
+	for i := pos; i+1 < len(data); i++ {
+		if data[i] != 0xFF {
+			continue
+		}
+		next := data[i+1]
+		if next == 0x00 || (next >= 0xD0 && next <= 0xD7) {
+			i++
+			continue
+		}
+		return i
+	}
+	return len(data)
+}
+
+func keepJPEGSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == 0xFE: // Comment
+		return false
+	case marker == 0xE0: // JFIF
+		return true
+	case marker == 0xE2:
+		return bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+	case marker == 0xEE: // Adobe, tells decoders how the colors are stored
+		return true
+	case marker >= 0xE1 && marker <= 0xEF: // EXIF, XMP, IPTC and vendor data
+		return false
+	}
+	return true
+}
+
+// stripPNG drops the eXIf, text and timestamp chunks
+func stripPNG(data []byte) ([]byte, int, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, 0, fmt.Errorf("%w: missing PNG signature", domain.ErrInvalidImage)
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	orientation := 1
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, 0, fmt.Errorf("%w: truncated PNG chunk", domain.ErrInvalidImage)
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length // Length, type, data and CRC
+		if end > len(data) || end < pos {
+			return nil, 0, fmt.Errorf("%w: truncated PNG chunk", domain.ErrInvalidImage)
+		}
+		chunkType := string(data[pos+4 : pos+8])
+
+		switch chunkType {
+		case "eXIf":
+			orientation = exifOrientation(data[pos+8 : pos+8+length])
+		case "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+		if chunkType == "IEND" {
+			return out, orientation, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("%w: missing PNG end chunk", domain.ErrInvalidImage)
+}
+
+// exifOrientation reads the orientation tag from a TIFF structured EXIF block,
+// 1 (upright) when it is missing or unreadable
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == tagOrientation {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value < 1 || value > 8 {
+				return 1
+			}
+			return value
+		}
+	}
+	return 1
+}
+
+// orient turns an image stored with the given EXIF orientation upright
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored
+				sx, sy = w-1-x, y
+			case 3: // Upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // Mirrored upside down
+				sx, sy = x, h-1-y
+			case 5: // Mirrored along the top-left diagonal
+				sx, sy = y, x
+			case 6: // Needs a clockwise turn
+				sx, sy = y, h-1-x
+			case 7: // Mirrored along the top-right diagonal
+				sx, sy = w-1-y, h-1-x
+			case 8: // Needs a counter-clockwise turn
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			si := src.PixOffset(b.Min.X+sx, b.Min.Y+sy)
+			copy(dst.Pix[dst.PixOffset(x, y):], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,177 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exifBlock is a big-endian TIFF structure with an orientation tag and a fake GPS marker
+func exifBlock(orientation int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("MM\x00*")
+	binary.Write(&buf, binary.BigEndian, uint32(8))
+	binary.Write(&buf, binary.BigEndian, uint16(1))
+	binary.Write(&buf, binary.BigEndian, uint16(tagOrientation))
+	binary.Write(&buf, binary.BigEndian, uint16(3)) // SHORT
+	binary.Write(&buf, binary.BigEndian, uint32(1))
+	binary.Write(&buf, binary.BigEndian, uint16(orientation))
+	binary.Write(&buf, binary.BigEndian, uint16(0))
+	binary.Write(&buf, binary.BigEndian, uint32(0))
+	buf.WriteString("GPS 52.5200N 13.4050E")
+	return buf.Bytes()
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 4, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// withPNGChunks inserts chunks right after IHDR
+func withPNGChunks(t *testing.T, data []byte, chunks ...[]byte) []byte {
+	t.Helper()
+	ihdrEnd := len(pngSignature) + 12 + 13
+	out := append([]byte{}, data[:ihdrEnd]...)
+	for _, chunk := range chunks {
+		out = append(out, chunk...)
+	}
+	return append(out, data[ihdrEnd:]...)
+}
+
+// markedImage is 3x2 with a red top-left pixel, everything else blue
+func markedImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.Set(x, y, color.RGBA{B: 255, A: 255})
+		}
+	}
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	return img
+}
+
+func TestMetadataStripper_JPEG(t *testing.T) {
+	stripper := NewMetadataStripper(0)
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 30, 20)), nil))
+	raw := encoded.Bytes()
+
+	t.Run("RemovesMetadataWithoutReencoding", func(t *testing.T) {
+		data := append([]byte{0xFF, 0xD8}, jpegSegment(0xE1, append([]byte("Exif\x00\x00"), exifBlock(1)...))...)
+		data = append(data, jpegSegment(0xFE, []byte("taken at home"))...)
+		data = append(data, raw[2:]...)
+
+		out, err := stripper.StripMetadata(context.Background(), data, "image/jpeg")
+		require.NoError(t, err)
+		assert.Equal(t, raw, out)
+	})
+
+	t.Run("AppliesOrientation", func(t *testing.T) {
+		data := append([]byte{0xFF, 0xD8}, jpegSegment(0xE1, append([]byte("Exif\x00\x00"), exifBlock(6)...))...)
+		data = append(data, raw[2:]...)
+
+		out, err := stripper.StripMetadata(context.Background(), data, "image/jpeg")
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), "Exif")
+		assert.NotContains(t, string(out), "GPS")
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(out))
+		require.NoError(t, err)
+		assert.Equal(t, 20, cfg.Width)
+		assert.Equal(t, 30, cfg.Height)
+	})
+
+	t.Run("DropsAppendedImages", func(t *testing.T) {
+		// Phones append a preview or depth map after the primary image, with its own EXIF
+		appended := append([]byte{0xFF, 0xD8}, jpegSegment(0xE1, append([]byte("Exif\x00\x00"), exifBlock(1)...))...)
+		appended = append(appended, raw[2:]...)
+		data := append(append([]byte{}, raw...), appended...)
+
+		out, err := stripper.StripMetadata(context.Background(), data, "image/jpeg")
+		require.NoError(t, err)
+		assert.Equal(t, raw, out)
+	})
+
+	t.Run("RemovesMetadataBetweenScans", func(t *testing.T) {
+		// Right before the end of image, where progressive files have their next scan
+		data := append([]byte{}, raw[:len(raw)-2]...)
+		data = append(data, jpegSegment(0xE1, append([]byte("Exif\x00\x00"), exifBlock(1)...))...)
+		data = append(data, jpegSegment(0xFE, []byte("taken at home"))...)
+		data = append(data, 0xFF, 0xD9)
+
+		out, err := stripper.StripMetadata(context.Background(), data, "image/jpeg")
+		require.NoError(t, err)
+		assert.Equal(t, raw, out)
+	})
+
+	t.Run("RejectsGarbage", func(t *testing.T) {
+		_, err := stripper.StripMetadata(context.Background(), []byte("\xFF\xD8\xFF\xE1\x00"), "image/jpeg")
+		assert.ErrorIs(t, err, domain.ErrInvalidImage)
+	})
+}
+
+func TestMetadataStripper_PNG(t *testing.T) {
+	stripper := NewMetadataStripper(0)
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, markedImage()))
+
+	t.Run("RemovesTextChunks", func(t *testing.T) {
+		data := withPNGChunks(t, encoded.Bytes(), pngChunk("tEXt", []byte("Location\x00home")))
+
+		out, err := stripper.StripMetadata(context.Background(), data, "image/png")
+		require.NoError(t, err)
+		assert.Equal(t, encoded.Bytes(), out)
+	})
+
+	t.Run("AppliesOrientation", func(t *testing.T) {
+		data := withPNGChunks(t, encoded.Bytes(), pngChunk("eXIf", exifBlock(6)))
+
+		out, err := stripper.StripMetadata(context.Background(), data, "image/png")
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), "eXIf")
+
+		img, err := png.Decode(bytes.NewReader(out))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 2, 3), img.Bounds())
+		// A clockwise turn moves the top-left corner to the top-right
+		r, _, _, _ := img.At(1, 0).RGBA()
+		assert.Equal(t, uint32(0xFFFF), r)
+	})
+}
+
+func TestOrient(t *testing.T) {
+	// Where the red top-left pixel of the 3x2 image ends up
+	tests := map[int]image.Point{
+		1: {0, 0}, 2: {2, 0}, 3: {2, 1}, 4: {0, 1},
+		5: {0, 0}, 6: {1, 0}, 7: {1, 2}, 8: {0, 2},
+	}
+	for orientation, want := range tests {
+		out := orient(markedImage(), orientation)
+		assert.Equal(t, color.RGBA{R: 255, A: 255}, out.RGBAAt(want.X, want.Y), "orientation %d", orientation)
+	}
+}
+
+func TestMetadataStripper_OtherTypesUnchanged(t *testing.T) {
+	data := []byte("%PDF-1.7")
+	out, err := NewMetadataStripper(0).StripMetadata(context.Background(), data, "application/pdf")
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+}
@@ -0,0 +1,170 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register decoders
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+)
+
+// DefaultMaxPixels bounds decoded images so small files cannot expand into huge bitmaps
+const DefaultMaxPixels = 40_000_000
+
+// Resizer scales images with the standard library only. Photos stay JPEG, anything
+// else becomes PNG so transparency survives.
+type Resizer struct {
+	maxPixels int
+}
+
+func NewResizer(maxPixels int) *Resizer {
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	return &Resizer{maxPixels: maxPixels}
+}
+
+func (r *Resizer) Resize(ctx context.Context, src io.Reader, sizes []int, crop bool) ([]domain.ResizedImage, error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the header before decoding anything
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, domain.ErrInvalidImage
+	}
+	if cfg.Width*cfg.Height > r.maxPixels {
+		return nil, domain.ErrImageTooLarge
+	}
+
+	decoded, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidImage, err)
+	}
+
+	source := toRGBA(decoded)
+	bounds := source.Bounds()
+	if crop {
+		bounds = centerSquare(bounds)
+	}
+
+	results := make([]domain.ResizedImage, 0, len(sizes))
+	for _, size := range sizes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("invalid size %d", size)
+		}
+
+		width, height := size, size
+		if !crop {
+			width, height = fitWithin(bounds.Dx(), bounds.Dy(), size)
+		}
+
+		scaled := scale(source, bounds, width, height)
+		var buf bytes.Buffer
+		contentType := "image/png"
+		if format == "jpeg" {
+			contentType = "image/jpeg"
+			err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			err = png.Encode(&buf, scaled)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, domain.ResizedImage{
+			Size:        size,
+			Width:       width,
+			Height:      height,
+			ContentType: contentType,
+			Data:        buf.Bytes(),
+		})
+	}
+	return results, nil
+}
+
+func (r *Resizer) Dimensions(ctx context.Context, src io.Reader) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(src)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", domain.ErrInvalidImage, err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(bounds)
+	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+	return rgba
+}
+
+func centerSquare(r image.Rectangle) image.Rectangle {
+	side := min(r.Dx(), r.Dy())
+	x := r.Min.X + (r.Dx()-side)/2
+	y := r.Min.Y + (r.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// fitWithin keeps the aspect ratio and never upscales
+func fitWithin(width, height, bound int) (int, int) {
+	if width <= bound && height <= bound {
+		return width, height
+	}
+	if width >= height {
+		return bound, max(1, (height*bound+width/2)/width)
+	}
+	return max(1, (width*bound+height/2)/height), bound
+}
+
+// scale averages every source pixel that falls into each destination pixel (box filter).
+// When upscaling each destination pixel samples at least one source pixel.
+func scale(src *image.RGBA, from image.Rectangle, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sw, sh := from.Dx(), from.Dy()
+
+	for dy := 0; dy < height; dy++ {
+		y0 := from.Min.Y + dy*sh/height
+		y1 := max(from.Min.Y+(dy+1)*sh/height, y0+1)
+
+		for dx := 0; dx < width; dx++ {
+			x0 := from.Min.X + dx*sw/width
+			x1 := max(from.Min.X+(dx+1)*sw/width, x0+1)
+
+			var r, g, b, a, n uint32
+			for y := y0; y < y1; y++ {
+				row := src.PixOffset(x0, y)
+				for x := x0; x < x1; x++ {
+					r += uint32(src.Pix[row])
+					g += uint32(src.Pix[row+1])
+					b += uint32(src.Pix[row+2])
+					a += uint32(src.Pix[row+3])
+					row += 4
+					n++
+				}
+			}
+
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
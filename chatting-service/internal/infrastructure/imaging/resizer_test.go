@@ -0,0 +1,106 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestResizer_CropProducesSquares(t *testing.T) {
+	resizer := NewResizer(0)
+
+	results, err := resizer.Resize(context.Background(), bytes.NewReader(encodePNG(t, 300, 200)), []int{64, 128, 256}, true)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	for _, r := range results {
+		assert.Equal(t, r.Size, r.Width)
+		assert.Equal(t, r.Size, r.Height)
+		assert.Equal(t, "image/png", r.ContentType)
+
+		decoded, err := png.Decode(bytes.NewReader(r.Data))
+		require.NoError(t, err)
+		assert.Equal(t, r.Size, decoded.Bounds().Dx())
+		red, green, blue, _ := decoded.At(r.Size/2, r.Size/2).RGBA()
+		assert.Equal(t, []uint32{200, 100, 50}, []uint32{red >> 8, green >> 8, blue >> 8})
+	}
+}
+
+func TestResizer_JPEGStaysJPEG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1600, 1200))
+	for y := 0; y < 1200; y++ {
+		for x := 0; x < 1600; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: 255})
+		}
+	}
+	var photo bytes.Buffer
+	require.NoError(t, jpeg.Encode(&photo, img, &jpeg.Options{Quality: jpegQuality}))
+
+	results, err := NewResizer(0).Resize(context.Background(), bytes.NewReader(photo.Bytes()), []int{800}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	assert.Equal(t, "image/jpeg", results[0].ContentType)
+	decoded, err := jpeg.Decode(bytes.NewReader(results[0].Data))
+	require.NoError(t, err)
+	assert.Equal(t, 800, decoded.Bounds().Dx())
+	assert.Less(t, len(results[0].Data), photo.Len())
+}
+
+func TestResizer_FitKeepsAspectRatioWithoutUpscaling(t *testing.T) {
+	resizer := NewResizer(0)
+
+	results, err := resizer.Resize(context.Background(), bytes.NewReader(encodePNG(t, 400, 100)), []int{200, 1000}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, 200, results[0].Width)
+	assert.Equal(t, 50, results[0].Height)
+	assert.Equal(t, 400, results[1].Width)
+	assert.Equal(t, 100, results[1].Height)
+}
+
+func TestResizer_Rejects(t *testing.T) {
+	t.Run("not an image", func(t *testing.T) {
+		_, err := NewResizer(0).Resize(context.Background(), strings.NewReader("hello"), []int{64}, true)
+		assert.ErrorIs(t, err, domain.ErrInvalidImage)
+	})
+
+	t.Run("too many pixels", func(t *testing.T) {
+		_, err := NewResizer(100).Resize(context.Background(), bytes.NewReader(encodePNG(t, 20, 20)), []int{64}, true)
+		assert.ErrorIs(t, err, domain.ErrImageTooLarge)
+	})
+}
+
+func TestResizer_Dimensions(t *testing.T) {
+	resizer := NewResizer(0)
+
+	width, height, err := resizer.Dimensions(context.Background(), bytes.NewReader(encodePNG(t, 30, 20)))
+	require.NoError(t, err)
+	assert.Equal(t, 30, width)
+	assert.Equal(t, 20, height)
+
+	_, _, err = resizer.Dimensions(context.Background(), strings.NewReader("not an image"))
+	assert.ErrorIs(t, err, domain.ErrInvalidImage)
+}
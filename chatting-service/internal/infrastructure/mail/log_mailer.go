@@ -0,0 +1,24 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+// LogMailer writes outgoing emails to the log instead of sending them.
+// Used for local development when no SMTP server is configured
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	shared.Log.Info("email not sent (no SMTP configured)",
+		zap.String("to", to),
+		zap.String("subject", subject),
+		zap.String("body", body))
+	return nil
+}
@@ -0,0 +1,339 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// Keys are refetched at most this often when an ID token carries an unknown key ID,
+// so a flood of forged tokens cannot hammer the provider
+const jwksRefreshInterval = time.Minute
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Client is an OpenID Connect relying party for the authorization code flow with PKCE.
+// Provider metadata and signing keys are discovered from the issuer and cached.
+type Client struct {
+	cfg        config.OIDCConfig
+	httpClient *http.Client
+
+	mu            sync.RWMutex
+	discovery     *discoveryDocument
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func NewClient(cfg config.OIDCConfig) *Client {
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) AuthCodeURL(ctx context.Context, state, codeChallenge, nonce string) (string, error) {
+	doc, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {c.cfg.RedirectURL},
+		"scope":                 {strings.Join(c.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return doc.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the claims of the verified ID token
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*domain.ExternalClaims, error) {
+	doc, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"client_id":     {c.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if c.cfg.ClientSecret != "" {
+		form.Set("client_secret", c.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, shared.ErrInternalServer.WithDetails("build token request failed")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		shared.Log.Error("oidc token request failed", zap.Error(err))
+		return nil, shared.ErrServiceUnavailable.WithDetails("identity provider unreachable")
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		shared.Log.Error("decode oidc token response failed", zap.Error(err), zap.Int("status", resp.StatusCode))
+		return nil, shared.ErrServiceUnavailable.WithDetails("invalid token response from identity provider")
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		shared.Log.Debug("oidc code exchange rejected",
+			zap.Int("status", resp.StatusCode),
+			zap.String("error", body.Error),
+			zap.String("description", body.ErrorDescription))
+		return nil, shared.ErrUnauthorized.WithDetails("authorization code rejected by identity provider")
+	}
+	if body.IDToken == "" {
+		return nil, shared.ErrUnauthorized.WithDetails("identity provider returned no id_token")
+	}
+
+	return c.verifyIDToken(ctx, doc, body.IDToken, nonce)
+}
+
+func (c *Client) verifyIDToken(ctx context.Context, doc *discoveryDocument, rawToken, nonce string) (*domain.ExternalClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return c.signingKey(ctx, doc, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		shared.Log.Debug("id token verification failed", zap.Error(err))
+		return nil, shared.ErrUnauthorized.WithDetails("invalid id token")
+	}
+
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, shared.ErrUnauthorized.WithDetails("id token nonce mismatch")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, shared.ErrUnauthorized.WithDetails("id token has no subject")
+	}
+
+	return &domain.ExternalClaims{
+		Issuer:        doc.Issuer,
+		Subject:       subject,
+		Email:         stringClaim(claims, c.cfg.EmailClaim),
+		EmailVerified: boolClaim(claims, c.cfg.EmailVerifiedClaim),
+		Username:      stringClaim(claims, c.cfg.UsernameClaim),
+	}, nil
+}
+
+func (c *Client) discover(ctx context.Context) (*discoveryDocument, error) {
+	c.mu.RLock()
+	doc := c.discovery
+	c.mu.RUnlock()
+	if doc != nil {
+		return doc, nil
+	}
+
+	var fetched discoveryDocument
+	if err := c.getJSON(ctx, c.cfg.IssuerURL+"/.well-known/openid-configuration", &fetched); err != nil {
+		shared.Log.Error("oidc discovery failed", zap.Error(err), zap.String("issuer", c.cfg.IssuerURL))
+		return nil, shared.ErrServiceUnavailable.WithDetails("identity provider discovery failed")
+	}
+	if strings.TrimSuffix(fetched.Issuer, "/") != c.cfg.IssuerURL {
+		shared.Log.Error("oidc issuer mismatch", zap.String("expected", c.cfg.IssuerURL), zap.String("got", fetched.Issuer))
+		return nil, shared.ErrServiceUnavailable.WithDetails("identity provider issuer mismatch")
+	}
+	if fetched.AuthorizationEndpoint == "" || fetched.TokenEndpoint == "" || fetched.JWKSURI == "" {
+		return nil, shared.ErrServiceUnavailable.WithDetails("identity provider metadata incomplete")
+	}
+
+	c.mu.Lock()
+	c.discovery = &fetched
+	c.mu.Unlock()
+	return &fetched, nil
+}
+
+// signingKey returns the provider key for kid, refreshing the key set once when the
+// provider has rotated keys since the last fetch
+func (c *Client) signingKey(ctx context.Context, doc *discoveryDocument, kid string) (interface{}, error) {
+	c.mu.RLock()
+	key, ok := c.lookupKey(kid)
+	stale := time.Since(c.keysFetchedAt) > jwksRefreshInterval
+	c.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, errors.New("unknown signing key")
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.getJSON(ctx, doc.JWKSURI, &set); err != nil {
+		shared.Log.Error("fetch oidc jwks failed", zap.Error(err))
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			shared.Log.Debug("skipping unsupported jwk", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		keys[jwk.Kid] = publicKey
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.keysFetchedAt = time.Now()
+	key, ok = c.lookupKey(kid)
+	c.mu.Unlock()
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	return key, nil
+}
+
+// lookupKey must be called with c.mu held. Tokens without a kid are accepted
+// only when the provider publishes a single key.
+func (c *Client) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+func (c *Client) getJSON(ctx context.Context, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// boolClaim accepts both JSON booleans and the "true" strings some providers send
+func boolClaim(claims jwt.MapClaims, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	}
+	return false
+}
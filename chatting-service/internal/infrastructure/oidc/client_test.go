@@ -0,0 +1,187 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIdP is a minimal OpenID provider: discovery, JWKS and a token endpoint that
+// checks the PKCE verifier against the challenge sent in the authorization request
+type stubIdP struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &stubIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.sign(t)})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *stubIdP) sign(t *testing.T) string {
+	claims := jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"sub":   "subject-1",
+		"aud":   "chat-client",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": idp.nonce,
+	}
+	for k, v := range idp.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(idp.key)
+	require.NoError(t, err)
+	return signed
+}
+
+// authorize plays the browser: follow the authorization URL and remember what the IdP received
+func (idp *stubIdP) authorize(t *testing.T, authURL string) {
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := parsed.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.Equal(t, "chat-client", q.Get("client_id"))
+	idp.challenge = q.Get("code_challenge")
+	idp.nonce = q.Get("nonce")
+}
+
+func newTestClient(idp *stubIdP) *Client {
+	return NewClient(config.OIDCConfig{
+		IssuerURL:          idp.server.URL,
+		ClientID:           "chat-client",
+		RedirectURL:        "http://localhost/callback",
+		Scopes:             []string{"openid", "email"},
+		UsernameClaim:      "preferred_username",
+		EmailClaim:         "email",
+		EmailVerifiedClaim: "email_verified",
+	})
+}
+
+func pkce(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestClient_CodeFlow(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	verifier := "verifier-0123456789-0123456789-0123456789-0123456789"
+
+	t.Run("ExchangeMapsClaims", func(t *testing.T) {
+		idp := newStubIdP(t)
+		idp.claims = jwt.MapClaims{"email": "jane@corp.example", "email_verified": "true", "preferred_username": "jane"}
+		client := newTestClient(idp)
+
+		authURL, err := client.AuthCodeURL(ctx, "state-1", pkce(verifier), "nonce-1")
+		require.NoError(t, err)
+		idp.authorize(t, authURL)
+
+		claims, err := client.Exchange(ctx, "good-code", verifier, "nonce-1")
+		require.NoError(t, err)
+		assert.Equal(t, idp.server.URL, claims.Issuer)
+		assert.Equal(t, "subject-1", claims.Subject)
+		assert.Equal(t, "jane@corp.example", claims.Email)
+		assert.True(t, claims.EmailVerified)
+		assert.Equal(t, "jane", claims.Username)
+	})
+
+	t.Run("RejectsWrongVerifier", func(t *testing.T) {
+		idp := newStubIdP(t)
+		client := newTestClient(idp)
+
+		authURL, err := client.AuthCodeURL(ctx, "state-1", pkce(verifier), "nonce-1")
+		require.NoError(t, err)
+		idp.authorize(t, authURL)
+
+		_, err = client.Exchange(ctx, "good-code", "another-verifier", "nonce-1")
+		var appErr shared.Error
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrUnauthorized.Code, appErr.Code)
+	})
+
+	t.Run("RejectsNonceMismatch", func(t *testing.T) {
+		idp := newStubIdP(t)
+		client := newTestClient(idp)
+
+		authURL, err := client.AuthCodeURL(ctx, "state-1", pkce(verifier), "nonce-1")
+		require.NoError(t, err)
+		idp.authorize(t, authURL)
+		idp.nonce = "replayed-nonce"
+
+		_, err = client.Exchange(ctx, "good-code", verifier, "nonce-1")
+		var appErr shared.Error
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrUnauthorized.Code, appErr.Code)
+	})
+
+	t.Run("RejectsForeignAudience", func(t *testing.T) {
+		idp := newStubIdP(t)
+		idp.claims = jwt.MapClaims{"aud": "some-other-app"}
+		client := newTestClient(idp)
+
+		authURL, err := client.AuthCodeURL(ctx, "state-1", pkce(verifier), "nonce-1")
+		require.NoError(t, err)
+		idp.authorize(t, authURL)
+
+		_, err = client.Exchange(ctx, "good-code", verifier, "nonce-1")
+		var appErr shared.Error
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, shared.ErrUnauthorized.Code, appErr.Code)
+	})
+}
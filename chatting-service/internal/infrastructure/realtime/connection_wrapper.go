@@ -3,13 +3,16 @@ package realtime
 import (
 	"errors"
 	"sync"
+	"time"
 
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/gofiber/contrib/websocket"
 )
 
 // ConnectionWrapper bridges fiber/websocket and our notifier
 type ConnectionWrapper struct {
 	conn *websocket.Conn
+	role domain.UserRole // Role at connect time, used to route announcements
 	mu   sync.Mutex
 }
 
@@ -27,6 +30,18 @@ func (w *ConnectionWrapper) ReadMessage() (int, []byte, error) {
 	return w.conn.ReadMessage()
 }
 
+// CloseWithReason tells the client why it is being disconnected before closing
+func (w *ConnectionWrapper) CloseWithReason(code int, reason string) error {
+	if w == nil || w.conn == nil {
+		return errors.New("nil connection")
+	}
+
+	w.mu.Lock()
+	_ = w.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	w.mu.Unlock()
+	return w.conn.Close()
+}
+
 func (w *ConnectionWrapper) Close() error {
 	return w.conn.Close()
 }
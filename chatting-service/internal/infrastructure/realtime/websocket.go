@@ -26,7 +26,9 @@ func NewWebSocketNotifier() *WebSocketNotifier {
 	}
 }
 
-func (w *WebSocketNotifier) Notify(ctx context.Context, message *domain.Message) error {
+// Notify pushes a direct message to its recipient. Silent events are still delivered,
+// clients are expected to skip sounds and badges for them.
+func (w *WebSocketNotifier) Notify(ctx context.Context, message *domain.Message, silent bool) error {
 	if message == nil {
 		return errors.New("nil message")
 	}
@@ -46,21 +48,25 @@ func (w *WebSocketNotifier) Notify(ctx context.Context, message *domain.Message)
 	}
 
 	wsMessage := struct {
-		ID          uint      `json:"id"`
-		Content     string    `json:"content"`
-		MessageType string    `json:"message_type"`
-		Status      string    `json:"status"`
-		SenderID    uint      `json:"sender_id"`
-		RecipientID uint      `json:"recipient_id"`
-		SentAt      time.Time `json:"sent_at"`
+		ID          uint               `json:"id"`
+		Content     string             `json:"content"`
+		MessageType string             `json:"message_type"`
+		Status      string             `json:"status"`
+		SenderID    uint               `json:"sender_id"`
+		Sender      *domain.SenderInfo `json:"sender,omitempty"`
+		RecipientID uint               `json:"recipient_id"`
+		SentAt      time.Time          `json:"sent_at"`
+		Silent      bool               `json:"silent"`
 	}{
 		ID:          message.ID,
 		Content:     message.Content,
 		MessageType: string(message.MessageType),
 		Status:      string(message.Status),
 		SenderID:    message.SenderID,
+		Sender:      message.Sender.SenderInfo(),
 		RecipientID: *message.RecipientID,
 		SentAt:      message.SentAt,
+		Silent:      silent,
 	}
 
 	w.logger.Debug("sending websocket message",
@@ -70,13 +76,18 @@ func (w *WebSocketNotifier) Notify(ctx context.Context, message *domain.Message)
 	return conn.WriteJSON(wsMessage)
 }
 
-func (w *WebSocketNotifier) Broadcast(ctx context.Context, message *domain.Message, recipientIDs []uint) error {
+func (w *WebSocketNotifier) Broadcast(ctx context.Context, message *domain.Message, recipientIDs []uint, silent map[uint]bool) error {
 	w.clientsMu.Lock()
 	defer w.clientsMu.Unlock()
 
 	for _, id := range recipientIDs {
 		if conn, ok := w.clients[id]; ok {
-			if err := conn.WriteJSON(message); err != nil {
+			event := struct {
+				*domain.Message
+				Sender *domain.SenderInfo `json:"sender,omitempty"`
+				Silent bool               `json:"silent"`
+			}{Message: message, Sender: message.Sender.SenderInfo(), Silent: silent[id]}
+			if err := conn.WriteJSON(event); err != nil {
 				zap.L().Error("websocket broadcast failed",
 					zap.Uint("userID", id),
 					zap.Error(err))
@@ -86,6 +97,50 @@ func (w *WebSocketNotifier) Broadcast(ctx context.Context, message *domain.Messa
 	return nil
 }
 
+// Announce pushes a post to every connected user who follows the channel. Offline users
+// pick it up through their unread count, nothing is queued per user.
+func (w *WebSocketNotifier) Announce(ctx context.Context, channel *domain.AnnouncementChannel, post *domain.Announcement) error {
+	if channel == nil || post == nil {
+		return errors.New("nil announcement")
+	}
+
+	event := struct {
+		Event     string             `json:"event"`
+		ID        uint               `json:"id"`
+		ChannelID uint               `json:"channel_id"`
+		Channel   string             `json:"channel"`
+		Content   string             `json:"content"`
+		MediaURL  string             `json:"media_url,omitempty"`
+		Author    *domain.SenderInfo `json:"author,omitempty"`
+		SentAt    time.Time          `json:"sent_at"`
+	}{
+		Event:     "announcement",
+		ID:        post.ID,
+		ChannelID: channel.ID,
+		Channel:   channel.Name,
+		Content:   post.Content,
+		MediaURL:  post.MediaURL,
+		Author:    post.Author.SenderInfo(),
+		SentAt:    post.CreatedAt,
+	}
+
+	w.clientsMu.Lock()
+	defer w.clientsMu.Unlock()
+
+	for id, conn := range w.clients {
+		if !channel.VisibleTo(conn.role) {
+			continue
+		}
+		if err := conn.WriteJSON(event); err != nil {
+			w.logger.Error("websocket announcement failed",
+				zap.Uint("userID", id),
+				zap.Uint("channelID", channel.ID),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
 func (w *WebSocketNotifier) RegisterClient(userID uint, conn *websocket.Conn) {
 	w.clientsMu.Lock()
 	defer w.clientsMu.Unlock()
@@ -104,6 +159,24 @@ func (w *WebSocketNotifier) RemoveClient(userID uint) {
 		zap.Int("activeConnections", len(w.clients)))
 }
 
+// CloseUserConnections disconnects the user, their read loop then cleans up the registration
+func (w *WebSocketNotifier) CloseUserConnections(userID uint, reason string) {
+	w.clientsMu.Lock()
+	conn, ok := w.clients[userID]
+	delete(w.clients, userID)
+	w.clientsMu.Unlock()
+
+	if !ok {
+		return
+	}
+	if err := conn.CloseWithReason(websocket.ClosePolicyViolation, reason); err != nil {
+		w.logger.Debug("close websocket failed", zap.Uint("userID", userID), zap.Error(err))
+	}
+	w.logger.Info("websocket connection revoked",
+		zap.Uint("userID", userID),
+		zap.String("reason", reason))
+}
+
 func (w *WebSocketNotifier) Upgrade(c *fiber.Ctx) error {
 	if websocket.IsWebSocketUpgrade(c) {
 		c.Locals("allowed", true)
@@ -118,9 +191,13 @@ func (w *WebSocketNotifier) Upgrade(c *fiber.Ctx) error {
 
 func (w *WebSocketNotifier) HandleConnection(conn *websocket.Conn) {
 	userID := conn.Locals("userID").(uint)
+	var role domain.UserRole
+	if claims, ok := conn.Locals("userClaims").(*domain.TokenClaims); ok {
+		role = claims.Role
+	}
 
 	// Register connection
-	wrapper := &ConnectionWrapper{conn: conn}
+	wrapper := &ConnectionWrapper{conn: conn, role: role}
 	w.clientsMu.Lock()
 	w.clients[userID] = wrapper
 	w.clientsMu.Unlock()
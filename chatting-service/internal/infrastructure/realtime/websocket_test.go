@@ -35,7 +35,7 @@ func TestWebSocketNotifier(t *testing.T) {
 			SentAt:      time.Now(),
 		}
 
-		err := notifier.Notify(context.Background(), msg)
+		err := notifier.Notify(context.Background(), msg, false)
 		assert.NoError(t, err)
 
 		// Cleanup
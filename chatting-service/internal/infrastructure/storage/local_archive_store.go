@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalArchiveStore keeps generated archives in a private directory. Archives are
+// only handed out by the application, never served statically.
+type LocalArchiveStore struct {
+	basePath string
+}
+
+func NewLocalArchiveStore(basePath string) *LocalArchiveStore {
+	return &LocalArchiveStore{basePath: basePath}
+}
+
+func (s *LocalArchiveStore) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	fullPath, err := s.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.basePath, 0700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(fullPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+}
+
+func (s *LocalArchiveStore) Open(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	fullPath, err := s.resolve(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+func (s *LocalArchiveStore) Delete(ctx context.Context, name string) error {
+	fullPath, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Archive names are generated by the application and never contain directories
+func (s *LocalArchiveStore) resolve(name string) (string, error) {
+	if name == "" || filepath.Base(name) != name || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid archive name %q", name)
+	}
+	return filepath.Join(s.basePath, name), nil
+}
@@ -2,25 +2,35 @@ package storage
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/AmeerHeiba/chatting-service/internal/shared"
 	"go.uber.org/zap"
 )
 
 type LocalStorage struct {
-	basePath string
-	baseURL  string
+	basePath   string
+	baseURL    string
+	signingKey string
 }
 
-func NewLocalStorage(basePath, baseURL string) *LocalStorage {
+func NewLocalStorage(basePath, baseURL, signingKey string) *LocalStorage {
 	return &LocalStorage{
-		basePath: basePath,
-		baseURL:  baseURL,
+		basePath:   basePath,
+		baseURL:    baseURL,
+		signingKey: signingKey,
 	}
 }
 
@@ -62,6 +72,36 @@ func (s *LocalStorage) Upload(ctx context.Context, file io.Reader, filename stri
 	return filepath.Join(fmt.Sprintf("user_%d", userId), filename), nil
 }
 
+func (s *LocalStorage) Put(ctx context.Context, file io.Reader, path string, contentType string, size int64) error {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		shared.Log.Error("failed to create media directory",
+			zap.String("path", fullPath),
+			zap.Error(err))
+		return err
+	}
+
+	dst, err := os.Create(fullPath)
+	if err != nil {
+		shared.Log.Error("failed to create file",
+			zap.String("path", fullPath),
+			zap.Error(err))
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		shared.Log.Error("failed to write file",
+			zap.String("path", fullPath),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 func (s *LocalStorage) GetURL(ctx context.Context, path string) (string, error) {
 	return s.baseURL + "/" + path, nil
 }
@@ -71,7 +111,94 @@ func (s *LocalStorage) Delete(ctx context.Context, path string) error {
 	return os.Remove(fullPath)
 }
 
-// Dummy for now
+// GetSignedURL returns a link the media route serves without a login until it expires
 func (s *LocalStorage) GetSignedURL(ctx context.Context, path string, expires time.Duration) (string, error) {
-	return s.baseURL + "/" + path, nil
+	if expires <= 0 {
+		return "", errors.New("signed URL expiry must be positive")
+	}
+	if _, err := s.resolve(path); err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().Add(expires).Unix()
+	return fmt.Sprintf("%s/%s?expires=%d&signature=%s", s.baseURL, path, expiresAt, s.sign(path, expiresAt)), nil
+}
+
+// VerifySignedURL checks the expires and signature parameters of a link from GetSignedURL
+func (s *LocalStorage) VerifySignedURL(path string, expires int64, signature string) error {
+	if s.signingKey == "" || !hmac.Equal([]byte(signature), []byte(s.sign(path, expires))) {
+		return domain.ErrInvalidMediaLink
+	}
+	if time.Now().Unix() >= expires {
+		return domain.ErrMediaLinkExpired
+	}
+	return nil
+}
+
+func (s *LocalStorage) sign(path string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.signingKey))
+	mac.Write([]byte(filepath.ToSlash(path) + ":" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalStorage) DeleteUserFiles(ctx context.Context, userId uint) error {
+	return os.RemoveAll(filepath.Join(s.basePath, fmt.Sprintf("user_%d", userId)))
+}
+
+// Walk lists every file below the base path with its path relative to it
+func (s *LocalStorage) Walk(ctx context.Context, fn func(file domain.StoredFile) error) error {
+	err := filepath.WalkDir(s.basePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Removed meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.basePath, path)
+		if err != nil {
+			return err
+		}
+		return fn(domain.StoredFile{Path: filepath.ToSlash(rel), Size: info.Size(), ModifiedAt: info.ModTime()})
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // Nothing uploaded yet
+	}
+	return err
+}
+
+func (s *LocalStorage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(fullPath)
+}
+
+func (s *LocalStorage) PathFromURL(url string) (string, bool) {
+	path, ok := strings.CutPrefix(url, s.baseURL+"/")
+	if !ok || path == "" {
+		return "", false
+	}
+	if _, err := s.resolve(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// resolve maps a storage path to the filesystem, refusing anything outside basePath
+func (s *LocalStorage) resolve(path string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid media path %q", path)
+	}
+	return filepath.Join(s.basePath, cleaned), nil
 }
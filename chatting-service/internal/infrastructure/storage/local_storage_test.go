@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_SignedURL(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	store := NewLocalStorage(t.TempDir(), "http://localhost:8080/media", "media-key")
+	path, err := store.Upload(ctx, strings.NewReader("hi"), "1_1_abc.png", "image/png", 2, 1)
+	require.NoError(t, err)
+
+	link, err := store.GetSignedURL(ctx, path, time.Minute)
+	require.NoError(t, err)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "/media/"+path, u.Path)
+	expires, _ := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	signature := u.Query().Get("signature")
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, store.VerifySignedURL(path, expires, signature))
+	})
+
+	t.Run("OtherPath", func(t *testing.T) {
+		assert.ErrorIs(t, store.VerifySignedURL("user_2/1_1_abc.png", expires, signature), domain.ErrInvalidMediaLink)
+	})
+
+	t.Run("ExtendedExpiry", func(t *testing.T) {
+		assert.ErrorIs(t, store.VerifySignedURL(path, expires+3600, signature), domain.ErrInvalidMediaLink)
+	})
+
+	t.Run("OtherKey", func(t *testing.T) {
+		other := NewLocalStorage(t.TempDir(), "http://localhost:8080/media", "another-key")
+		assert.ErrorIs(t, other.VerifySignedURL(path, expires, signature), domain.ErrInvalidMediaLink)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		past := time.Now().Add(-time.Second).Unix()
+		assert.ErrorIs(t, store.VerifySignedURL(path, past, store.sign(path, past)), domain.ErrMediaLinkExpired)
+	})
+}
+
+func TestLocalStorage_Put(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+	store := NewLocalStorage(t.TempDir(), "http://localhost:8080/media", "media-key")
+
+	t.Run("CreatesDirectories", func(t *testing.T) {
+		path := domain.MediaBlobPath(strings.Repeat("ab", 32), "0123456789abcdef", ".txt")
+		require.NoError(t, store.Put(ctx, strings.NewReader("hi"), path, "text/plain", 2))
+
+		file, err := store.Open(ctx, path)
+		require.NoError(t, err)
+		defer file.Close()
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+		assert.Equal(t, "hi", string(content))
+	})
+
+	t.Run("StaysInsideRoot", func(t *testing.T) {
+		assert.Error(t, store.Put(ctx, strings.NewReader("hi"), "../escaped.txt", "text/plain", 2))
+	})
+}
+
+func TestLocalStorage_Walk(t *testing.T) {
+	shared.InitLogger("test")
+	ctx := context.Background()
+
+	t.Run("ListsNestedFiles", func(t *testing.T) {
+		store := NewLocalStorage(t.TempDir(), "http://localhost:8080/media", "media-key")
+		path, err := store.Upload(ctx, strings.NewReader("hi"), "1_1_abc.png", "image/png", 2, 1)
+		require.NoError(t, err)
+		blob := domain.MediaBlobPath(strings.Repeat("ab", 32), "0123456789abcdef", ".txt")
+		require.NoError(t, store.Put(ctx, strings.NewReader("hello"), blob, "text/plain", 5))
+
+		files := map[string]int64{}
+		require.NoError(t, store.Walk(ctx, func(file domain.StoredFile) error {
+			files[file.Path] = file.Size
+			assert.False(t, file.ModifiedAt.IsZero())
+			return nil
+		}))
+		assert.Equal(t, map[string]int64{path: 2, blob: 5}, files)
+	})
+
+	t.Run("NothingUploadedYet", func(t *testing.T) {
+		store := NewLocalStorage(t.TempDir()+"/missing", "http://localhost:8080/media", "media-key")
+		assert.NoError(t, store.Walk(ctx, func(file domain.StoredFile) error { return nil }))
+	})
+}
@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+)
+
+// NewMediaStorage builds the backend cfg.Driver picks
+func NewMediaStorage(cfg config.StorageConfig) (domain.MediaStorage, error) {
+	if cfg.Driver != config.StorageS3 {
+		return NewLocalStorage(cfg.LocalPath, cfg.BaseURL, cfg.SigningKey), nil
+	}
+	return NewS3Storage(cfg.S3)
+}
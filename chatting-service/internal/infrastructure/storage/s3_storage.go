@@ -0,0 +1,481 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/config"
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"go.uber.org/zap"
+)
+
+const (
+	// S3 refuses presigned URLs valid for longer than a week
+	maxPresignExpiry = 7 * 24 * time.Hour
+	// DeleteObjects takes at most this many keys per request
+	maxDeleteBatch = 1000
+)
+
+// S3Storage keeps media in an S3-compatible bucket so every API instance sees the
+// same files. Keys use the same user_<ID>/<filename> layout as LocalStorage.
+type S3Storage struct {
+	cfg        config.S3Config
+	endpoint   *url.URL
+	signer     *s3Signer
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+func NewS3Storage(cfg config.S3Config) (*S3Storage, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT: %w", err)
+	}
+	return &S3Storage{
+		cfg:      cfg,
+		endpoint: endpoint,
+		signer: &s3Signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			region:          cfg.Region,
+		},
+		// No overall timeout, large uploads are bounded by the request context instead
+		httpClient: &http.Client{},
+		now:        time.Now,
+	}, nil
+}
+
+func (s *S3Storage) Upload(ctx context.Context, file io.Reader, filename string, contentType string, size int64, userId uint) (string, error) {
+	key := fmt.Sprintf("user_%d/%s", userId, filename)
+	if err := s.Put(ctx, file, key, contentType, size); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, file io.Reader, path string, contentType string, size int64) error {
+	var err error
+	if size < 0 || size > int64(s.cfg.MultipartThresholdMB)<<20 {
+		err = s.uploadMultipart(ctx, path, file, contentType)
+	} else {
+		err = s.putObject(ctx, path, file, contentType, size)
+	}
+	if err != nil {
+		shared.Log.Error("failed to upload object",
+			zap.String("bucket", s.cfg.Bucket),
+			zap.String("key", path),
+			zap.Error(err))
+	}
+	return err
+}
+
+func (s *S3Storage) putObject(ctx context.Context, key string, body io.Reader, contentType string, size int64) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// uploadMultipart streams the file in PartSizeMB chunks, so memory use stays at one
+// part however large the upload is. A failed upload is aborted to free the stored parts.
+func (s *S3Storage) uploadMultipart(ctx context.Context, key string, file io.Reader, contentType string) error {
+	uploadID, err := s.createMultipartUpload(ctx, key, contentType)
+	if err != nil {
+		return err
+	}
+
+	parts, err := s.uploadParts(ctx, key, uploadID, file)
+	if err == nil {
+		err = s.completeMultipartUpload(ctx, key, uploadID, parts)
+	}
+	if err != nil {
+		// The caller's context may be the reason we failed, the abort gets its own
+		abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if abortErr := s.abortMultipartUpload(abortCtx, key, uploadID); abortErr != nil {
+			shared.Log.Warn("failed to abort multipart upload",
+				zap.String("key", key),
+				zap.String("uploadID", uploadID),
+				zap.Error(abortErr))
+		}
+		return err
+	}
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3Storage) createMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	req, err := s.newRequest(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode multipart upload: %w", err)
+	}
+	if result.UploadID == "" {
+		return "", errors.New("s3 returned no upload ID")
+	}
+	return result.UploadID, nil
+}
+
+func (s *S3Storage) uploadParts(ctx context.Context, key, uploadID string, file io.Reader) ([]completedPart, error) {
+	buf := make([]byte, s.cfg.PartSizeMB<<20)
+	var parts []completedPart
+	for number := 1; ; number++ {
+		n, readErr := io.ReadFull(file, buf)
+		if readErr != nil && !errors.Is(readErr, io.EOF) && !errors.Is(readErr, io.ErrUnexpectedEOF) {
+			return nil, readErr
+		}
+		// An empty file still needs one (empty) part to complete
+		if n == 0 && len(parts) > 0 {
+			break
+		}
+
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		req, err := s.newRequest(ctx, http.MethodPut, key, query, bytes.NewReader(buf[:n]))
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = int64(n)
+		resp, err := s.do(req, hashHex(buf[:n]))
+		if err != nil {
+			return nil, fmt.Errorf("upload part %d: %w", number, err)
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		if readErr != nil {
+			break // Short read, that was the last part
+		}
+	}
+	return parts, nil
+}
+
+func (s *S3Storage) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/xml")
+	resp, err := s.do(req, hashHex(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// S3 may report a failure with a 200 status once it has started the response
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(respBody, []byte("<Error>")) {
+		return parseS3Error(resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+func (s *S3Storage) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetURL returns the public link stored in messages. The bucket or PublicURL has to
+// allow anonymous reads for it to work, GetSignedURL does not need that.
+func (s *S3Storage) GetURL(ctx context.Context, path string) (string, error) {
+	if s.cfg.PublicURL != "" {
+		return s.cfg.PublicURL + "/" + canonicalPath(path), nil
+	}
+	return s.objectURL(path, nil).String(), nil
+}
+
+// GetSignedURL returns a presigned GET link to the object, valid for expires (at most a week)
+func (s *S3Storage) GetSignedURL(ctx context.Context, path string, expires time.Duration) (string, error) {
+	if expires <= 0 {
+		return "", errors.New("signed URL expiry must be positive")
+	}
+	if expires > maxPresignExpiry {
+		expires = maxPresignExpiry
+	}
+	return s.signer.presign(http.MethodGet, s.objectURL(path, nil), expires, s.now()).String(), nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, path string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) DeleteUserFiles(ctx context.Context, userId uint) error {
+	var keys []string
+	err := s.listObjects(ctx, fmt.Sprintf("user_%d/", userId), func(obj s3Object) error {
+		keys = append(keys, obj.Key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(keys); start += maxDeleteBatch {
+		end := min(start+maxDeleteBatch, len(keys))
+		if err := s.deleteObjects(ctx, keys[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Storage) deleteObjects(ctx context.Context, keys []string) error {
+	type object struct {
+		Key string `xml:"Key"`
+	}
+	request := struct {
+		XMLName xml.Name `xml:"Delete"`
+		Quiet   bool     `xml:"Quiet"`
+		Objects []object `xml:"Object"`
+	}{Quiet: true}
+	for _, key := range keys {
+		request.Objects = append(request.Objects, object{Key: key})
+	}
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPost, "", url.Values{"delete": {""}}, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(body)
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := s.do(req, hashHex(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Quiet mode only lists the keys that failed
+	var result struct {
+		Errors []struct {
+			Key     string `xml:"Key"`
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		} `xml:"Error"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decode delete result: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		first := result.Errors[0]
+		return fmt.Errorf("s3 delete of %d objects failed, first %s: %s %s", len(result.Errors), first.Key, first.Code, first.Message)
+	}
+	return nil
+}
+
+// Walk lists every object in the bucket
+func (s *S3Storage) Walk(ctx context.Context, fn func(file domain.StoredFile) error) error {
+	return s.listObjects(ctx, "", func(obj s3Object) error {
+		return fn(domain.StoredFile{Path: obj.Key, Size: obj.Size, ModifiedAt: obj.LastModified})
+	})
+}
+
+func (s *S3Storage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) PathFromURL(rawURL string) (string, bool) {
+	base, _ := s.GetURL(context.Background(), "")
+	path, ok := strings.CutPrefix(rawURL, base)
+	if !ok || path == "" {
+		return "", false
+	}
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	if strings.HasPrefix(path, "/") || strings.Contains("/"+path+"/", "/../") {
+		return "", false
+	}
+	return path, true
+}
+
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// listObjects calls fn for every object under prefix, following continuation tokens.
+// An error returned by fn ends the listing.
+func (s *S3Storage) listObjects(ctx context.Context, prefix string, fn func(s3Object) error) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.do(req, emptyPayloadHash)
+		if err != nil {
+			return err
+		}
+
+		var page struct {
+			Contents              []s3Object `xml:"Contents"`
+			IsTruncated           bool       `xml:"IsTruncated"`
+			NextContinuationToken string     `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("decode object list: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// objectURL addresses key in the bucket, an empty key addresses the bucket itself
+func (s *S3Storage) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	path := "/" + key
+	if s.cfg.UsePathStyle {
+		path = "/" + s.cfg.Bucket + path
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(s.endpoint.Path, "/") + path
+	u.RawPath = canonicalPath(u.Path)
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+	return &u
+}
+
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), body)
+}
+
+// do signs and sends the request, turning S3 error responses into errors. A missing
+// object is reported as fs.ErrNotExist like LocalStorage does.
+func (s *S3Storage) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.signer.sign(req, payloadHash, s.now())
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := parseS3Error(resp.StatusCode, body)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %w", fs.ErrNotExist, apiErr)
+	}
+	return nil, apiErr
+}
+
+type s3Error struct {
+	Status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	return fmt.Sprintf("s3 %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+func parseS3Error(status int, body []byte) error {
+	apiErr := &s3Error{Status: status}
+	if err := xml.Unmarshal(body, apiErr); err != nil || apiErr.Code == "" {
+		apiErr.Code = http.StatusText(status)
+	}
+	return apiErr
+}
@@ -0,0 +1,41 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErasedIdentitiesAndVerificationsAreGone(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := database.NewUserRepository(db)
+	identityRepo := database.NewExternalIdentityRepository(db)
+	verificationRepo := database.NewEmailVerificationRepository(db)
+	ctx := context.Background()
+
+	user, err := userRepo.Create(ctx, "erased", "erased@example.com", "hash")
+	require.NoError(t, err)
+	identity := &domain.ExternalIdentity{UserID: user.ID, Issuer: "https://idp", Subject: "abc", Email: "erased@example.com", LastLoginAt: time.Now()}
+	_, err = identityRepo.Create(ctx, identity)
+	require.NoError(t, err)
+	_, err = verificationRepo.Create(ctx, user.ID, "erased@example.com", "tokenhash", domain.VerificationEmail, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, identityRepo.DeleteForUser(ctx, user.ID))
+	require.NoError(t, verificationRepo.DeleteForUser(ctx, user.ID))
+
+	var identities, verifications int64
+	require.NoError(t, db.Unscoped().Model(&domain.ExternalIdentity{}).Where("user_id = ?", user.ID).Count(&identities).Error)
+	require.NoError(t, db.Unscoped().Model(&domain.EmailVerification{}).Where("user_id = ?", user.ID).Count(&verifications).Error)
+	assert.Zero(t, identities)
+	assert.Zero(t, verifications)
+
+	// The same person can sign in through single sign-on again
+	_, err = identityRepo.Create(ctx, &domain.ExternalIdentity{UserID: user.ID, Issuer: "https://idp", Subject: "abc", LastLoginAt: time.Now()})
+	assert.NoError(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/config"
 	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/database"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/realtime"
@@ -25,6 +26,11 @@ func TestBroadcastMessaging(t *testing.T) {
 		userRepo,
 		notifier,
 		nil,
+		config.MessagingConfig{},
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 
 	// Create test users
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/config"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/database"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,6 +22,11 @@ func TestErrorScenarios(t *testing.T) {
 		userRepo,
 		nil,
 		nil,
+		config.MessagingConfig{},
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 
 	t.Run("SendToNonexistentUser", func(t *testing.T) {
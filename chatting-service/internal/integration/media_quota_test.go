@@ -0,0 +1,33 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/database"
+	"github.com/AmeerHeiba/chatting-service/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMediaUsageAboveTwoGigabytes(t *testing.T) {
+	db := setupTestDB(t)
+	mediaRepo := database.NewMediaRepository(db)
+	ctx := context.Background()
+
+	const gigabyte = int64(1024 * 1024 * 1024)
+	quota := 5 * gigabyte // MEDIA_USER_QUOTAS_MB=1:5120
+
+	require.NoError(t, mediaRepo.AddUsage(ctx, 1, 3*gigabyte, quota))
+	assert.ErrorIs(t, mediaRepo.AddUsage(ctx, 1, 3*gigabyte, quota), shared.ErrStorageQuotaExceeded)
+	require.NoError(t, mediaRepo.AddUsage(ctx, 1, 2*gigabyte, quota))
+
+	used, err := mediaRepo.FindUsage(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 5*gigabyte, used)
+
+	require.NoError(t, mediaRepo.AddUsage(ctx, 1, -3*gigabyte, 0))
+	used, err = mediaRepo.FindUsage(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2*gigabyte, used)
+}
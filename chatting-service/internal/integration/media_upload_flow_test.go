@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/config"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/database"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/storage"
 	"github.com/stretchr/testify/assert"
@@ -19,8 +20,14 @@ func TestMediaUploadFlow(t *testing.T) {
 
 	// Setup storage
 	tempDir := t.TempDir()
-	storage := storage.NewLocalStorage(tempDir, "http://localhost:8080/media")
-	mediaService := application.NewMediaService(storage)
+	storage := storage.NewLocalStorage(tempDir, "http://localhost:8080/media", "test-signing-key")
+	mediaService := application.NewMediaService(application.MediaServiceDeps{
+		Storage:      storage,
+		Messages:     database.NewMessageRepository(db),
+		Media:        database.NewMediaRepository(db),
+		Uploads:      config.UploadConfig{MaxSizeMB: 10, AllowedTypes: map[string]int{"text/plain": 0}},
+		Transactions: database.NewTransactionManager(db),
+	})
 
 	// Create test user
 	user, err := userRepo.Create(context.Background(), "mediauser", "media@test.com", "password")
@@ -44,6 +51,7 @@ func TestMediaUploadFlow(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, mediaResp.URL, "http://localhost:8080/media/user_")
 	assert.Equal(t, int64(len(fileContent)), mediaResp.Size)
+	assert.NotZero(t, mediaResp.ID)
 
 	// Verify file exists
 	filePath := filepath.Join(tempDir, strings.TrimPrefix(mediaResp.URL, "http://localhost:8080/media/"))
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/AmeerHeiba/chatting-service/internal/application"
+	"github.com/AmeerHeiba/chatting-service/internal/config"
 	"github.com/AmeerHeiba/chatting-service/internal/domain"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/database"
 	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/realtime"
@@ -28,6 +29,11 @@ func TestMessageFlow(t *testing.T) {
 		userRepo,
 		notifier,
 		nil, // no media uploader for this test
+		config.MessagingConfig{},
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 
 	// Create test users
@@ -56,6 +56,7 @@ func runMigrations(db *gorm.DB) error {
 		`CREATE TYPE message_status AS ENUM ('sent', 'delivered', 'read')`,
 		`CREATE TYPE user_status AS ENUM ('online', 'offline', 'away')`,
 		`CREATE TYPE message_type AS ENUM ('direct', 'broadcast')`,
+		`CREATE TYPE user_role AS ENUM ('user', 'admin')`,
 	}
 
 	for _, e := range enums {
@@ -68,7 +69,58 @@ func runMigrations(db *gorm.DB) error {
 		&domain.User{},
 		&domain.Message{},
 		&domain.MessageRecipient{},
+		&domain.EmailVerification{},
+		&domain.LoginAttempt{},
+		&domain.APIToken{},
+		&domain.ExternalIdentity{},
+		&domain.OIDCLoginState{},
+		&domain.AuditEvent{},
+		&domain.UserBlock{},
+		&domain.NotificationPreferences{},
+		&domain.Mute{},
+		&domain.Contact{},
+		&domain.DataExport{},
+		&domain.AnnouncementChannel{},
+		&domain.AnnouncementPoster{},
+		&domain.Announcement{},
+		&domain.AnnouncementReadState{},
+		&domain.Media{},
+		&domain.MediaBlob{},
+		&domain.MediaUsage{},
+		&domain.MediaUploadSession{},
 	}
 
-	return db.AutoMigrate(models...)
+	if err := db.AutoMigrate(models...); err != nil {
+		return err
+	}
+
+	guards := []string{
+		`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_events is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events`,
+		`CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+		FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+	}
+
+	for _, g := range guards {
+		if err := db.Exec(g).Error; err != nil {
+			return err
+		}
+	}
+
+	indexes := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (username gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_users_display_name_trgm ON users USING gin (display_name gin_trgm_ops)`,
+	}
+
+	for _, idx := range indexes {
+		if err := db.Exec(idx).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
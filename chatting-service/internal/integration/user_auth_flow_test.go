@@ -14,11 +14,12 @@ import (
 func TestCompleteAuthFlow(t *testing.T) {
 	db := setupTestDB(t)
 	userRepo := database.NewUserRepository(db)
-	userService := application.NewUserService(userRepo)
+	userService := application.NewUserService(userRepo, nil, nil, nil, nil, nil)
 
 	authCfg := config.LoadAuthConfig()
 	jwtProvider := auth.NewJWTProvider(authCfg)
-	authService := application.NewAuthService(userRepo, userService, jwtProvider)
+	loginGuard := application.NewLoginGuard(database.NewLoginAttemptRepository(db), config.LoadLoginProtectionConfig(), nil)
+	authService := application.NewAuthService(userRepo, userService, jwtProvider, loginGuard, nil, nil)
 
 	// Test registration
 	registerResp, err := authService.Register(
@@ -36,6 +37,7 @@ func TestCompleteAuthFlow(t *testing.T) {
 		context.Background(),
 		"testuser",
 		"password123",
+		"127.0.0.1",
 	)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, loginResp.AccessToken)
@@ -0,0 +1,40 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AmeerHeiba/chatting-service/internal/domain"
+	"github.com/AmeerHeiba/chatting-service/internal/infrastructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserDirectorySearch(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := database.NewUserRepository(db)
+	ctx := context.Background()
+
+	jane, err := userRepo.Create(ctx, "jdoe", "jane@example.com", "hash")
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&domain.User{}).Where("id = ?", jane.ID).Update("display_name", "Jane Doe").Error)
+	_, err = userRepo.Create(ctx, "janet", "janet@example.com", "hash")
+	require.NoError(t, err)
+	_, err = userRepo.Create(ctx, "bob", "bob@example.com", "hash")
+	require.NoError(t, err)
+
+	// Matches the display name of jdoe and the username of janet
+	users, err := userRepo.Search(ctx, domain.UserSearch{Query: "JANE", Limit: 10})
+	require.NoError(t, err)
+	var usernames []string
+	for _, u := range users {
+		usernames = append(usernames, u.Username)
+	}
+	assert.Equal(t, []string{"janet", "jdoe"}, usernames)
+
+	users, err = userRepo.Search(ctx, domain.UserSearch{Query: "ne do", Limit: 10})
+	require.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, "Jane Doe", users[0].DisplayName)
+	}
+}
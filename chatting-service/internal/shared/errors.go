@@ -18,6 +18,13 @@ type Error struct {
 	Details interface{} `json:"details,omitempty"`
 }
 
+// RetryAfterDetails is attached to throttling errors, the error handler
+// also exposes it through the Retry-After header
+type RetryAfterDetails struct {
+	RetryAfterSeconds int    `json:"retry_after_seconds"`
+	Reason            string `json:"reason,omitempty"`
+}
+
 func (e Error) Error() string {
 	return e.Message
 }
@@ -52,6 +59,24 @@ var (
 		Status:  http.StatusForbidden,
 	}
 
+	ErrInsufficientScope = Error{
+		Code:    "INSUFFICIENT_SCOPE",
+		Message: "API token does not grant access to this resource",
+		Status:  http.StatusForbidden,
+	}
+
+	ErrBlocked = Error{
+		Code:    "BLOCKED",
+		Message: "You cannot message this user",
+		Status:  http.StatusForbidden,
+	}
+
+	ErrDirectMessageNotAllowed = Error{
+		Code:    "DIRECT_MESSAGE_NOT_ALLOWED",
+		Message: "You can only message your contacts",
+		Status:  http.StatusForbidden,
+	}
+
 	ErrNotFound = Error{
 		Code:    "NOT_FOUND",
 		Message: "Resource not found",
@@ -69,6 +94,16 @@ var (
 		Message: "Validation failed",
 		Status:  http.StatusBadRequest,
 	}
+	ErrUnsupportedMediaType = Error{
+		Code:    "UNSUPPORTED_MEDIA_TYPE",
+		Message: "File type is not allowed",
+		Status:  http.StatusUnsupportedMediaType,
+	}
+	ErrStorageQuotaExceeded = Error{
+		Code:    "STORAGE_QUOTA_EXCEEDED",
+		Message: "Storage quota exceeded",
+		Status:  http.StatusRequestEntityTooLarge,
+	}
 
 	ErrUsernameTooShort = Error{
 		Code:    "USERNAME_TOO_SHORT",
@@ -82,7 +117,7 @@ var (
 	}
 	ErrPasswordTooWeak = Error{
 		Code:    "PASSWORD_TOO_WEAK",
-		Message: "Password must be at least 8 characters",
+		Message: "Password does not meet the password policy",
 		Status:  http.StatusBadRequest,
 	}
 
@@ -118,6 +153,30 @@ var (
 		Status:  http.StatusBadRequest,
 	}
 
+	ErrEmailNotVerified = Error{
+		Code:    "EMAIL_NOT_VERIFIED",
+		Message: "Email address must be verified first",
+		Status:  http.StatusForbidden,
+	}
+
+	ErrAccountSuspended = Error{
+		Code:    "ACCOUNT_SUSPENDED",
+		Message: "Account is suspended",
+		Status:  http.StatusForbidden,
+	}
+
+	ErrPasswordResetRequired = Error{
+		Code:    "PASSWORD_RESET_REQUIRED",
+		Message: "Password must be changed before signing in",
+		Status:  http.StatusForbidden,
+	}
+
+	ErrInvalidVerificationToken = Error{
+		Code:    "INVALID_VERIFICATION_TOKEN",
+		Message: "Verification link is invalid or expired",
+		Status:  http.StatusBadRequest,
+	}
+
 	// Database errors
 	ErrDatabaseOperation = Error{
 		Code:    "DATABASE_ERROR",
@@ -143,6 +202,11 @@ var (
 		Message: "Too many requests",
 		Status:  http.StatusTooManyRequests,
 	}
+	ErrAccountLocked = Error{
+		Code:    "ACCOUNT_LOCKED",
+		Message: "Too many failed login attempts, try again later",
+		Status:  http.StatusTooManyRequests,
+	}
 	ErrServiceUnavailable = Error{
 		Code:    "SERVICE_UNAVAILABLE",
 		Message: "Service temporarily unavailable",
@@ -0,0 +1,29 @@
+package shared
+
+import "context"
+
+type requestMetaKey struct{}
+
+// RequestMetaKey stores RequestMeta in Fiber locals. fasthttp exposes locals through
+// ctx.Value, so services reading c.Context() find it the same way as a plain context.
+var RequestMetaKey = requestMetaKey{}
+
+// RequestMeta describes the HTTP request a service call originated from
+type RequestMeta struct {
+	RequestID string
+	ClientIP  string
+	UserAgent string
+}
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, RequestMetaKey, meta)
+}
+
+// RequestMetaFrom returns the request metadata, empty for calls not made from a request
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	if ctx == nil {
+		return RequestMeta{}
+	}
+	meta, _ := ctx.Value(RequestMetaKey).(RequestMeta)
+	return meta
+}